@@ -0,0 +1,89 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/kierank/herald/config"
+	"github.com/kierank/herald/ssh"
+	"github.com/kierank/herald/store"
+	"github.com/spf13/cobra"
+)
+
+func rateLimitCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rate-limit <fingerprint> <emails_per_minute>",
+		Short: "Override a user's email rate limit",
+		Long: `Set how many digest emails per minute a user may send. Use 0 to restore
+the default of one per minute. The fingerprint is the SHA256 key fingerprint
+shown when the user runs ssh with no command.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			perMinute, err := strconv.Atoi(args[1])
+			if err != nil || perMinute < 0 {
+				return fmt.Errorf("invalid emails per minute %q", args[1])
+			}
+
+			cfg, err := config.LoadAppConfig(cfgFile)
+			if err != nil {
+				return withExitCode(exitConfig, fmt.Errorf("failed to load config: %w", err))
+			}
+
+			db, err := store.Open(cfg.DBPath)
+			if err != nil {
+				return withExitCode(exitDatabase, fmt.Errorf("failed to open database: %w", err))
+			}
+			defer func() { _ = db.Close() }()
+
+			if err := db.Migrate(); err != nil {
+				return withExitCode(exitDatabase, fmt.Errorf("failed to migrate database: %w", err))
+			}
+
+			if err := db.SetUserEmailRateLimit(cmd.Context(), args[0], perMinute); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return fmt.Errorf("no user with fingerprint %s", args[0])
+				}
+				return err
+			}
+
+			if perMinute == 0 {
+				logger.Info("restored default email rate limit", "fingerprint", args[0])
+			} else {
+				logger.Info("set email rate limit", "fingerprint", args[0], "per_minute", perMinute)
+			}
+			return nil
+		},
+	}
+}
+
+func rotateHostKeyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rotate-hostkey",
+		Short: "Replace the SSH host key with a new one",
+		Long: `Generate a new SSH host key at host_key_path, keeping the old key next to
+it with a timestamped .bak suffix. Restart the server to start using the new
+key. Clients that connected before will see a changed host key warning and
+must remove the old entry from known_hosts.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadAppConfig(cfgFile)
+			if err != nil {
+				return withExitCode(exitConfig, fmt.Errorf("failed to load config: %w", err))
+			}
+
+			rotation, err := ssh.RotateHostKey(cfg.HostKeyPath)
+			if err != nil {
+				return withExitCode(exitHostKey, fmt.Errorf("failed to rotate host key: %w", err))
+			}
+
+			if rotation.BackupPath != "" {
+				logger.Info("backed up old host key", "path", rotation.BackupPath, "fingerprint", rotation.OldFingerprint)
+			}
+			logger.Info("wrote new host key", "path", cfg.HostKeyPath, "fingerprint", rotation.NewFingerprint)
+			logger.Warn("restart the server to use the new key; clients will see a changed host key and must update known_hosts")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kierank/herald/config"
+	"github.com/kierank/herald/email"
+	"github.com/kierank/herald/ssh"
+	"github.com/spf13/cobra"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// Exit codes for distinct classes of startup failure
+const (
+	exitFailure  = 1 // unclassified runtime error
+	exitConfig   = 2 // config file unreadable or unparseable
+	exitOrigin   = 3 // origin URL invalid
+	exitDatabase = 4 // database path unusable
+	exitHostKey  = 5 // SSH host key unusable
+	exitSMTP     = 6 // SMTP misconfigured or unreachable
+	exitTLS      = 7 // TLS certificate or key unusable
+)
+
+const (
+	// maxFetchRetries bounds fetch_retries so a dead feed can't stall a run
+	maxFetchRetries = 5
+	// maxBackfillPages bounds backfill_pages so uploads stay quick
+	maxBackfillPages = 20
+)
+
+// exitError pairs an error with the process exit code it should produce
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// exitCodeFor returns the exit code for an error returned from a command
+func exitCodeFor(err error) int {
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+	return exitFailure
+}
+
+func checkCmd() *cobra.Command {
+	var checkSMTP bool
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Validate configuration without starting the server",
+		Long: `Check the config file, origin URL, database path, host key path, and TLS
+files, exiting non-zero with a distinct code for each class of failure.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadAppConfig(cfgFile)
+			if err != nil {
+				return withExitCode(exitConfig, fmt.Errorf("failed to load config: %w", err))
+			}
+
+			if err := validateStartup(cfg); err != nil {
+				return err
+			}
+
+			if checkSMTP {
+				if _, err := newValidatedMailer(cfg); err != nil {
+					return err
+				}
+			}
+
+			logger.Info("configuration ok")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&checkSMTP, "smtp", false, "also connect to the SMTP server and authenticate")
+	return cmd
+}
+
+// validateStartup checks the parts of the app config that can be verified
+// locally before any listeners are started.
+func validateStartup(cfg *config.AppConfig) error {
+	if err := checkOrigin(cfg.Origin); err != nil {
+		return withExitCode(exitOrigin, fmt.Errorf("invalid origin %q: %w", cfg.Origin, err))
+	}
+	if err := checkWritableFile(cfg.DBPath); err != nil {
+		return withExitCode(exitDatabase, fmt.Errorf("database path %q is not usable: %w", cfg.DBPath, err))
+	}
+	if err := checkHostKey(cfg.HostKeyPath); err != nil {
+		return withExitCode(exitHostKey, fmt.Errorf("host key path %q is not usable: %w", cfg.HostKeyPath, err))
+	}
+	if err := checkTLS(cfg); err != nil {
+		return withExitCode(exitTLS, err)
+	}
+	if err := cfg.CheckAuditEmailBodies(); err != nil {
+		return withExitCode(exitConfig, err)
+	}
+	if err := cfg.CheckFutureItems(); err != nil {
+		return withExitCode(exitConfig, err)
+	}
+	return nil
+}
+
+// configCheck is one line of a validate report; err is nil when it passed
+type configCheck struct {
+	name string
+	err  error
+}
+
+// validateAppConfig runs every local check against cfg and reports each
+// result, rather than stopping at the first failure like validateStartup
+func validateAppConfig(cfg *config.AppConfig) []configCheck {
+	checks := []configCheck{
+		{"origin", checkOrigin(cfg.Origin)},
+		{"ssh_port", checkPort(cfg.SSHPort)},
+		{"http_port", checkPort(cfg.HTTPPort)},
+		{"external_ssh_port", checkPort(cfg.ExternalSSHPort)},
+		{"db_path", checkWritableFile(cfg.DBPath)},
+		{"host_key_path", checkHostKey(cfg.HostKeyPath)},
+		{"smtp.host", checkRequired(cfg.SMTP.Host)},
+		{"smtp.port", checkPort(cfg.SMTP.Port)},
+		{"smtp.from", checkAddress(cfg.SMTP.From)},
+		{"smtp.user", checkSMTPAuth(cfg.SMTP.User, cfg.SMTP.Pass)},
+		{"future_items", cfg.CheckFutureItems()},
+	}
+	if cfg.SSHPort == cfg.HTTPPort && cfg.SSHPort != 0 {
+		checks = append(checks, configCheck{"ports", fmt.Errorf("ssh_port and http_port are both %d", cfg.SSHPort)})
+	}
+	if cfg.TLSEnabled() {
+		checks = append(checks, configCheck{"tls", checkTLS(cfg)})
+	}
+	if cfg.FeedProxyURL != "" {
+		checks = append(checks, configCheck{"feed_proxy_url", config.ValidateFeedProxyURL(cfg.FeedProxyURL)})
+	}
+	if cfg.AuditEmailBodies != "" {
+		checks = append(checks, configCheck{"audit_email_bodies", cfg.CheckAuditEmailBodies()})
+	}
+	if cfg.FetchRetries < 0 || cfg.FetchRetries > maxFetchRetries {
+		checks = append(checks, configCheck{"fetch_retries", fmt.Errorf("must be between 0 and %d, got %d", maxFetchRetries, cfg.FetchRetries)})
+	}
+	if cfg.BackfillPages < 0 || cfg.BackfillPages > maxBackfillPages {
+		checks = append(checks, configCheck{"backfill_pages", fmt.Errorf("must be between 0 and %d, got %d", maxBackfillPages, cfg.BackfillPages)})
+	}
+	if cfg.FetchRetryBackoff < 0 {
+		checks = append(checks, configCheck{"fetch_retry_backoff", fmt.Errorf("must not be negative, got %s", cfg.FetchRetryBackoff)})
+	}
+	if len(cfg.AllowedCommands) > 0 {
+		checks = append(checks, configCheck{"allowed_commands", checkCommands(cfg.AllowedCommands)})
+	}
+	if len(cfg.DisabledCommands) > 0 {
+		checks = append(checks, configCheck{"disabled_commands", checkCommands(cfg.DisabledCommands)})
+	}
+	if cfg.MOTDFile != "" {
+		_, err := cfg.MOTDText()
+		checks = append(checks, configCheck{"motd_file", err})
+	}
+	if cfg.SMTP.DKIMCanonicalization != "" {
+		_, _, err := email.ParseDKIMCanonicalization(cfg.SMTP.DKIMCanonicalization)
+		checks = append(checks, configCheck{"smtp.dkim_canonicalization", err})
+	}
+	if len(cfg.SMTP.DKIMKeys) > 0 {
+		_, err := email.LoadDKIMKey(mailerConfig(cfg))
+		checks = append(checks, configCheck{"smtp.dkim_keys", err})
+	}
+	if len(cfg.SMTP.DKIMHeaders) > 0 {
+		_, err := email.DKIMHeaderKeys(cfg.SMTP.DKIMHeaders)
+		checks = append(checks, configCheck{"smtp.dkim_headers", err})
+	}
+	return checks
+}
+
+// printValidateReport writes one line per check and returns how many failed
+func printValidateReport(w io.Writer, path string, checks []configCheck) int {
+	failed := 0
+	_, _ = fmt.Fprintf(w, "Validating %s\n", path)
+	for _, c := range checks {
+		if c.err != nil {
+			failed++
+			_, _ = fmt.Fprintf(w, "  FAIL  %s: %v\n", c.name, c.err)
+		} else {
+			_, _ = fmt.Fprintf(w, "  ok    %s\n", c.name)
+		}
+	}
+	if failed > 0 {
+		_, _ = fmt.Fprintf(w, "%d problem(s) found\n", failed)
+	} else {
+		_, _ = fmt.Fprintln(w, "No problems found")
+	}
+	return failed
+}
+
+func checkTLS(cfg *config.AppConfig) error {
+	if !cfg.TLSEnabled() {
+		return nil
+	}
+	if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+		return fmt.Errorf("tls_cert_file and tls_key_file must be set together")
+	}
+	if _, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile); err != nil {
+		return fmt.Errorf("invalid TLS certificate: %w", err)
+	}
+	return nil
+}
+
+func checkPort(port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("%d is outside 1-65535", port)
+	}
+	return nil
+}
+
+// checkCommands reports command names that aren't SSH commands, which would
+// otherwise be ignored silently
+func checkCommands(names []string) error {
+	for _, name := range names {
+		if !ssh.KnownCommand(strings.TrimSpace(name)) {
+			return fmt.Errorf("unknown command %q", name)
+		}
+	}
+	return nil
+}
+
+func checkRequired(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("required")
+	}
+	return nil
+}
+
+func checkAddress(addr string) error {
+	if err := checkRequired(addr); err != nil {
+		return err
+	}
+	if _, err := mail.ParseAddress(addr); err != nil {
+		return fmt.Errorf("invalid address %q", addr)
+	}
+	return nil
+}
+
+// checkSMTPAuth requires the SMTP user and password to be set together
+func checkSMTPAuth(user, pass string) error {
+	if (user == "") != (pass == "") {
+		return fmt.Errorf("user and pass must be set together")
+	}
+	return nil
+}
+
+// mailerConfig converts the app's SMTP settings for the email package
+func mailerConfig(cfg *config.AppConfig) email.SMTPConfig {
+	keys := make([]email.DKIMKey, len(cfg.SMTP.DKIMKeys))
+	for i, k := range cfg.SMTP.DKIMKeys {
+		keys[i] = email.DKIMKey{Selector: k.Selector, PrivateKey: k.PrivateKey, PrivateKeyFile: k.PrivateKeyFile}
+	}
+	return email.SMTPConfig{
+		Host:                 cfg.SMTP.Host,
+		Port:                 cfg.SMTP.Port,
+		User:                 cfg.SMTP.User,
+		Pass:                 cfg.SMTP.Pass,
+		From:                 cfg.SMTP.From,
+		DKIMPrivateKey:       cfg.SMTP.DKIMPrivateKey,
+		DKIMPrivateKeyFile:   cfg.SMTP.DKIMPrivateKeyFile,
+		DKIMSelector:         cfg.SMTP.DKIMSelector,
+		DKIMDomain:           cfg.SMTP.DKIMDomain,
+		DKIMCanonicalization: cfg.SMTP.DKIMCanonicalization,
+		DKIMHeaders:          cfg.SMTP.DKIMHeaders,
+		DKIMKeys:             keys,
+		SendTimeout:          cfg.SMTP.SendTimeout,
+	}
+}
+
+// newValidatedMailer builds the mailer and verifies SMTP connectivity and auth
+func newValidatedMailer(cfg *config.AppConfig) (*email.Mailer, error) {
+	mailer, err := email.NewMailer(mailerConfig(cfg), cfg.LinkOrigin())
+	if err != nil {
+		return nil, withExitCode(exitSMTP, fmt.Errorf("failed to create mailer: %w", err))
+	}
+
+	if err := mailer.ValidateConfig(); err != nil {
+		return nil, withExitCode(exitSMTP, fmt.Errorf("SMTP validation failed: %w", err))
+	}
+
+	return mailer, nil
+}
+
+func checkOrigin(origin string) error {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("missing host")
+	}
+	return nil
+}
+
+// checkWritableFile verifies an existing file can be opened for writing, or
+// that its directory accepts new files if it doesn't exist yet.
+func checkWritableFile(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		f, err := os.OpenFile(path, os.O_RDWR, 0) //nolint:gosec // Path from app config
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return checkWritableDir(filepath.Dir(path))
+}
+
+func checkWritableDir(dir string) error {
+	f, err := os.CreateTemp(dir, ".herald-check-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	_ = f.Close()
+	return os.Remove(name)
+}
+
+// checkHostKey verifies an existing host key parses, or that one can be
+// generated at the configured path.
+func checkHostKey(path string) error {
+	data, err := os.ReadFile(path) //nolint:gosec // Path from app config
+	if errors.Is(err, os.ErrNotExist) {
+		return checkWritableDir(filepath.Dir(path))
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := gossh.ParsePrivateKey(data); err != nil {
+		return fmt.Errorf("parse private key: %w", err)
+	}
+	return nil
+}
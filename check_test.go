@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kierank/herald/config"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		origin  string
+		wantErr bool
+	}{
+		{"http://localhost:8080", false},
+		{"https://herald.example.com", false},
+		{"ftp://example.com", true},
+		{"https://", true},
+		{"://bad", true},
+	}
+
+	for _, tt := range tests {
+		if err := checkOrigin(tt.origin); (err != nil) != tt.wantErr {
+			t.Errorf("checkOrigin(%q) error = %v, wantErr %v", tt.origin, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateStartupExitCodes(t *testing.T) {
+	dir := t.TempDir()
+
+	base := func() *config.AppConfig {
+		cfg := config.DefaultAppConfig()
+		cfg.Origin = "http://localhost:8080"
+		cfg.DBPath = filepath.Join(dir, "herald.db")
+		cfg.HostKeyPath = filepath.Join(dir, "host_key")
+		return cfg
+	}
+
+	if err := validateStartup(base()); err != nil {
+		t.Fatalf("validateStartup() error = %v", err)
+	}
+
+	badOrigin := base()
+	badOrigin.Origin = "not a url"
+	if code := exitCodeFor(validateStartup(badOrigin)); code != exitOrigin {
+		t.Errorf("bad origin exit code = %d, want %d", code, exitOrigin)
+	}
+
+	badDB := base()
+	badDB.DBPath = filepath.Join(dir, "missing", "herald.db")
+	if code := exitCodeFor(validateStartup(badDB)); code != exitDatabase {
+		t.Errorf("bad db path exit code = %d, want %d", code, exitDatabase)
+	}
+
+	halfTLS := base()
+	halfTLS.TLSCertFile = filepath.Join(dir, "cert.pem")
+	if code := exitCodeFor(validateStartup(halfTLS)); code != exitTLS {
+		t.Errorf("missing TLS key exit code = %d, want %d", code, exitTLS)
+	}
+
+	badKey := base()
+	if err := os.WriteFile(badKey.HostKeyPath, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if code := exitCodeFor(validateStartup(badKey)); code != exitHostKey {
+		t.Errorf("bad host key exit code = %d, want %d", code, exitHostKey)
+	}
+}
+
+func TestExitCodeForUnclassified(t *testing.T) {
+	if code := exitCodeFor(errors.New("boom")); code != exitFailure {
+		t.Errorf("exitCodeFor() = %d, want %d", code, exitFailure)
+	}
+}
+
+func TestValidateAppConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	base := func() *config.AppConfig {
+		cfg := config.DefaultAppConfig()
+		cfg.Origin = "http://localhost:8080"
+		cfg.DBPath = filepath.Join(dir, "herald.db")
+		cfg.HostKeyPath = filepath.Join(dir, "host_key")
+		cfg.ExternalSSHPort = cfg.SSHPort
+		cfg.SMTP.Host = "smtp.example.com"
+		cfg.SMTP.Port = 587
+		cfg.SMTP.From = "herald@example.com"
+		return cfg
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*config.AppConfig)
+		failed []string
+	}{
+		{"valid", func(*config.AppConfig) {}, nil},
+		{"missing smtp host", func(c *config.AppConfig) { c.SMTP.Host = "" }, []string{"smtp.host"}},
+		{"bad from", func(c *config.AppConfig) { c.SMTP.From = "not an address" }, []string{"smtp.from"}},
+		{"user without pass", func(c *config.AppConfig) { c.SMTP.User = "sender" }, []string{"smtp.user"}},
+		{"port out of range", func(c *config.AppConfig) { c.HTTPPort = 70000 }, []string{"http_port"}},
+		{"ports collide", func(c *config.AppConfig) { c.HTTPPort = c.SSHPort }, []string{"ports"}},
+		{"known commands", func(c *config.AppConfig) { c.DisabledCommands = []string{"run", "run-all"} }, nil},
+		{"too many backfill pages", func(c *config.AppConfig) { c.BackfillPages = 100 }, []string{"backfill_pages"}},
+		{"unknown future items mode", func(c *config.AppConfig) { c.FutureItems = "drop" }, []string{"future_items"}},
+		{"dkim key without a key", func(c *config.AppConfig) {
+			c.SMTP.DKIMSelector = "herald2026"
+			c.SMTP.DKIMKeys = []config.DKIMKeyConfig{{Selector: "herald2026"}}
+		}, []string{"smtp.dkim_keys"}},
+		{"unknown command", func(c *config.AppConfig) { c.AllowedCommands = []string{"ls", "test"} }, []string{"allowed_commands"}},
+		{"several problems", func(c *config.AppConfig) {
+			c.Origin = "ftp://example.com"
+			c.DBPath = filepath.Join(dir, "missing", "herald.db")
+		}, []string{"origin", "db_path"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base()
+			tt.modify(cfg)
+
+			var failed []string
+			for _, c := range validateAppConfig(cfg) {
+				if c.err != nil {
+					failed = append(failed, c.name)
+				}
+			}
+			if strings.Join(failed, ",") != strings.Join(tt.failed, ",") {
+				t.Errorf("failed checks = %v, want %v", failed, tt.failed)
+			}
+		})
+	}
+}
+
+func TestValidateConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	yaml := "origin: ftp://example.com\ndb_path: " + filepath.Join(dir, "herald.db") + "\nhost_key_path: " + filepath.Join(dir, "host_key") + "\n"
+	if err := os.WriteFile(path, []byte(yaml), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err := validateConfigFile(&out, path)
+	if code := exitCodeFor(err); code != exitConfig {
+		t.Errorf("exit code = %d, want %d", code, exitConfig)
+	}
+	for _, want := range []string{"FAIL  origin", "ok    db_path", "1 problem(s) found"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, out.String())
+		}
+	}
+
+	if code := exitCodeFor(validateConfigFile(&out, filepath.Join(dir, "missing.yaml"))); code != exitConfig {
+		t.Errorf("missing file exit code = %d, want %d", code, exitConfig)
+	}
+}
@@ -2,39 +2,105 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
 )
 
 type AppConfig struct {
-	Host            string     `yaml:"host"`
-	SSHPort         int        `yaml:"ssh_port"`
-	ExternalSSHPort int        `yaml:"external_ssh_port"`
-	HTTPPort        int        `yaml:"http_port"`
-	HostKeyPath     string     `yaml:"host_key_path"`
-	DBPath          string     `yaml:"db_path"`
-	Origin          string     `yaml:"origin"`
-	LogLevel        string     `yaml:"log_level"`
-	SMTP            SMTPConfig `yaml:"smtp"`
-	AllowAllKeys    bool       `yaml:"allow_all_keys"`
-	AllowedKeys     []string   `yaml:"allowed_keys"`
+	Host                string        `yaml:"host"`
+	SSHPort             int           `yaml:"ssh_port"`
+	ExternalSSHPort     int           `yaml:"external_ssh_port"`
+	HTTPPort            int           `yaml:"http_port"`
+	HostKeyPath         string        `yaml:"host_key_path"`
+	DBPath              string        `yaml:"db_path"`
+	Origin              string        `yaml:"origin"`
+	BasePath            string        `yaml:"base_path"`
+	LogLevel            string        `yaml:"log_level"`
+	SMTP                SMTPConfig    `yaml:"smtp"`
+	AllowAllKeys        bool          `yaml:"allow_all_keys"`
+	AllowedKeys         []string      `yaml:"allowed_keys"`
+	AllowedCommands     []string      `yaml:"allowed_commands"`
+	DisabledCommands    []string      `yaml:"disabled_commands"`
+	AllowedFeedSchemes  []string      `yaml:"allowed_feed_schemes"`
+	FeedHostAllowlist   []string      `yaml:"feed_host_allowlist"`
+	FeedHostBlocklist   []string      `yaml:"feed_host_blocklist"`
+	FeedProxyURL        string        `yaml:"feed_proxy_url"`
+	FeedNoProxy         string        `yaml:"feed_no_proxy"`
+	FeedProxyHosts      []string      `yaml:"feed_proxy_hosts"`
+	MaxSeenItemsPerFeed int           `yaml:"max_seen_items_per_feed"`
+	MaxItemsPerFeed     int           `yaml:"max_items_per_feed"`
+	MinFetchInterval    time.Duration `yaml:"min_fetch_interval"`
+	FetchRetries        int           `yaml:"fetch_retries"`
+	FetchRetryBackoff   time.Duration `yaml:"fetch_retry_backoff"`
+	BackfillPages       int           `yaml:"backfill_pages"`
+	FutureItems         string        `yaml:"future_items"`
+	DBReadConns         int           `yaml:"db_read_conns"`
+	DigestWebhookURL    string        `yaml:"digest_webhook_url"`
+	MetricsPushURL      string        `yaml:"metrics_push_url"`
+	MetricsPushInterval time.Duration `yaml:"metrics_push_interval"`
+	ForceHTTPSLinks     bool          `yaml:"force_https_links"`
+	StaleFeedDays       int           `yaml:"stale_feed_days"`
+	FirstRunWindow      time.Duration `yaml:"first_run_window"`
+	LogRetentionDays    int           `yaml:"log_retention_days"`
+	ScheduleJitterMins  int           `yaml:"schedule_jitter_minutes"`
+	CompressRawText     bool          `yaml:"compress_raw_text"`
+	// AutoDeactivateInactive turns off configs whose digests go unopened for 90 days
+	AutoDeactivateInactive bool `yaml:"auto_deactivate_inactive"`
+	// AuditEmailBodies records each sent digest: "hash" keeps a SHA-256 of
+	// the text body, "full" the body itself; empty turns auditing off
+	AuditEmailBodies string `yaml:"audit_email_bodies"`
+	// AdminToken enables the admin endpoints for bearer requests carrying it
+	AdminToken  string `yaml:"admin_token"`
+	TLSCertFile string `yaml:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file"`
+	// MOTD is shown to users after the SSH welcome text; MOTDFile, if set,
+	// is read instead
+	MOTD     string `yaml:"motd"`
+	MOTDFile string `yaml:"motd_file"`
 }
 
+// Handling for items dated in the future, for future_items
+const (
+	FutureItemsClamp = "clamp"
+	FutureItemsSkip  = "skip"
+	FutureItemsAllow = "allow"
+)
+
+// Email audit modes for audit_email_bodies
+const (
+	AuditEmailHash = "hash"
+	AuditEmailFull = "full"
+)
+
 type SMTPConfig struct {
-	Host               string `yaml:"host"`
-	Port               int    `yaml:"port"`
-	User               string `yaml:"user"`
-	Pass               string `yaml:"pass"`
-	From               string `yaml:"from"`
-	DKIMPrivateKey     string `yaml:"dkim_private_key"`
-	DKIMPrivateKeyFile string `yaml:"dkim_private_key_file"`
-	DKIMSelector       string `yaml:"dkim_selector"`
-	DKIMDomain         string `yaml:"dkim_domain"`
+	Host                 string          `yaml:"host"`
+	Port                 int             `yaml:"port"`
+	User                 string          `yaml:"user"`
+	Pass                 string          `yaml:"pass"`
+	From                 string          `yaml:"from"`
+	DKIMPrivateKey       string          `yaml:"dkim_private_key"`
+	DKIMPrivateKeyFile   string          `yaml:"dkim_private_key_file"`
+	DKIMSelector         string          `yaml:"dkim_selector"`
+	DKIMDomain           string          `yaml:"dkim_domain"`
+	DKIMCanonicalization string          `yaml:"dkim_canonicalization"`
+	DKIMHeaders          []string        `yaml:"dkim_headers"`
+	DKIMKeys             []DKIMKeyConfig `yaml:"dkim_keys"`
+	SendTimeout          time.Duration   `yaml:"send_timeout"`
+}
+
+// DKIMKeyConfig is one DKIM key in smtp.dkim_keys, which lists keys by
+// selector so a rotation only changes dkim_selector
+type DKIMKeyConfig struct {
+	Selector       string `yaml:"selector"`
+	PrivateKey     string `yaml:"private_key"`
+	PrivateKeyFile string `yaml:"private_key_file"`
 }
 
 func DefaultAppConfig() *AppConfig {
@@ -47,11 +113,22 @@ func DefaultAppConfig() *AppConfig {
 		Origin:      "http://localhost:8080",
 		LogLevel:    "info",
 		SMTP: SMTPConfig{
-			Host: "localhost",
-			Port: 587,
-			From: "herald@localhost",
+			Host:        "localhost",
+			Port:        587,
+			From:        "herald@localhost",
+			SendTimeout: 30 * time.Second,
 		},
-		AllowAllKeys: true,
+		AllowAllKeys:           true,
+		MaxSeenItemsPerFeed:    1000,
+		MaxItemsPerFeed:        500,
+		FetchRetries:           2,
+		FetchRetryBackoff:      500 * time.Millisecond,
+		FutureItems:            FutureItemsClamp,
+		DBReadConns:            4,
+		StaleFeedDays:          90,
+		FirstRunWindow:         48 * time.Hour,
+		LogRetentionDays:       30,
+		AutoDeactivateInactive: true,
 	}
 }
 
@@ -85,9 +162,101 @@ func LoadAppConfig(path string) (*AppConfig, error) {
 		cfg.ExternalSSHPort = cfg.SSHPort
 	}
 
+	// Default feed proxy exceptions to the standard NO_PROXY variable
+	if cfg.FeedNoProxy == "" {
+		cfg.FeedNoProxy = firstEnv("NO_PROXY", "no_proxy")
+	}
+
 	return cfg, nil
 }
 
+// LinkOrigin returns the origin to use when generating links in emails and
+// web pages. With force_https_links set or built-in TLS enabled, an http
+// origin is upgraded to https.
+func (c *AppConfig) LinkOrigin() string {
+	origin := strings.TrimSuffix(c.Origin, "/")
+	if !c.ForceHTTPSLinks && !c.TLSEnabled() {
+		return origin
+	}
+	return upgradeToHTTPS(origin)
+}
+
+// WebBasePath returns the path prefix the web UI is served under, e.g.
+// /herald, or "" at the root. It defaults to the path of the origin.
+func (c *AppConfig) WebBasePath() string {
+	base := c.BasePath
+	if base == "" && strings.Contains(c.Origin, "://") {
+		if u, err := url.Parse(c.Origin); err == nil {
+			base = u.Path
+		}
+	}
+	base = strings.Trim(base, "/")
+	if base == "" {
+		return ""
+	}
+	return "/" + base
+}
+
+// FeedPolicy returns the restrictions on which feed URLs users may add
+func (c *AppConfig) FeedPolicy() FeedPolicy {
+	return FeedPolicy{
+		Schemes:    c.AllowedFeedSchemes,
+		Allowlist:  c.FeedHostAllowlist,
+		Blocklist:  c.FeedHostBlocklist,
+		ProxyHosts: c.FeedProxyHosts,
+	}
+}
+
+// CheckFutureItems reports whether future_items is a known mode; empty
+// means FutureItemsClamp
+func (c *AppConfig) CheckFutureItems() error {
+	switch c.FutureItems {
+	case "", FutureItemsClamp, FutureItemsSkip, FutureItemsAllow:
+		return nil
+	default:
+		return fmt.Errorf("future_items must be %q, %q, or %q, got %q", FutureItemsClamp, FutureItemsSkip, FutureItemsAllow, c.FutureItems)
+	}
+}
+
+// CheckAuditEmailBodies reports whether audit_email_bodies is a known mode
+func (c *AppConfig) CheckAuditEmailBodies() error {
+	switch c.AuditEmailBodies {
+	case "", AuditEmailHash, AuditEmailFull:
+		return nil
+	default:
+		return fmt.Errorf("audit_email_bodies must be %q or %q, got %q", AuditEmailHash, AuditEmailFull, c.AuditEmailBodies)
+	}
+}
+
+// TLSEnabled reports whether the web server should serve HTTPS itself
+func (c *AppConfig) TLSEnabled() bool {
+	return c.TLSCertFile != "" || c.TLSKeyFile != ""
+}
+
+// MOTDText returns the message of the day, reading motd_file if it is set
+func (c *AppConfig) MOTDText() (string, error) {
+	if c.MOTDFile == "" {
+		return strings.TrimSpace(c.MOTD), nil
+	}
+	data, err := os.ReadFile(c.MOTDFile) //nolint:gosec // Path from app config
+	if err != nil {
+		return "", fmt.Errorf("read motd_file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func upgradeToHTTPS(origin string) string {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme != "http" {
+		return origin
+	}
+	u.Scheme = "https"
+	if u.Port() == "80" {
+		u.Host = u.Hostname()
+	}
+	return u.String()
+}
+
 // findEnvFile looks for .env file in the config file's directory or current directory
 func findEnvFile(configPath string) string {
 	// If config path provided, look in its directory
@@ -132,6 +301,9 @@ func applyEnvOverrides(cfg *AppConfig) {
 	if v := os.Getenv("HERALD_DB_PATH"); v != "" {
 		cfg.DBPath = v
 	}
+	if v := os.Getenv("HERALD_BASE_PATH"); v != "" {
+		cfg.BasePath = v
+	}
 	if v := os.Getenv("HERALD_SMTP_HOST"); v != "" {
 		cfg.SMTP.Host = v
 	}
@@ -161,6 +333,17 @@ func applyEnvOverrides(cfg *AppConfig) {
 	if v := os.Getenv("HERALD_SMTP_DKIM_DOMAIN"); v != "" {
 		cfg.SMTP.DKIMDomain = v
 	}
+	if v := os.Getenv("HERALD_SMTP_DKIM_CANONICALIZATION"); v != "" {
+		cfg.SMTP.DKIMCanonicalization = v
+	}
+	if v := os.Getenv("HERALD_SMTP_DKIM_HEADERS"); v != "" {
+		cfg.SMTP.DKIMHeaders = splitList(v)
+	}
+	if v := os.Getenv("HERALD_SMTP_SEND_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.SMTP.SendTimeout = d
+		}
+	}
 	if v := os.Getenv("HERALD_ALLOW_ALL_KEYS"); v != "" {
 		cfg.AllowAllKeys = strings.ToLower(v) == "true"
 	}
@@ -170,4 +353,145 @@ func applyEnvOverrides(cfg *AppConfig) {
 	if v := os.Getenv("HERALD_LOG_LEVEL"); v != "" {
 		cfg.LogLevel = v
 	}
+	if v := os.Getenv("HERALD_FORCE_HTTPS_LINKS"); v != "" {
+		cfg.ForceHTTPSLinks = strings.ToLower(v) == "true"
+	}
+	if v := os.Getenv("HERALD_DIGEST_WEBHOOK_URL"); v != "" {
+		cfg.DigestWebhookURL = v
+	}
+	if v := os.Getenv("HERALD_MAX_SEEN_ITEMS_PER_FEED"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.MaxSeenItemsPerFeed = n
+		}
+	}
+	if v := os.Getenv("HERALD_DB_READ_CONNS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.DBReadConns = n
+		}
+	}
+	if v := os.Getenv("HERALD_MAX_ITEMS_PER_FEED"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.MaxItemsPerFeed = n
+		}
+	}
+	if v := os.Getenv("HERALD_TLS_CERT_FILE"); v != "" {
+		cfg.TLSCertFile = v
+	}
+	if v := os.Getenv("HERALD_TLS_KEY_FILE"); v != "" {
+		cfg.TLSKeyFile = v
+	}
+	if v := os.Getenv("HERALD_MOTD"); v != "" {
+		cfg.MOTD = v
+	}
+	if v := os.Getenv("HERALD_MOTD_FILE"); v != "" {
+		cfg.MOTDFile = v
+	}
+	if v := os.Getenv("HERALD_COMPRESS_RAW_TEXT"); v != "" {
+		cfg.CompressRawText = strings.ToLower(v) == "true"
+	}
+	if v := os.Getenv("HERALD_AUTO_DEACTIVATE_INACTIVE"); v != "" {
+		cfg.AutoDeactivateInactive = strings.ToLower(v) == "true"
+	}
+	if v := os.Getenv("HERALD_AUDIT_EMAIL_BODIES"); v != "" {
+		cfg.AuditEmailBodies = strings.ToLower(v)
+	}
+	if v := os.Getenv("HERALD_ADMIN_TOKEN"); v != "" {
+		cfg.AdminToken = v
+	}
+	if v := os.Getenv("HERALD_ALLOWED_COMMANDS"); v != "" {
+		cfg.AllowedCommands = splitList(v)
+	}
+	if v := os.Getenv("HERALD_DISABLED_COMMANDS"); v != "" {
+		cfg.DisabledCommands = splitList(v)
+	}
+	if v := os.Getenv("HERALD_LOG_RETENTION_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.LogRetentionDays = n
+		}
+	}
+	if v := os.Getenv("HERALD_METRICS_PUSH_URL"); v != "" {
+		cfg.MetricsPushURL = v
+	}
+	if v := os.Getenv("HERALD_METRICS_PUSH_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.MetricsPushInterval = d
+		}
+	}
+	if v := os.Getenv("HERALD_MIN_FETCH_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.MinFetchInterval = d
+		}
+	}
+	if v := os.Getenv("HERALD_FETCH_RETRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.FetchRetries = n
+		}
+	}
+	if v := os.Getenv("HERALD_BACKFILL_PAGES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.BackfillPages = n
+		}
+	}
+	if v := os.Getenv("HERALD_FUTURE_ITEMS"); v != "" {
+		cfg.FutureItems = strings.ToLower(v)
+	}
+	if v := os.Getenv("HERALD_FETCH_RETRY_BACKOFF"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.FetchRetryBackoff = d
+		}
+	}
+	if v := os.Getenv("HERALD_FIRST_RUN_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.FirstRunWindow = d
+		}
+	}
+	if v := os.Getenv("HERALD_SCHEDULE_JITTER_MINUTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.ScheduleJitterMins = n
+		}
+	}
+	if v := os.Getenv("HERALD_ALLOWED_FEED_SCHEMES"); v != "" {
+		cfg.AllowedFeedSchemes = splitList(v)
+	}
+	if v := os.Getenv("HERALD_FEED_HOST_ALLOWLIST"); v != "" {
+		cfg.FeedHostAllowlist = splitList(v)
+	}
+	if v := os.Getenv("HERALD_FEED_HOST_BLOCKLIST"); v != "" {
+		cfg.FeedHostBlocklist = splitList(v)
+	}
+	if v := os.Getenv("HERALD_FEED_PROXY_HOSTS"); v != "" {
+		cfg.FeedProxyHosts = splitList(v)
+	}
+	if v := os.Getenv("HERALD_FEED_PROXY_URL"); v != "" {
+		cfg.FeedProxyURL = v
+	}
+	if v := os.Getenv("HERALD_FEED_NO_PROXY"); v != "" {
+		cfg.FeedNoProxy = v
+	}
+	if v := os.Getenv("HERALD_STALE_FEED_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.StaleFeedDays = n
+		}
+	}
+}
+
+// splitList splits a comma-separated env value, dropping empty entries
+func splitList(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// firstEnv returns the first non-empty environment variable among keys
+func firstEnv(keys ...string) string {
+	for _, key := range keys {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+	}
+	return ""
 }
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLinkOrigin(t *testing.T) {
+	tests := []struct {
+		origin     string
+		forceHTTPS bool
+		expected   string
+	}{
+		{"http://herald.example.com", false, "http://herald.example.com"},
+		{"http://herald.example.com", true, "https://herald.example.com"},
+		{"http://herald.example.com:80", true, "https://herald.example.com"},
+		{"http://localhost:8080", true, "https://localhost:8080"},
+		{"https://herald.example.com", true, "https://herald.example.com"},
+	}
+
+	for _, tt := range tests {
+		cfg := &AppConfig{Origin: tt.origin, ForceHTTPSLinks: tt.forceHTTPS}
+		if got := cfg.LinkOrigin(); got != tt.expected {
+			t.Errorf("LinkOrigin(%q, %v) = %q, expected %q", tt.origin, tt.forceHTTPS, got, tt.expected)
+		}
+	}
+}
+
+func TestWebBasePath(t *testing.T) {
+	tests := []struct {
+		origin   string
+		basePath string
+		expected string
+	}{
+		{"http://localhost:8080", "", ""},
+		{"https://example.com/", "", ""},
+		{"https://example.com/herald", "", "/herald"},
+		{"https://example.com/herald/", "", "/herald"},
+		{"https://example.com", "herald/", "/herald"},
+		{"https://example.com/herald", "/", ""},
+		{"example.com/herald", "", ""},
+	}
+
+	for _, tt := range tests {
+		cfg := &AppConfig{Origin: tt.origin, BasePath: tt.basePath}
+		if got := cfg.WebBasePath(); got != tt.expected {
+			t.Errorf("WebBasePath(%q, %q) = %q, expected %q", tt.origin, tt.basePath, got, tt.expected)
+		}
+	}
+
+	cfg := &AppConfig{Origin: "https://example.com/herald/"}
+	if got := cfg.LinkOrigin(); got != "https://example.com/herald" {
+		t.Errorf("LinkOrigin() = %q, expected trailing slash trimmed", got)
+	}
+}
+
+func TestLoadAppConfigSendTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("smtp:\n  send_timeout: 90s\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadAppConfig(path)
+	if err != nil {
+		t.Fatalf("LoadAppConfig failed: %v", err)
+	}
+	if cfg.SMTP.SendTimeout != 90*time.Second {
+		t.Errorf("expected 90s send timeout, got %s", cfg.SMTP.SendTimeout)
+	}
+
+	if DefaultAppConfig().SMTP.SendTimeout != 30*time.Second {
+		t.Errorf("expected 30s default send timeout")
+	}
+}
+
+func TestLinkOriginWithTLS(t *testing.T) {
+	cfg := &AppConfig{Origin: "http://herald.example.com", TLSCertFile: "cert.pem", TLSKeyFile: "key.pem"}
+	if !cfg.TLSEnabled() {
+		t.Fatal("expected TLS to be enabled")
+	}
+	if got := cfg.LinkOrigin(); got != "https://herald.example.com" {
+		t.Errorf("LinkOrigin() = %q, expected https origin", got)
+	}
+}
+
+func TestCheckAuditEmailBodies(t *testing.T) {
+	for _, mode := range []string{"", AuditEmailHash, AuditEmailFull} {
+		if err := (&AppConfig{AuditEmailBodies: mode}).CheckAuditEmailBodies(); err != nil {
+			t.Errorf("%q: unexpected error: %v", mode, err)
+		}
+	}
+	if err := (&AppConfig{AuditEmailBodies: "all"}).CheckAuditEmailBodies(); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func TestMOTDText(t *testing.T) {
+	cfg := &AppConfig{MOTD: "  Be kind.\n"}
+	if got, err := cfg.MOTDText(); err != nil || got != "Be kind." {
+		t.Errorf("MOTDText() = %q, %v", got, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "motd.txt")
+	if err := os.WriteFile(path, []byte("Terms: https://example.com/terms\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg.MOTDFile = path
+	if got, err := cfg.MOTDText(); err != nil || got != "Terms: https://example.com/terms" {
+		t.Errorf("MOTDText() with motd_file = %q, %v", got, err)
+	}
+
+	cfg.MOTDFile = filepath.Join(t.TempDir(), "missing.txt")
+	if _, err := cfg.MOTDText(); err == nil {
+		t.Error("expected an error for a missing motd_file")
+	}
+}
@@ -5,34 +5,118 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type FeedEntry struct {
-	URL  string
-	Name string
+	URL      string
+	Name     string
+	Headers  map[string]string
+	Method   string
+	Body     string
+	Note     string
+	Proxy    string
+	Warmup   string
+	Disabled bool
 }
 
 type ParsedConfig struct {
-	Email    string
+	Email        string
+	CronExpr     string
+	Digest       bool
+	Inline       bool
+	Theme        string
+	Footer       []string
+	Style        []string
+	MinSend      int
+	MaxHold      time.Duration
+	QuietHours   string
+	Languages    []string
+	Adaptive     bool
+	Favicons     bool
+	RetryFailed  bool
+	Thread       bool
+	SkipDates    []string
+	SkipWeekends bool
+	// AutoDeactivate is false when the config opts out of being turned off
+	// after going unopened
+	AutoDeactivate bool
+	// MergeInto names another of the user's configs whose digest also
+	// carries this config's items, instead of sending its own
+	MergeInto string
+	// AttachHTML also attaches the HTML digest to the email as a file
+	AttachHTML bool
+	// FeedDescriptions shows each feed's own description under its name
+	FeedDescriptions bool
+	// Public publishes the config's digest history at a shareable archive URL
+	Public bool
+	Feeds  []FeedEntry
+
+	// set records which directives appeared in the text, so defaults only
+	// fill in the ones left out
+	set map[string]bool
+}
+
+// Defaults are account-level values for directives a config leaves out.
+// Nil fields leave the usual defaults in place.
+type Defaults struct {
 	CronExpr string
-	Digest   bool
-	Inline   bool
-	Feeds    []FeedEntry
+	Digest   *bool
+	Inline   *bool
+}
+
+// ApplyDefaults fills in cron, digest, and inline from d where the config
+// text didn't set them
+func (c *ParsedConfig) ApplyDefaults(d Defaults) {
+	if c.CronExpr == "" {
+		c.CronExpr = d.CronExpr
+	}
+	if d.Digest != nil && !c.set["digest"] {
+		c.Digest = *d.Digest
+	}
+	if d.Inline != nil && !c.set["inline"] {
+		c.Inline = *d.Inline
+	}
 }
 
-var feedLineRegex = regexp.MustCompile(`^=>\s+(\S+)(?:\s+"([^"]*)")?$`)
+var feedLineRegex = regexp.MustCompile(`^=>\s+(\S+)(?:\s+"([^"]*)")?((?:\s+\S+)*)$`)
+
+// utf8BOM is the byte order mark some Windows editors put at the start of
+// a file
+const utf8BOM = "\ufeff"
+
+// splitLines splits config text into lines, dropping a leading byte order
+// mark and accepting \r\n, \n, and bare \r line endings
+func splitLines(text string) []string {
+	text = strings.TrimPrefix(text, utf8BOM)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	return strings.Split(text, "\n")
+}
 
 func Parse(text string) (*ParsedConfig, error) {
 	cfg := &ParsedConfig{
-		Digest: true,
-		Inline: false,
-		Feeds:  []FeedEntry{},
+		Digest:         true,
+		Inline:         false,
+		AutoDeactivate: true,
+		Feeds:          []FeedEntry{},
+		set:            map[string]bool{},
 	}
 
-	lines := strings.Split(text, "\n")
-	for _, line := range lines {
+	for _, line := range splitLines(text) {
 		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
+		if line == "" {
+			continue
+		}
+
+		// A commented-out feed line is kept as a disabled feed
+		if strings.HasPrefix(line, "#=>") {
+			if err := parseFeed(cfg, strings.TrimPrefix(line, "#"), true); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
 			continue
 		}
 
@@ -41,7 +125,7 @@ func Parse(text string) (*ParsedConfig, error) {
 				return nil, err
 			}
 		} else if strings.HasPrefix(line, "=>") {
-			if err := parseFeed(cfg, line); err != nil {
+			if err := parseFeed(cfg, line, false); err != nil {
 				return nil, err
 			}
 		}
@@ -61,6 +145,7 @@ func parseDirective(cfg *ParsedConfig, line string) error {
 
 	key := strings.ToLower(parts[0])
 	value := strings.TrimSpace(parts[1])
+	cfg.set[key] = true
 
 	switch key {
 	case "email":
@@ -71,26 +156,241 @@ func parseDirective(cfg *ParsedConfig, line string) error {
 		cfg.Digest = parseBool(value, true)
 	case "inline":
 		cfg.Inline = parseBool(value, false)
+	case "theme":
+		cfg.Theme = strings.ToLower(value)
+	case "footer":
+		cfg.Footer = append(cfg.Footer, value)
+	case "style":
+		cfg.Style = append(cfg.Style, value)
+	case "min_send":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			n = -1
+		}
+		cfg.MinSend = n
+	case "max_hold":
+		d, err := parseDays(value)
+		if err != nil {
+			d = -1
+		}
+		cfg.MaxHold = d
+	case "quiet_hours":
+		cfg.QuietHours = value
+	case "adaptive":
+		cfg.Adaptive = parseBool(value, false)
+	case "favicons":
+		cfg.Favicons = parseBool(value, false)
+	case "retry_failed":
+		cfg.RetryFailed = parseBool(value, false)
+	case "thread":
+		cfg.Thread = parseBool(value, false)
+	case "attach_html":
+		cfg.AttachHTML = parseBool(value, false)
+	case "feed_descriptions":
+		cfg.FeedDescriptions = parseBool(value, false)
+	case "skip_dates":
+		for _, date := range strings.Split(value, ",") {
+			if date = strings.TrimSpace(date); date != "" {
+				cfg.SkipDates = append(cfg.SkipDates, date)
+			}
+		}
+	case "auto_deactivate":
+		cfg.AutoDeactivate = parseBool(value, true)
+	case "skip_weekends":
+		cfg.SkipWeekends = parseBool(value, false)
+	case "merge_into":
+		cfg.MergeInto = value
+	case "public":
+		cfg.Public = parseBool(value, false)
+	case "lang":
+		for _, code := range strings.Split(value, ",") {
+			if code = strings.ToLower(strings.TrimSpace(code)); code != "" {
+				cfg.Languages = append(cfg.Languages, code)
+			}
+		}
 	}
 
 	return nil
 }
 
-func parseFeed(cfg *ParsedConfig, line string) error {
+func parseFeed(cfg *ParsedConfig, line string, disabled bool) error {
 	matches := feedLineRegex.FindStringSubmatch(line)
 	if matches == nil {
 		return nil
 	}
 
 	entry := FeedEntry{
-		URL:  matches[1],
-		Name: matches[2],
+		URL:      matches[1],
+		Name:     matches[2],
+		Disabled: disabled,
+	}
+
+	// Trailing options, e.g. header:Accept=application/rss+xml method:POST
+	// body:'{"limit":50}' note:"Must read" proxy:socks5://127.0.0.1:9050
+	for _, opt := range splitOptions(matches[3]) {
+		key, value, ok := strings.Cut(opt, ":")
+		if !ok {
+			key, value, ok = strings.Cut(opt, "=")
+		}
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "header":
+			name, val, ok := strings.Cut(value, "=")
+			if !ok {
+				continue
+			}
+			if entry.Headers == nil {
+				entry.Headers = make(map[string]string)
+			}
+			entry.Headers[name] = val
+		case "method":
+			entry.Method = strings.ToUpper(value)
+		case "body":
+			entry.Body = value
+		case "note":
+			entry.Note = value
+		case "proxy":
+			entry.Proxy = value
+		case "warmup":
+			entry.Warmup = value
+		case "enabled":
+			entry.Disabled = !parseBool(value, true)
+		}
 	}
+
 	cfg.Feeds = append(cfg.Feeds, entry)
 
 	return nil
 }
 
+// splitOptions splits feed options on whitespace, keeping quoted sections
+// together and dropping the quotes. Single quotes work anywhere; double quotes
+// only right after the option key, as in note:"Must read", so JSON bodies and
+// header values keep theirs.
+func splitOptions(s string) []string {
+	var opts []string
+	var cur strings.Builder
+	var quote rune
+	started := false
+
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && r == '\'':
+			quote = r
+			started = true
+		case quote == 0 && r == '"' && isOptionKey(cur.String()):
+			quote = r
+		case quote == 0 && (r == ' ' || r == '\t'):
+			if started {
+				opts = append(opts, cur.String())
+				cur.Reset()
+				started = false
+			}
+		default:
+			cur.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		opts = append(opts, cur.String())
+	}
+
+	return opts
+}
+
+// isOptionKey reports whether s is just an option key and its colon, e.g. "note:"
+func isOptionKey(s string) bool {
+	key, rest, ok := strings.Cut(s, ":")
+	return ok && key != "" && rest == ""
+}
+
+// parseDays parses a duration that may use a "d" suffix for days, e.g. 7d or 36h
+func parseDays(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// QuietHours is a daily window during which digests are not sent
+type QuietHours struct {
+	Start    int // minutes after midnight
+	End      int // minutes after midnight
+	Location *time.Location
+}
+
+// ParseQuietHours parses "HH:MM-HH:MM" with an optional IANA zone, e.g.
+// "22:00-07:00 Europe/Berlin". Times are UTC when no zone is given.
+func ParseQuietHours(s string) (*QuietHours, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, ErrBadQuietHours
+	}
+
+	startStr, endStr, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return nil, ErrBadQuietHours
+	}
+	start, err := parseClock(startStr)
+	if err != nil {
+		return nil, ErrBadQuietHours
+	}
+	end, err := parseClock(endStr)
+	if err != nil || start == end {
+		return nil, ErrBadQuietHours
+	}
+
+	loc := time.UTC
+	if len(fields) == 2 {
+		loc, err = time.LoadLocation(fields[1])
+		if err != nil {
+			return nil, ErrBadQuietHours
+		}
+	}
+
+	return &QuietHours{Start: start, End: end, Location: loc}, nil
+}
+
+// parseClock parses HH:MM into minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains reports whether t falls inside the quiet window
+func (q *QuietHours) Contains(t time.Time) bool {
+	local := t.In(q.Location)
+	m := local.Hour()*60 + local.Minute()
+	if q.Start < q.End {
+		return m >= q.Start && m < q.End
+	}
+	// Window wraps past midnight
+	return m >= q.Start || m < q.End
+}
+
+// EndAfter returns when the quiet window containing t ends
+func (q *QuietHours) EndAfter(t time.Time) time.Time {
+	local := t.In(q.Location)
+	m := local.Hour()*60 + local.Minute()
+	day := local
+	if q.Start > q.End && m >= q.Start {
+		day = local.AddDate(0, 0, 1)
+	}
+	end := time.Date(day.Year(), day.Month(), day.Day(), q.End/60, q.End%60, 0, 0, q.Location)
+	return end.UTC()
+}
+
 func parseBool(s string, defaultVal bool) bool {
 	b, err := strconv.ParseBool(s)
 	if err != nil {
@@ -98,3 +398,34 @@ func parseBool(s string, defaultVal bool) bool {
 	}
 	return b
 }
+
+// emailDirectiveRegex matches an email directive line, which Parse reads
+// case-insensitively with the last one winning
+var emailDirectiveRegex = regexp.MustCompile(`(?i)^(\s*=:\s*)email(\s.*)?$`)
+
+// SetEmail rewrites every email directive in text to address, adding one at
+// the top if there is none, and leaves the rest of the config untouched
+func SetEmail(text, address string) string {
+	// Line endings are kept as they are, but a byte order mark would hide
+	// an email directive on the first line
+	text = strings.TrimPrefix(text, utf8BOM)
+	sep := "\n"
+	if !strings.Contains(text, "\n") && strings.Contains(text, "\r") {
+		sep = "\r"
+	}
+	lines := strings.Split(text, sep)
+	found := false
+	for i, line := range lines {
+		if m := emailDirectiveRegex.FindStringSubmatch(strings.TrimRight(line, "\r")); m != nil {
+			lines[i] = m[1] + "email " + address
+			if strings.HasSuffix(line, "\r") {
+				lines[i] += "\r"
+			}
+			found = true
+		}
+	}
+	if !found {
+		return "=: email " + address + sep + text
+	}
+	return strings.Join(lines, sep)
+}
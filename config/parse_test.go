@@ -1,7 +1,10 @@
 package config
 
 import (
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParse_Empty(t *testing.T) {
@@ -107,6 +110,98 @@ func TestParse_InlineDirective(t *testing.T) {
 	}
 }
 
+func TestParse_FaviconsDirective(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"=: favicons true", true},
+		{"=: favicons false", false},
+		{"", false}, // default
+	}
+
+	for _, tt := range tests {
+		cfg, err := Parse(tt.input)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.input, err)
+		}
+		if cfg.Favicons != tt.expected {
+			t.Errorf("Parse(%q): expected Favicons=%v, got %v", tt.input, tt.expected, cfg.Favicons)
+		}
+	}
+}
+
+func TestParse_ThreadDirective(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"=: thread true", true},
+		{"=: thread false", false},
+		{"", false}, // default
+	}
+
+	for _, tt := range tests {
+		cfg, err := Parse(tt.input)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.input, err)
+		}
+		if cfg.Thread != tt.expected {
+			t.Errorf("Parse(%q): expected Thread=%v, got %v", tt.input, tt.expected, cfg.Thread)
+		}
+	}
+}
+
+func TestParse_SkipDirectives(t *testing.T) {
+	cfg, err := Parse("=: skip_dates 2025-12-25, 2026-01-01\n=: skip_dates 2026-07-04\n=: skip_weekends true")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	want := []string{"2025-12-25", "2026-01-01", "2026-07-04"}
+	if strings.Join(cfg.SkipDates, ",") != strings.Join(want, ",") {
+		t.Errorf("expected SkipDates %v, got %v", want, cfg.SkipDates)
+	}
+	if !cfg.SkipWeekends {
+		t.Error("expected SkipWeekends to be set")
+	}
+}
+
+func TestParse_AutoDeactivate(t *testing.T) {
+	cfg, err := Parse("=: email a@b.com")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !cfg.AutoDeactivate {
+		t.Error("expected AutoDeactivate to default to true")
+	}
+
+	cfg, err = Parse("=: auto_deactivate false")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if cfg.AutoDeactivate {
+		t.Error("expected auto_deactivate false to opt out")
+	}
+}
+
+func TestParse_MergeInto(t *testing.T) {
+	cfg, err := Parse("=: email a@b.com\n=: cron 0 8 * * *\n=: merge_into daily.txt\n=> https://example.com/feed.xml")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if cfg.MergeInto != "daily.txt" {
+		t.Errorf("expected merge_into daily.txt, got %q", cfg.MergeInto)
+	}
+	if err := Validate(cfg, FeedPolicy{}); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+
+	cfg.MergeInto = "daily"
+	if err := Validate(cfg, FeedPolicy{}); err != ErrBadMergeInto {
+		t.Errorf("expected ErrBadMergeInto, got %v", err)
+	}
+}
+
 func TestParse_FeedWithoutName(t *testing.T) {
 	input := "=> https://example.com/feed.xml"
 	cfg, err := Parse(input)
@@ -255,3 +350,382 @@ func TestParseBool(t *testing.T) {
 		}
 	}
 }
+
+func TestParse_FeedHeaders(t *testing.T) {
+	input := `=> https://example.com/feed.xml "Example" header:Accept=application/rss+xml header:X-Token=abc`
+	cfg, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(cfg.Feeds) != 1 {
+		t.Fatalf("expected 1 feed, got %d", len(cfg.Feeds))
+	}
+	feed := cfg.Feeds[0]
+	if feed.Name != "Example" {
+		t.Errorf("expected name 'Example', got %s", feed.Name)
+	}
+	if feed.Headers["Accept"] != "application/rss+xml" {
+		t.Errorf("expected Accept header, got %q", feed.Headers["Accept"])
+	}
+	if feed.Headers["X-Token"] != "abc" {
+		t.Errorf("expected X-Token header, got %q", feed.Headers["X-Token"])
+	}
+}
+
+func TestParse_FeedHeadersWithoutName(t *testing.T) {
+	input := `=> https://example.com/feed.xml header:Accept=application/atom+xml`
+	cfg, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(cfg.Feeds) != 1 {
+		t.Fatalf("expected 1 feed, got %d", len(cfg.Feeds))
+	}
+	if cfg.Feeds[0].Name != "" {
+		t.Errorf("expected empty name, got %s", cfg.Feeds[0].Name)
+	}
+	if cfg.Feeds[0].Headers["Accept"] != "application/atom+xml" {
+		t.Errorf("expected Accept header, got %q", cfg.Feeds[0].Headers["Accept"])
+	}
+}
+
+func TestParse_Footer(t *testing.T) {
+	input := `=: email test@example.com
+=: cron 0 8 * * *
+=: footer Questions? Email admin@example.com
+=: footer   Have a good day
+=> https://example.com/feed.xml`
+
+	cfg, err := Parse(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"Questions? Email admin@example.com", "Have a good day"}
+	if len(cfg.Footer) != len(want) {
+		t.Fatalf("expected %d footer lines, got %d", len(want), len(cfg.Footer))
+	}
+	for i := range want {
+		if cfg.Footer[i] != want[i] {
+			t.Errorf("footer[%d] = %q, want %q", i, cfg.Footer[i], want[i])
+		}
+	}
+}
+
+func TestParse_Style(t *testing.T) {
+	input := `=: email test@example.com
+=: cron 0 8 * * *
+=: style body { font-family: Georgia, serif; }
+=: style a { color: #0645ad; }
+=> https://example.com/feed.xml`
+
+	cfg, err := Parse(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"body { font-family: Georgia, serif; }", "a { color: #0645ad; }"}
+	if strings.Join(cfg.Style, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected style %q, got %q", want, cfg.Style)
+	}
+}
+
+func TestParse_FeedMethodAndBody(t *testing.T) {
+	input := `=> https://api.example.com/feed "API" method:post body:'{"limit": 50}' header:X-Token=abc`
+	cfg, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(cfg.Feeds) != 1 {
+		t.Fatalf("expected 1 feed, got %d", len(cfg.Feeds))
+	}
+	feed := cfg.Feeds[0]
+	if feed.Method != "POST" {
+		t.Errorf("expected method POST, got %q", feed.Method)
+	}
+	if feed.Body != `{"limit": 50}` {
+		t.Errorf("expected quoted body with space, got %q", feed.Body)
+	}
+	if feed.Headers["X-Token"] != "abc" {
+		t.Errorf("expected X-Token header, got %q", feed.Headers["X-Token"])
+	}
+}
+
+func TestParse_FeedNote(t *testing.T) {
+	input := `=> https://example.com/feed.xml "Example" note:"Must read" body:'{"q":"a b"}'
+=> https://example.com/other.xml note:daily header:If-Match="abc"`
+	cfg, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(cfg.Feeds) != 2 {
+		t.Fatalf("expected 2 feeds, got %d", len(cfg.Feeds))
+	}
+	if cfg.Feeds[0].Note != "Must read" {
+		t.Errorf("expected note %q, got %q", "Must read", cfg.Feeds[0].Note)
+	}
+	if cfg.Feeds[0].Body != `{"q":"a b"}` {
+		t.Errorf("expected body quotes to be kept, got %q", cfg.Feeds[0].Body)
+	}
+	if cfg.Feeds[1].Note != "daily" {
+		t.Errorf("expected unquoted note, got %q", cfg.Feeds[1].Note)
+	}
+	if cfg.Feeds[1].Headers["If-Match"] != `"abc"` {
+		t.Errorf("expected header value quotes to be kept, got %q", cfg.Feeds[1].Headers["If-Match"])
+	}
+}
+
+func TestParse_FeedProxy(t *testing.T) {
+	cfg, err := Parse(`=> http://example.onion/feed "Hidden" proxy:socks5://127.0.0.1:9050`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(cfg.Feeds) != 1 || cfg.Feeds[0].Proxy != "socks5://127.0.0.1:9050" {
+		t.Fatalf("expected feed proxy, got %+v", cfg.Feeds)
+	}
+	if cfg.Feeds[0].Name != "Hidden" {
+		t.Errorf("expected name Hidden, got %q", cfg.Feeds[0].Name)
+	}
+}
+
+func TestParse_FeedWarmup(t *testing.T) {
+	cfg, err := Parse(`=: email a@b.com
+=: cron 0 8 * * *
+=> https://example.com/feed.xml warmup:https://example.com/`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(cfg.Feeds) != 1 || cfg.Feeds[0].Warmup != "https://example.com/" {
+		t.Fatalf("expected feed warmup, got %+v", cfg.Feeds)
+	}
+	if err := Validate(cfg, FeedPolicy{}); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+
+	cfg.Feeds[0].Warmup = "ftp://example.com/"
+	if err := Validate(cfg, FeedPolicy{}); err != ErrBadWarmupURL {
+		t.Errorf("expected ErrBadWarmupURL, got %v", err)
+	}
+}
+
+func TestParse_DisabledFeeds(t *testing.T) {
+	input := `# A regular comment
+#=> https://example.com/paused.xml "Paused"
+=> https://example.com/off.xml enabled=false
+=> https://example.com/on.xml`
+
+	cfg, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(cfg.Feeds) != 3 {
+		t.Fatalf("expected 3 feeds, got %d", len(cfg.Feeds))
+	}
+	if !cfg.Feeds[0].Disabled || cfg.Feeds[0].Name != "Paused" {
+		t.Errorf("expected commented feed to be disabled, got %+v", cfg.Feeds[0])
+	}
+	if !cfg.Feeds[1].Disabled {
+		t.Error("expected enabled=false feed to be disabled")
+	}
+	if cfg.Feeds[2].Disabled {
+		t.Error("expected plain feed to be enabled")
+	}
+}
+
+func TestParse_MinSendAndMaxHold(t *testing.T) {
+	input := `=: min_send 3
+=: max_hold 7d`
+
+	cfg, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if cfg.MinSend != 3 {
+		t.Errorf("expected min_send 3, got %d", cfg.MinSend)
+	}
+	if cfg.MaxHold != 7*24*time.Hour {
+		t.Errorf("expected max_hold 7d, got %s", cfg.MaxHold)
+	}
+
+	cfg, _ = Parse("=: max_hold 36h\n=: min_send lots")
+	if cfg.MaxHold != 36*time.Hour {
+		t.Errorf("expected max_hold 36h, got %s", cfg.MaxHold)
+	}
+	if cfg.MinSend != -1 {
+		t.Errorf("expected invalid min_send to be flagged, got %d", cfg.MinSend)
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	cfg, _ := Parse("=: quiet_hours 22:00-07:00")
+	if cfg.QuietHours != "22:00-07:00" {
+		t.Fatalf("expected quiet_hours to be kept, got %q", cfg.QuietHours)
+	}
+
+	q, err := ParseQuietHours(cfg.QuietHours)
+	if err != nil {
+		t.Fatalf("ParseQuietHours failed: %v", err)
+	}
+
+	day := func(h, m int) time.Time { return time.Date(2026, 3, 10, h, m, 0, 0, time.UTC) }
+	tests := []struct {
+		at    time.Time
+		quiet bool
+		end   time.Time
+	}{
+		{day(3, 0), true, day(7, 0)},
+		{day(23, 15), true, day(7, 0).AddDate(0, 0, 1)},
+		{day(22, 0), true, day(7, 0).AddDate(0, 0, 1)},
+		{day(7, 0), false, time.Time{}},
+		{day(12, 0), false, time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := q.Contains(tt.at); got != tt.quiet {
+			t.Errorf("Contains(%s) = %v, expected %v", tt.at, got, tt.quiet)
+			continue
+		}
+		if tt.quiet {
+			if got := q.EndAfter(tt.at); !got.Equal(tt.end) {
+				t.Errorf("EndAfter(%s) = %s, expected %s", tt.at, got, tt.end)
+			}
+		}
+	}
+
+	// Same-day window in a named zone
+	q, err = ParseQuietHours("09:00-17:00 America/New_York")
+	if err != nil {
+		t.Fatalf("ParseQuietHours failed: %v", err)
+	}
+	at := day(15, 0) // 11:00 in New York
+	if !q.Contains(at) {
+		t.Error("expected 11:00 New York to be quiet")
+	}
+	if end := q.EndAfter(at); !end.Equal(day(21, 0)) {
+		t.Errorf("expected window to end at 21:00 UTC, got %s", end)
+	}
+}
+
+func TestParse_Languages(t *testing.T) {
+	cfg, err := Parse("=: lang EN, es ,")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(cfg.Languages) != 2 || cfg.Languages[0] != "en" || cfg.Languages[1] != "es" {
+		t.Errorf("expected [en es], got %v", cfg.Languages)
+	}
+}
+
+func TestSetEmail(t *testing.T) {
+	text := "=: email old@example.com\n=: cron 0 8 * * *\n=> https://example.com/feed.xml\n"
+	got := SetEmail(text, "new@example.com")
+	want := "=: email new@example.com\n=: cron 0 8 * * *\n=> https://example.com/feed.xml\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	parsed, err := Parse(SetEmail("=: EMAIL a@example.com\n=:email\n=: email b@example.com\n", "c@example.com"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if parsed.Email != "c@example.com" {
+		t.Errorf("expected every email directive rewritten, got %q", parsed.Email)
+	}
+
+	got = SetEmail("=: cron 0 8 * * *\n", "new@example.com")
+	if got != "=: email new@example.com\n=: cron 0 8 * * *\n" {
+		t.Errorf("expected email directive added at the top, got %q", got)
+	}
+}
+
+func TestParse_LineEndingsAndBOM(t *testing.T) {
+	unix := "=: email user@example.com\n=: cron 0 8 * * *\n=: digest false\n# a comment\n=> https://example.com/feed.xml Example\n=> https://example.com/other.xml\n"
+	want, err := Parse(unix)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	variants := map[string]string{
+		"crlf":     strings.ReplaceAll(unix, "\n", "\r\n"),
+		"cr":       strings.ReplaceAll(unix, "\n", "\r"),
+		"bom":      "\ufeff" + unix,
+		"bom crlf": "\ufeff" + strings.ReplaceAll(unix, "\n", "\r\n"),
+	}
+	for name, text := range variants {
+		got, err := Parse(text)
+		if err != nil {
+			t.Fatalf("%s: Parse failed: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: parsed %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestSetEmail_LineEndingsAndBOM(t *testing.T) {
+	got := SetEmail("\ufeff=: email old@example.com\r\n=: cron 0 8 * * *\r\n", "new@example.com")
+	if want := "=: email new@example.com\r\n=: cron 0 8 * * *\r\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	got = SetEmail("=: email old@example.com\r=: cron 0 8 * * *\r", "new@example.com")
+	if want := "=: email new@example.com\r=: cron 0 8 * * *\r"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestParse_PublicDirective(t *testing.T) {
+	cfg, err := Parse("=: email a@b.com")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if cfg.Public {
+		t.Error("expected Public to default to false")
+	}
+
+	cfg, err = Parse("=: public true")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !cfg.Public {
+		t.Error("expected public true to be parsed")
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	yes, no := true, false
+	defaults := Defaults{CronExpr: "0 8 * * *", Digest: &no, Inline: &yes}
+
+	cfg, err := Parse("=: email a@b.com\n=> https://example.com/feed.xml")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	cfg.ApplyDefaults(defaults)
+	if cfg.CronExpr != "0 8 * * *" || cfg.Digest || !cfg.Inline {
+		t.Errorf("expected defaults to fill in omitted directives, got cron=%q digest=%v inline=%v", cfg.CronExpr, cfg.Digest, cfg.Inline)
+	}
+
+	cfg, err = Parse("=: email a@b.com\n=: cron 0 9 * * 1\n=: digest true\n=: inline false")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	cfg.ApplyDefaults(defaults)
+	if cfg.CronExpr != "0 9 * * 1" || !cfg.Digest || cfg.Inline {
+		t.Errorf("expected explicit directives to win, got cron=%q digest=%v inline=%v", cfg.CronExpr, cfg.Digest, cfg.Inline)
+	}
+
+	cfg, _ = Parse("=: email a@b.com")
+	cfg.ApplyDefaults(Defaults{})
+	if cfg.CronExpr != "" || !cfg.Digest || cfg.Inline {
+		t.Error("expected empty defaults to change nothing")
+	}
+}
+
+func TestParse_FeedDescriptionsDirective(t *testing.T) {
+	cfg, err := Parse("=: feed_descriptions true")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !cfg.FeedDescriptions {
+		t.Error("expected feed_descriptions true to be parsed")
+	}
+}
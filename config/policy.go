@@ -0,0 +1,144 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+var (
+	// ErrFeedNotAllowed is returned for feed URLs the instance's feed policy rejects
+	ErrFeedNotAllowed = errors.New("feed URL not allowed on this instance")
+	// ErrNonPublicAddress is returned when a connection would reach a
+	// loopback, private, or otherwise non-public address
+	ErrNonPublicAddress = errors.New("refusing to fetch non-public address")
+)
+
+// nonPublicNets are ranges net.IP's own checks don't cover: CGNAT shared
+// address space and "this network"
+var nonPublicNets = []*net.IPNet{
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("0.0.0.0/8"),
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// IsPublicIP reports whether ip is a globally reachable unicast address
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range nonPublicNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// RejectNonPublic is a net.Dialer Control func that refuses connections to
+// addresses IsPublicIP rejects. Checking at dial time covers redirects and
+// every DNS answer.
+func RejectNonPublic(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !IsPublicIP(ip) {
+		return ErrNonPublicAddress
+	}
+	return nil
+}
+
+// maxFeedRedirects matches net/http's default redirect limit
+const maxFeedRedirects = 10
+
+// FeedPolicy restricts which feed URLs users may add. Empty lists mean no
+// restriction; a host on the blocklist is rejected even if it is allowlisted.
+// Host entries match the host itself and any subdomain of it. ProxyHosts
+// are the per-feed SOCKS proxies that may be private or loopback addresses,
+// such as a local Tor daemon.
+type FeedPolicy struct {
+	Schemes    []string
+	Allowlist  []string
+	Blocklist  []string
+	ProxyHosts []string
+}
+
+// Check reports whether u may be used as a feed URL under p
+func (p *FeedPolicy) Check(u *url.URL) error {
+	scheme := strings.ToLower(u.Scheme)
+	if len(p.Schemes) > 0 && !containsFold(p.Schemes, scheme) {
+		return fmt.Errorf("%w: %s URLs are not accepted (allowed: %s)", ErrFeedNotAllowed, scheme, strings.Join(p.Schemes, ", "))
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if matchesHost(p.Blocklist, host) {
+		return fmt.Errorf("%w: %s is blocked", ErrFeedNotAllowed, host)
+	}
+	if len(p.Allowlist) > 0 && !matchesHost(p.Allowlist, host) {
+		return fmt.Errorf("%w: %s is not on the allowlist", ErrFeedNotAllowed, host)
+	}
+	return nil
+}
+
+// CheckProxy reports whether u may be used as a per-feed proxy under p. The
+// host lists apply as they do to feeds, and a proxy that isn't one of
+// ProxyHosts must not be a literal non-public address.
+func (p *FeedPolicy) CheckProxy(u *url.URL) error {
+	host := strings.ToLower(u.Hostname())
+	if matchesHost(p.ProxyHosts, host) {
+		return nil
+	}
+	if matchesHost(p.Blocklist, host) {
+		return fmt.Errorf("%w: proxy %s is blocked", ErrFeedNotAllowed, host)
+	}
+	if len(p.Allowlist) > 0 && !matchesHost(p.Allowlist, host) {
+		return fmt.Errorf("%w: proxy %s is not on the allowlist", ErrFeedNotAllowed, host)
+	}
+	if ip := net.ParseIP(host); ip != nil && !IsPublicIP(ip) {
+		return fmt.Errorf("%w: proxy %s", ErrNonPublicAddress, host)
+	}
+	return nil
+}
+
+// isProxyHost reports whether u's host is one of p's ProxyHosts
+func (p *FeedPolicy) isProxyHost(u *url.URL) bool {
+	return matchesHost(p.ProxyHosts, strings.ToLower(u.Hostname()))
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesHost reports whether host is one of the entries or a subdomain of one
+func matchesHost(entries []string, host string) bool {
+	for _, e := range entries {
+		e = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(e)), "*.")
+		e = strings.Trim(e, ".")
+		if e == "" {
+			continue
+		}
+		if host == e || strings.HasSuffix(host, "."+e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func TestFeedPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  FeedPolicy
+		url     string
+		allowed bool
+	}{
+		{"empty policy", FeedPolicy{}, "http://example.com/feed.xml", true},
+		{"scheme allowed", FeedPolicy{Schemes: []string{"https"}}, "https://example.com/feed.xml", true},
+		{"scheme rejected", FeedPolicy{Schemes: []string{"https"}}, "http://example.com/feed.xml", false},
+		{"scheme case", FeedPolicy{Schemes: []string{"HTTPS"}}, "https://example.com/feed.xml", true},
+		{"allowlisted host", FeedPolicy{Allowlist: []string{"example.com"}}, "https://example.com/feed.xml", true},
+		{"allowlisted subdomain", FeedPolicy{Allowlist: []string{"example.com"}}, "https://blog.example.com/feed.xml", true},
+		{"wildcard entry", FeedPolicy{Allowlist: []string{"*.example.com"}}, "https://blog.example.com/feed.xml", true},
+		{"not allowlisted", FeedPolicy{Allowlist: []string{"example.com"}}, "https://other.com/feed.xml", false},
+		{"suffix is not a subdomain", FeedPolicy{Allowlist: []string{"example.com"}}, "https://badexample.com/feed.xml", false},
+		{"blocked host", FeedPolicy{Blocklist: []string{"spam.com"}}, "https://www.spam.com/feed.xml", false},
+		{"not blocked", FeedPolicy{Blocklist: []string{"spam.com"}}, "https://example.com/feed.xml", true},
+		{"block beats allow", FeedPolicy{
+			Allowlist: []string{"example.com"},
+			Blocklist: []string{"private.example.com"},
+		}, "https://private.example.com/feed.xml", false},
+		{"allow alongside block", FeedPolicy{
+			Allowlist: []string{"example.com"},
+			Blocklist: []string{"private.example.com"},
+		}, "https://blog.example.com/feed.xml", true},
+		{"host with port", FeedPolicy{Allowlist: []string{"example.com"}}, "https://example.com:8443/feed.xml", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = tt.policy.Check(u)
+			if tt.allowed && err != nil {
+				t.Errorf("Check(%s) = %v, want allowed", tt.url, err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrFeedNotAllowed) {
+				t.Errorf("Check(%s) = %v, want ErrFeedNotAllowed", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestValidate_FeedPolicy(t *testing.T) {
+	policy := FeedPolicy{Schemes: []string{"https"}, Blocklist: []string{"blocked.com"}}
+
+	cfg := &ParsedConfig{
+		Email:    "user@example.com",
+		CronExpr: "0 8 * * *",
+		Feeds:    []FeedEntry{{URL: "https://example.com/feed.xml"}},
+	}
+	if err := Validate(cfg, policy); err != nil {
+		t.Errorf("expected allowed feed to validate, got %v", err)
+	}
+
+	for _, feedURL := range []string{"http://example.com/feed.xml", "https://blocked.com/feed.xml"} {
+		cfg.Feeds = []FeedEntry{{URL: feedURL}}
+		if err := Validate(cfg, policy); !errors.Is(err, ErrFeedNotAllowed) {
+			t.Errorf("Validate(%s) = %v, want ErrFeedNotAllowed", feedURL, err)
+		}
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip       string
+		expected bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:4700::1111", true},
+		{"127.0.0.1", false},
+		{"10.0.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"0.1.2.3", false},
+		{"100.64.0.1", false},
+		{"100.127.255.254", false},
+		{"100.128.0.1", true},
+		{"::ffff:100.64.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsPublicIP(net.ParseIP(tt.ip)); got != tt.expected {
+			t.Errorf("IsPublicIP(%s) = %v, expected %v", tt.ip, got, tt.expected)
+		}
+	}
+}
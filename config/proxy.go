@@ -0,0 +1,225 @@
+package config
+
+import (
+	"container/list"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+type proxyFunc func(*url.URL) (*url.URL, error)
+
+// FeedAccess applies an instance's feed policy and proxy settings to feed
+// requests. Its transports are shared by the fetches that use it, so
+// connections are reused.
+type FeedAccess struct {
+	policy    FeedPolicy
+	proxy     atomic.Pointer[proxyFunc]
+	transport *http.Transport
+	// socks caches one transport per feed proxy
+	socks *transportCache
+}
+
+// NewFeedAccess returns a FeedAccess enforcing policy. Requests use the proxy
+// settings from the environment until SetProxy is called.
+func NewFeedAccess(policy FeedPolicy) *FeedAccess {
+	a := &FeedAccess{
+		policy: policy,
+		socks:  newTransportCache(maxSOCKSTransports),
+	}
+	a.transport = http.DefaultTransport.(*http.Transport).Clone()
+	a.transport.Proxy = a.proxyFor
+	return a
+}
+
+// Policy returns the feed policy a enforces
+func (a *FeedAccess) Policy() FeedPolicy {
+	return a.policy
+}
+
+// SetProxy routes feed fetches through proxyURL, except for hosts matched by
+// noProxy (NO_PROXY syntax). An empty proxyURL restores the proxy settings
+// from the environment.
+func (a *FeedAccess) SetProxy(proxyURL, noProxy string) error {
+	if proxyURL == "" {
+		a.proxy.Store(nil)
+		return nil
+	}
+	if err := ValidateFeedProxyURL(proxyURL); err != nil {
+		return err
+	}
+
+	fn := proxyFunc((&httpproxy.Config{
+		HTTPProxy:  proxyURL,
+		HTTPSProxy: proxyURL,
+		NoProxy:    noProxy,
+	}).ProxyFunc())
+	a.proxy.Store(&fn)
+	return nil
+}
+
+// proxyFor is the http.Transport Proxy func for feed requests
+func (a *FeedAccess) proxyFor(req *http.Request) (*url.URL, error) {
+	if fn := a.proxy.Load(); fn != nil {
+		return (*fn)(req.URL)
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// CheckURL checks u against the feed policy
+func (a *FeedAccess) CheckURL(u *url.URL) error {
+	return a.policy.Check(u)
+}
+
+// CheckRedirect is an http.Client CheckRedirect func that applies the feed
+// policy to every redirect target
+func (a *FeedAccess) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxFeedRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxFeedRedirects)
+	}
+	return a.CheckURL(req.URL)
+}
+
+// ValidateFeedProxyURL checks that proxyURL can be used with SetProxy
+func ValidateFeedProxyURL(proxyURL string) error {
+	u, err := url.Parse(proxyURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid feed proxy URL %q", proxyURL)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return nil
+	default:
+		return fmt.Errorf("feed proxy scheme must be http, https, or socks5, got %q", u.Scheme)
+	}
+}
+
+// maxSOCKSTransports bounds how many per-feed proxy transports are kept;
+// users choose the proxy URLs, so the cache must not grow with them
+const maxSOCKSTransports = 32
+
+// transportCache keeps transports by key so connections are reused,
+// evicting the least recently used one once it is full
+type transportCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func newTransportCache(max int) *transportCache {
+	return &transportCache{
+		max:     max,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+type cachedTransport struct {
+	key       string
+	transport *http.Transport
+}
+
+func (c *transportCache) get(key string) (*http.Transport, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cachedTransport).transport, true
+}
+
+// add stores t under key unless another caller got there first, and returns
+// the transport to use
+func (c *transportCache) add(key string, t *http.Transport) *http.Transport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		return el.Value.(*cachedTransport).transport
+	}
+	c.entries[key] = c.order.PushFront(&cachedTransport{key: key, transport: t})
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		evicted := oldest.Value.(*cachedTransport)
+		delete(c.entries, evicted.key)
+		evicted.transport.CloseIdleConnections()
+	}
+	return t
+}
+
+// ValidateSOCKSProxyURL checks a per-feed proxy, which must be a socks5:// or
+// socks5h:// URL with a host and port
+func ValidateSOCKSProxyURL(proxyURL string) error {
+	u, err := url.Parse(proxyURL)
+	if err != nil || u.Hostname() == "" || u.Port() == "" {
+		return fmt.Errorf("%w: %q", ErrBadFeedProxy, proxyURL)
+	}
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return fmt.Errorf("%w: %q", ErrBadFeedProxy, proxyURL)
+	}
+	return nil
+}
+
+// CheckProxyURL checks a per-feed proxy URL and that p allows its host.
+// Unless the host is one of p's ProxyHosts, it must also be a public address:
+// private and loopback proxies are only reachable when the operator lists
+// them.
+func (p *FeedPolicy) CheckProxyURL(proxyURL string) error {
+	if err := ValidateSOCKSProxyURL(proxyURL); err != nil {
+		return err
+	}
+	u, _ := url.Parse(proxyURL)
+	return p.CheckProxy(u)
+}
+
+// socksTransport returns a transport that dials through the SOCKS5 proxy at
+// proxyURL, ignoring the instance-wide feed proxy. Host names are resolved by
+// the proxy, so .onion feeds work through Tor. The proxy is checked with
+// CheckProxyURL, and unless it is one of the policy's ProxyHosts the address
+// it resolves to is checked again when dialing.
+func (a *FeedAccess) socksTransport(proxyURL string) (*http.Transport, error) {
+	if err := a.policy.CheckProxyURL(proxyURL); err != nil {
+		return nil, err
+	}
+	if t, ok := a.socks.get(proxyURL); ok {
+		return t, nil
+	}
+	u, _ := url.Parse(proxyURL)
+	forward := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !a.policy.isProxyHost(u) {
+		forward.Control = RejectNonPublic
+	}
+	dialer, err := proxy.FromURL(u, forward)
+	if err != nil {
+		return nil, fmt.Errorf("feed proxy %s: %w", u.Redacted(), err)
+	}
+	contextDialer, ok := dialer.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("feed proxy %s: dialer does not support contexts", u.Redacted())
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = contextDialer.DialContext
+	return a.socks.add(proxyURL, t), nil
+}
+
+// Transport returns the transport for a feed with the given proxy option:
+// the shared feed transport, or a SOCKS5 transport for a per-feed proxy
+func (a *FeedAccess) Transport(proxyURL string) (*http.Transport, error) {
+	if proxyURL == "" {
+		return a.transport, nil
+	}
+	return a.socksTransport(proxyURL)
+}
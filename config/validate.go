@@ -1,28 +1,91 @@
 package config
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime"
 	"net/http"
 	"net/mail"
 	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/adhocore/gronx"
+	"github.com/kierank/herald/lang"
 	"github.com/mmcdole/gofeed"
+	"golang.org/x/sync/errgroup"
 )
 
 var (
-	ErrNoEmail    = errors.New("email is required")
-	ErrBadEmail   = errors.New("invalid email format")
-	ErrNoCron     = errors.New("cron expression is required")
-	ErrBadCron    = errors.New("invalid cron expression")
-	ErrNoFeeds    = errors.New("at least one feed URL is required")
-	ErrBadFeedURL = errors.New("invalid feed URL")
+	ErrNoEmail       = errors.New("email is required")
+	ErrBadEmail      = errors.New("invalid email format")
+	ErrNoCron        = errors.New("cron expression is required")
+	ErrBadCron       = errors.New("invalid cron expression")
+	ErrNoFeeds       = errors.New("at least one feed URL is required")
+	ErrBadFeedURL    = errors.New("invalid feed URL")
+	ErrBadHeader     = errors.New("invalid feed header")
+	ErrHeaderCap     = errors.New("too many or too large feed headers")
+	ErrDuplicateFeed = errors.New("duplicate feed URL")
+	ErrBadTheme      = errors.New("unknown theme (use default, compact, or newspaper)")
+	ErrFooterTooLong = errors.New("footer text too long")
+	ErrStyleTooLong  = errors.New("style too long")
+	ErrBadMethod     = errors.New("feed method must be GET or POST")
+	ErrBodyTooLarge  = errors.New("feed request body too large")
+	ErrNoteTooLong   = errors.New("feed note too long")
+	ErrBadFeedProxy  = errors.New("feed proxy must be a socks5:// URL with a host and port")
+	ErrBadMinSend    = errors.New("min_send must be between 1 and 1000")
+	ErrBadMaxHold    = errors.New("max_hold must be between 1h and 60d")
+	ErrBadQuietHours = errors.New("quiet_hours must look like 22:00-07:00 with an optional timezone")
+	ErrBadLanguage   = errors.New("unsupported lang code")
+	ErrBadSkipDate   = errors.New("skip_dates must be YYYY-MM-DD dates")
+	ErrBadMergeInto  = errors.New("merge_into must name a .txt config file")
+	ErrNotAFeed      = errors.New("this looks like a web page, not a feed")
+	ErrBadWarmupURL  = errors.New("feed warmup must be an http or https URL")
 )
 
-func Validate(cfg *ParsedConfig) error {
+const (
+	maxFeedHeaders     = 8
+	maxHeaderValueSize = 512
+	maxFooterSize      = 1000
+	maxStyleSize       = 2000
+	maxFeedBodySize    = 4096
+	maxFeedNoteSize    = 200
+	maxMinSend         = 1000
+	minMaxHold         = time.Hour
+	maxMaxHold         = 60 * 24 * time.Hour
+	maxSkipDates       = 366
+	// feedSniffSize is how much of an HTML response is checked for feed markup
+	feedSniffSize = 1024
+)
+
+// validThemes mirrors the digest themes embedded in the email package
+var validThemes = map[string]bool{
+	"default":   true,
+	"compact":   true,
+	"newspaper": true,
+}
+
+// headerNameRegex matches RFC 7230 token characters
+var headerNameRegex = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// forbiddenHeaders can't be overridden per feed since they control the transport
+var forbiddenHeaders = map[string]bool{
+	"host":              true,
+	"content-length":    true,
+	"transfer-encoding": true,
+	"connection":        true,
+}
+
+// Validate checks a parsed config, holding its feeds, proxies, and warmup
+// URLs to the instance's feed policy
+func Validate(cfg *ParsedConfig, policy FeedPolicy) error {
 	if cfg.Email == "" {
 		return ErrNoEmail
 	}
@@ -33,59 +96,338 @@ func Validate(cfg *ParsedConfig) error {
 	if cfg.CronExpr == "" {
 		return ErrNoCron
 	}
-	gron := gronx.New()
-	if !gron.IsValid(cfg.CronExpr) {
+	if !ValidCron(cfg.CronExpr) {
 		return ErrBadCron
 	}
 
+	if cfg.Theme != "" && !validThemes[cfg.Theme] {
+		return ErrBadTheme
+	}
+
+	if len(strings.Join(cfg.Footer, "\n")) > maxFooterSize {
+		return ErrFooterTooLong
+	}
+	if len(strings.Join(cfg.Style, "\n")) > maxStyleSize {
+		return ErrStyleTooLong
+	}
+
+	if cfg.MinSend < 0 || cfg.MinSend > maxMinSend {
+		return ErrBadMinSend
+	}
+	if cfg.MaxHold != 0 && (cfg.MaxHold < minMaxHold || cfg.MaxHold > maxMaxHold) {
+		return ErrBadMaxHold
+	}
+
+	if cfg.QuietHours != "" {
+		if _, err := ParseQuietHours(cfg.QuietHours); err != nil {
+			return err
+		}
+	}
+
+	for _, code := range cfg.Languages {
+		if !lang.Supported(code) {
+			return fmt.Errorf("%w: %s", ErrBadLanguage, code)
+		}
+	}
+
+	if len(cfg.SkipDates) > maxSkipDates {
+		return fmt.Errorf("%w: at most %d allowed", ErrBadSkipDate, maxSkipDates)
+	}
+	for _, date := range cfg.SkipDates {
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return fmt.Errorf("%w: %s", ErrBadSkipDate, date)
+		}
+	}
+
+	if cfg.MergeInto != "" && (!strings.HasSuffix(cfg.MergeInto, ".txt") || strings.ContainsAny(cfg.MergeInto, "/ \t")) {
+		return ErrBadMergeInto
+	}
+
 	if len(cfg.Feeds) == 0 {
 		return ErrNoFeeds
 	}
 
+	seen := make(map[string]bool, len(cfg.Feeds))
 	for _, feed := range cfg.Feeds {
 		u, err := url.Parse(feed.URL)
 		if err != nil || u.Scheme == "" || u.Host == "" {
 			return ErrBadFeedURL
 		}
+		if err := policy.Check(u); err != nil {
+			return fmt.Errorf("feed %s: %w", feed.URL, err)
+		}
+		key := normalizeFeedURL(u)
+		if seen[key] {
+			return ErrDuplicateFeed
+		}
+		seen[key] = true
+		if err := validateHeaders(feed.Headers); err != nil {
+			return err
+		}
+		if err := validateRequest(feed); err != nil {
+			return err
+		}
+		if len(feed.Note) > maxFeedNoteSize {
+			return ErrNoteTooLong
+		}
+		if feed.Proxy != "" {
+			if err := policy.CheckProxyURL(feed.Proxy); err != nil {
+				return err
+			}
+		}
+		if feed.Warmup != "" {
+			if err := validateWarmup(feed.Warmup, &policy); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
-// ValidateFeedURLs attempts to fetch and parse each feed URL with a short timeout
-func ValidateFeedURLs(ctx context.Context, cfg *ParsedConfig) error {
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
+func validateRequest(feed FeedEntry) error {
+	switch feed.Method {
+	case "", http.MethodGet:
+		if feed.Body != "" {
+			return ErrBadMethod
+		}
+	case http.MethodPost:
+	default:
+		return ErrBadMethod
+	}
+	if len(feed.Body) > maxFeedBodySize {
+		return ErrBodyTooLarge
+	}
+	return nil
+}
 
-	parser := gofeed.NewParser()
-	client := &http.Client{
-		Timeout: 5 * time.Second,
+// RequestContentType picks the Content-Type for a feed request body
+func RequestContentType(body string) string {
+	if json.Valid([]byte(body)) {
+		return "application/json"
 	}
+	return "application/x-www-form-urlencoded"
+}
 
-	for _, feed := range cfg.Feeds {
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, feed.URL, nil)
-		if err != nil {
-			return fmt.Errorf("invalid feed URL %s: %w", feed.URL, err)
+// normalizeFeedURL folds URL variations that point at the same feed
+func normalizeFeedURL(u *url.URL) string {
+	n := *u
+	n.Scheme = strings.ToLower(n.Scheme)
+	n.Host = strings.ToLower(n.Host)
+	n.Path = strings.TrimSuffix(n.Path, "/")
+	n.Fragment = ""
+	return n.String()
+}
+
+func validateHeaders(headers map[string]string) error {
+	if len(headers) > maxFeedHeaders {
+		return ErrHeaderCap
+	}
+	for name, value := range headers {
+		if !headerNameRegex.MatchString(name) || forbiddenHeaders[strings.ToLower(name)] {
+			return ErrBadHeader
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return ErrBadHeader
 		}
+		if len(value) > maxHeaderValueSize {
+			return ErrHeaderCap
+		}
+	}
+	return nil
+}
+
+// ValidCron reports whether expr is a cron expression Herald can schedule
+func ValidCron(expr string) bool {
+	return gronx.New().IsValid(expr)
+}
 
-		req.Header.Set("User-Agent", "Herald/1.0 (RSS Aggregator)")
+// feedMarkers are the opening tags of RSS, Atom, and RDF documents
+var feedMarkers = [][]byte{[]byte("<rss"), []byte("<feed"), []byte("<rdf:rdf")}
 
-		resp, err := client.Do(req)
-		if err != nil {
-			return fmt.Errorf("failed to fetch feed %s: %w", feed.URL, err)
+// looksLikeWebPage reports whether a response is an HTML page rather than a
+// feed. Only responses served as HTML are checked, and their first bytes are
+// peeked so feeds mislabelled as text/html still parse.
+func looksLikeWebPage(contentType string, body *bufio.Reader) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return false
+	}
+
+	head, _ := body.Peek(feedSniffSize)
+	head = bytes.ToLower(head)
+	if trimmed := bytes.TrimSpace(head); bytes.HasPrefix(trimmed, []byte("{")) {
+		return false
+	}
+	for _, marker := range feedMarkers {
+		if bytes.Contains(head, marker) {
+			return false
 		}
+	}
+	return true
+}
+
+const (
+	// maxValidateHosts is how many hosts ValidateFeedURLs checks at once
+	maxValidateHosts = 4
+	// validateTimeout bounds the requests of ValidateFeedURLs, on top of the
+	// pauses between feeds on the same host
+	validateTimeout = 10 * time.Second
+)
+
+// validateHostSpacing is the pause between validation requests to the same
+// host, plus up to half again of jitter, so one publisher's feeds aren't
+// fetched back to back
+var validateHostSpacing = 300 * time.Millisecond
+
+// ValidateFeedURLs attempts to fetch and parse each feed URL with a short
+// timeout. Different hosts are checked concurrently; feeds on the same host
+// are checked one at a time with a short pause between them, and the timeout
+// grows to cover the pauses of the largest host. Requests go through access.
+func ValidateFeedURLs(ctx context.Context, cfg *ParsedConfig, access *FeedAccess) error {
+	groups := feedsByHost(cfg.Feeds)
+	ctx, cancel := context.WithTimeout(ctx, validateDeadline(groups))
+	defer cancel()
 
-		if resp.StatusCode != http.StatusOK {
-			_ = resp.Body.Close()
-			return fmt.Errorf("feed %s returned status %d", feed.URL, resp.StatusCode)
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(maxValidateHosts)
+	for _, feeds := range groups {
+		g.Go(func() error {
+			parser := gofeed.NewParser()
+			for i, feed := range feeds {
+				if i > 0 {
+					if err := sleepCtx(ctx, jittered(validateHostSpacing)); err != nil {
+						return err
+					}
+				}
+				if err := validateFeedURL(ctx, access, parser, feed); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// feedsByHost groups enabled feeds by lowercased host, keeping config order
+// within and across groups
+func feedsByHost(feeds []FeedEntry) [][]FeedEntry {
+	var groups [][]FeedEntry
+	index := map[string]int{}
+	for _, feed := range feeds {
+		if feed.Disabled {
+			continue
 		}
+		host := feed.URL
+		if u, err := url.Parse(feed.URL); err == nil && u.Host != "" {
+			host = strings.ToLower(u.Hostname())
+		}
+		i, ok := index[host]
+		if !ok {
+			i = len(groups)
+			index[host] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], feed)
+	}
+	return groups
+}
+
+// validateDeadline is validateTimeout plus the longest the largest host
+// group can spend pausing between its feeds
+func validateDeadline(groups [][]FeedEntry) time.Duration {
+	largest := 0
+	for _, feeds := range groups {
+		largest = max(largest, len(feeds))
+	}
+	if largest <= 1 {
+		return validateTimeout
+	}
+	maxPause := validateHostSpacing + validateHostSpacing/2
+	return validateTimeout + time.Duration(largest-1)*maxPause
+}
 
-		_, err = parser.Parse(resp.Body)
-		_ = resp.Body.Close()
-		if err != nil {
-			return fmt.Errorf("failed to parse feed %s: %w", feed.URL, err)
+// jittered returns d plus a random extra of up to half of d
+func jittered(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return d + rand.N(d/2+1)
+}
+
+// sleepCtx waits for d or until ctx is done
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// validateFeedURL fetches one feed and checks that it parses
+func validateFeedURL(ctx context.Context, access *FeedAccess, parser *gofeed.Parser, feed FeedEntry) error {
+	transport, err := access.Transport(feed.Proxy)
+	if err != nil {
+		return fmt.Errorf("feed %s: %w", feed.URL, err)
+	}
+	client := &http.Client{
+		Timeout:       5 * time.Second,
+		Transport:     transport,
+		CheckRedirect: access.CheckRedirect,
+	}
+	if feed.Warmup != "" {
+		if err := access.Warmup(ctx, client, feed.Warmup); err != nil {
+			return fmt.Errorf("feed %s: %w", feed.URL, err)
 		}
 	}
 
+	method := http.MethodGet
+	var body io.Reader
+	if feed.Method == http.MethodPost {
+		method = http.MethodPost
+		body = strings.NewReader(feed.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, feed.URL, body)
+	if err != nil {
+		return fmt.Errorf("invalid feed URL %s: %w", feed.URL, err)
+	}
+	if err := access.CheckURL(req.URL); err != nil {
+		return fmt.Errorf("feed %s: %w", feed.URL, err)
+	}
+
+	req.Header.Set("User-Agent", "Herald/1.0 (RSS Aggregator)")
+	if body != nil {
+		req.Header.Set("Content-Type", RequestContentType(feed.Body))
+	}
+	for name, value := range feed.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch feed %s: %w", feed.URL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("feed %s returned status %d", feed.URL, resp.StatusCode)
+	}
+
+	reader := bufio.NewReaderSize(resp.Body, feedSniffSize)
+	if looksLikeWebPage(resp.Header.Get("Content-Type"), reader) {
+		return fmt.Errorf("feed %s: %w", feed.URL, ErrNotAFeed)
+	}
+
+	if _, err := parser.Parse(reader); err != nil {
+		return fmt.Errorf("failed to parse feed %s: %w", feed.URL, err)
+	}
 	return nil
 }
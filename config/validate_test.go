@@ -1,7 +1,15 @@
 package config
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestValidate_NoEmail(t *testing.T) {
@@ -9,7 +17,7 @@ func TestValidate_NoEmail(t *testing.T) {
 		CronExpr: "0 8 * * *",
 		Feeds:    []FeedEntry{{URL: "https://example.com/feed.xml"}},
 	}
-	err := Validate(cfg)
+	err := Validate(cfg, FeedPolicy{})
 	if err != ErrNoEmail {
 		t.Errorf("expected ErrNoEmail, got %v", err)
 	}
@@ -21,7 +29,7 @@ func TestValidate_BadEmail(t *testing.T) {
 		CronExpr: "0 8 * * *",
 		Feeds:    []FeedEntry{{URL: "https://example.com/feed.xml"}},
 	}
-	err := Validate(cfg)
+	err := Validate(cfg, FeedPolicy{})
 	if err != ErrBadEmail {
 		t.Errorf("expected ErrBadEmail, got %v", err)
 	}
@@ -41,7 +49,7 @@ func TestValidate_GoodEmail(t *testing.T) {
 			CronExpr: "0 8 * * *",
 			Feeds:    []FeedEntry{{URL: "https://example.com/feed.xml"}},
 		}
-		err := Validate(cfg)
+		err := Validate(cfg, FeedPolicy{})
 		if err != nil {
 			t.Errorf("email %s should be valid, got error: %v", email, err)
 		}
@@ -53,7 +61,7 @@ func TestValidate_NoCron(t *testing.T) {
 		Email: "user@example.com",
 		Feeds: []FeedEntry{{URL: "https://example.com/feed.xml"}},
 	}
-	err := Validate(cfg)
+	err := Validate(cfg, FeedPolicy{})
 	if err != ErrNoCron {
 		t.Errorf("expected ErrNoCron, got %v", err)
 	}
@@ -73,7 +81,7 @@ func TestValidate_BadCron(t *testing.T) {
 			CronExpr: cron,
 			Feeds:    []FeedEntry{{URL: "https://example.com/feed.xml"}},
 		}
-		err := Validate(cfg)
+		err := Validate(cfg, FeedPolicy{})
 		if err != ErrBadCron {
 			t.Errorf("cron %q should be invalid, got error: %v", cron, err)
 		}
@@ -95,7 +103,7 @@ func TestValidate_GoodCron(t *testing.T) {
 			CronExpr: cron,
 			Feeds:    []FeedEntry{{URL: "https://example.com/feed.xml"}},
 		}
-		err := Validate(cfg)
+		err := Validate(cfg, FeedPolicy{})
 		if err != nil {
 			t.Errorf("cron %q should be valid, got error: %v", cron, err)
 		}
@@ -108,7 +116,7 @@ func TestValidate_NoFeeds(t *testing.T) {
 		CronExpr: "0 8 * * *",
 		Feeds:    []FeedEntry{},
 	}
-	err := Validate(cfg)
+	err := Validate(cfg, FeedPolicy{})
 	if err != ErrNoFeeds {
 		t.Errorf("expected ErrNoFeeds, got %v", err)
 	}
@@ -127,7 +135,7 @@ func TestValidate_BadFeedURL(t *testing.T) {
 			CronExpr: "0 8 * * *",
 			Feeds:    []FeedEntry{{URL: url}},
 		}
-		err := Validate(cfg)
+		err := Validate(cfg, FeedPolicy{})
 		if err != ErrBadFeedURL {
 			t.Errorf("URL %q should be invalid, got error: %v", url, err)
 		}
@@ -149,7 +157,7 @@ func TestValidate_GoodFeedURL(t *testing.T) {
 			CronExpr: "0 8 * * *",
 			Feeds:    []FeedEntry{{URL: url}},
 		}
-		err := Validate(cfg)
+		err := Validate(cfg, FeedPolicy{})
 		if err != nil {
 			t.Errorf("URL %q should be valid, got error: %v", url, err)
 		}
@@ -166,7 +174,7 @@ func TestValidate_MultipleFeeds(t *testing.T) {
 			{URL: "https://feed3.com/json"},
 		},
 	}
-	err := Validate(cfg)
+	err := Validate(cfg, FeedPolicy{})
 	if err != nil {
 		t.Errorf("valid config failed: %v", err)
 	}
@@ -183,8 +191,355 @@ func TestValidate_CompleteConfig(t *testing.T) {
 			{URL: "https://news.example.com/rss"},
 		},
 	}
-	err := Validate(cfg)
+	err := Validate(cfg, FeedPolicy{})
 	if err != nil {
 		t.Errorf("complete valid config failed: %v", err)
 	}
 }
+
+func TestValidate_FeedHeaders(t *testing.T) {
+	tests := []struct {
+		headers  map[string]string
+		expected error
+	}{
+		{map[string]string{"Accept": "application/rss+xml"}, nil},
+		{map[string]string{"Bad Header": "x"}, ErrBadHeader},
+		{map[string]string{"Host": "example.org"}, ErrBadHeader},
+		{map[string]string{"Accept": strings.Repeat("a", maxHeaderValueSize+1)}, ErrHeaderCap},
+	}
+
+	for _, tt := range tests {
+		cfg := &ParsedConfig{
+			Email:    "user@example.com",
+			CronExpr: "0 8 * * *",
+			Feeds:    []FeedEntry{{URL: "https://example.com/feed.xml", Headers: tt.headers}},
+		}
+		if err := Validate(cfg, FeedPolicy{}); err != tt.expected {
+			t.Errorf("headers %v: expected %v, got %v", tt.headers, tt.expected, err)
+		}
+	}
+}
+
+func TestValidate_TooManyFeedHeaders(t *testing.T) {
+	headers := make(map[string]string)
+	for i := 0; i <= maxFeedHeaders; i++ {
+		headers[fmt.Sprintf("X-Header-%d", i)] = "v"
+	}
+	cfg := &ParsedConfig{
+		Email:    "user@example.com",
+		CronExpr: "0 8 * * *",
+		Feeds:    []FeedEntry{{URL: "https://example.com/feed.xml", Headers: headers}},
+	}
+	if err := Validate(cfg, FeedPolicy{}); err != ErrHeaderCap {
+		t.Errorf("expected ErrHeaderCap, got %v", err)
+	}
+}
+
+func TestValidate_DuplicateFeedURL(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"https://example.com/feed.xml", "https://example.com/feed.xml"},
+		{"https://example.com/feed", "https://example.com/feed/"},
+		{"https://Example.COM/feed.xml", "https://example.com/feed.xml"},
+		{"HTTPS://example.com/rss", "https://example.com/rss"},
+	}
+
+	for _, tt := range tests {
+		cfg := &ParsedConfig{
+			Email:    "user@example.com",
+			CronExpr: "0 8 * * *",
+			Feeds:    []FeedEntry{{URL: tt.a}, {URL: tt.b}},
+		}
+		if err := Validate(cfg, FeedPolicy{}); err != ErrDuplicateFeed {
+			t.Errorf("%q and %q should be duplicates, got error: %v", tt.a, tt.b, err)
+		}
+	}
+}
+
+func TestValidate_DistinctFeedURLs(t *testing.T) {
+	cfg := &ParsedConfig{
+		Email:    "user@example.com",
+		CronExpr: "0 8 * * *",
+		Feeds: []FeedEntry{
+			{URL: "https://example.com/feed.xml"},
+			{URL: "https://example.com/Feed.xml"},
+			{URL: "https://example.com/feed.xml?page=2"},
+			{URL: "http://example.org/feed.xml"},
+		},
+	}
+	if err := Validate(cfg, FeedPolicy{}); err != nil {
+		t.Errorf("distinct feeds should be valid, got error: %v", err)
+	}
+}
+
+func TestValidate_Theme(t *testing.T) {
+	tests := []struct {
+		theme    string
+		expected error
+	}{
+		{"", nil},
+		{"default", nil},
+		{"compact", nil},
+		{"newspaper", nil},
+		{"fancy", ErrBadTheme},
+	}
+
+	for _, tt := range tests {
+		cfg := &ParsedConfig{
+			Email:    "user@example.com",
+			CronExpr: "0 8 * * *",
+			Theme:    tt.theme,
+			Feeds:    []FeedEntry{{URL: "https://example.com/feed.xml"}},
+		}
+		if err := Validate(cfg, FeedPolicy{}); err != tt.expected {
+			t.Errorf("theme %q: expected %v, got %v", tt.theme, tt.expected, err)
+		}
+	}
+}
+
+func TestValidate_FooterTooLong(t *testing.T) {
+	cfg := &ParsedConfig{
+		Email:    "user@example.com",
+		CronExpr: "0 8 * * *",
+		Footer:   []string{strings.Repeat("a", maxFooterSize+1)},
+		Feeds:    []FeedEntry{{URL: "https://example.com/feed.xml"}},
+	}
+	if err := Validate(cfg, FeedPolicy{}); err != ErrFooterTooLong {
+		t.Errorf("expected ErrFooterTooLong, got %v", err)
+	}
+}
+
+func TestValidate_StyleTooLong(t *testing.T) {
+	cfg := &ParsedConfig{
+		Email:    "user@example.com",
+		CronExpr: "0 8 * * *",
+		Style:    []string{strings.Repeat("a", maxStyleSize+1)},
+		Feeds:    []FeedEntry{{URL: "https://example.com/feed.xml"}},
+	}
+	if err := Validate(cfg, FeedPolicy{}); err != ErrStyleTooLong {
+		t.Errorf("expected ErrStyleTooLong, got %v", err)
+	}
+}
+
+func TestValidate_FeedMethod(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		expected error
+	}{
+		{"default", "", "", nil},
+		{"get", "GET", "", nil},
+		{"post with body", "POST", `{"limit":50}`, nil},
+		{"body without post", "", "a=b", ErrBadMethod},
+		{"unsupported method", "PUT", "", ErrBadMethod},
+		{"body too large", "POST", strings.Repeat("a", maxFeedBodySize+1), ErrBodyTooLarge},
+	}
+
+	for _, tt := range tests {
+		cfg := &ParsedConfig{
+			Email:    "user@example.com",
+			CronExpr: "0 8 * * *",
+			Feeds:    []FeedEntry{{URL: "https://example.com/feed.xml", Method: tt.method, Body: tt.body}},
+		}
+		if err := Validate(cfg, FeedPolicy{}); err != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestValidate_MinSendAndMaxHold(t *testing.T) {
+	tests := []struct {
+		name     string
+		minSend  int
+		maxHold  time.Duration
+		expected error
+	}{
+		{"unset", 0, 0, nil},
+		{"valid", 3, 7 * 24 * time.Hour, nil},
+		{"invalid min_send", -1, 0, ErrBadMinSend},
+		{"min_send too large", maxMinSend + 1, 0, ErrBadMinSend},
+		{"max_hold too short", 3, time.Minute, ErrBadMaxHold},
+		{"max_hold too long", 3, 90 * 24 * time.Hour, ErrBadMaxHold},
+		{"invalid max_hold", 3, -1, ErrBadMaxHold},
+	}
+
+	for _, tt := range tests {
+		cfg := &ParsedConfig{
+			Email:    "user@example.com",
+			CronExpr: "0 8 * * *",
+			MinSend:  tt.minSend,
+			MaxHold:  tt.maxHold,
+			Feeds:    []FeedEntry{{URL: "https://example.com/feed.xml"}},
+		}
+		if err := Validate(cfg, FeedPolicy{}); err != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestValidate_QuietHours(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected error
+	}{
+		{"", nil},
+		{"22:00-07:00", nil},
+		{"09:30-17:00 Europe/Berlin", nil},
+		{"22:00", ErrBadQuietHours},
+		{"22:00-22:00", ErrBadQuietHours},
+		{"25:00-07:00", ErrBadQuietHours},
+		{"10pm-7am", ErrBadQuietHours},
+		{"22:00-07:00 Mars/Olympus", ErrBadQuietHours},
+	}
+
+	for _, tt := range tests {
+		cfg := &ParsedConfig{
+			Email:      "user@example.com",
+			CronExpr:   "0 8 * * *",
+			QuietHours: tt.value,
+			Feeds:      []FeedEntry{{URL: "https://example.com/feed.xml"}},
+		}
+		if err := Validate(cfg, FeedPolicy{}); err != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.value, tt.expected, err)
+		}
+	}
+}
+
+func TestValidate_Languages(t *testing.T) {
+	cfg := &ParsedConfig{
+		Email:     "user@example.com",
+		CronExpr:  "0 8 * * *",
+		Languages: []string{"en", "es"},
+		Feeds:     []FeedEntry{{URL: "https://example.com/feed.xml"}},
+	}
+	if err := Validate(cfg, FeedPolicy{}); err != nil {
+		t.Errorf("expected valid languages, got %v", err)
+	}
+
+	cfg.Languages = []string{"en", "klingon"}
+	if err := Validate(cfg, FeedPolicy{}); !errors.Is(err, ErrBadLanguage) {
+		t.Errorf("expected ErrBadLanguage, got %v", err)
+	}
+}
+
+func TestValidate_SkipDates(t *testing.T) {
+	tests := []struct {
+		dates   []string
+		wantErr bool
+	}{
+		{nil, false},
+		{[]string{"2025-12-25", "2026-01-01"}, false},
+		{[]string{"12/25/2025"}, true},
+		{[]string{"2025-13-01"}, true},
+		{make([]string, maxSkipDates+1), true},
+	}
+
+	for _, tt := range tests {
+		cfg := &ParsedConfig{
+			Email:     "user@example.com",
+			CronExpr:  "0 8 * * *",
+			SkipDates: tt.dates,
+			Feeds:     []FeedEntry{{URL: "https://example.com/feed.xml"}},
+		}
+		err := Validate(cfg, FeedPolicy{})
+		if tt.wantErr && !errors.Is(err, ErrBadSkipDate) {
+			t.Errorf("Validate(%v, FeedPolicy{}): expected ErrBadSkipDate, got %v", tt.dates, err)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("Validate(%v, FeedPolicy{}): unexpected error %v", tt.dates, err)
+		}
+	}
+}
+
+func TestValidateFeedURLs_WebPage(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("<!DOCTYPE html><html><head><title>Blog</title></head><body></body></html>"))
+	})
+	mux.HandleFunc("/mislabelled", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>Blog</title></channel></rss>`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cfg := &ParsedConfig{Feeds: []FeedEntry{{URL: srv.URL + "/page"}}}
+	if err := ValidateFeedURLs(context.Background(), cfg, NewFeedAccess(FeedPolicy{})); !errors.Is(err, ErrNotAFeed) {
+		t.Errorf("expected ErrNotAFeed for an HTML page, got %v", err)
+	}
+
+	cfg = &ParsedConfig{Feeds: []FeedEntry{{URL: srv.URL + "/mislabelled"}}}
+	if err := ValidateFeedURLs(context.Background(), cfg, NewFeedAccess(FeedPolicy{})); err != nil {
+		t.Errorf("expected a feed served as text/html to validate, got %v", err)
+	}
+}
+
+func TestValidateFeedURLs_SpacesSameHost(t *testing.T) {
+	old := validateHostSpacing
+	validateHostSpacing = 40 * time.Millisecond
+	t.Cleanup(func() { validateHostSpacing = old })
+
+	var mu sync.Mutex
+	var times []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		_, _ = w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>Blog</title></channel></rss>`))
+	}))
+	defer srv.Close()
+
+	cfg := &ParsedConfig{Feeds: []FeedEntry{
+		{URL: srv.URL + "/a"},
+		{URL: srv.URL + "/b"},
+		{URL: srv.URL + "/c"},
+	}}
+	if err := ValidateFeedURLs(context.Background(), cfg, NewFeedAccess(FeedPolicy{})); err != nil {
+		t.Fatalf("ValidateFeedURLs: %v", err)
+	}
+	if len(times) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < validateHostSpacing {
+			t.Errorf("request %d came %s after the previous one, want at least %s", i, gap, validateHostSpacing)
+		}
+	}
+}
+
+func TestValidateDeadline(t *testing.T) {
+	feeds := func(n int) []FeedEntry { return make([]FeedEntry, n) }
+
+	if got := validateDeadline([][]FeedEntry{feeds(1), feeds(1)}); got != validateTimeout {
+		t.Errorf("expected %s with one feed per host, got %s", validateTimeout, got)
+	}
+
+	// 25 feeds from one host pause up to 24 times, which alone would use
+	// most of the base timeout
+	got := validateDeadline([][]FeedEntry{feeds(3), feeds(25)})
+	want := validateTimeout + 24*(validateHostSpacing+validateHostSpacing/2)
+	if got != want {
+		t.Errorf("expected %s for 25 feeds on one host, got %s", want, got)
+	}
+}
+
+func TestFeedsByHost(t *testing.T) {
+	groups := feedsByHost([]FeedEntry{
+		{URL: "https://a.example.com/1"},
+		{URL: "https://b.example.com/1"},
+		{URL: "https://A.example.com/2"},
+		{URL: "https://a.example.com/3", Disabled: true},
+	})
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 host groups, got %d", len(groups))
+	}
+	if len(groups[0]) != 2 || groups[0][1].URL != "https://A.example.com/2" {
+		t.Errorf("a.example.com group = %v", groups[0])
+	}
+	if len(groups[1]) != 1 {
+		t.Errorf("b.example.com group = %v", groups[1])
+	}
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+)
+
+// maxWarmupBody is how much of a warmup page is read before it's closed
+const maxWarmupBody = 1024 * 1024
+
+// validateWarmup checks a feed's warmup URL against the same rules as feeds
+func validateWarmup(raw string, policy *FeedPolicy) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrBadWarmupURL
+	}
+	if err := policy.Check(u); err != nil {
+		return fmt.Errorf("warmup %s: %w", raw, err)
+	}
+	return nil
+}
+
+// Warmup gives client a fresh cookie jar and requests warmupURL with it, so
+// cookies the page sets are sent with the feed request that follows. It
+// shares ctx, and so the fetch's timeout, with that request.
+func (a *FeedAccess) Warmup(ctx context.Context, client *http.Client, warmupURL string) error {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return fmt.Errorf("create cookie jar: %w", err)
+	}
+	client.Jar = jar
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, warmupURL, nil)
+	if err != nil {
+		return fmt.Errorf("warmup %s: %w", warmupURL, err)
+	}
+	if err := a.CheckURL(req.URL); err != nil {
+		return fmt.Errorf("warmup %s: %w", warmupURL, err)
+	}
+	req.Header.Set("User-Agent", "Herald/1.0 (RSS Aggregator)")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("warmup %s: %w", warmupURL, err)
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxWarmupBody))
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("warmup %s returned status %d", warmupURL, resp.StatusCode)
+	}
+	return nil
+}
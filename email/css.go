@@ -0,0 +1,121 @@
+package email
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maxCustomCSSSize caps the sanitized stylesheet added to a digest
+const maxCustomCSSSize = 2000
+
+// cssProperties are the declarations a config's custom style may set; they
+// change how the digest looks without loading anything or moving it offscreen
+var cssProperties = map[string]bool{
+	"color":            true,
+	"background-color": true,
+	"font-family":      true,
+	"font-size":        true,
+	"font-style":       true,
+	"font-weight":      true,
+	"line-height":      true,
+	"letter-spacing":   true,
+	"text-align":       true,
+	"text-decoration":  true,
+	"text-transform":   true,
+	"vertical-align":   true,
+	"margin":           true,
+	"margin-top":       true,
+	"margin-right":     true,
+	"margin-bottom":    true,
+	"margin-left":      true,
+	"padding":          true,
+	"padding-top":      true,
+	"padding-right":    true,
+	"padding-bottom":   true,
+	"padding-left":     true,
+	"border":           true,
+	"border-top":       true,
+	"border-right":     true,
+	"border-bottom":    true,
+	"border-left":      true,
+	"border-color":     true,
+	"border-style":     true,
+	"border-width":     true,
+	"border-radius":    true,
+	"width":            true,
+	"max-width":        true,
+}
+
+// cssFunctions are the only functions allowed in values, all color notations
+var cssFunctions = map[string]bool{
+	"rgb":  true,
+	"rgba": true,
+	"hsl":  true,
+	"hsla": true,
+}
+
+var (
+	cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	// cssSelector allows type, class, id, and pseudo-class selectors and
+	// combinators, but no attribute selectors, escapes, or at-rules
+	cssSelector = regexp.MustCompile(`^[A-Za-z0-9\s.#,>+~:*_-]+$`)
+	// cssValue allows keywords, numbers, units, colors, quoted font names,
+	// and function calls; no escapes, colons, braces, or angle brackets
+	cssValue    = regexp.MustCompile(`^[A-Za-z0-9\s#%.,!'"()/+-]+$`)
+	cssFunction = regexp.MustCompile(`([A-Za-z-]*)\s*\(`)
+)
+
+// SanitizeCSS keeps only the rules and declarations of a config's custom
+// style that pass an allowlist, so nothing in it can load remote resources,
+// run script, or break out of the digest's <style> element. Anything else,
+// including every at-rule, url(), and expression(), is dropped.
+func SanitizeCSS(css string) string {
+	css = cssComment.ReplaceAllString(css, "")
+	var b strings.Builder
+	for _, rule := range strings.Split(css, "}") {
+		selector, body, ok := strings.Cut(rule, "{")
+		selector = strings.Join(strings.Fields(selector), " ")
+		if !ok || selector == "" || !cssSelector.MatchString(selector) {
+			continue
+		}
+		var decls []string
+		for _, decl := range strings.Split(body, ";") {
+			if decl, ok := sanitizeDeclaration(decl); ok {
+				decls = append(decls, decl)
+			}
+		}
+		if len(decls) == 0 {
+			continue
+		}
+		line := selector + " { " + strings.Join(decls, "; ") + "; }\n"
+		if b.Len()+len(line) > maxCustomCSSSize {
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// sanitizeDeclaration returns a "property: value" declaration tidied up, or
+// false when the property or value isn't allowed
+func sanitizeDeclaration(decl string) (string, bool) {
+	prop, value, ok := strings.Cut(decl, ":")
+	if !ok {
+		return "", false
+	}
+	prop = strings.ToLower(strings.TrimSpace(prop))
+	value = strings.Join(strings.Fields(value), " ")
+	if !cssProperties[prop] || value == "" || !cssValue.MatchString(value) {
+		return "", false
+	}
+	if strings.Count(value, "(") != strings.Count(value, ")") ||
+		strings.Count(value, `"`)%2 != 0 || strings.Count(value, "'")%2 != 0 {
+		return "", false
+	}
+	for _, m := range cssFunction.FindAllStringSubmatch(value, -1) {
+		if !cssFunctions[strings.ToLower(m[1])] {
+			return "", false
+		}
+	}
+	return prop + ": " + value, true
+}
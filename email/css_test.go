@@ -0,0 +1,93 @@
+package email
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeCSS(t *testing.T) {
+	tests := []struct {
+		name string
+		css  string
+		want string
+	}{
+		{
+			name: "allowed rules kept",
+			css:  "body { font-family: Georgia, \"Times New Roman\", serif; color: #333 }\na:hover{color:rgb(0, 0, 255)}",
+			want: "body { font-family: Georgia, \"Times New Roman\", serif; color: #333; }\na:hover { color: rgb(0, 0, 255); }\n",
+		},
+		{
+			name: "unknown properties dropped",
+			css:  "p { position: fixed; color: red; display: none }",
+			want: "p { color: red; }\n",
+		},
+		{
+			name: "url and expression dropped",
+			css:  "body { background-color: url(https://evil.example/x.png); width: expression(alert(1)); color: red }",
+			want: "body { color: red; }\n",
+		},
+		{
+			name: "at-rules dropped",
+			css:  "@import url(https://evil.example/x.css); @media print { p { color: red } } h1 { font-size: 20px }",
+			want: "h1 { font-size: 20px; }\n",
+		},
+		{
+			name: "style element breakout dropped",
+			css:  "p { color: red </style><script>alert(1)</script> }",
+			want: "",
+		},
+		{
+			name: "escapes and attribute selectors dropped",
+			css:  "p { color: \\72 ed } a[href^=javascript] { color: red } h2 { color: blue }",
+			want: "h2 { color: blue; }\n",
+		},
+		{
+			name: "comments removed",
+			css:  "/* heading */ h1 { /* size */ font-size: 2em }",
+			want: "h1 { font-size: 2em; }\n",
+		},
+		{
+			name: "unbalanced quotes dropped",
+			css:  "p { font-family: \"Georgia; color: red }",
+			want: "p { color: red; }\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeCSS(tt.css); got != tt.want {
+				t.Errorf("SanitizeCSS(%q) = %q, want %q", tt.css, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeCSS_SizeCap(t *testing.T) {
+	css := strings.Repeat("p { color: red; }\n", maxCustomCSSSize)
+	if got := SanitizeCSS(css); len(got) > maxCustomCSSSize {
+		t.Errorf("expected at most %d bytes, got %d", maxCustomCSSSize, len(got))
+	}
+}
+
+func TestRenderDigest_CustomStyle(t *testing.T) {
+	for _, theme := range []string{"default", "compact", "newspaper"} {
+		data := &DigestData{
+			ConfigName: "Test Config",
+			Theme:      theme,
+			Style:      "h1 { color: #c00 } body { background-color: url(x) }",
+		}
+		htmlOutput, textOutput, err := RenderDigest(data, true, 30, false, false)
+		if err != nil {
+			t.Fatalf("RenderDigest(theme=%q) failed: %v", theme, err)
+		}
+		if !strings.Contains(htmlOutput, "<style>h1 { color: #c00; }\n</style>") {
+			t.Errorf("theme %q: expected custom style in HTML output", theme)
+		}
+		if strings.Contains(htmlOutput, "url(x)") {
+			t.Errorf("theme %q: expected unsafe declaration to be dropped", theme)
+		}
+		if strings.Contains(textOutput, "#c00") {
+			t.Errorf("theme %q: expected no style in text output", theme)
+		}
+	}
+}
@@ -20,19 +20,47 @@ type DigestData struct {
 	ConfigName string
 	TotalItems int
 	FeedGroups []FeedGroup
+	// Theme selects the HTML template; empty or unknown uses DefaultTheme
+	Theme string
+	// Style is the config's custom CSS, added to the HTML digest after
+	// SanitizeCSS drops anything outside its allowlist
+	Style string
+}
+
+// DefaultTheme is the digest theme used when none is configured
+const DefaultTheme = "default"
+
+// themeTemplates maps theme names to their embedded HTML template files
+var themeTemplates = map[string]string{
+	DefaultTheme: "templates/digest.html",
+	"compact":    "templates/digest_compact.html",
+	"newspaper":  "templates/digest_newspaper.html",
 }
 
 type FeedGroup struct {
 	FeedName string
 	FeedURL  string
-	Items    []FeedItem
+	// Favicon is a data: URI shown beside the feed name; anything else is ignored
+	Favicon string
+	// Note is a short annotation from the config shown beside the feed name
+	Note string
+	// Description is the feed's own description, shown under its name as
+	// plain text
+	Description string
+	// Section is the config a group came from in a merged digest; groups
+	// from the same config are listed together under its name
+	Section string
+	Items   []FeedItem
 }
 
 type FeedItem struct {
+	GUID      string
 	Title     string
 	Link      string
 	Content   string
 	Published time.Time
+	// PlainText skips HTML sanitizing; Content is escaped and line breaks kept
+	PlainText bool
 }
 
 // templateFeedItem is used for template rendering with sanitized HTML content
@@ -49,7 +77,13 @@ type templateFeedItem struct {
 type templateFeedGroup struct {
 	FeedName string
 	FeedURL  string
-	Items    []templateFeedItem
+	Favicon  htmltemplate.URL
+	Note     string
+	// Description is the feed's description as one line of plain text
+	Description string
+	// Section is set on the first group of each merged config
+	Section string
+	Items   []templateFeedItem
 }
 
 // emailUnsafeTags are HTML5 semantic tags not supported by most email clients (Gmail, Outlook, etc.)
@@ -88,6 +122,22 @@ var whitespaceCollapse = regexp.MustCompile(`[ \t]+`)
 // multipleNewlines collapses 3+ newlines to 2
 var multipleNewlines = regexp.MustCompile(`\n{3,}`)
 
+// maxDescriptionRunes caps a feed description so it stays a short header line
+const maxDescriptionRunes = 280
+
+// plainDescription flattens a feed description, which may hold HTML, to one
+// line of plain text no longer than maxDescriptionRunes
+func plainDescription(description string) string {
+	if description == "" {
+		return ""
+	}
+	text := strings.Join(strings.Fields(decodeEntities(htmlTagRegex.ReplaceAllString(description, " "))), " ")
+	if runes := []rune(text); len(runes) > maxDescriptionRunes {
+		text = strings.TrimSpace(string(runes[:maxDescriptionRunes-1])) + "…"
+	}
+	return text
+}
+
 // decodeEntities decodes common HTML entities
 func decodeEntities(text string) string {
 	text = strings.ReplaceAll(text, "&amp;", "&")
@@ -141,17 +191,49 @@ func stripHTML(html string) string {
 	return strings.TrimSpace(text)
 }
 
+// faviconURL passes through inlined raster images only, so digests never
+// reference remote images or SVG
+func faviconURL(favicon string) htmltemplate.URL {
+	if !strings.HasPrefix(favicon, "data:image/") || strings.HasPrefix(favicon, "data:image/svg") {
+		return ""
+	}
+	return htmltemplate.URL(favicon) // #nosec G203 -- limited to inline raster image data
+}
+
+// textToHTML escapes plain text content, turning blank lines into paragraphs
+// and single newlines into line breaks
+func textToHTML(text string) string {
+	text = strings.ReplaceAll(strings.TrimSpace(text), "\r\n", "\n")
+	if text == "" {
+		return ""
+	}
+	var b strings.Builder
+	for _, para := range strings.Split(text, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(htmltemplate.HTMLEscapeString(para), "\n", "<br>"))
+		b.WriteString("</p>")
+	}
+	return b.String()
+}
+
 var (
-	htmlTmpl *htmltemplate.Template
-	textTmpl *texttemplate.Template
-	policy   *bluemonday.Policy
+	htmlTmpls map[string]*htmltemplate.Template
+	textTmpl  *texttemplate.Template
+	policy    *bluemonday.Policy
 )
 
 func init() {
 	var err error
-	htmlTmpl, err = htmltemplate.ParseFS(templateFS, "templates/digest.html")
-	if err != nil {
-		panic("failed to parse HTML template: " + err.Error())
+	htmlTmpls = make(map[string]*htmltemplate.Template, len(themeTemplates))
+	for theme, path := range themeTemplates {
+		htmlTmpls[theme], err = htmltemplate.ParseFS(templateFS, path)
+		if err != nil {
+			panic("failed to parse HTML template " + path + ": " + err.Error())
+		}
 	}
 	textTmpl, err = texttemplate.ParseFS(templateFS, "templates/digest.txt")
 	if err != nil {
@@ -167,22 +249,35 @@ func init() {
 func RenderDigest(data *DigestData, inline bool, daysUntilExpiry int, showUrgentBanner, showWarningBanner bool) (html string, text string, err error) {
 	// Convert FeedGroups to templateFeedGroups with sanitized HTML content
 	sanitizedGroups := make([]templateFeedGroup, len(data.FeedGroups))
+	section := ""
 	for i, group := range data.FeedGroups {
 		sanitizedItems := make([]templateFeedItem, len(group.Items))
 		for j, item := range group.Items {
 			sanitizedItems[j] = templateFeedItem{
-				Title:            item.Title,
-				Link:             item.Link,
-				Content:          item.Content,
-				PlainContent:     stripHTML(item.Content),
-				SanitizedContent: htmltemplate.HTML(sanitizeHTML(item.Content)), // #nosec G203 -- Content is sanitized by bluemonday before conversion
-				Published:        item.Published,
+				Title:     item.Title,
+				Link:      item.Link,
+				Content:   item.Content,
+				Published: item.Published,
+			}
+			if item.PlainText {
+				sanitizedItems[j].PlainContent = strings.TrimSpace(item.Content)
+				sanitizedItems[j].SanitizedContent = htmltemplate.HTML(textToHTML(item.Content)) // #nosec G203 -- Content is escaped before conversion
+			} else {
+				sanitizedItems[j].PlainContent = stripHTML(item.Content)
+				sanitizedItems[j].SanitizedContent = htmltemplate.HTML(sanitizeHTML(item.Content)) // #nosec G203 -- Content is sanitized by bluemonday before conversion
 			}
 		}
 		sanitizedGroups[i] = templateFeedGroup{
-			FeedName: group.FeedName,
-			FeedURL:  group.FeedURL,
-			Items:    sanitizedItems,
+			FeedName:    group.FeedName,
+			FeedURL:     group.FeedURL,
+			Favicon:     faviconURL(group.Favicon),
+			Note:        group.Note,
+			Description: plainDescription(group.Description),
+			Items:       sanitizedItems,
+		}
+		if group.Section != section {
+			section = group.Section
+			sanitizedGroups[i].Section = section
 		}
 	}
 
@@ -195,6 +290,7 @@ func RenderDigest(data *DigestData, inline bool, daysUntilExpiry int, showUrgent
 		DaysUntilExpiry   int
 		ShowUrgentBanner  bool
 		ShowWarningBanner bool
+		Style             htmltemplate.CSS
 	}{
 		ConfigName:        data.ConfigName,
 		TotalItems:        data.TotalItems,
@@ -203,6 +299,7 @@ func RenderDigest(data *DigestData, inline bool, daysUntilExpiry int, showUrgent
 		DaysUntilExpiry:   daysUntilExpiry,
 		ShowUrgentBanner:  showUrgentBanner,
 		ShowWarningBanner: showWarningBanner,
+		Style:             htmltemplate.CSS(SanitizeCSS(data.Style)), // #nosec G203 -- limited to allowlisted rules by SanitizeCSS
 	}
 
 	// Prepare template data for text template (with plain text content)
@@ -226,6 +323,11 @@ func RenderDigest(data *DigestData, inline bool, daysUntilExpiry int, showUrgent
 
 	var htmlBuf, textBuf bytes.Buffer
 
+	htmlTmpl, ok := htmlTmpls[data.Theme]
+	if !ok {
+		htmlTmpl = htmlTmpls[DefaultTheme]
+	}
+
 	if err = htmlTmpl.Execute(&htmlBuf, htmlTmplData); err != nil {
 		return "", "", err
 	}
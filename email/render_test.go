@@ -135,6 +135,40 @@ func TestRenderDigest_TextOutputNoHTMLTags(t *testing.T) {
 	}
 }
 
+func TestRenderDigest_PlainTextContent(t *testing.T) {
+	data := &DigestData{
+		ConfigName: "Test Config",
+		TotalItems: 1,
+		FeedGroups: []FeedGroup{
+			{
+				FeedName: "Test Feed",
+				FeedURL:  "https://example.com/feed",
+				Items: []FeedItem{
+					{
+						Title:     "Text Article",
+						Link:      "https://example.com/article",
+						Content:   "if a < b && <b>c</b>\nsecond line\n\nnext paragraph",
+						Published: time.Now(),
+						PlainText: true,
+					},
+				},
+			},
+		},
+	}
+
+	htmlOutput, textOutput, err := RenderDigest(data, true, 30, false, false)
+	if err != nil {
+		t.Fatalf("RenderDigest failed: %v", err)
+	}
+
+	if !strings.Contains(htmlOutput, "<p>if a &lt; b &amp;&amp; &lt;b&gt;c&lt;/b&gt;<br>second line</p><p>next paragraph</p>") {
+		t.Error("HTML output should escape plain text and keep line breaks")
+	}
+	if !strings.Contains(textOutput, "if a < b && <b>c</b>\nsecond line") {
+		t.Error("Text output should keep plain text content verbatim")
+	}
+}
+
 func TestRenderDigest_CodeBlockFormatting(t *testing.T) {
 	data := &DigestData{
 		ConfigName: "Test Config",
@@ -181,3 +215,161 @@ echo hello</pre><p>Done.</p>`,
 		t.Error("Text output should not contain HTML tags")
 	}
 }
+
+func TestRenderDigest_Themes(t *testing.T) {
+	for _, theme := range []string{"", "default", "compact", "newspaper", "unknown"} {
+		data := &DigestData{
+			ConfigName: "Test Config",
+			TotalItems: 1,
+			Theme:      theme,
+			FeedGroups: []FeedGroup{
+				{
+					FeedName: "Test Feed",
+					FeedURL:  "https://example.com/feed",
+					Items: []FeedItem{
+						{
+							Title:     "Test Article",
+							Link:      "https://example.com/article",
+							Content:   "<p>Body</p>",
+							Published: time.Now(),
+						},
+					},
+				},
+			},
+		}
+
+		htmlOutput, _, err := RenderDigest(data, true, 30, false, false)
+		if err != nil {
+			t.Fatalf("RenderDigest(theme=%q) failed: %v", theme, err)
+		}
+		if !strings.Contains(htmlOutput, "https://example.com/article") {
+			t.Errorf("theme %q: expected article link in output", theme)
+		}
+	}
+}
+
+func TestRenderDigest_NewspaperThemeMasthead(t *testing.T) {
+	data := &DigestData{
+		ConfigName: "Morning Paper",
+		TotalItems: 0,
+		Theme:      "newspaper",
+	}
+
+	htmlOutput, _, err := RenderDigest(data, false, 30, false, false)
+	if err != nil {
+		t.Fatalf("RenderDigest failed: %v", err)
+	}
+	if !strings.Contains(htmlOutput, "Morning Paper") {
+		t.Error("newspaper theme should render the config name masthead")
+	}
+}
+
+func TestRenderDigest_Favicons(t *testing.T) {
+	const icon = "data:image/png;base64,iVBORw0KGgo="
+	for _, theme := range []string{"default", "compact", "newspaper"} {
+		data := &DigestData{
+			ConfigName: "Test Config",
+			TotalItems: 2,
+			Theme:      theme,
+			FeedGroups: []FeedGroup{
+				{FeedName: "With Icon", FeedURL: "https://a.example.com/feed", Favicon: icon, Items: []FeedItem{{Title: "A", Link: "https://a.example.com/1"}}},
+				{FeedName: "Remote Icon", FeedURL: "https://b.example.com/feed", Favicon: "https://b.example.com/favicon.ico", Items: []FeedItem{{Title: "B", Link: "https://b.example.com/1"}}},
+			},
+		}
+
+		htmlOutput, _, err := RenderDigest(data, false, 30, false, false)
+		if err != nil {
+			t.Fatalf("%s: RenderDigest failed: %v", theme, err)
+		}
+		if !strings.Contains(htmlOutput, `src="`+icon+`"`) {
+			t.Errorf("%s: expected inlined favicon", theme)
+		}
+		if strings.Contains(htmlOutput, "b.example.com/favicon.ico") {
+			t.Errorf("%s: remote favicon should not be rendered", theme)
+		}
+	}
+}
+
+func TestRenderDigest_FeedNote(t *testing.T) {
+	for _, theme := range []string{"default", "compact", "newspaper"} {
+		data := &DigestData{
+			ConfigName: "Test Config",
+			TotalItems: 1,
+			Theme:      theme,
+			FeedGroups: []FeedGroup{
+				{FeedName: "Example", FeedURL: "https://example.com/feed", Note: "Must <read>", Items: []FeedItem{{Title: "A", Link: "https://example.com/1"}}},
+			},
+		}
+
+		htmlOutput, textOutput, err := RenderDigest(data, false, 30, false, false)
+		if err != nil {
+			t.Fatalf("%s: RenderDigest failed: %v", theme, err)
+		}
+		if !strings.Contains(htmlOutput, "Must &lt;read&gt;") {
+			t.Errorf("%s: expected escaped note in HTML", theme)
+		}
+		if !strings.Contains(textOutput, "Example (Must <read>)") {
+			t.Errorf("%s: expected note beside feed name in text", theme)
+		}
+	}
+}
+
+func TestRenderDigest_MergedSections(t *testing.T) {
+	item := []FeedItem{{Title: "A", Link: "https://example.com/1"}}
+	data := &DigestData{
+		ConfigName: "daily.txt",
+		TotalItems: 3,
+		FeedGroups: []FeedGroup{
+			{FeedName: "One", FeedURL: "https://one.example.com/feed", Section: "daily.txt", Items: item},
+			{FeedName: "Two", FeedURL: "https://two.example.com/feed", Section: "work.txt", Items: item},
+			{FeedName: "Three", FeedURL: "https://three.example.com/feed", Section: "work.txt", Items: item},
+		},
+	}
+
+	for _, theme := range []string{"default", "compact", "newspaper"} {
+		data.Theme = theme
+		htmlOutput, textOutput, err := RenderDigest(data, false, 30, false, false)
+		if err != nil {
+			t.Fatalf("%s: RenderDigest failed: %v", theme, err)
+		}
+		// work.txt heads one section even though two groups came from it
+		if n := strings.Count(htmlOutput, ">work.txt<"); n != 1 {
+			t.Errorf("%s: expected one work.txt heading in HTML, got %d", theme, n)
+		}
+		if n := strings.Count(textOutput, "== work.txt =="); n != 1 {
+			t.Errorf("%s: expected one work.txt heading in text, got %d", theme, n)
+		}
+		if strings.Index(textOutput, "== daily.txt ==") > strings.Index(textOutput, "== work.txt ==") {
+			t.Errorf("%s: expected sections in group order", theme)
+		}
+	}
+}
+
+func TestRenderDigest_FeedDescription(t *testing.T) {
+	long := strings.Repeat("word ", 100)
+	for _, theme := range []string{"default", "compact", "newspaper"} {
+		data := &DigestData{
+			ConfigName: "Test Config",
+			TotalItems: 1,
+			Theme:      theme,
+			FeedGroups: []FeedGroup{
+				{FeedName: "Example", FeedURL: "https://example.com/feed", Description: "<p>Notes &amp; <b>links</b></p>", Items: []FeedItem{{Title: "A", Link: "https://example.com/1"}}},
+				{FeedName: "Long", FeedURL: "https://example.com/long", Description: long, Items: []FeedItem{{Title: "B", Link: "https://example.com/2"}}},
+			},
+		}
+
+		htmlOutput, textOutput, err := RenderDigest(data, false, 30, false, false)
+		if err != nil {
+			t.Fatalf("%s: RenderDigest failed: %v", theme, err)
+		}
+		if !strings.Contains(htmlOutput, "Notes &amp; links") {
+			t.Errorf("%s: expected the description as plain text in HTML", theme)
+		}
+		if !strings.Contains(textOutput, "Example\nNotes & links\n") {
+			t.Errorf("%s: expected the description under the feed name in text", theme)
+		}
+		if strings.Contains(textOutput, long) || !strings.Contains(textOutput, "…") {
+			t.Errorf("%s: expected a long description to be truncated", theme)
+		}
+	}
+}
@@ -6,14 +6,20 @@ import (
 	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/pem"
 	"fmt"
+	"html"
 	"mime"
 	"mime/quotedprintable"
 	"net"
+	"net/mail"
 	"net/smtp"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,67 +27,210 @@ import (
 )
 
 type SMTPConfig struct {
-	Host               string
-	Port               int
-	User               string
-	Pass               string
-	From               string
-	DKIMPrivateKey     string
-	DKIMPrivateKeyFile string
-	DKIMSelector       string
-	DKIMDomain         string
+	Host                 string
+	Port                 int
+	User                 string
+	Pass                 string
+	From                 string
+	DKIMPrivateKey       string
+	DKIMPrivateKeyFile   string
+	DKIMSelector         string
+	DKIMDomain           string
+	DKIMCanonicalization string
+	DKIMHeaders          []string
+	DKIMKeys             []DKIMKey
+	SendTimeout          time.Duration
 }
 
+// DKIMKey is a DKIM private key published in DNS under Selector. Listing
+// several lets DKIMSelector pick the one that signs during a rotation.
+type DKIMKey struct {
+	Selector       string
+	PrivateKey     string
+	PrivateKeyFile string
+}
+
+const (
+	// alternativeBoundary delimits the text and HTML bodies
+	alternativeBoundary = "==herald-boundary-a1b2c3d4e5f6=="
+	// mixedBoundary delimits the bodies from attachments
+	mixedBoundary = "==herald-mixed-f6e5d4c3b2a1=="
+	// htmlAttachmentName is the file name of the attached HTML digest
+	htmlAttachmentName = "digest.html"
+	base64LineLength   = 76
+)
+
+// defaultDKIMHeaders are always signed. Listed headers missing from a message
+// are still named in the signature, so they can't be added in transit.
+var defaultDKIMHeaders = []string{
+	"From",
+	"To",
+	"Subject",
+	"Date",
+	"Message-ID",
+	"List-Unsubscribe",
+	"List-Unsubscribe-Post",
+}
+
+// dkimHeaderNameRegex matches RFC 5322 header field names
+var dkimHeaderNameRegex = regexp.MustCompile(`^[!-9;-~]+$`)
+
 type Mailer struct {
 	cfg          SMTPConfig
 	unsubBaseURL string
 	dkimKey      *rsa.PrivateKey
+	dkimHeaderC  dkim.Canonicalization
+	dkimBodyC    dkim.Canonicalization
+	dkimHeaders  []string
 }
 
 func NewMailer(cfg SMTPConfig, unsubBaseURL string) (*Mailer, error) {
+	if cfg.SendTimeout <= 0 {
+		return nil, fmt.Errorf("send timeout must be positive, got %s", cfg.SendTimeout)
+	}
+
 	m := &Mailer{
 		cfg:          cfg,
 		unsubBaseURL: unsubBaseURL,
 	}
 
-	// Parse DKIM private key if provided
-	var keyData string
-	if cfg.DKIMPrivateKey != "" {
-		keyData = cfg.DKIMPrivateKey
-	} else if cfg.DKIMPrivateKeyFile != "" {
-		keyBytes, err := os.ReadFile(cfg.DKIMPrivateKeyFile)
+	var err error
+	m.dkimHeaderC, m.dkimBodyC, err = ParseDKIMCanonicalization(cfg.DKIMCanonicalization)
+	if err != nil {
+		return nil, err
+	}
+	m.dkimHeaders, err = DKIMHeaderKeys(cfg.DKIMHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	m.dkimKey, err = LoadDKIMKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// LoadDKIMKey parses every configured DKIM key and returns the one that signs
+// under cfg.DKIMSelector: its DKIMKeys entry, or else the single
+// DKIMPrivateKey or DKIMPrivateKeyFile. Keys for other selectors are parsed
+// too, so switching back to one is only a config change. It returns nil when
+// no key is configured.
+func LoadDKIMKey(cfg SMTPConfig) (*rsa.PrivateKey, error) {
+	active, err := parseDKIMKey(cfg.DKIMPrivateKey, cfg.DKIMPrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.DKIMKeys) == 0 {
+		return active, nil
+	}
+
+	found := false
+	seen := make(map[string]bool, len(cfg.DKIMKeys))
+	for _, k := range cfg.DKIMKeys {
+		selector := strings.TrimSpace(k.Selector)
+		if selector == "" {
+			return nil, fmt.Errorf("DKIM key without a selector")
+		}
+		if seen[selector] {
+			return nil, fmt.Errorf("duplicate DKIM selector %q", selector)
+		}
+		seen[selector] = true
+
+		key, err := parseDKIMKey(k.PrivateKey, k.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("DKIM selector %q: %w", selector, err)
+		}
+		if key == nil {
+			return nil, fmt.Errorf("DKIM selector %q has no private key", selector)
+		}
+		if selector == cfg.DKIMSelector {
+			active, found = key, true
+		}
+	}
+	if !found && active == nil {
+		return nil, fmt.Errorf("no DKIM key for selector %q", cfg.DKIMSelector)
+	}
+	return active, nil
+}
+
+// parseDKIMKey parses a PEM RSA key given inline or as a file path, returning
+// nil if neither is set
+func parseDKIMKey(keyData, keyFile string) (*rsa.PrivateKey, error) {
+	if keyData == "" && keyFile != "" {
+		keyBytes, err := os.ReadFile(keyFile)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read DKIM private key file: %w", err)
 		}
 		keyData = string(keyBytes)
 	}
+	if keyData == "" || !strings.Contains(keyData, "BEGIN") {
+		return nil, nil
+	}
 
-	if keyData != "" && strings.Contains(keyData, "BEGIN") {
-		// Replace literal \n with actual newlines (for .env file compatibility)
-		keyData = strings.ReplaceAll(keyData, "\\n", "\n")
+	// Replace literal \n with actual newlines (for .env file compatibility)
+	keyData = strings.ReplaceAll(keyData, "\\n", "\n")
 
-		block, _ := pem.Decode([]byte(keyData))
-		if block == nil {
-			return nil, fmt.Errorf("failed to decode DKIM private key PEM")
-		}
+	block, _ := pem.Decode([]byte(keyData))
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode DKIM private key PEM")
+	}
 
-		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		// Try PKCS8 format
+		keyInterface, err := x509.ParsePKCS8PrivateKey(block.Bytes)
 		if err != nil {
-			// Try PKCS8 format
-			keyInterface, err := x509.ParsePKCS8PrivateKey(block.Bytes)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse DKIM private key: %w", err)
-			}
-			var ok bool
-			key, ok = keyInterface.(*rsa.PrivateKey)
-			if !ok {
-				return nil, fmt.Errorf("DKIM private key is not RSA")
-			}
+			return nil, fmt.Errorf("failed to parse DKIM private key: %w", err)
+		}
+		var ok bool
+		key, ok = keyInterface.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("DKIM private key is not RSA")
 		}
-		m.dkimKey = key
 	}
+	return key, nil
+}
 
-	return m, nil
+// ParseDKIMCanonicalization parses "header/body" canonicalization modes such
+// as "relaxed/simple". A single mode applies to both; empty means relaxed.
+func ParseDKIMCanonicalization(s string) (header, body dkim.Canonicalization, err error) {
+	if s == "" {
+		return dkim.CanonicalizationRelaxed, dkim.CanonicalizationRelaxed, nil
+	}
+	h, b, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "/")
+	if !ok {
+		b = h
+	}
+	for _, mode := range []string{h, b} {
+		if mode != string(dkim.CanonicalizationSimple) && mode != string(dkim.CanonicalizationRelaxed) {
+			return "", "", fmt.Errorf("DKIM canonicalization must be simple or relaxed, got %q", s)
+		}
+	}
+	return dkim.Canonicalization(h), dkim.Canonicalization(b), nil
+}
+
+// DKIMHeaderKeys returns the default signed headers followed by extra,
+// skipping duplicates regardless of case
+func DKIMHeaderKeys(extra []string) ([]string, error) {
+	keys := append([]string(nil), defaultDKIMHeaders...)
+	seen := make(map[string]bool, len(keys)+len(extra))
+	for _, k := range keys {
+		seen[strings.ToLower(k)] = true
+	}
+	for _, k := range extra {
+		k = strings.TrimSpace(k)
+		if !dkimHeaderNameRegex.MatchString(k) {
+			return nil, fmt.Errorf("invalid DKIM header name %q", k)
+		}
+		if seen[strings.ToLower(k)] {
+			continue
+		}
+		seen[strings.ToLower(k)] = true
+		keys = append(keys, k)
+	}
+	return keys, nil
 }
 
 // ValidateConfig tests SMTP connectivity and auth
@@ -152,54 +301,217 @@ func (m *Mailer) ValidateConfig() error {
 	return client.Quit()
 }
 
-func (m *Mailer) Send(to, subject, htmlBody, textBody, unsubToken, dashboardURL, keepAliveURL string) error {
-	addr := net.JoinHostPort(m.cfg.Host, fmt.Sprintf("%d", m.cfg.Port))
-
-	boundary := "==herald-boundary-a1b2c3d4e5f6=="
+// buildFooter renders the custom footer text followed by the keep-alive,
+// profile, and unsubscribe links for both message parts.
+func (m *Mailer) buildFooter(footer, unsubToken, dashboardURL, keepAliveURL string) (string, string) {
+	hasLinks := keepAliveURL != "" || unsubToken != "" || dashboardURL != ""
+	if footer == "" && !hasLinks {
+		return "", ""
+	}
 
-	// Add footer with keep-alive, unsubscribe, and dashboard links
 	var htmlFooter strings.Builder
 	var textFooter strings.Builder
 
-	if keepAliveURL != "" || unsubToken != "" || dashboardURL != "" {
-		htmlFooter.WriteString(`<hr><p style="font-size: 12px; color: #666;">`)
-		textFooter.WriteString("\n\n---\n")
+	htmlFooter.WriteString("<hr>")
+	textFooter.WriteString("\n\n---\n")
 
-		if keepAliveURL != "" {
-			htmlFooter.WriteString(fmt.Sprintf(`<a href="%s">keep this digest active</a>`, keepAliveURL))
-			textFooter.WriteString(fmt.Sprintf("keep this digest active: %s\n", keepAliveURL))
+	if footer != "" {
+		htmlFooter.WriteString(`<p style="font-size: 12px; color: #666;">`)
+		htmlFooter.WriteString(strings.ReplaceAll(html.EscapeString(footer), "\n", "<br>"))
+		htmlFooter.WriteString("</p>")
+		textFooter.WriteString(footer + "\n")
+		if hasLinks {
+			textFooter.WriteString("\n")
 		}
+	}
 
-		if dashboardURL != "" {
-			if keepAliveURL != "" {
-				htmlFooter.WriteString(" • ")
-			}
-			htmlFooter.WriteString(fmt.Sprintf(`<a href="%s">profile</a>`, dashboardURL))
-			textFooter.WriteString(fmt.Sprintf("profile: %s\n", dashboardURL))
+	if !hasLinks {
+		return htmlFooter.String(), textFooter.String()
+	}
+
+	htmlFooter.WriteString(`<p style="font-size: 12px; color: #666;">`)
+
+	if keepAliveURL != "" {
+		htmlFooter.WriteString(fmt.Sprintf(`<a href="%s">keep this digest active</a>`, keepAliveURL))
+		textFooter.WriteString(fmt.Sprintf("keep this digest active: %s\n", keepAliveURL))
+	}
+
+	if dashboardURL != "" {
+		if keepAliveURL != "" {
+			htmlFooter.WriteString(" • ")
 		}
+		htmlFooter.WriteString(fmt.Sprintf(`<a href="%s">profile</a>`, dashboardURL))
+		textFooter.WriteString(fmt.Sprintf("profile: %s\n", dashboardURL))
+	}
 
-		if unsubToken != "" {
-			unsubURL := m.unsubBaseURL + "/unsubscribe/" + unsubToken
-			if dashboardURL != "" || keepAliveURL != "" {
-				htmlFooter.WriteString(" • ")
-			}
-			htmlFooter.WriteString(fmt.Sprintf(`<a href="%s">unsubscribe</a>`, unsubURL))
-			textFooter.WriteString(fmt.Sprintf("unsubscribe: %s\n", unsubURL))
+	if unsubToken != "" {
+		unsubURL := m.unsubBaseURL + "/unsubscribe/" + unsubToken
+		if dashboardURL != "" || keepAliveURL != "" {
+			htmlFooter.WriteString(" • ")
 		}
+		htmlFooter.WriteString(fmt.Sprintf(`<a href="%s">unsubscribe</a>`, unsubURL))
+		textFooter.WriteString(fmt.Sprintf("unsubscribe: %s\n", unsubURL))
+	}
 
-		htmlFooter.WriteString("</p>")
-		htmlBody = htmlBody + htmlFooter.String()
-		textBody = textBody + textFooter.String()
+	htmlFooter.WriteString("</p>")
+	return htmlFooter.String(), textFooter.String()
+}
+
+// DigestMeta describes the digest being sent, exposed to recipients as
+// X-Herald-* headers for client-side filtering.
+type DigestMeta struct {
+	ConfigName string
+	FeedCount  int
+	// ThreadID, when set, links every digest with the same ID into one thread
+	ThreadID string
+	// AttachHTML also attaches the HTML digest as digest.html, for saving
+	AttachHTML bool
+}
+
+// filterHeaders returns the X-Herald-Config and X-Herald-Feed-Count headers
+func (d DigestMeta) filterHeaders() map[string]string {
+	if d.ConfigName == "" {
+		return nil
+	}
+	return map[string]string{
+		"X-Herald-Config":     mime.QEncoding.Encode("utf-8", d.ConfigName),
+		"X-Herald-Feed-Count": strconv.Itoa(d.FeedCount),
+	}
+}
+
+// SendReceipt records the SMTP server accepting a message
+type SendReceipt struct {
+	To      string
+	Subject string
+	// Server is the SMTP host that accepted the message
+	Server string
+	// Response is the server's reply to the message data, which often
+	// carries a queue ID
+	Response string
+}
+
+func (m *Mailer) Send(to, subject, htmlBody, textBody, unsubToken, dashboardURL, keepAliveURL, footer string, meta DigestMeta) (SendReceipt, error) {
+	receipt := SendReceipt{To: to, Subject: subject, Server: m.cfg.Host}
+	addr := net.JoinHostPort(m.cfg.Host, fmt.Sprintf("%d", m.cfg.Port))
+
+	messageBytes, err := m.BuildMessage(to, subject, htmlBody, textBody, unsubToken, dashboardURL, keepAliveURL, footer, meta)
+	if err != nil {
+		return receipt, err
+	}
+
+	var auth smtp.Auth
+	if m.cfg.User != "" && m.cfg.Pass != "" {
+		auth = smtp.PlainAuth("", m.cfg.User, m.cfg.Pass, m.cfg.Host)
+	}
+
+	if m.cfg.Port == 465 {
+		receipt.Response, err = m.sendWithTLS(addr, auth, to, messageBytes)
+	} else {
+		receipt.Response, err = m.sendWithSTARTTLS(addr, auth, to, messageBytes)
+	}
+	return receipt, err
+}
+
+// BuildMessage returns the MIME message Send would deliver, footer added and
+// DKIM-signed if configured
+func (m *Mailer) BuildMessage(to, subject, htmlBody, textBody, unsubToken, dashboardURL, keepAliveURL, footer string, meta DigestMeta) ([]byte, error) {
+	// The attachment is the digest alone, without the footer's personal links
+	attachment := htmlBody
+
+	htmlFooter, textFooter := m.buildFooter(footer, unsubToken, dashboardURL, keepAliveURL)
+	htmlBody += htmlFooter
+	textBody += textFooter
+
+	contentType := fmt.Sprintf("multipart/alternative; boundary=%q", alternativeBoundary)
+	if meta.AttachHTML {
+		contentType = fmt.Sprintf("multipart/mixed; boundary=%q", mixedBoundary)
+	}
+
+	headers := m.buildHeaders(to, subject, unsubToken, dashboardURL, contentType, meta)
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
 	}
+	sort.Strings(names)
 
+	var msg strings.Builder
+	for _, k := range names {
+		msg.WriteString(fmt.Sprintf("%s: %s\r\n", k, headers[k]))
+	}
+	msg.WriteString("\r\n")
+
+	if meta.AttachHTML {
+		msg.WriteString(fmt.Sprintf("--%s\r\n", mixedBoundary))
+		msg.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=%q\r\n\r\n", alternativeBoundary))
+		writeAlternative(&msg, textBody, htmlBody)
+		msg.WriteString("\r\n")
+
+		msg.WriteString(fmt.Sprintf("--%s\r\n", mixedBoundary))
+		msg.WriteString(fmt.Sprintf("Content-Type: text/html; charset=utf-8; name=%q\r\n", htmlAttachmentName))
+		msg.WriteString(fmt.Sprintf("Content-Disposition: attachment; filename=%q\r\n", htmlAttachmentName))
+		msg.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		msg.WriteString(encodeBase64Lines(attachment))
+		msg.WriteString(fmt.Sprintf("--%s--\r\n", mixedBoundary))
+	} else {
+		writeAlternative(&msg, textBody, htmlBody)
+	}
+
+	messageBytes := []byte(msg.String())
+
+	// Sign with DKIM if configured
+	if m.dkimKey != nil && m.cfg.DKIMDomain != "" && m.cfg.DKIMSelector != "" {
+		signed, err := m.signDKIM(messageBytes)
+		if err != nil {
+			return nil, fmt.Errorf("DKIM signing: %w", err)
+		}
+		messageBytes = signed
+	}
+
+	return messageBytes, nil
+}
+
+// writeAlternative writes the text and HTML bodies as the parts of a
+// multipart/alternative section delimited by alternativeBoundary
+func writeAlternative(msg *strings.Builder, textBody, htmlBody string) {
+	msg.WriteString(fmt.Sprintf("--%s\r\n", alternativeBoundary))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	msg.WriteString(encodeQuotedPrintable(textBody))
+	msg.WriteString("\r\n")
+
+	msg.WriteString(fmt.Sprintf("--%s\r\n", alternativeBoundary))
+	msg.WriteString("Content-Type: text/html; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	msg.WriteString(encodeQuotedPrintable(htmlBody))
+	msg.WriteString("\r\n")
+
+	msg.WriteString(fmt.Sprintf("--%s--\r\n", alternativeBoundary))
+}
+
+// encodeBase64Lines base64-encodes s in 76-character lines, as MIME requires
+func encodeBase64Lines(s string) string {
+	encoded := base64.StdEncoding.EncodeToString([]byte(s))
+	var b strings.Builder
+	for len(encoded) > base64LineLength {
+		b.WriteString(encoded[:base64LineLength])
+		b.WriteString("\r\n")
+		encoded = encoded[base64LineLength:]
+	}
+	b.WriteString(encoded)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// buildHeaders returns the top-level headers of a digest message
+func (m *Mailer) buildHeaders(to, subject, unsubToken, dashboardURL, contentType string, meta DigestMeta) map[string]string {
 	headers := make(map[string]string)
 	headers["From"] = m.cfg.From
 	headers["To"] = to
 	headers["Subject"] = mime.QEncoding.Encode("utf-8", subject)
 	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = fmt.Sprintf("multipart/alternative; boundary=%q", boundary)
-	headers["Date"] = time.Now().Format(time.RFC1123Z)
-	headers["Message-ID"] = fmt.Sprintf("<%d.%s@%s>", time.Now().Unix(), generateMessageIDToken(), m.cfg.Host)
+	headers["Content-Type"] = contentType
+	headers["Date"] = formatDate(time.Now())
+	headers["Message-ID"] = m.messageID()
 
 	// RFC 2369 list headers
 	headers["List-Id"] = fmt.Sprintf("<herald.%s>", m.cfg.Host)
@@ -217,55 +529,73 @@ func (m *Mailer) Send(to, subject, htmlBody, textBody, unsubToken, dashboardURL,
 	headers["Precedence"] = "bulk"
 	headers["X-Mailer"] = "Herald"
 
-	var msg strings.Builder
-	for k, v := range headers {
-		msg.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
+	// Per-config headers so recipients can filter digests
+	for k, v := range meta.filterHeaders() {
+		headers[k] = v
 	}
-	msg.WriteString("\r\n")
-
-	msg.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
-	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
-	textQP := encodeQuotedPrintable(textBody)
-	msg.WriteString(textQP)
-	msg.WriteString("\r\n")
+	for k, v := range m.threadHeaders(meta.ThreadID) {
+		headers[k] = v
+	}
+	return headers
+}
 
-	msg.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-	msg.WriteString("Content-Type: text/html; charset=utf-8\r\n")
-	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
-	htmlQP := encodeQuotedPrintable(htmlBody)
-	msg.WriteString(htmlQP)
-	msg.WriteString("\r\n")
+// HeaderBlock returns the header section of a message built by BuildMessage,
+// including any DKIM-Signature, without the trailing blank line
+func HeaderBlock(msg []byte) string {
+	if i := bytes.Index(msg, []byte("\r\n\r\n")); i >= 0 {
+		return string(msg[:i])
+	}
+	return string(msg)
+}
 
-	msg.WriteString(fmt.Sprintf("--%s--\r\n", boundary))
+func generateMessageIDToken() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
 
-	messageBytes := []byte(msg.String())
+// messageID returns an RFC 5322 Message-ID scoped to the From domain
+func (m *Mailer) messageID() string {
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().Unix(), generateMessageIDToken(), m.messageIDDomain())
+}
 
-	// Sign with DKIM if configured
-	if m.dkimKey != nil && m.cfg.DKIMDomain != "" && m.cfg.DKIMSelector != "" {
-		signed, err := m.signDKIM(messageBytes)
-		if err != nil {
-			return fmt.Errorf("DKIM signing: %w", err)
-		}
-		messageBytes = signed
+// messageIDDomain is the From domain, falling back to the SMTP host
+func (m *Mailer) messageIDDomain() string {
+	if domain := fromDomain(m.cfg.From); domain != "" {
+		return domain
 	}
+	return m.cfg.Host
+}
 
-	var auth smtp.Auth
-	if m.cfg.User != "" && m.cfg.Pass != "" {
-		auth = smtp.PlainAuth("", m.cfg.User, m.cfg.Pass, m.cfg.Host)
+// threadHeaders points every digest in a thread at the same root message ID
+// so mail clients group them, even though that message is never sent
+func (m *Mailer) threadHeaders(threadID string) map[string]string {
+	if threadID == "" {
+		return nil
 	}
-
-	if m.cfg.Port == 465 {
-		return m.sendWithTLS(addr, auth, to, messageBytes)
+	root := fmt.Sprintf("<%s@%s>", threadID, m.messageIDDomain())
+	return map[string]string{
+		"References":  root,
+		"In-Reply-To": root,
 	}
+}
 
-	return m.sendWithSTARTTLS(addr, auth, to, messageBytes)
+// fromDomain extracts the domain from a From address, or "" if it can't be parsed
+func fromDomain(from string) string {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return ""
+	}
+	at := strings.LastIndex(addr.Address, "@")
+	if at < 0 {
+		return ""
+	}
+	return addr.Address[at+1:]
 }
 
-func generateMessageIDToken() string {
-	b := make([]byte, 8)
-	_, _ = rand.Read(b)
-	return hex.EncodeToString(b)
+// formatDate renders t as an RFC 5322 Date header value
+func formatDate(t time.Time) string {
+	return t.Format(time.RFC1123Z)
 }
 
 func encodeQuotedPrintable(s string) string {
@@ -276,74 +606,47 @@ func encodeQuotedPrintable(s string) string {
 	return buf.String()
 }
 
-func (m *Mailer) sendWithTLS(addr string, auth smtp.Auth, to string, msg []byte) error {
+func (m *Mailer) sendWithTLS(addr string, auth smtp.Auth, to string, msg []byte) (string, error) {
 	tlsConfig := &tls.Config{
 		ServerName: m.cfg.Host,
 		MinVersion: tls.VersionTLS12,
 	}
 
-	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	dialer := &net.Dialer{Timeout: m.cfg.SendTimeout}
 	conn, err := tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
 	if err != nil {
-		return fmt.Errorf("TLS dial: %w", err)
+		return "", fmt.Errorf("TLS dial: %w", err)
 	}
-	if err := conn.SetDeadline(time.Now().Add(30 * time.Second)); err != nil {
+	if err := conn.SetDeadline(time.Now().Add(m.cfg.SendTimeout)); err != nil {
 		_ = conn.Close()
-		return fmt.Errorf("set deadline: %w", err)
+		return "", fmt.Errorf("set deadline: %w", err)
 	}
 	defer func() { _ = conn.Close() }()
 
 	client, err := smtp.NewClient(conn, m.cfg.Host)
 	if err != nil {
-		return fmt.Errorf("SMTP client: %w", err)
+		return "", fmt.Errorf("SMTP client: %w", err)
 	}
 	defer func() { _ = client.Close() }()
 
-	if auth != nil {
-		if err = client.Auth(auth); err != nil {
-			return fmt.Errorf("auth: %w", err)
-		}
-	}
-
-	if err = client.Mail(m.cfg.From); err != nil {
-		return fmt.Errorf("mail from: %w", err)
-	}
-
-	if err = client.Rcpt(to); err != nil {
-		return fmt.Errorf("rcpt to: %w", err)
-	}
-
-	w, err := client.Data()
-	if err != nil {
-		return fmt.Errorf("data: %w", err)
-	}
-
-	if _, err = w.Write(msg); err != nil {
-		return fmt.Errorf("write: %w", err)
-	}
-
-	if err = w.Close(); err != nil {
-		return fmt.Errorf("close data: %w", err)
-	}
-
-	return client.Quit()
+	return m.deliver(client, auth, to, msg)
 }
 
-func (m *Mailer) sendWithSTARTTLS(addr string, auth smtp.Auth, to string, msg []byte) error {
-	dialer := &net.Dialer{Timeout: 30 * time.Second}
+func (m *Mailer) sendWithSTARTTLS(addr string, auth smtp.Auth, to string, msg []byte) (string, error) {
+	dialer := &net.Dialer{Timeout: m.cfg.SendTimeout}
 	conn, err := dialer.Dial("tcp", addr)
 	if err != nil {
-		return fmt.Errorf("dial: %w", err)
+		return "", fmt.Errorf("dial: %w", err)
 	}
-	if err := conn.SetDeadline(time.Now().Add(30 * time.Second)); err != nil {
+	if err := conn.SetDeadline(time.Now().Add(m.cfg.SendTimeout)); err != nil {
 		_ = conn.Close()
-		return fmt.Errorf("set deadline: %w", err)
+		return "", fmt.Errorf("set deadline: %w", err)
 	}
 	defer func() { _ = conn.Close() }()
 
 	client, err := smtp.NewClient(conn, m.cfg.Host)
 	if err != nil {
-		return fmt.Errorf("SMTP client: %w", err)
+		return "", fmt.Errorf("SMTP client: %w", err)
 	}
 	defer func() { _ = client.Close() }()
 
@@ -351,37 +654,55 @@ func (m *Mailer) sendWithSTARTTLS(addr string, auth smtp.Auth, to string, msg []
 		ServerName: m.cfg.Host,
 		MinVersion: tls.VersionTLS12,
 	}); err != nil {
-		return fmt.Errorf("STARTTLS: %w", err)
+		return "", fmt.Errorf("STARTTLS: %w", err)
 	}
 
+	return m.deliver(client, auth, to, msg)
+}
+
+// deliver runs the SMTP transaction for one message on a connected client and
+// returns the server's reply to the message data
+func (m *Mailer) deliver(client *smtp.Client, auth smtp.Auth, to string, msg []byte) (string, error) {
 	if auth != nil {
-		if err = client.Auth(auth); err != nil {
-			return fmt.Errorf("auth: %w", err)
+		if err := client.Auth(auth); err != nil {
+			return "", fmt.Errorf("auth: %w", err)
 		}
 	}
 
-	if err = client.Mail(m.cfg.From); err != nil {
-		return fmt.Errorf("mail from: %w", err)
+	if err := client.Mail(m.cfg.From); err != nil {
+		return "", fmt.Errorf("mail from: %w", err)
 	}
 
-	if err = client.Rcpt(to); err != nil {
-		return fmt.Errorf("rcpt to: %w", err)
+	if err := client.Rcpt(to); err != nil {
+		return "", fmt.Errorf("rcpt to: %w", err)
 	}
 
-	w, err := client.Data()
+	// client.Data discards the server's final reply, so DATA is sent by
+	// hand to keep it
+	id, err := client.Text.Cmd("DATA")
 	if err != nil {
-		return fmt.Errorf("data: %w", err)
+		return "", fmt.Errorf("data: %w", err)
 	}
-
-	if _, err = w.Write(msg); err != nil {
-		return fmt.Errorf("write: %w", err)
+	client.Text.StartResponse(id)
+	_, _, err = client.Text.ReadResponse(354)
+	client.Text.EndResponse(id)
+	if err != nil {
+		return "", fmt.Errorf("data: %w", err)
 	}
 
-	if err = w.Close(); err != nil {
-		return fmt.Errorf("close data: %w", err)
+	w := client.Text.DotWriter()
+	if _, err := w.Write(msg); err != nil {
+		return "", fmt.Errorf("write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("close data: %w", err)
+	}
+	_, reply, err := client.Text.ReadResponse(250)
+	if err != nil {
+		return "", fmt.Errorf("close data: %w", err)
 	}
 
-	return client.Quit()
+	return reply, client.Quit()
 }
 
 func (m *Mailer) signDKIM(message []byte) ([]byte, error) {
@@ -389,16 +710,10 @@ func (m *Mailer) signDKIM(message []byte) ([]byte, error) {
 		Domain:                 m.cfg.DKIMDomain,
 		Selector:               m.cfg.DKIMSelector,
 		Signer:                 m.dkimKey,
-		HeaderCanonicalization: dkim.CanonicalizationRelaxed,
-		BodyCanonicalization:   dkim.CanonicalizationRelaxed,
-		HeaderKeys: []string{
-			"From",
-			"To",
-			"Subject",
-			"List-Unsubscribe",
-			"List-Unsubscribe-Post",
-		},
-		Expiration: time.Now().Add(72 * time.Hour),
+		HeaderCanonicalization: m.dkimHeaderC,
+		BodyCanonicalization:   m.dkimBodyC,
+		HeaderKeys:             m.dkimHeaders,
+		Expiration:             time.Now().Add(72 * time.Hour),
 	}
 
 	var b bytes.Buffer
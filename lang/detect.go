@@ -0,0 +1,162 @@
+// Package lang provides best-effort language detection for feed items.
+package lang
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	// minWords is the fewest words needed to guess a Latin-script language
+	minWords = 5
+	// minHits is the fewest stopword matches needed for the winning language
+	minHits = 2
+	// minScriptShare is the share of letters a non-Latin script needs
+	minScriptShare = 0.3
+)
+
+// stopwords are very common words that rarely appear in other languages.
+// Words shared by several languages (e.g. "a", "de", "la") are left out.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "was", "were", "of", "to", "in", "that", "it", "with", "for", "this", "you", "have", "not", "be", "on", "by", "from", "they", "we", "which", "will", "would", "has", "been"},
+	"es": {"el", "los", "las", "del", "y", "que", "en", "una", "por", "con", "para", "es", "su", "pero", "como", "más", "fue", "este", "esta", "son", "muy", "también", "sobre", "entre", "cuando"},
+	"fr": {"le", "les", "des", "et", "est", "une", "du", "que", "qui", "dans", "pour", "pas", "sur", "avec", "sont", "ce", "cette", "mais", "nous", "vous", "ils", "été", "aux", "très", "être"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "mit", "auf", "für", "den", "dem", "sich", "auch", "wird", "wurde", "sind", "noch", "nach", "bei", "aus", "oder", "werden", "über"},
+	"it": {"il", "gli", "della", "che", "è", "non", "per", "sono", "con", "una", "nel", "alla", "anche", "come", "più", "questo", "questa", "degli", "delle", "essere", "stato", "dei", "ha", "ma"},
+	"pt": {"os", "das", "dos", "não", "uma", "com", "para", "em", "que", "é", "mais", "foi", "como", "mas", "ao", "pelo", "pela", "são", "também", "está", "seu", "sua", "isso", "muito", "já"},
+	"nl": {"het", "een", "van", "en", "is", "niet", "dat", "zijn", "voor", "met", "op", "ook", "maar", "wordt", "werd", "naar", "bij", "deze", "heeft", "hebben", "kan", "nog", "wel", "om"},
+}
+
+// scriptLanguages maps non-Latin scripts to the language they most likely mean
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	code  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Han, "zh"},
+}
+
+var stopwordIndex = buildIndex()
+
+func buildIndex() map[string][]string {
+	index := make(map[string][]string)
+	for code, words := range stopwords {
+		for _, w := range words {
+			index[w] = append(index[w], code)
+		}
+	}
+	return index
+}
+
+// Supported reports whether Detect can return the given ISO 639-1 code
+func Supported(code string) bool {
+	if _, ok := stopwords[code]; ok {
+		return true
+	}
+	if code == "ja" {
+		return true
+	}
+	for _, s := range scriptLanguages {
+		if s.code == code {
+			return true
+		}
+	}
+	return false
+}
+
+var tagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// Detect guesses the ISO 639-1 language of text, which may contain HTML.
+// It returns "" when the text is too short or ambiguous to call.
+func Detect(text string) string {
+	text = html.UnescapeString(tagRegex.ReplaceAllString(text, " "))
+
+	if code := detectScript(text); code != "" {
+		return code
+	}
+	return detectLatin(text)
+}
+
+// detectScript picks a language from a dominant non-Latin script
+func detectScript(text string) string {
+	var letters, kana int
+	counts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.In(r, unicode.Hiragana, unicode.Katakana) {
+			kana++
+			continue
+		}
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.table, r) {
+				counts[s.code]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	// Japanese mixes kanji with kana
+	if float64(kana)/float64(letters) >= minScriptShare/3 {
+		return "ja"
+	}
+
+	best, bestCount := "", 0
+	for code, n := range counts {
+		if n > bestCount {
+			best, bestCount = code, n
+		}
+	}
+	if float64(bestCount)/float64(letters) >= minScriptShare {
+		return best
+	}
+	return ""
+}
+
+// detectLatin scores stopword hits per language and returns a clear winner
+func detectLatin(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) < minWords {
+		return ""
+	}
+
+	scores := make(map[string]int)
+	for _, w := range words {
+		for _, code := range stopwordIndex[w] {
+			scores[code]++
+		}
+	}
+
+	best, second := "", 0
+	bestScore := 0
+	for code, n := range scores {
+		switch {
+		case n > bestScore:
+			second = bestScore
+			best, bestScore = code, n
+		case n > second:
+			second = n
+		}
+	}
+
+	// Require a clear margin so mixed or short text passes through
+	if bestScore < minHits || bestScore < 2*second {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,43 @@
+package lang
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"english", "The new release of the compiler is faster and it has been tested with all of the packages.", "en"},
+		{"spanish", "El equipo publicó una nueva versión del programa con muchas mejoras para los usuarios.", "es"},
+		{"french", "Le gouvernement a annoncé une nouvelle loi qui sera votée dans les prochains jours.", "fr"},
+		{"german", "Die neue Version ist nicht nur schneller, sondern auch für den Einsatz mit der Cloud gedacht.", "de"},
+		{"html", "<p>The <b>quick</b> brown fox is jumping over the lazy dog and it was fast.</p>", "en"},
+		{"russian", "Новая версия программы вышла сегодня утром", "ru"},
+		{"japanese", "新しいバージョンがリリースされました", "ja"},
+		{"chinese", "新版本今天发布了", "zh"},
+		{"korean", "새 버전이 출시되었습니다", "ko"},
+		{"too short", "Hello world", ""},
+		{"empty", "", ""},
+		{"no stopwords", "Kubernetes Terraform Docker Prometheus Grafana Loki", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Detect(tt.text); got != tt.expected {
+			t.Errorf("%s: Detect() = %q, expected %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestSupported(t *testing.T) {
+	for _, code := range []string{"en", "es", "fr", "de", "it", "pt", "nl", "ru", "ja", "zh", "ko"} {
+		if !Supported(code) {
+			t.Errorf("expected %q to be supported", code)
+		}
+	}
+	for _, code := range []string{"", "xx", "english", "EN"} {
+		if Supported(code) {
+			t.Errorf("expected %q to be unsupported", code)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
@@ -11,7 +12,6 @@ import (
 	"github.com/charmbracelet/fang"
 	"github.com/charmbracelet/log"
 	"github.com/kierank/herald/config"
-	"github.com/kierank/herald/email"
 	"github.com/kierank/herald/scheduler"
 	"github.com/kierank/herald/ssh"
 	"github.com/kierank/herald/store"
@@ -45,6 +45,9 @@ Upload a feed config via SCP, get email digests on a schedule.`,
 
 	rootCmd.AddCommand(serveCmd())
 	rootCmd.AddCommand(initCmd())
+	rootCmd.AddCommand(checkCmd())
+	rootCmd.AddCommand(rateLimitCmd())
+	rootCmd.AddCommand(rotateHostKeyCmd())
 
 	if err := fang.Execute(
 		context.Background(),
@@ -53,7 +56,7 @@ Upload a feed config via SCP, get email digests on a schedule.`,
 		fang.WithCommit(commitHash),
 		fang.WithNotifySignal(os.Interrupt, os.Kill),
 	); err != nil {
-		os.Exit(1)
+		os.Exit(exitCodeFor(err))
 	}
 }
 
@@ -68,17 +71,26 @@ func serveCmd() *cobra.Command {
 }
 
 func initCmd() *cobra.Command {
-	return &cobra.Command{
+	var validate bool
+
+	cmd := &cobra.Command{
 		Use:   "init [config_path]",
 		Short: "Generate a sample configuration file",
-		Long:  "Create a config.yaml file with default values. If no path is provided, uses config.yaml",
-		Args:  cobra.MaximumNArgs(1),
+		Long: `Create a config.yaml file with default values. If no path is provided, uses config.yaml
+
+With --validate, check an existing config file instead: SMTP fields, origin URL,
+port ranges, and database and host key paths are checked and reported together.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			path := "config.yaml"
 			if len(args) > 0 {
 				path = args[0]
 			}
 
+			if validate {
+				return validateConfigFile(cmd.OutOrStdout(), path)
+			}
+
 			if _, err := os.Stat(path); err == nil {
 				return fmt.Errorf("config file already exists at %s", path)
 			}
@@ -92,6 +104,14 @@ http_port: 8080
 # Public URL where Herald is accessible
 origin: http://localhost:8080
 
+# Path prefix when served under a subpath, e.g. https://example.com/herald
+# (defaults to the path in origin)
+# base_path: /herald
+
+# Rewrite generated links to https even if origin is http
+# (useful when a TLS-terminating proxy sits in front of Herald)
+# force_https_links: false
+
 # External SSH port (defaults to ssh_port if not set)
 # Use this when SSH is exposed through a different port publicly
 # external_ssh_port: 22
@@ -101,6 +121,87 @@ host_key_path: ./host_key
 
 # Database
 db_path: ./herald.db
+# Read-only connections for the web dashboard and feeds (writes use one connection)
+# db_read_conns: 4
+
+# Maximum seen items kept per feed (0 disables the cap)
+# max_seen_items_per_feed: 1000
+
+# Maximum items read from a single fetch, newest first (guards against huge feeds)
+# max_items_per_feed: 500
+
+# Fetch each feed URL at most this often across all configs, reusing the last
+# result in between, to go easy on publishers (0 disables)
+# min_fetch_interval: 15m
+
+# Retry a feed fetch this many times after a network error or 5xx response,
+# waiting fetch_retry_backoff before the first retry and doubling after
+# fetch_retries: 2
+# fetch_retry_backoff: 500ms
+
+# When a feed is added, follow its rel="next" pages this many times past the
+# first so older posts are marked seen too (0 disables; scheduled runs only
+# ever read the first page)
+# backfill_pages: 0
+
+# Items dated in the future: "clamp" treats them as published now, "skip"
+# leaves them out until their date arrives, "allow" keeps the feed's date
+# future_items: clamp
+
+# Days without new items before a feed is flagged as stale (0 disables)
+# stale_feed_days: 90
+
+# A config's first run only sends items published this recently and marks
+# older ones seen, in case feeds were down when it was uploaded (0 disables)
+# first_run_window: 48h
+
+# Days to keep per-config activity logs
+# log_retention_days: 30
+
+# Spread sends over this many minutes after their cron time (0 disables).
+# Each config gets a fixed offset, so an 8:00 digest may always arrive at 8:04.
+# schedule_jitter_minutes: 0
+
+# Gzip stored config text to keep the database small
+# compress_raw_text: false
+
+# Turn off configs whose digests go unopened for 90 days. Reading a config's
+# RSS/JSON feed or the dashboard counts as activity too.
+# auto_deactivate_inactive: true
+
+# Serve HTTPS directly instead of behind a proxy (links default to https)
+# tls_cert_file: ./cert.pem
+# tls_key_file: ./key.pem
+
+# Restrict which feeds users can add (empty lists mean no restriction).
+# Hosts match subdomains too; the blocklist wins over the allowlist.
+# allowed_feed_schemes: [https]
+# feed_host_allowlist: [example.com]
+# feed_host_blocklist: [ads.example.com]
+
+# Fetch feeds through a proxy (http, https, or socks5). Hosts in
+# feed_no_proxy are fetched directly; it defaults to NO_PROXY.
+# feed_proxy_url: http://proxy.internal:3128
+# feed_no_proxy: localhost,.internal
+
+# Per-feed proxy: options must point at public addresses, except for the
+# hosts listed here, such as a local Tor daemon
+# feed_proxy_hosts: [127.0.0.1]
+
+# Webhook that receives a JSON summary whenever a digest is sent
+# digest_webhook_url: https://example.com/hooks/herald
+
+# Push the /metrics snapshot to a collector: JSON POST for http(s) URLs,
+# StatsD gauges for udp://host:port
+# metrics_push_url: udp://localhost:8125
+# metrics_push_interval: 60s
+
+# Keep an audit record of each sent digest: "hash" stores a SHA-256 of the
+# text body, "full" stores the text body too (off by default)
+# audit_email_bodies: hash
+
+# Bearer token for the /admin endpoints (disabled when unset)
+# admin_token: ${HERALD_ADMIN_TOKEN}
 
 # SMTP
 smtp:
@@ -109,11 +210,36 @@ smtp:
   user: sender@example.com
   pass: ${SMTP_PASS}  # Env var substitution
   from: herald@example.com
+  # send_timeout: 30s  # Dial and connection deadline per message
+  # DKIM signing. List keys under dkim_keys to rotate: dkim_selector picks the
+  # one that signs, and the old selector can stay in DNS until mail drains.
+  # dkim_domain: example.com
+  # dkim_selector: herald2026
+  # dkim_keys:
+  #   - selector: herald2025
+  #     private_key_file: ./dkim-2025.pem
+  #   - selector: herald2026
+  #     private_key_file: ./dkim-2026.pem
+  # DKIM canonicalization as header/body: simple or relaxed (default relaxed/relaxed)
+  # dkim_canonicalization: relaxed/simple
+  # Headers to sign besides From, To, Subject, Date, Message-ID and List-Unsubscribe*
+  # dkim_headers: [List-Id, Reply-To]
 
 # Auth
 allow_all_keys: true
 # allowed_keys:
 #   - "ssh-ed25519 AAAA... user@host"
+
+# Limit which SSH commands users may run, e.g. on a public instance with
+# allow_all_keys. disabled_commands wins over allowed_commands; uploads over
+# scp/sftp aren't affected.
+# allowed_commands: [ls, cat, feeds, logs]
+# disabled_commands: [run, run-all, fetch]
+
+# Message shown to users after the SSH welcome text (terms of use, contact,
+# donation link). motd_file is read instead when set.
+# motd: "Be kind to publishers. Questions: admin@example.com"
+# motd_file: ./motd.txt
 `
 
 			if err := os.WriteFile(path, []byte(sampleConfig), 0600); err != nil {
@@ -124,12 +250,36 @@ allow_all_keys: true
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&validate, "validate", false, "validate an existing config file instead of creating one")
+	return cmd
+}
+
+// validateConfigFile loads the config at path and prints a report of every
+// problem found, returning an error if there were any
+func validateConfigFile(w io.Writer, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return withExitCode(exitConfig, fmt.Errorf("config file not found: %w", err))
+	}
+	cfg, err := config.LoadAppConfig(path)
+	if err != nil {
+		return withExitCode(exitConfig, fmt.Errorf("failed to load config: %w", err))
+	}
+
+	if failed := printValidateReport(w, path, validateAppConfig(cfg)); failed > 0 {
+		return withExitCode(exitConfig, fmt.Errorf("%s has %d problem(s)", path, failed))
+	}
+	return nil
 }
 
 func runServer(ctx context.Context) error {
 	cfg, err := config.LoadAppConfig(cfgFile)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return withExitCode(exitConfig, fmt.Errorf("failed to load config: %w", err))
+	}
+
+	if err := validateStartup(cfg); err != nil {
+		return err
 	}
 
 	// Set log level from config
@@ -154,42 +304,60 @@ func runServer(ctx context.Context) error {
 
 	db, err := store.Open(cfg.DBPath)
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return withExitCode(exitDatabase, fmt.Errorf("failed to open database: %w", err))
 	}
 	defer func() { _ = db.Close() }()
+	db.SetCompressRawText(cfg.CompressRawText)
+	db.SetReadConns(cfg.DBReadConns)
+	db.SetScheduleJitter(time.Duration(cfg.ScheduleJitterMins) * time.Minute)
 
 	if err := db.Migrate(); err != nil {
-		return fmt.Errorf("failed to migrate database: %w", err)
-	}
-
-	mailer, err := email.NewMailer(email.SMTPConfig{
-		Host:               cfg.SMTP.Host,
-		Port:               cfg.SMTP.Port,
-		User:               cfg.SMTP.User,
-		Pass:               cfg.SMTP.Pass,
-		From:               cfg.SMTP.From,
-		DKIMPrivateKey:     cfg.SMTP.DKIMPrivateKey,
-		DKIMPrivateKeyFile: cfg.SMTP.DKIMPrivateKeyFile,
-		DKIMSelector:       cfg.SMTP.DKIMSelector,
-		DKIMDomain:         cfg.SMTP.DKIMDomain,
-	}, cfg.Origin)
+		return withExitCode(exitDatabase, fmt.Errorf("failed to migrate database: %w", err))
+	}
+
+	mailer, err := newValidatedMailer(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create mailer: %w", err)
+		return err
 	}
 
-	// Validate SMTP configuration
-	if err := mailer.ValidateConfig(); err != nil {
-		return fmt.Errorf("SMTP validation failed: %w", err)
+	feedAccess := config.NewFeedAccess(cfg.FeedPolicy())
+	if err := feedAccess.SetProxy(cfg.FeedProxyURL, cfg.FeedNoProxy); err != nil {
+		return withExitCode(exitConfig, err)
 	}
 
-	sched := scheduler.NewScheduler(db, mailer, logger, 60*time.Second, cfg.Origin)
+	sched := scheduler.NewScheduler(scheduler.Config{
+		Interval:            60 * time.Second,
+		OriginURL:           cfg.LinkOrigin(),
+		MaxSeenItemsPerFeed: cfg.MaxSeenItemsPerFeed,
+		DigestWebhookURL:    cfg.DigestWebhookURL,
+		StaleFeedThreshold:  time.Duration(cfg.StaleFeedDays) * 24 * time.Hour,
+		FirstRunWindow:      cfg.FirstRunWindow,
+		MinFetchInterval:    cfg.MinFetchInterval,
+		LogRetentionDays:    cfg.LogRetentionDays,
+		KeepInactive:        !cfg.AutoDeactivateInactive,
+		AuditEmailBodies:    cfg.AuditEmailBodies,
+		Feeds:               feedAccess,
+		MaxItemsPerFeed:     cfg.MaxItemsPerFeed,
+		FetchRetries:        cfg.FetchRetries,
+		FetchRetryBackoff:   cfg.FetchRetryBackoff,
+		BackfillPages:       cfg.BackfillPages,
+		FutureItems:         cfg.FutureItems,
+	}, db, mailer, logger)
+
+	motd, err := cfg.MOTDText()
+	if err != nil {
+		return withExitCode(exitConfig, err)
+	}
 
 	sshServer := ssh.NewServer(ssh.Config{
-		Host:         cfg.Host,
-		Port:         cfg.SSHPort,
-		HostKeyPath:  cfg.HostKeyPath,
-		AllowAllKeys: cfg.AllowAllKeys,
-		AllowedKeys:  cfg.AllowedKeys,
+		Host:             cfg.Host,
+		Port:             cfg.SSHPort,
+		HostKeyPath:      cfg.HostKeyPath,
+		AllowAllKeys:     cfg.AllowAllKeys,
+		AllowedKeys:      cfg.AllowedKeys,
+		AllowedCommands:  cfg.AllowedCommands,
+		DisabledCommands: cfg.DisabledCommands,
+		MOTD:             motd,
 	}, db, sched, logger)
 
 	// Get commit hash - prefer build-time embedded hash, fallback to git
@@ -203,7 +371,17 @@ func runServer(ctx context.Context) error {
 		}
 	}
 
-	webServer := web.NewServer(db, fmt.Sprintf("%s:%d", cfg.Host, cfg.HTTPPort), cfg.Origin, cfg.ExternalSSHPort, logger, hash)
+	webServer := web.NewServer(db, fmt.Sprintf("%s:%d", cfg.Host, cfg.HTTPPort), cfg.LinkOrigin(), cfg.ExternalSSHPort, logger, hash)
+	if cfg.TLSEnabled() {
+		webServer.SetTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	webServer.SetHostKeyPath(cfg.HostKeyPath)
+	webServer.SetBasePath(cfg.WebBasePath())
+	webServer.SetAdminToken(cfg.AdminToken)
+	if cfg.MetricsPushURL != "" {
+		webServer.SetMetricsPush(cfg.MetricsPushURL, cfg.MetricsPushInterval)
+	}
+	sched.SetTickRecorder(webServer.Metrics())
 
 	g, ctx := errgroup.WithContext(ctx)
 
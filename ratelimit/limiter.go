@@ -46,6 +46,29 @@ func (l *Limiter) Allow(key string) bool {
 	return limiter.Allow()
 }
 
+// AllowRate is like Allow but uses the given rate and burst for this key
+// instead of the limiter's defaults, e.g. for per-user overrides.
+func (l *Limiter) AllowRate(key string, rps float64, burst int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limiter, exists := l.limiters[key]
+	if !exists {
+		limiter = rate.NewLimiter(rate.Limit(rps), burst)
+		l.limiters[key] = limiter
+	} else {
+		if limiter.Limit() != rate.Limit(rps) {
+			limiter.SetLimit(rate.Limit(rps))
+		}
+		if limiter.Burst() != burst {
+			limiter.SetBurst(burst)
+		}
+	}
+
+	l.lastSeen[key] = time.Now()
+	return limiter.Allow()
+}
+
 // cleanupLoop removes limiters that haven't been used recently
 func (l *Limiter) cleanupLoop() {
 	ticker := time.NewTicker(l.cleanup)
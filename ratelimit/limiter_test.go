@@ -44,6 +44,36 @@ func TestAllow_SingleKey(t *testing.T) {
 	}
 }
 
+func TestAllowRate(t *testing.T) {
+	limiter := New(0.01, 1)
+	key := "email:1"
+
+	// Override allows a larger burst than the default
+	for i := 0; i < 3; i++ {
+		if !limiter.AllowRate(key, 0.01, 3) {
+			t.Errorf("request %d should be allowed with burst 3", i+1)
+		}
+	}
+	if limiter.AllowRate(key, 0.01, 3) {
+		t.Error("fourth request should be blocked")
+	}
+
+	// Default limiter for other keys is unaffected
+	if !limiter.Allow("email:2") {
+		t.Error("first default request should be allowed")
+	}
+	if limiter.Allow("email:2") {
+		t.Error("second default request should be blocked")
+	}
+
+	// Changing the rate updates the existing bucket
+	limiter.AllowRate(key, 1000, 3)
+	time.Sleep(5 * time.Millisecond)
+	if !limiter.AllowRate(key, 1000, 3) {
+		t.Error("request should be allowed after raising the rate")
+	}
+}
+
 func TestAllow_MultipleKeys(t *testing.T) {
 	limiter := New(10, 1)
 
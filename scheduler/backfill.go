@@ -0,0 +1,150 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kierank/herald/store"
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/atom"
+)
+
+const (
+	// maxBackfillItems bounds the items a backfill collects across all pages
+	maxBackfillItems = 5000
+	// backfillTimeout bounds a whole backfill, however many pages it follows
+	backfillTimeout = 60 * time.Second
+
+	// nextPageKey carries a translated feed's next-page link in Feed.Custom
+	nextPageKey = "herald:next_page"
+)
+
+// FetchFeedBackfill fetches a feed, then follows its rel="next" (or JSON
+// Feed next_url) links for up to Config.BackfillPages pages, so a newly added
+// feed's seen items cover more of its history. Later pages that fail end the
+// backfill without failing the result, and so does a next page on another
+// host, since the feed's headers, warmup, and proxy are meant for its own
+// host. It is meant for seeding new feeds, not for scheduled runs.
+func (s *Scheduler) FetchFeedBackfill(ctx context.Context, feed *store.Feed) *FetchResult {
+	return s.fetcher.fetchBackfill(ctx, feed)
+}
+
+func (f *fetcher) fetchBackfill(ctx context.Context, feed *store.Feed) *FetchResult {
+	pages := f.backfillPages
+	if pages == 0 || feed.Method == http.MethodPost {
+		return f.fetch(ctx, feed)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, backfillTimeout)
+	defer cancel()
+
+	result := f.fetch(ctx, feed)
+	if result.Error != nil || result.NotModified {
+		return result
+	}
+
+	seen := make(map[string]bool, len(result.Items))
+	for _, item := range result.Items {
+		seen[item.GUID] = true
+	}
+	visited := map[string]bool{feed.URL: true}
+
+	next := result.nextPage
+	for range pages {
+		if next == "" || visited[next] || len(result.Items) >= maxBackfillItems || !sameHost(feed.URL, next) {
+			break
+		}
+		visited[next] = true
+
+		page := *feed
+		page.URL = next
+		page.ETag = sql.NullString{}
+		page.LastModified = sql.NullString{}
+		pageResult := f.fetch(ctx, &page)
+		if pageResult.Error != nil || pageResult.NotModified {
+			break
+		}
+
+		for _, item := range pageResult.Items {
+			if seen[item.GUID] || len(result.Items) >= maxBackfillItems {
+				continue
+			}
+			seen[item.GUID] = true
+			result.Items = append(result.Items, item)
+		}
+		next = pageResult.nextPage
+	}
+	return result
+}
+
+// sameHost reports whether two URLs share a scheme and host
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host)
+}
+
+// atomFeedTranslator keeps an Atom feed's rel="next" link, which gofeed
+// drops when translating to its universal feed
+type atomFeedTranslator struct {
+	gofeed.DefaultAtomTranslator
+}
+
+func (t *atomFeedTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	parsed, err := t.DefaultAtomTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+	if af, ok := feed.(*atom.Feed); ok {
+		for _, link := range af.Links {
+			if strings.EqualFold(link.Rel, "next") {
+				setNextPage(parsed, link.Href)
+				break
+			}
+		}
+	}
+	return parsed, nil
+}
+
+// setNextPage records a next-page link on a translated feed
+func setNextPage(parsed *gofeed.Feed, href string) {
+	if href = strings.TrimSpace(href); href == "" {
+		return
+	}
+	if parsed.Custom == nil {
+		parsed.Custom = make(map[string]string)
+	}
+	parsed.Custom[nextPageKey] = href
+}
+
+// nextPageURL returns the feed's next-page link resolved against the page
+// it came from. RSS feeds carry it as an <atom:link rel="next"> extension.
+func nextPageURL(base *url.URL, parsed *gofeed.Feed) string {
+	href := parsed.Custom[nextPageKey]
+	if href == "" {
+		for _, link := range parsed.Extensions["atom"]["link"] {
+			if strings.EqualFold(link.Attrs["rel"], "next") {
+				href = strings.TrimSpace(link.Attrs["href"])
+				break
+			}
+		}
+	}
+	if href == "" {
+		return ""
+	}
+	u, err := base.Parse(href)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
@@ -0,0 +1,118 @@
+package scheduler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kierank/herald/store"
+)
+
+func TestFetchFeedBackfill(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/atom", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title>
+<link rel="next" href="rss?page=2"/>
+<entry><id>a1</id><title>One</title><link href="https://example.com/1"/></entry>
+</feed>`))
+	})
+	mux.HandleFunc("/rss", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>Blog</title>
+<atom:link rel="next" href="/json"/>
+<item><guid>a1</guid><title>One again</title></item>
+<item><guid>r2</guid><title>Two</title></item>
+</channel></rss>`))
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/feed+json")
+		_, _ = w.Write([]byte(`{"version":"https://jsonfeed.org/version/1.1","title":"Blog",
+"next_url":"/atom","items":[{"id":"j3","content_text":"Three"}]}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	feed := &store.Feed{ID: 1, URL: srv.URL + "/atom"}
+
+	tests := []struct {
+		pages int
+		want  []string
+	}{
+		{0, []string{"a1"}},
+		{1, []string{"a1", "r2"}},
+		// The JSON page links back to the first, which isn't fetched twice
+		{5, []string{"a1", "r2", "j3"}},
+	}
+	for _, tt := range tests {
+		result := newFetcher(Config{BackfillPages: tt.pages}).fetchBackfill(context.Background(), feed)
+		if result.Error != nil {
+			t.Fatalf("pages=%d: %v", tt.pages, result.Error)
+		}
+		var got []string
+		for _, item := range result.Items {
+			got = append(got, item.GUID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("pages=%d: items = %v, want %v", tt.pages, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("pages=%d: items = %v, want %v", tt.pages, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestFetchFeedBackfillStopsOnPageError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>Blog</title>
+<atom:link rel="next" href="/missing"/>
+<item><guid>r1</guid><title>One</title></item>
+</channel></rss>`))
+	}))
+	defer srv.Close()
+
+	result := newFetcher(Config{BackfillPages: 3}).fetchBackfill(context.Background(), &store.Feed{ID: 1, URL: srv.URL + "/feed"})
+	if result.Error != nil {
+		t.Fatalf("a failing later page should not fail the backfill: %v", result.Error)
+	}
+	if len(result.Items) != 1 || result.Items[0].GUID != "r1" {
+		t.Errorf("items = %+v, want just the first page's", result.Items)
+	}
+}
+
+func TestFetchFeedBackfillStaysOnFeedHost(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("backfill followed a next page to another host with header %q", r.Header.Get("Authorization"))
+	}))
+	defer other.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>Blog</title>
+<atom:link rel="next" href="` + other.URL + `/page2"/>
+<item><guid>r1</guid><title>One</title></item>
+</channel></rss>`))
+	}))
+	defer srv.Close()
+
+	feed := &store.Feed{ID: 1, URL: srv.URL + "/feed", Headers: map[string]string{"Authorization": "Bearer secret"}}
+	result := newFetcher(Config{BackfillPages: 3}).fetchBackfill(context.Background(), feed)
+	if result.Error != nil {
+		t.Fatalf("fetchBackfill failed: %v", result.Error)
+	}
+	if len(result.Items) != 1 {
+		t.Errorf("items = %+v, want just the first page's", result.Items)
+	}
+}
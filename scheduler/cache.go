@@ -0,0 +1,220 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kierank/herald/store"
+	"golang.org/x/sync/singleflight"
+)
+
+// fetchCacheTTL bounds how long a fetched feed is shared between configs.
+// It's short enough that results are effectively per-tick.
+const fetchCacheTTL = 2 * time.Minute
+
+type cachedFetch struct {
+	result    *FetchResult
+	fetchedAt time.Time
+}
+
+// fetchCache shares feed fetch results across configs subscribed to the same
+// URL. Entries are keyed by the request and its stored conditional headers so
+// a feed is only reused when the request would have been identical.
+type fetchCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedFetch
+	group   singleflight.Group
+	// fetchFn fetches a feed on a cache miss
+	fetchFn func(context.Context, *store.Feed) *FetchResult
+}
+
+func newFetchCache(fetchFn func(context.Context, *store.Feed) *FetchResult) *fetchCache {
+	return &fetchCache{
+		entries: make(map[string]cachedFetch),
+		fetchFn: fetchFn,
+	}
+}
+
+func fetchCacheKey(feed *store.Feed) string {
+	return requestKey(feed) + "\x00" + feed.ETag.String + "\x00" + feed.LastModified.String
+}
+
+// requestKey identifies the request for a feed apart from its conditional
+// headers: URL, method, body, proxy, warmup, and custom headers
+func requestKey(feed *store.Feed) string {
+	var b strings.Builder
+	b.WriteString(feed.URL)
+	b.WriteString("\x00")
+	b.WriteString(feed.Method)
+	b.WriteString("\x00")
+	b.WriteString(feed.Body)
+	b.WriteString("\x00")
+	b.WriteString(feed.Proxy)
+	b.WriteString("\x00")
+	b.WriteString(feed.Warmup)
+
+	names := make([]string, 0, len(feed.Headers))
+	for name := range feed.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString("\x00")
+		b.WriteString(name)
+		b.WriteString("=")
+		b.WriteString(feed.Headers[name])
+	}
+	return b.String()
+}
+
+// fetch returns a cached result for the feed if one is fresh, otherwise fetches
+// it. Concurrent fetches of the same key are collapsed into one request.
+func (c *fetchCache) fetch(ctx context.Context, feed *store.Feed) *FetchResult {
+	key := fetchCacheKey(feed)
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < fetchCacheTTL {
+		return entry.result.forFeed(feed)
+	}
+
+	// The fetch is shared, so it mustn't be cancelled when the caller that
+	// started it goes away; each caller stops waiting on its own context
+	// instead, and the fetch itself is bounded by feedFetchTimeout
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		result := c.fetchFn(context.WithoutCancel(ctx), feed)
+		if result.Error == nil && !result.skipped {
+			c.put(key, result)
+		}
+		return result, nil
+	})
+
+	select {
+	case res := <-ch:
+		return res.Val.(*FetchResult).forFeed(feed)
+	case <-ctx.Done():
+		return &FetchResult{
+			FeedID:   feed.ID,
+			FeedURL:  feed.URL,
+			FeedName: feedDisplayName(feed, ""),
+			Note:     feed.Note,
+			Error:    ctx.Err(),
+		}
+	}
+}
+
+func (c *fetchCache) put(key string, result *FetchResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) >= fetchCacheTTL {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cachedFetch{result: result, fetchedAt: now}
+}
+
+// lastFetches keeps the last full result fetched for each request, whatever
+// conditional headers it was sent with, so configs running inside the minimum
+// fetch interval can reuse it even when their stored ETag is out of date
+type lastFetches struct {
+	mu      sync.Mutex
+	entries map[string]cachedFetch
+}
+
+func newLastFetches() *lastFetches {
+	return &lastFetches{entries: make(map[string]cachedFetch)}
+}
+
+func (l *lastFetches) get(key string) (cachedFetch, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.entries[key]
+	return entry, ok
+}
+
+// put stores a result fetched or confirmed unchanged at fetchedAt, dropping
+// entries that are too old to be reused
+func (l *lastFetches) put(key string, result *FetchResult, fetchedAt time.Time, window time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for k, entry := range l.entries {
+		if fetchedAt.Sub(entry.fetchedAt) >= window {
+			delete(l.entries, k)
+		}
+	}
+	l.entries[key] = cachedFetch{result: result, fetchedAt: fetchedAt}
+}
+
+// fetchPolitely fetches a feed at most once per minimum fetch interval.
+// Inside the interval it returns the last full result for the same request,
+// so every config sees the feed's items whatever its stored ETag. Only
+// successful fetches count, so a failing feed is retried on the next run.
+//
+// With nothing cached the feed is fetched without conditional headers, since
+// a 304 can't be shared with configs whose ETag differs. After a restart,
+// when the only record is the stored fetch time, the fetch is skipped with an
+// empty result until the interval has passed.
+func (s *Scheduler) fetchPolitely(ctx context.Context, feed *store.Feed) *FetchResult {
+	key := requestKey(feed)
+	entry, cached := s.lastFetches.get(key)
+	if cached && time.Since(entry.fetchedAt) < s.fetchFloor {
+		return entry.result.forFeed(feed)
+	}
+
+	req := feed
+	if !cached {
+		last, err := s.store.LastGlobalFetch(ctx, feed.URL)
+		if err != nil {
+			s.logger.Warn("failed to get last global fetch", "url", feed.URL, "err", err)
+		} else if !last.IsZero() && time.Since(last) < s.fetchFloor {
+			s.logger.Debug("skipping fetch under minimum interval", "url", feed.URL, "last_fetch", last)
+			return &FetchResult{
+				FeedID:   feed.ID,
+				FeedURL:  feed.URL,
+				FeedName: feedDisplayName(feed, ""),
+				Note:     feed.Note,
+				skipped:  true,
+			}
+		}
+
+		unconditional := *feed
+		unconditional.ETag = sql.NullString{}
+		unconditional.LastModified = sql.NullString{}
+		req = &unconditional
+	}
+
+	fetchedAt := time.Now()
+	result := s.fetcher.fetch(ctx, req)
+	if result.Error != nil {
+		return result
+	}
+	if err := s.store.RecordGlobalFetch(ctx, feed.URL, fetchedAt); err != nil {
+		s.logger.Warn("failed to record global fetch", "url", feed.URL, "err", err)
+	}
+
+	switch {
+	case !result.NotModified:
+		s.lastFetches.put(key, result, fetchedAt, s.fetchFloor)
+	case sameVersion(entry.result, feed):
+		// The 304 confirms the cached body is still the feed's content
+		s.lastFetches.put(key, entry.result, fetchedAt, s.fetchFloor)
+	}
+	return result
+}
+
+// sameVersion reports whether result carries the version of the feed named by
+// its stored conditional headers
+func sameVersion(result *FetchResult, feed *store.Feed) bool {
+	if result == nil || (result.ETag == "" && result.LastModified == "") {
+		return false
+	}
+	return result.ETag == feed.ETag.String && result.LastModified == feed.LastModified.String
+}
@@ -0,0 +1,255 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/kierank/herald/email"
+	"github.com/kierank/herald/store"
+)
+
+func TestFetchCache_SharesResultsAcrossFeeds(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	cache := newFetchCache(newFetcher(Config{}).fetch)
+	feeds := []*store.Feed{
+		{ID: 1, URL: srv.URL},
+		{ID: 2, URL: srv.URL, Name: sql.NullString{String: "Custom", Valid: true}},
+		{ID: 3, URL: srv.URL},
+	}
+
+	results := fetchFeedsWith(context.Background(), feeds, nil, cache.fetch)
+
+	if n := hits.Load(); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+	for i, result := range results {
+		if result.Error != nil {
+			t.Fatalf("result %d failed: %v", i, result.Error)
+		}
+		if result.FeedID != feeds[i].ID {
+			t.Errorf("result %d: expected feed ID %d, got %d", i, feeds[i].ID, result.FeedID)
+		}
+		if len(result.Items) != 2 {
+			t.Errorf("result %d: expected 2 items, got %d", i, len(result.Items))
+		}
+	}
+	if results[1].FeedName != "Custom" {
+		t.Errorf("expected custom name to be kept, got %q", results[1].FeedName)
+	}
+	if results[2].FeedName != "Test Feed" {
+		t.Errorf("expected fetched title, got %q", results[2].FeedName)
+	}
+}
+
+func TestFetchCache_DifferentETagsNotShared(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	cache := newFetchCache(newFetcher(Config{}).fetch)
+	_ = cache.fetch(context.Background(), &store.Feed{ID: 1, URL: srv.URL})
+	_ = cache.fetch(context.Background(), &store.Feed{ID: 2, URL: srv.URL, ETag: sql.NullString{String: `"abc"`, Valid: true}})
+
+	if n := hits.Load(); n != 2 {
+		t.Errorf("expected 2 requests for differing ETags, got %d", n)
+	}
+}
+
+func TestFetchCache_CallerCancelDoesNotFailSharedFetch(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	cache := newFetchCache(func(ctx context.Context, feed *store.Feed) *FetchResult {
+		once.Do(func() { close(started) })
+		<-release
+		return &FetchResult{FeedID: feed.ID, FeedURL: feed.URL, Error: ctx.Err()}
+	})
+
+	first, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan *FetchResult)
+	go func() { firstDone <- cache.fetch(first, &store.Feed{ID: 1, URL: "https://example.com/feed.xml"}) }()
+	<-started
+
+	secondDone := make(chan *FetchResult)
+	go func() {
+		secondDone <- cache.fetch(context.Background(), &store.Feed{ID: 2, URL: "https://example.com/feed.xml"})
+	}()
+
+	cancel()
+	if result := <-firstDone; !errors.Is(result.Error, context.Canceled) {
+		t.Errorf("expected the cancelled caller to see its own cancellation, got %v", result.Error)
+	}
+
+	close(release)
+	if result := <-secondDone; result.Error != nil || result.FeedID != 2 {
+		t.Errorf("expected the other caller to get the shared fetch, got %+v", result)
+	}
+}
+
+func TestFetchPolitely_ReusesLastResult(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	s, db, _ := setupTestScheduler(t, Config{MinFetchInterval: time.Hour})
+
+	ctx := context.Background()
+
+	// A stale ETag still gets the feed's items inside the interval
+	first := s.fetchPolitely(ctx, &store.Feed{ID: 1, URL: srv.URL})
+	second := s.fetchPolitely(ctx, &store.Feed{ID: 2, URL: srv.URL, ETag: sql.NullString{String: `"v0"`, Valid: true}})
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("expected 1 request within the interval, got %d", n)
+	}
+	if len(first.Items) != 2 {
+		t.Errorf("expected fetched items, got %d", len(first.Items))
+	}
+	if second.Error != nil || len(second.Items) != 2 || second.FeedID != 2 || second.skipped {
+		t.Errorf("expected the last result reused for feed 2, got %+v", second)
+	}
+
+	// Once the interval has passed the feed is fetched again, and a 304 for
+	// the cached version keeps the cached items available to others
+	key := requestKey(&store.Feed{URL: srv.URL})
+	entry, _ := s.lastFetches.get(key)
+	s.lastFetches.put(key, entry.result, time.Now().Add(-2*time.Hour), time.Hour)
+	if err := db.RecordGlobalFetch(ctx, srv.URL, time.Now().Add(-2*time.Hour)); err != nil {
+		t.Fatalf("RecordGlobalFetch failed: %v", err)
+	}
+	again := s.fetchPolitely(ctx, &store.Feed{ID: 1, URL: srv.URL, ETag: sql.NullString{String: `"v1"`, Valid: true}})
+	if n := hits.Load(); n != 2 || !again.NotModified {
+		t.Fatalf("expected a conditional request answered 304, got %d requests and %+v", n, again)
+	}
+	stale := s.fetchPolitely(ctx, &store.Feed{ID: 2, URL: srv.URL})
+	if n := hits.Load(); n != 2 || len(stale.Items) != 2 {
+		t.Errorf("expected the confirmed result reused, got %d requests and %d items", n, len(stale.Items))
+	}
+
+	// After a restart only the stored fetch time is left, so the fetch waits
+	restarted := NewScheduler(Config{MinFetchInterval: time.Hour}, db, nil, log.New(io.Discard))
+	if result := restarted.fetchPolitely(ctx, &store.Feed{ID: 2, URL: srv.URL}); !result.skipped {
+		t.Errorf("expected a skipped fetch after a restart, got %+v", result)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Errorf("expected no request after a restart, got %d", n)
+	}
+}
+
+func TestMinFetchIntervalAcrossSchedules(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	s, db, _ := setupTestScheduler(t, Config{MinFetchInterval: time.Hour})
+
+	ctx := context.Background()
+	sent := map[string]int{}
+	s.send = func(to, subject, htmlBody, textBody, unsubToken, dashboardURL, keepAliveURL, footer string, meta email.DigestMeta) (email.SendReceipt, error) {
+		sent[meta.ConfigName]++
+		return email.SendReceipt{To: to, Subject: subject}, nil
+	}
+	// Separate users so the per-user email rate limit doesn't hold a digest
+	first, _ := db.GetOrCreateUser(ctx, "first-fp", "first-pubkey")
+	second, _ := db.GetOrCreateUser(ctx, "second-fp", "second-pubkey")
+
+	// frequent.txt runs every 30 minutes, daily.txt once a day, so every
+	// daily run lands inside the frequent config's fetch interval
+	now := time.Now().UTC()
+	frequent, _ := db.CreateConfig(ctx, first.ID, "frequent.txt", "user@example.com", "*/30 * * * *", true, false,
+		"=: email user@example.com\n=: cron */30 * * * *\n=> "+srv.URL, now)
+	daily, _ := db.CreateConfig(ctx, second.ID, "daily.txt", "user@example.com", "0 8 * * *", true, false,
+		"=: email user@example.com\n=: cron 0 8 * * *\n=> "+srv.URL, now)
+	for _, cfg := range []*store.Config{frequent, daily} {
+		if _, err := db.CreateFeed(ctx, cfg.ID, srv.URL, "", store.FeedOptions{}); err != nil {
+			t.Fatalf("CreateFeed failed: %v", err)
+		}
+	}
+
+	if err := s.processConfig(ctx, frequent); err != nil {
+		t.Fatalf("processConfig failed: %v", err)
+	}
+	if err := s.processConfig(ctx, daily); err != nil {
+		t.Fatalf("processConfig failed: %v", err)
+	}
+
+	if n := hits.Load(); n != 1 {
+		t.Errorf("expected 1 request for both configs, got %d", n)
+	}
+	if sent["frequent.txt"] != 1 || sent["daily.txt"] != 1 {
+		t.Errorf("expected a digest for each config, got %v", sent)
+	}
+}
+
+func TestFetchPolitely_FailedFetchNotRecorded(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	s, db, _ := setupTestScheduler(t, Config{MinFetchInterval: time.Hour})
+
+	ctx := context.Background()
+
+	if result := s.fetchPolitely(ctx, &store.Feed{ID: 1, URL: srv.URL}); result.Error == nil {
+		t.Fatal("expected the fetch to fail")
+	}
+	last, err := db.LastGlobalFetch(ctx, srv.URL)
+	if err != nil {
+		t.Fatalf("LastGlobalFetch failed: %v", err)
+	}
+	if !last.IsZero() {
+		t.Errorf("expected a failed fetch not to count toward the interval, got %v", last)
+	}
+}
+
+func TestRecordFeedResults_SkippedLeavesFeedUntouched(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{})
+
+	ctx := context.Background()
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.txt", "test@example.com", "0 8 * * *", true, false, "raw", time.Now().Add(time.Hour))
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", store.FeedOptions{})
+
+	s.recordFeedResults(ctx, []*FetchResult{{FeedID: feed.ID, FeedURL: feed.URL, skipped: true}}, false)
+
+	feeds, err := db.GetAllFeedsByConfig(ctx, cfg.ID)
+	if err != nil || len(feeds) != 1 {
+		t.Fatalf("GetAllFeedsByConfig = %v, %v", feeds, err)
+	}
+	if feeds[0].LastFetched.Valid {
+		t.Errorf("expected a skipped fetch to leave last_fetched unset, got %v", feeds[0].LastFetched.Time)
+	}
+}
@@ -0,0 +1,30 @@
+package scheduler
+
+import (
+	"context"
+
+	"github.com/kierank/herald/store"
+)
+
+// attachDescriptions sets each result's feed description. A full fetch
+// stores the description it found; 304s and skipped fetches reuse the one
+// stored last time.
+func (s *Scheduler) attachDescriptions(ctx context.Context, feeds []*store.Feed, results []*FetchResult) {
+	for i, feed := range feeds {
+		if i >= len(results) || results[i] == nil {
+			continue
+		}
+		result := results[i]
+
+		if result.Error != nil || result.NotModified || result.skipped {
+			result.Description = feed.Description
+			continue
+		}
+		if result.Description == feed.Description {
+			continue
+		}
+		if err := s.store.SetFeedDescription(ctx, feed.ID, result.Description); err != nil {
+			s.logger.Warn("failed to store feed description", "feed_id", feed.ID, "err", err)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kierank/herald/store"
+)
+
+func TestAttachDescriptions(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{})
+
+	ctx := context.Background()
+	cfg, _ := db.CreateConfig(ctx, user.ID, "news.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now().Add(time.Hour))
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", store.FeedOptions{})
+
+	fresh := &FetchResult{FeedID: feed.ID, Description: "Notes on Go"}
+	s.attachDescriptions(ctx, []*store.Feed{feed}, []*FetchResult{fresh})
+
+	feeds, _ := db.GetFeedsByConfig(ctx, cfg.ID)
+	if len(feeds) != 1 || feeds[0].Description != "Notes on Go" {
+		t.Fatalf("expected the description to be stored, got %+v", feeds)
+	}
+
+	cached := &FetchResult{FeedID: feed.ID, NotModified: true}
+	s.attachDescriptions(ctx, feeds, []*FetchResult{cached})
+	if cached.Description != "Notes on Go" {
+		t.Errorf("expected a 304 to reuse the stored description, got %q", cached.Description)
+	}
+}
@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kierank/herald/config"
+	"github.com/kierank/herald/store"
+)
+
+const (
+	faviconTimeout = 5 * time.Second
+	maxFaviconSize = 32 * 1024
+	// faviconRefresh is how long a looked-up favicon, or its absence, is trusted
+	faviconRefresh = 7 * 24 * time.Hour
+)
+
+// faviconClient fetches favicons with a short timeout and, like probeTransport,
+// refuses non-public addresses since the site link comes from feed content
+var faviconClient = &http.Client{
+	Timeout: faviconTimeout,
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: faviconTimeout,
+			Control: config.RejectNonPublic,
+		}).DialContext,
+		TLSHandshakeTimeout: faviconTimeout,
+	},
+}
+
+// faviconURL returns /favicon.ico on the feed's site, falling back to the
+// host serving the feed itself
+func faviconURL(siteLink, feedURL string) (string, error) {
+	for _, raw := range []string{siteLink, feedURL} {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/favicon.ico"}).String(), nil
+	}
+	return "", fmt.Errorf("no usable site URL")
+}
+
+// fetchFavicon downloads a favicon and returns it as a data: URI, so digests
+// never load images from feed sites
+func fetchFavicon(ctx context.Context, client *http.Client, iconURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, faviconTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, iconURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "Herald/1.0 (RSS Aggregator)")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &httpError{StatusCode: resp.StatusCode}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFaviconSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) == 0 {
+		return "", fmt.Errorf("empty favicon")
+	}
+	if len(data) > maxFaviconSize {
+		return "", fmt.Errorf("favicon larger than %d bytes", maxFaviconSize)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	if !strings.HasPrefix(contentType, "image/") || contentType == "image/svg+xml" {
+		return "", fmt.Errorf("unsupported favicon type %q", contentType)
+	}
+
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// attachFavicons sets each result's favicon, looking up any that are missing
+// or older than faviconRefresh. Lookups are best-effort: failures are
+// remembered as "no favicon" until the next refresh.
+func (s *Scheduler) attachFavicons(ctx context.Context, feeds []*store.Feed, results []*FetchResult) {
+	for i, feed := range feeds {
+		if i >= len(results) || results[i] == nil || results[i].Error != nil {
+			continue
+		}
+		result := results[i]
+		// A lookup would bypass the feed's own proxy
+		if feed.Proxy != "" {
+			continue
+		}
+
+		if feed.FaviconCheckedAt.Valid && time.Since(feed.FaviconCheckedAt.Time) < faviconRefresh {
+			result.Favicon = feed.Favicon
+			continue
+		}
+
+		favicon := ""
+		iconURL, err := faviconURL(result.SiteLink, feed.URL)
+		if err == nil {
+			favicon, err = fetchFavicon(ctx, s.favicons, iconURL)
+		}
+		if err != nil {
+			s.logger.Debug("no favicon for feed", "feed_id", feed.ID, "url", feed.URL, "err", err)
+		}
+		if err := s.store.SetFeedFavicon(ctx, feed.ID, favicon); err != nil {
+			s.logger.Warn("failed to store favicon", "feed_id", feed.ID, "err", err)
+		}
+		result.Favicon = favicon
+	}
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// pngHeader is enough of a PNG for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestFaviconURL(t *testing.T) {
+	tests := []struct {
+		site, feed string
+		want       string
+	}{
+		{"https://blog.example.com/posts/", "https://feeds.example.com/rss", "https://blog.example.com/favicon.ico"},
+		{"", "http://example.com/feed.xml", "http://example.com/favicon.ico"},
+		{"not a url", "https://example.com/feed.xml", "https://example.com/favicon.ico"},
+	}
+	for _, tt := range tests {
+		got, err := faviconURL(tt.site, tt.feed)
+		if err != nil {
+			t.Errorf("faviconURL(%q, %q) failed: %v", tt.site, tt.feed, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("faviconURL(%q, %q) = %q, want %q", tt.site, tt.feed, got, tt.want)
+		}
+	}
+
+	if _, err := faviconURL("", "ftp://example.com/feed"); err == nil {
+		t.Error("expected error for non-http URLs")
+	}
+}
+
+func TestFetchFavicon(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/favicon.ico":
+			_, _ = w.Write(pngHeader)
+		case "/html":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<html></html>"))
+		case "/huge":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(bytes.Repeat([]byte{0}, maxFaviconSize+1))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	got, err := fetchFavicon(ctx, srv.Client(), srv.URL+"/favicon.ico")
+	if err != nil {
+		t.Fatalf("fetchFavicon failed: %v", err)
+	}
+	if !strings.HasPrefix(got, "data:image/png;base64,") {
+		t.Errorf("expected PNG data URI, got %q", got)
+	}
+
+	for _, path := range []string{"/html", "/huge", "/missing"} {
+		if _, err := fetchFavicon(ctx, srv.Client(), srv.URL+path); err == nil {
+			t.Errorf("%s: expected error", path)
+		}
+	}
+}
+
+func TestFetchFaviconBlocksLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(pngHeader)
+	}))
+	defer srv.Close()
+
+	if _, err := fetchFavicon(context.Background(), faviconClient, srv.URL+"/favicon.ico"); err == nil {
+		t.Error("expected loopback favicon fetch to be refused")
+	}
+}
@@ -2,21 +2,78 @@
 package scheduler
 
 import (
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/kierank/herald/config"
 	"github.com/kierank/herald/store"
 	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/json"
 )
 
 const (
 	feedFetchTimeout   = 15 * time.Second
 	maxConcurrentFetch = 30
+	maxFeedSize        = 10 * 1024 * 1024 // 10MB decompressed
+
+	// DefaultMaxItemsPerFeed caps items kept from a single fetch
+	DefaultMaxItemsPerFeed = 500
+
+	// futureItemSkew is how far ahead an item's date may be before it counts
+	// as future-dated, allowing for clock differences
+	futureItemSkew = 5 * time.Minute
 )
 
+// fetcher fetches feeds with the instance's fetch settings from Config
+type fetcher struct {
+	access        *config.FeedAccess
+	maxItems      int
+	retries       int
+	retryBackoff  time.Duration
+	backfillPages int
+	futureItems   string
+	// probeClient fetches the arbitrary URLs of ProbeFeed
+	probeClient *http.Client
+}
+
+// newFetcher returns a fetcher for cfg's fetch settings. A nil cfg.Feeds
+// allows any feed and uses the proxy settings from the environment.
+func newFetcher(cfg Config) *fetcher {
+	access := cfg.Feeds
+	if access == nil {
+		access = config.NewFeedAccess(config.FeedPolicy{})
+	}
+	maxItems := cfg.MaxItemsPerFeed
+	if maxItems <= 0 {
+		maxItems = DefaultMaxItemsPerFeed
+	}
+	return &fetcher{
+		access:        access,
+		maxItems:      maxItems,
+		retries:       max(cfg.FetchRetries, 0),
+		retryBackoff:  max(cfg.FetchRetryBackoff, 0),
+		backfillPages: max(cfg.BackfillPages, 0),
+		futureItems:   cfg.FutureItems,
+		probeClient: &http.Client{
+			Timeout:       feedFetchTimeout,
+			CheckRedirect: access.CheckRedirect,
+			Transport:     probeTransport,
+		},
+	}
+}
+
 type FetchResult struct {
 	FeedID       int64
 	FeedName     string
@@ -25,6 +82,59 @@ type FetchResult struct {
 	ETag         string
 	LastModified string
 	Error        error
+	// Truncated is how many items were dropped by the per-feed item cap
+	Truncated int
+	// BadDates is how many items had a date that couldn't be parsed
+	BadDates int
+	// FutureDates is how many items were dated in the future, and clamped
+	// or skipped as Config.FutureItems says
+	FutureDates int
+	// SiteLink is the website the feed belongs to, if it names one
+	SiteLink string
+	// Favicon is the feed's inlined favicon, set when the config asks for one
+	Favicon string
+	// Note is the feed's note from the config, shown beside its name
+	Note string
+	// Description is the feed's own description or subtitle
+	Description string
+	// NotModified is set when a conditional request came back 304
+	NotModified bool
+
+	// title is the feed's own title, kept so shared results can be renamed
+	title string
+	// skipped is set when the fetch was skipped for the minimum fetch interval
+	skipped bool
+	// nextPage is the absolute URL of the feed's next page, if it paginates
+	nextPage string
+}
+
+// forFeed returns a copy of the result attributed to the given feed, so a
+// result fetched for one config can be reused by another with the same URL.
+func (r *FetchResult) forFeed(feed *store.Feed) *FetchResult {
+	if r.FeedID == feed.ID {
+		return r
+	}
+
+	shared := *r
+	shared.FeedID = feed.ID
+	shared.FeedURL = feed.URL
+	shared.FeedName = feedDisplayName(feed, r.title)
+	shared.Note = feed.Note
+	return &shared
+}
+
+// feedDisplayName returns the name shown for a feed: the name set in the
+// config always wins, then the feed's own title, then its URL
+func feedDisplayName(feed *store.Feed, title string) string {
+	if feed.Name.Valid {
+		if name := strings.TrimSpace(feed.Name.String); name != "" {
+			return name
+		}
+	}
+	if title = strings.TrimSpace(title); title != "" {
+		return title
+	}
+	return feed.URL
 }
 
 type FetchedItem struct {
@@ -33,28 +143,81 @@ type FetchedItem struct {
 	Link      string
 	Content   string
 	Published time.Time
+	// PlainText is set when Content came from a JSON Feed content_text or summary
+	PlainText bool
 }
 
-func FetchFeed(ctx context.Context, feed *store.Feed) *FetchResult {
-	result := &FetchResult{
-		FeedID:  feed.ID,
-		FeedURL: feed.URL,
+// jsonPlainTextKey marks translated JSON Feed items that have no content_html
+const jsonPlainTextKey = "herald:plain_text"
+
+// jsonFeedTranslator records which JSON Feed items only carry plain text,
+// since gofeed folds content_html and content_text into one Content field.
+type jsonFeedTranslator struct {
+	gofeed.DefaultJSONTranslator
+}
+
+func (t *jsonFeedTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	parsed, err := t.DefaultJSONTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+	jf, ok := feed.(*json.Feed)
+	if !ok {
+		return parsed, nil
 	}
+	setNextPage(parsed, jf.NextURL)
+	if len(jf.Items) != len(parsed.Items) {
+		return parsed, nil
+	}
+	for i, item := range jf.Items {
+		if item.ContentHTML != "" {
+			continue
+		}
+		if parsed.Items[i].Custom == nil {
+			parsed.Items[i].Custom = make(map[string]string)
+		}
+		parsed.Items[i].Custom[jsonPlainTextKey] = "true"
+	}
+	return parsed, nil
+}
 
-	if feed.Name.Valid {
-		result.FeedName = feed.Name.String
+// fetch fetches and parses one feed, sending its stored conditional headers
+func (f *fetcher) fetch(ctx context.Context, feed *store.Feed) *FetchResult {
+	result := &FetchResult{
+		FeedID:   feed.ID,
+		FeedURL:  feed.URL,
+		FeedName: feedDisplayName(feed, ""),
+		Note:     feed.Note,
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, feedFetchTimeout)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feed.URL, nil)
+	method := http.MethodGet
+	var reqBody io.Reader
+	if feed.Method == http.MethodPost {
+		method = http.MethodPost
+		reqBody = strings.NewReader(feed.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, feed.URL, reqBody)
 	if err != nil {
 		result.Error = err
 		return result
 	}
+	if err := f.access.CheckURL(req.URL); err != nil {
+		result.Error = err
+		return result
+	}
 
 	req.Header.Set("User-Agent", "Herald/1.0 (RSS Aggregator)")
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	if reqBody != nil {
+		req.Header.Set("Content-Type", config.RequestContentType(feed.Body))
+	}
+	for name, value := range feed.Headers {
+		req.Header.Set(name, value)
+	}
 
 	if feed.ETag.Valid && feed.ETag.String != "" {
 		req.Header.Set("If-None-Match", feed.ETag.String)
@@ -63,11 +226,24 @@ func FetchFeed(ctx context.Context, feed *store.Feed) *FetchResult {
 		req.Header.Set("If-Modified-Since", feed.LastModified.String)
 	}
 
+	transport, err := f.access.Transport(feed.Proxy)
+	if err != nil {
+		result.Error = err
+		return result
+	}
 	client := &http.Client{
-		Timeout: 15 * time.Second,
+		Timeout:       15 * time.Second,
+		Transport:     transport,
+		CheckRedirect: f.access.CheckRedirect,
+	}
+	if feed.Warmup != "" {
+		if err := f.access.Warmup(ctx, client, feed.Warmup); err != nil {
+			result.Error = err
+			return result
+		}
 	}
 
-	resp, err := client.Do(req)
+	resp, err := f.doWithRetry(ctx, client, req)
 	if err != nil {
 		result.Error = err
 		return result
@@ -75,6 +251,7 @@ func FetchFeed(ctx context.Context, feed *store.Feed) *FetchResult {
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode == http.StatusNotModified {
+		result.NotModified = true
 		return result
 	}
 
@@ -86,17 +263,29 @@ func FetchFeed(ctx context.Context, feed *store.Feed) *FetchResult {
 	result.ETag = resp.Header.Get("ETag")
 	result.LastModified = resp.Header.Get("Last-Modified")
 
-	parser := gofeed.NewParser()
-	parsedFeed, err := parser.Parse(resp.Body)
+	body, err := decodeBody(resp)
 	if err != nil {
 		result.Error = err
 		return result
 	}
+	defer func() { _ = body.Close() }()
 
-	if result.FeedName == "" && parsedFeed.Title != "" {
-		result.FeedName = parsedFeed.Title
+	parser := gofeed.NewParser()
+	parser.JSONTranslator = &jsonFeedTranslator{}
+	parser.AtomTranslator = &atomFeedTranslator{}
+	parsedFeed, err := parser.Parse(io.LimitReader(body, maxFeedSize))
+	if err != nil {
+		result.Error = err
+		return result
 	}
+	result.nextPage = nextPageURL(resp.Request.URL, parsedFeed)
 
+	result.title = parsedFeed.Title
+	result.SiteLink = parsedFeed.Link
+	result.Description = strings.TrimSpace(parsedFeed.Description)
+	result.FeedName = feedDisplayName(feed, parsedFeed.Title)
+
+	now := time.Now().UTC()
 	for _, item := range parsedFeed.Items {
 		fetchedItem := FetchedItem{
 			GUID:  item.GUID,
@@ -113,20 +302,92 @@ func FetchFeed(ctx context.Context, feed *store.Feed) *FetchResult {
 		} else if item.Description != "" {
 			fetchedItem.Content = item.Description
 		}
+		if parsedFeed.FeedType == "json" {
+			fetchedItem.PlainText = item.Custom[jsonPlainTextKey] == "true"
+		}
 
-		if item.PublishedParsed != nil {
+		switch {
+		case item.PublishedParsed != nil:
 			fetchedItem.Published = *item.PublishedParsed
-		} else if item.UpdatedParsed != nil {
+		case item.UpdatedParsed != nil:
 			fetchedItem.Published = *item.UpdatedParsed
+		default:
+			if t, ok := parseFallbackDate(item.Published, item.Updated); ok {
+				fetchedItem.Published = t
+			} else if item.Published != "" || item.Updated != "" {
+				result.BadDates++
+			}
+		}
+
+		if fetchedItem.Published.After(now.Add(futureItemSkew)) {
+			result.FutureDates++
+			if !f.handleFutureItem(&fetchedItem, now) {
+				continue
+			}
 		}
 
 		result.Items = append(result.Items, fetchedItem)
 	}
 
+	result.Items, result.Truncated = capItems(result.Items, f.maxItems)
+
 	return result
 }
 
-func FetchFeeds(ctx context.Context, feeds []*store.Feed, progress *atomic.Int32) []*FetchResult {
+// fallbackDateLayouts are tried on raw item dates gofeed couldn't parse
+var fallbackDateLayouts = []string{
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	"Monday, January 2, 2006",
+	"January 2, 2006 3:04 PM",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"02 Jan 2006 15:04",
+	"02 Jan 2006",
+	"Mon, 2 Jan 2006",
+}
+
+// parseFallbackDate tries each raw date against fallbackDateLayouts, reading
+// dates without a zone as UTC
+func parseFallbackDate(raws ...string) (time.Time, bool) {
+	for _, raw := range raws {
+		raw = strings.Join(strings.Fields(raw), " ")
+		if raw == "" {
+			continue
+		}
+		for _, layout := range fallbackDateLayouts {
+			if t, err := time.Parse(layout, raw); err == nil {
+				return t.UTC(), true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// capItems keeps the newest limit items by published date. Undated items sort
+// after dated ones and otherwise keep their feed order.
+func capItems(items []FetchedItem, limit int) ([]FetchedItem, int) {
+	if limit <= 0 || len(items) <= limit {
+		return items, 0
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].Published, items[j].Published
+		if a.IsZero() || b.IsZero() {
+			return !a.IsZero() && b.IsZero()
+		}
+		return a.After(b)
+	})
+	return items[:limit], len(items) - limit
+}
+
+func fetchFeedsWith(ctx context.Context, feeds []*store.Feed, progress *atomic.Int32, fetch func(context.Context, *store.Feed) *FetchResult) []*FetchResult {
 	results := make([]*FetchResult, len(feeds))
 	var wg sync.WaitGroup
 
@@ -147,7 +408,7 @@ func FetchFeeds(ctx context.Context, feeds []*store.Feed, progress *atomic.Int32
 			}()
 			semaphore <- struct{}{}        // Acquire
 			defer func() { <-semaphore }() // Release
-			results[idx] = FetchFeed(ctx, f)
+			results[idx] = fetch(ctx, f)
 		}(i, feed)
 	}
 
@@ -155,6 +416,74 @@ func FetchFeeds(ctx context.Context, feeds []*store.Feed, progress *atomic.Int32
 	return results
 }
 
+// decodeBody wraps the response body in a decompressor matching its
+// Content-Encoding. Setting Accept-Encoding ourselves disables the transport's
+// transparent gzip handling, so this has to be done by hand.
+func decodeBody(resp *http.Response) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip", "x-gzip":
+		r, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		return r, nil
+	case "deflate":
+		r, err := zlib.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("deflate: %w", err)
+		}
+		return r, nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", resp.Header.Get("Content-Encoding"))
+	}
+}
+
+// doWithRetry sends req, retrying network errors and 5xx responses with a
+// doubling backoff. Any other response, 304 included, is returned as is.
+func (f *fetcher) doWithRetry(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := client.Do(req)
+		if attempt >= f.retries || !retryableFetch(resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			_ = resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(f.retryBackoff << attempt):
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
+
+// retryableFetch reports whether a fetch failed in a way that may pass on
+// retry: a network error or a 5xx. Policy and redirect errors are final.
+func retryableFetch(resp *http.Response, err error) bool {
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		var netErr net.Error
+		return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+	}
+	return resp.StatusCode >= 500
+}
+
 type httpError struct {
 	StatusCode int
 }
@@ -162,3 +491,16 @@ type httpError struct {
 func (e *httpError) Error() string {
 	return http.StatusText(e.StatusCode)
 }
+
+// handleFutureItem applies the FutureItems mode to a future-dated item:
+// clamping moves its date to now, and skipping reports false so it's left out
+func (f *fetcher) handleFutureItem(item *FetchedItem, now time.Time) bool {
+	switch f.futureItems {
+	case config.FutureItemsSkip:
+		return false
+	case config.FutureItemsAllow:
+	default:
+		item.Published = now
+	}
+	return true
+}
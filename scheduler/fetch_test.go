@@ -0,0 +1,580 @@
+package scheduler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/kierank/herald/config"
+	"github.com/kierank/herald/store"
+)
+
+const testRSS = `<?xml version="1.0"?>
+<rss version="2.0">
+<channel>
+<title>Test Feed</title>
+<item><title>First</title><link>https://example.com/1</link><guid>1</guid></item>
+<item><title>Second</title><link>https://example.com/2</link><guid>2</guid></item>
+</channel>
+</rss>`
+
+const testJSONFeed = `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "JSON Test Feed",
+  "items": [
+    {"id": "1", "url": "https://example.com/1", "title": "HTML", "content_html": "<p>Hello <b>world</b></p>", "content_text": "Hello world"},
+    {"id": "2", "url": "https://example.com/2", "title": "Text", "content_text": "5 < 6 & <b>not bold</b>"},
+    {"id": "3", "url": "https://example.com/3", "title": "Summary", "summary": "Just a summary"}
+  ]
+}`
+
+func TestFetchFeed_Gzip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") == "" {
+			t.Error("expected Accept-Encoding header")
+		}
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		_, _ = gz.Write([]byte(testRSS))
+		_ = gz.Close()
+
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	result := newFetcher(Config{}).fetch(context.Background(), &store.Feed{ID: 1, URL: srv.URL})
+	if result.Error != nil {
+		t.Fatalf("fetch failed: %v", result.Error)
+	}
+	if result.FeedName != "Test Feed" {
+		t.Errorf("expected feed name 'Test Feed', got %q", result.FeedName)
+	}
+	if len(result.Items) != 2 {
+		t.Errorf("expected 2 items, got %d", len(result.Items))
+	}
+}
+
+func TestFetchFeed_Deflate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		_, _ = zw.Write([]byte(testRSS))
+		_ = zw.Close()
+
+		w.Header().Set("Content-Encoding", "deflate")
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	result := newFetcher(Config{}).fetch(context.Background(), &store.Feed{ID: 1, URL: srv.URL})
+	if result.Error != nil {
+		t.Fatalf("fetch failed: %v", result.Error)
+	}
+	if len(result.Items) != 2 {
+		t.Errorf("expected 2 items, got %d", len(result.Items))
+	}
+}
+
+func TestFetchFeed_Uncompressed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	result := newFetcher(Config{}).fetch(context.Background(), &store.Feed{ID: 1, URL: srv.URL})
+	if result.Error != nil {
+		t.Fatalf("fetch failed: %v", result.Error)
+	}
+	if len(result.Items) != 2 {
+		t.Errorf("expected 2 items, got %d", len(result.Items))
+	}
+}
+
+func TestFetchFeed_NotModified(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	feed := &store.Feed{ID: 1, URL: srv.URL}
+	result := newFetcher(Config{}).fetch(context.Background(), feed)
+	if result.Error != nil || result.NotModified {
+		t.Fatalf("expected a full fetch, got err=%v not_modified=%v", result.Error, result.NotModified)
+	}
+
+	feed.ETag = sql.NullString{String: result.ETag, Valid: true}
+	result = newFetcher(Config{}).fetch(context.Background(), feed)
+	if result.Error != nil || !result.NotModified || len(result.Items) != 0 {
+		t.Errorf("expected 304 with no items, got err=%v not_modified=%v items=%d", result.Error, result.NotModified, len(result.Items))
+	}
+
+	s := NewScheduler(Config{}, nil, nil, log.New(io.Discard))
+	rec := &tickRecord{}
+	s.SetTickRecorder(rec)
+	s.recordNotModified([]*FetchResult{result, {FeedID: 2}})
+	if rec.notModified != 1 {
+		t.Errorf("expected 1 not-modified fetch recorded, got %d", rec.notModified)
+	}
+}
+
+func TestFetchFeed_RetriesTransientFailures(t *testing.T) {
+	f := newFetcher(Config{FetchRetries: 2, FetchRetryBackoff: time.Millisecond})
+
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "q=1" {
+			t.Errorf("expected the body on every attempt, got %q", body)
+		}
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	result := f.fetch(context.Background(), &store.Feed{ID: 1, URL: srv.URL, Method: http.MethodPost, Body: "q=1"})
+	if result.Error != nil {
+		t.Fatalf("expected success after retries, got %v", result.Error)
+	}
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+	if len(result.Items) != 2 {
+		t.Errorf("expected 2 items, got %d", len(result.Items))
+	}
+
+	// Client errors aren't retried, and retries stop at the limit
+	for _, tc := range []struct {
+		status int
+		want   int32
+	}{
+		{http.StatusNotFound, 1},
+		{http.StatusBadGateway, 3},
+	} {
+		attempts.Store(0)
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(tc.status)
+		}))
+		result := f.fetch(context.Background(), &store.Feed{ID: 1, URL: failing.URL})
+		failing.Close()
+		if result.Error == nil {
+			t.Errorf("%d: expected an error", tc.status)
+		}
+		if n := attempts.Load(); n != tc.want {
+			t.Errorf("%d: expected %d attempts, got %d", tc.status, tc.want, n)
+		}
+	}
+}
+
+func TestFetchFeed_Post(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected application/json, got %q", ct)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"limit":50}` {
+			t.Errorf("unexpected body %q", body)
+		}
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	feed := &store.Feed{ID: 1, URL: srv.URL, Method: http.MethodPost, Body: `{"limit":50}`}
+	result := newFetcher(Config{}).fetch(context.Background(), feed)
+	if result.Error != nil {
+		t.Fatalf("fetch failed: %v", result.Error)
+	}
+	if len(result.Items) != 2 {
+		t.Errorf("expected 2 items, got %d", len(result.Items))
+	}
+}
+
+func TestFetchFeed_JSONFeed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/feed+json")
+		_, _ = w.Write([]byte(testJSONFeed))
+	}))
+	defer srv.Close()
+
+	result := newFetcher(Config{}).fetch(context.Background(), &store.Feed{ID: 1, URL: srv.URL})
+	if result.Error != nil {
+		t.Fatalf("fetch failed: %v", result.Error)
+	}
+	if result.FeedName != "JSON Test Feed" {
+		t.Errorf("expected feed name 'JSON Test Feed', got %q", result.FeedName)
+	}
+	if len(result.Items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(result.Items))
+	}
+
+	tests := []struct {
+		guid      string
+		content   string
+		plainText bool
+	}{
+		{"1", "<p>Hello <b>world</b></p>", false},
+		{"2", "5 < 6 & <b>not bold</b>", true},
+		{"3", "Just a summary", true},
+	}
+	for i, tt := range tests {
+		item := result.Items[i]
+		if item.GUID != tt.guid {
+			t.Errorf("item %d: expected GUID %q, got %q", i, tt.guid, item.GUID)
+		}
+		if item.Content != tt.content {
+			t.Errorf("item %d: expected content %q, got %q", i, tt.content, item.Content)
+		}
+		if item.PlainText != tt.plainText {
+			t.Errorf("item %d: expected PlainText %v, got %v", i, tt.plainText, item.PlainText)
+		}
+	}
+}
+
+func TestFetchFeed_RSSNotPlainText(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	result := newFetcher(Config{}).fetch(context.Background(), &store.Feed{ID: 1, URL: srv.URL})
+	if result.Error != nil {
+		t.Fatalf("fetch failed: %v", result.Error)
+	}
+	for _, item := range result.Items {
+		if item.PlainText {
+			t.Errorf("RSS item %q should not be marked plain text", item.GUID)
+		}
+	}
+}
+
+func TestFetchFeed_CapsItems(t *testing.T) {
+	const total = 5000
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var feed strings.Builder
+	feed.WriteString(`<?xml version="1.0"?><rss version="2.0"><channel><title>Bomb</title>`)
+	// Oldest first so the cap has to sort rather than take a prefix
+	for i := 0; i < total; i++ {
+		fmt.Fprintf(&feed, "<item><title>Item %d</title><guid>%d</guid><pubDate>%s</pubDate></item>",
+			i, i, base.Add(time.Duration(i)*time.Minute).Format(time.RFC1123Z))
+	}
+	feed.WriteString(`</channel></rss>`)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(feed.String()))
+	}))
+	defer srv.Close()
+
+	result := newFetcher(Config{}).fetch(context.Background(), &store.Feed{ID: 1, URL: srv.URL})
+	if result.Error != nil {
+		t.Fatalf("fetch failed: %v", result.Error)
+	}
+	if len(result.Items) != DefaultMaxItemsPerFeed {
+		t.Fatalf("expected %d items, got %d", DefaultMaxItemsPerFeed, len(result.Items))
+	}
+	if result.Truncated != total-DefaultMaxItemsPerFeed {
+		t.Errorf("expected %d truncated, got %d", total-DefaultMaxItemsPerFeed, result.Truncated)
+	}
+	if result.Items[0].GUID != strconv.Itoa(total-1) {
+		t.Errorf("expected newest item first, got guid %s", result.Items[0].GUID)
+	}
+	if last := result.Items[len(result.Items)-1].GUID; last != strconv.Itoa(total-DefaultMaxItemsPerFeed) {
+		t.Errorf("expected oldest kept guid %d, got %s", total-DefaultMaxItemsPerFeed, last)
+	}
+
+	result = newFetcher(Config{MaxItemsPerFeed: 10}).fetch(context.Background(), &store.Feed{ID: 1, URL: srv.URL})
+	if len(result.Items) != 10 {
+		t.Errorf("expected 10 items with MaxItemsPerFeed 10, got %d", len(result.Items))
+	}
+}
+
+func TestCapItems_UndatedLast(t *testing.T) {
+	now := time.Now()
+	items := []FetchedItem{
+		{GUID: "undated-1"},
+		{GUID: "old", Published: now.Add(-time.Hour)},
+		{GUID: "undated-2"},
+		{GUID: "new", Published: now},
+	}
+
+	kept, dropped := capItems(items, 3)
+	if dropped != 1 {
+		t.Errorf("expected 1 dropped, got %d", dropped)
+	}
+	want := []string{"new", "old", "undated-1"}
+	for i, guid := range want {
+		if kept[i].GUID != guid {
+			t.Errorf("item %d: expected %s, got %s", i, guid, kept[i].GUID)
+		}
+	}
+
+	if kept, dropped := capItems(items[:2], 3); len(kept) != 2 || dropped != 0 {
+		t.Errorf("expected no truncation under the cap, got %d kept, %d dropped", len(kept), dropped)
+	}
+}
+
+func TestParseFallbackDate(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want time.Time
+		ok   bool
+	}{
+		{"2024-03-05", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), true},
+		{"2024-03-05 14:30", time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC), true},
+		{"March 5, 2024", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), true},
+		{"  5   March 2024 ", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), true},
+		{"sometime last week", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseFallbackDate(tt.raw)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parseFallbackDate(%q) = %v, %v; want %v, %v", tt.raw, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFetchFeed_CountsBadDates(t *testing.T) {
+	const feed = `<?xml version="1.0"?>
+<rss version="2.0">
+<channel>
+<title>Dates</title>
+<item><title>Good</title><guid>1</guid><pubDate>Mon, 04 Mar 2024 10:00:00 GMT</pubDate></item>
+<item><title>Bad</title><guid>2</guid><pubDate>sometime last week</pubDate></item>
+<item><title>None</title><guid>3</guid></item>
+</channel>
+</rss>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(feed))
+	}))
+	defer srv.Close()
+
+	result := newFetcher(Config{}).fetch(context.Background(), &store.Feed{ID: 1, URL: srv.URL})
+	if result.Error != nil {
+		t.Fatalf("fetch failed: %v", result.Error)
+	}
+	if result.BadDates != 1 {
+		t.Errorf("expected 1 bad date, got %d", result.BadDates)
+	}
+}
+
+func TestFetchFeed_FutureItems(t *testing.T) {
+	future := time.Now().AddDate(1, 0, 0).UTC()
+	feed := fmt.Sprintf(`<?xml version="1.0"?>
+<rss version="2.0">
+<channel>
+<title>Dates</title>
+<item><title>Future</title><guid>1</guid><pubDate>%s</pubDate></item>
+<item><title>Past</title><guid>2</guid><pubDate>Mon, 04 Mar 2024 10:00:00 GMT</pubDate></item>
+</channel>
+</rss>`, future.Format(time.RFC1123Z))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(feed))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		mode      string
+		wantItems int
+		check     func(published time.Time) bool
+	}{
+		{config.FutureItemsClamp, 2, func(p time.Time) bool { return !p.After(time.Now()) && p.After(time.Now().Add(-time.Minute)) }},
+		{config.FutureItemsSkip, 1, nil},
+		{config.FutureItemsAllow, 2, func(p time.Time) bool { return p.Equal(future.Truncate(time.Second)) }},
+	}
+	for _, tt := range tests {
+		result := newFetcher(Config{FutureItems: tt.mode}).fetch(context.Background(), &store.Feed{ID: 1, URL: srv.URL})
+		if result.Error != nil {
+			t.Fatalf("%s: fetch failed: %v", tt.mode, result.Error)
+		}
+		if result.FutureDates != 1 {
+			t.Errorf("%s: expected 1 future date, got %d", tt.mode, result.FutureDates)
+		}
+		if len(result.Items) != tt.wantItems {
+			t.Fatalf("%s: expected %d items, got %d", tt.mode, tt.wantItems, len(result.Items))
+		}
+		if tt.check == nil {
+			if result.Items[0].GUID != "2" {
+				t.Errorf("%s: expected only the past item, got %q", tt.mode, result.Items[0].GUID)
+			}
+		} else if !tt.check(result.Items[0].Published) {
+			t.Errorf("%s: unexpected date for the future item: %v", tt.mode, result.Items[0].Published)
+		}
+	}
+}
+
+func TestFetchFeeds_ReportsProgress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	feeds := []*store.Feed{
+		{ID: 1, URL: srv.URL + "/a"},
+		{ID: 2, URL: srv.URL + "/b"},
+		{ID: 3, URL: srv.URL + "/missing"},
+	}
+
+	var progress atomic.Int32
+	fetchFeedsWith(context.Background(), feeds, &progress, newFetcher(Config{}).fetch)
+	if got := progress.Load(); got != int32(len(feeds)) {
+		t.Errorf("expected progress %d, got %d", len(feeds), got)
+	}
+}
+
+func TestFetchFeed_UsesProxy(t *testing.T) {
+	var proxiedHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.Host
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer proxy.Close()
+
+	access := config.NewFeedAccess(config.FeedPolicy{})
+	if err := access.SetProxy(proxy.URL, ""); err != nil {
+		t.Fatalf("SetProxy failed: %v", err)
+	}
+
+	result := newFetcher(Config{Feeds: access}).fetch(context.Background(), &store.Feed{ID: 1, URL: "http://feeds.example.test/feed.xml"})
+	if result.Error != nil {
+		t.Fatalf("fetch failed: %v", result.Error)
+	}
+	if proxiedHost != "feeds.example.test" {
+		t.Errorf("expected request for feeds.example.test through the proxy, got %q", proxiedHost)
+	}
+	if len(result.Items) != 2 {
+		t.Errorf("expected 2 items, got %d", len(result.Items))
+	}
+}
+
+// startSOCKS5 runs a minimal no-auth SOCKS5 server that connects every
+// request to backend and records the requested host
+func startSOCKS5(t *testing.T, backend string) (addr string, requested <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	hosts := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				buf := make([]byte, 262)
+				// Greeting: version, method count, methods
+				if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+					return
+				}
+				if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+					return
+				}
+				_, _ = conn.Write([]byte{5, 0})
+
+				// Request: version, CONNECT, reserved, domain name, port
+				if _, err := io.ReadFull(conn, buf[:5]); err != nil || buf[3] != 3 {
+					return
+				}
+				n := int(buf[4])
+				if _, err := io.ReadFull(conn, buf[:n+2]); err != nil {
+					return
+				}
+				hosts <- string(buf[:n])
+
+				upstream, err := net.Dial("tcp", backend)
+				if err != nil {
+					return
+				}
+				defer func() { _ = upstream.Close() }()
+				_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+				go func() { _, _ = io.Copy(upstream, conn) }()
+				_, _ = io.Copy(conn, upstream)
+			}()
+		}
+	}()
+	return ln.Addr().String(), hosts
+}
+
+func TestFetchFeed_UsesFeedSOCKSProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer backend.Close()
+
+	addr, requested := startSOCKS5(t, backend.Listener.Addr().String())
+	access := config.NewFeedAccess(config.FeedPolicy{ProxyHosts: []string{"127.0.0.1"}})
+
+	feed := &store.Feed{ID: 1, URL: "http://example.onion/feed", Proxy: "socks5://" + addr}
+	result := newFetcher(Config{Feeds: access}).fetch(context.Background(), feed)
+	if result.Error != nil {
+		t.Fatalf("fetch failed: %v", result.Error)
+	}
+	if host := <-requested; host != "example.onion" {
+		t.Errorf("expected the proxy to resolve example.onion, got %q", host)
+	}
+	if len(result.Items) != 2 {
+		t.Errorf("expected 2 items, got %d", len(result.Items))
+	}
+}
+
+func TestFetchFeed_Warmup(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/landing", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "ok", Path: "/"})
+	})
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err != nil || c.Value != "ok" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(testRSS))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	result := newFetcher(Config{}).fetch(context.Background(), &store.Feed{ID: 1, URL: srv.URL + "/feed"})
+	if result.Error == nil {
+		t.Fatal("expected the feed to refuse a request without the cookie")
+	}
+
+	result = newFetcher(Config{}).fetch(context.Background(), &store.Feed{ID: 1, URL: srv.URL + "/feed", Warmup: srv.URL + "/landing"})
+	if result.Error != nil || len(result.Items) != 2 {
+		t.Errorf("expected the warmup cookie to unlock the feed, got err=%v items=%d", result.Error, len(result.Items))
+	}
+}
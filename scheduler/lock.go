@@ -0,0 +1,31 @@
+package scheduler
+
+import "sync"
+
+// configLocks keeps a config from being run twice at once, e.g. by a slow
+// tick overlapping the next one or a manual run during a scheduled one
+type configLocks struct {
+	mu   sync.Mutex
+	held map[int64]bool
+}
+
+func newConfigLocks() *configLocks {
+	return &configLocks{held: make(map[int64]bool)}
+}
+
+// tryLock claims configID, reporting false if it is already running
+func (l *configLocks) tryLock(configID int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[configID] {
+		return false
+	}
+	l.held[configID] = true
+	return true
+}
+
+func (l *configLocks) unlock(configID int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.held, configID)
+}
@@ -0,0 +1,163 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kierank/herald/config"
+	"github.com/kierank/herald/email"
+	"github.com/kierank/herald/store"
+)
+
+// mergeTarget returns the config whose digest carries cfg's items, or nil if
+// cfg sends its own. The target must be another active config of the same
+// user that isn't merged elsewhere itself.
+func (s *Scheduler) mergeTarget(ctx context.Context, cfg *store.Config, opts *config.ParsedConfig) *store.Config {
+	name := opts.MergeInto
+	if name == "" || name == cfg.Filename {
+		return nil
+	}
+
+	target, err := s.store.GetConfig(ctx, cfg.UserID, name)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logger.Warn("failed to get merge target", "config_id", cfg.ID, "merge_into", name, "err", err)
+		}
+		return nil
+	}
+	if !target.NextRun.Valid || s.configOptions(target).MergeInto != "" {
+		return nil
+	}
+	return target
+}
+
+// mergedSource is a config merged into another's digest, with its options
+// parsed once for the run
+type mergedSource struct {
+	*store.Config
+	opts *config.ParsedConfig
+}
+
+// mergedSources returns the configs whose items go into cfg's digest, each
+// locked against running on its own until releaseMerged. A source that is
+// already running is left out, and its items wait for the next digest.
+func (s *Scheduler) mergedSources(ctx context.Context, cfg *store.Config, opts *config.ParsedConfig) []mergedSource {
+	if opts.MergeInto != "" {
+		return nil
+	}
+
+	configs, err := s.store.ListConfigs(ctx, cfg.UserID)
+	if err != nil {
+		s.logger.Warn("failed to list configs for merging", "config_id", cfg.ID, "err", err)
+		return nil
+	}
+
+	var sources []mergedSource
+	for _, other := range configs {
+		if other.ID == cfg.ID || !other.NextRun.Valid {
+			continue
+		}
+		// Most configs merge nowhere, so only parse the ones that might
+		if !strings.Contains(strings.ToLower(other.RawText), "merge_into") {
+			continue
+		}
+		otherOpts := s.configOptions(other)
+		if otherOpts.MergeInto != cfg.Filename {
+			continue
+		}
+		if !s.running.tryLock(other.ID) {
+			s.logger.Info("merged config already running, leaving it out", "config_id", other.ID, "merge_into", cfg.Filename)
+			continue
+		}
+		sources = append(sources, mergedSource{Config: other, opts: otherOpts})
+	}
+	return sources
+}
+
+// releaseMerged unlocks the sources claimed by mergedSources
+func (s *Scheduler) releaseMerged(sources []mergedSource) {
+	for _, src := range sources {
+		s.running.unlock(src.ID)
+	}
+}
+
+// collectMerged fetches each merged config's feeds and collects its new
+// items, with every group labelled by the config it came from
+func (s *Scheduler) collectMerged(ctx context.Context, sources []mergedSource) ([]email.FeedGroup, int, []*FetchResult) {
+	var feedGroups []email.FeedGroup
+	var results []*FetchResult
+	totalNew := 0
+
+	for _, src := range sources {
+		feeds, err := s.store.GetFeedsByConfig(ctx, src.ID)
+		if err != nil {
+			s.logger.Warn("failed to get merged feeds", "config_id", src.ID, "err", err)
+			continue
+		}
+		if len(feeds) == 0 {
+			continue
+		}
+
+		srcResults := fetchFeedsWith(ctx, feeds, nil, s.fetchCache.fetch)
+		if src.opts.Favicons {
+			s.attachFavicons(ctx, feeds, srcResults)
+		}
+		if src.opts.FeedDescriptions {
+			s.attachDescriptions(ctx, feeds, srcResults)
+		}
+
+		groups, n, err := s.collectNewItems(ctx, src.Config, src.opts, srcResults)
+		if err != nil {
+			s.logger.Warn("failed to collect merged items", "config_id", src.ID, "err", err)
+		}
+		feedGroups = append(feedGroups, sectioned(groups, src.Filename)...)
+		results = append(results, srcResults...)
+		totalNew += n
+	}
+	return feedGroups, totalNew, results
+}
+
+// sectioned labels each group with the config it came from
+func sectioned(groups []email.FeedGroup, section string) []email.FeedGroup {
+	for i := range groups {
+		groups[i].Section = section
+	}
+	return groups
+}
+
+// finishMerged records the run on each merged config and moves it to its next
+// cron tick, so its own schedule never sends it separately
+func (s *Scheduler) finishMerged(ctx context.Context, target *store.Config, sources []mergedSource, now time.Time, sent bool) {
+	for _, src := range sources {
+		nextRun, err := src.NextRunAfter(now)
+		if err != nil {
+			s.logger.Warn("failed to calculate merged next run", "config_id", src.ID, "err", err)
+			continue
+		}
+		if err := s.store.UpdateLastRun(ctx, src.ID, now, nextRun); err != nil {
+			s.logger.Warn("failed to update merged last run", "config_id", src.ID, "err", err)
+			continue
+		}
+		if sent {
+			_ = s.store.AddLog(ctx, src.ID, "info", fmt.Sprintf("Sent as part of the %s digest", target.Filename))
+		}
+	}
+}
+
+// deferToMergeTarget pushes a merged config's next run forward without
+// fetching, since its items are sent with the target's digest
+func (s *Scheduler) deferToMergeTarget(ctx context.Context, cfg, target *store.Config) error {
+	nextRun, err := cfg.NextRunAfter(s.now().UTC())
+	if err != nil {
+		return fmt.Errorf("calculate next run: %w", err)
+	}
+	if err := s.store.UpdateNextRun(ctx, cfg.ID, &nextRun); err != nil {
+		return fmt.Errorf("update next run: %w", err)
+	}
+	s.logger.Info("merged config left for its target", "config_id", cfg.ID, "merge_into", target.Filename)
+	return nil
+}
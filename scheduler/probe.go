@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/kierank/herald/config"
+	"github.com/mmcdole/gofeed"
+)
+
+// probeItemLimit caps how many items a probe reports
+const probeItemLimit = 5
+
+// ErrBlockedAddress is returned when a probe resolves to a non-public address
+var ErrBlockedAddress = config.ErrNonPublicAddress
+
+// ProbeResult is a one-off fetch of a feed URL, used for debugging feeds
+// without touching stored state.
+type ProbeResult struct {
+	Status      string
+	StatusCode  int
+	ContentType string
+	Title       string
+	ItemCount   int
+	Items       []FetchedItem
+}
+
+// probeTransport refuses connections to loopback, private, and link-local
+// addresses. The check runs at dial time so redirects and DNS answers are
+// covered too.
+var probeTransport = &http.Transport{
+	Proxy: nil,
+	DialContext: (&net.Dialer{
+		Timeout: feedFetchTimeout,
+		Control: config.RejectNonPublic,
+	}).DialContext,
+	TLSHandshakeTimeout: 10 * time.Second,
+}
+
+// ProbeFeed fetches and parses an arbitrary feed URL
+func (s *Scheduler) ProbeFeed(ctx context.Context, rawURL string) (*ProbeResult, error) {
+	return s.fetcher.probeFeed(ctx, s.fetcher.probeClient, rawURL)
+}
+
+func (f *fetcher) probeFeed(ctx context.Context, client *http.Client, rawURL string) (*ProbeResult, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid feed URL %q", rawURL)
+	}
+	if err := f.access.CheckURL(u); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, feedFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Herald/1.0 (RSS Aggregator)")
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	result := &ProbeResult{
+		Status:      resp.Status,
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if resp.StatusCode != http.StatusOK {
+		return result, nil
+	}
+
+	body, err := decodeBody(resp)
+	if err != nil {
+		return result, err
+	}
+	defer func() { _ = body.Close() }()
+
+	parsed, err := gofeed.NewParser().Parse(io.LimitReader(body, maxFeedSize))
+	if err != nil {
+		return result, fmt.Errorf("parse feed: %w", err)
+	}
+
+	result.Title = parsed.Title
+	result.ItemCount = len(parsed.Items)
+	for _, item := range parsed.Items[:min(len(parsed.Items), probeItemLimit)] {
+		guid := item.GUID
+		if guid == "" {
+			guid = item.Link
+		}
+		result.Items = append(result.Items, FetchedItem{GUID: guid, Title: item.Title, Link: item.Link})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbeFeed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	result, err := newFetcher(Config{}).probeFeed(context.Background(), srv.Client(), srv.URL)
+	if err != nil {
+		t.Fatalf("probeFeed failed: %v", err)
+	}
+	if result.StatusCode != http.StatusOK || result.ContentType != "application/rss+xml" {
+		t.Errorf("unexpected status or content type: %d %q", result.StatusCode, result.ContentType)
+	}
+	if result.Title != "Test Feed" || result.ItemCount != 2 || len(result.Items) != 2 {
+		t.Errorf("unexpected parse result: %+v", result)
+	}
+}
+
+func TestProbeFeedBlocksLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("loopback server should not be reached")
+	}))
+	defer srv.Close()
+
+	f := newFetcher(Config{})
+	_, err := f.probeFeed(context.Background(), f.probeClient, srv.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("expected ErrBlockedAddress, got %v", err)
+	}
+}
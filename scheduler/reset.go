@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/kierank/herald/store"
+)
+
+// ResetStats summarizes a reset of a config's seen items
+type ResetStats struct {
+	Cleared      int64
+	Seeded       int
+	FetchedFeeds int
+	FailedFeeds  int
+}
+
+// ResetSeen forgets the seen items of cfg's feeds and marks their current
+// items seen again, so later runs only send what is published from now on.
+// Feeds are fetched first without conditional headers, since a 304 has no
+// items to re-seed, and only the feeds that fetched are cleared and re-seeded,
+// in one transaction. A feed that fails keeps its seen items. The config's run
+// lock is held throughout so a scheduled run can't send in between.
+func (s *Scheduler) ResetSeen(ctx context.Context, cfg *store.Config) (*ResetStats, error) {
+	if !s.beginRun() {
+		return nil, ErrShuttingDown
+	}
+	defer s.inflight.Done()
+	if !s.running.tryLock(cfg.ID) {
+		return nil, ErrConfigBusy
+	}
+	defer s.running.unlock(cfg.ID)
+
+	feeds, err := s.store.GetFeedsByConfig(ctx, cfg.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get feeds: %w", err)
+	}
+
+	unconditional := make([]*store.Feed, len(feeds))
+	for i, feed := range feeds {
+		f := *feed
+		f.ETag = sql.NullString{}
+		f.LastModified = sql.NullString{}
+		unconditional[i] = &f
+	}
+	results := fetchFeedsWith(ctx, unconditional, nil, s.fetcher.fetch)
+
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stats := &ResetStats{}
+	for _, result := range results {
+		if result.Error != nil {
+			stats.FailedFeeds++
+			s.logger.Warn("reset: failed to fetch feed", "feed_url", result.FeedURL, "err", result.Error)
+			continue
+		}
+		deleted, err := s.store.DeleteFeedSeenItemsTx(ctx, tx, result.FeedID)
+		if err != nil {
+			return nil, err
+		}
+		stats.Cleared += deleted
+		for _, item := range result.Items {
+			if err := s.store.MarkItemSeenTx(ctx, tx, result.FeedID, item.GUID, item.Title, item.Link); err != nil {
+				return nil, err
+			}
+			stats.Seeded++
+		}
+		stats.FetchedFeeds++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	return stats, nil
+}
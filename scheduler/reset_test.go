@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kierank/herald/store"
+)
+
+func TestResetSeen(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(testRSS))
+	})
+	mux.HandleFunc("/gone", http.NotFound)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	s, db, user := setupTestScheduler(t, Config{})
+
+	ctx := context.Background()
+	cfg, _ := db.CreateConfig(ctx, user.ID, "reset.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now().Add(time.Hour))
+	feed, _ := db.CreateFeed(ctx, cfg.ID, srv.URL+"/feed", "", store.FeedOptions{})
+	gone, _ := db.CreateFeed(ctx, cfg.ID, srv.URL+"/gone", "", store.FeedOptions{})
+
+	// The stored ETag is current, so a conditional fetch would come back 304
+	if err := db.UpdateFeedFetched(ctx, feed.ID, `"v1"`, ""); err != nil {
+		t.Fatalf("UpdateFeedFetched failed: %v", err)
+	}
+	_ = db.MarkItemSeen(ctx, feed.ID, "old", "Old", "https://example.com/old")
+	_ = db.MarkItemSeen(ctx, gone.ID, "kept", "Kept", "https://example.com/kept")
+
+	stats, err := s.ResetSeen(ctx, cfg)
+	if err != nil {
+		t.Fatalf("ResetSeen failed: %v", err)
+	}
+	if stats.Cleared != 1 || stats.Seeded != 2 || stats.FetchedFeeds != 1 || stats.FailedFeeds != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	seen, _ := db.GetSeenGUIDs(ctx, feed.ID, []string{"old", "1", "2"})
+	if seen["old"] || !seen["1"] || !seen["2"] {
+		t.Errorf("expected the feed's current items re-seeded in place of old ones, got %v", seen)
+	}
+	if ok, _ := db.IsItemSeen(ctx, gone.ID, "kept"); !ok {
+		t.Error("expected a feed that failed to fetch to keep its seen items")
+	}
+
+	// A config that is running can't be reset under it
+	s.running.tryLock(cfg.ID)
+	defer s.running.unlock(cfg.ID)
+	if _, err := s.ResetSeen(ctx, cfg); !errors.Is(err, ErrConfigBusy) {
+		t.Errorf("expected ErrConfigBusy, got %v", err)
+	}
+}
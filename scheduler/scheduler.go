@@ -2,13 +2,20 @@ package scheduler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/adhocore/gronx"
 	"github.com/charmbracelet/log"
+	"github.com/kierank/herald/config"
 	"github.com/kierank/herald/email"
+	"github.com/kierank/herald/lang"
 	"github.com/kierank/herald/ratelimit"
 	"github.com/kierank/herald/store"
 )
@@ -24,15 +31,53 @@ const (
 	seenItemsRetention  = 6 * 30 * 24 * time.Hour // 6 months
 	itemMaxAge          = 3 * 30 * 24 * time.Hour // 3 months
 	emailSendsRetention = 6 * 30                  // 6 months in days
+	logsRetention       = 30                      // default days to keep logs
+
+	// overdueThreshold is how far past next_run a config counts as backlog
+	overdueThreshold = 10 * time.Minute
 
 	// Item limits
 	minItemsForDigest = 5
 
+	// Default for the max_hold directive
+	defaultMaxHold = 7 * 24 * time.Hour
+
 	// Engagement tracking
 	inactivityThreshold      = 90 // days without opens
 	minSendsBeforeDeactivate = 3  // minimum sends before considering deactivation
+
+	// Adaptive scheduling slows configs whose digests go unopened
+	adaptiveRecentSends   = 5   // latest digests the open rate is judged on
+	adaptiveMinSends      = 3   // sends needed before judging open rate
+	adaptiveLowOpenRate   = 0.1 // below this the cadence slows down
+	adaptiveRestoreRate   = 0.3 // at or above this the cadence is restored
+	maxAdaptiveMultiplier = 7   // e.g. daily at most slows to weekly
+
+	// The retry_failed directive re-fetches failed feeds before the next cron run
+	retryFailedDelay = 15 * time.Minute
+	maxFailedRetries = 3 // per cycle
+
+	// How far ahead to look for a run outside skip_dates and skip_weekends
+	maxSkipSearchDays = 400
+
+	// How long shutdown waits for in-flight runs to finish their sends
+	shutdownTimeout = 30 * time.Second
 )
 
+// ErrEmailRateLimited is returned when a digest is held back by the per-user
+// email rate limit. Its items stay unseen and go out on a later run.
+var ErrEmailRateLimited = errors.New("rate limit exceeded for email sending")
+
+// ErrConfigBusy is returned by RunNow while the config is already being run
+var ErrConfigBusy = errors.New("config is already running")
+
+// ErrShuttingDown is returned by RunNow once the scheduler has begun to stop
+var ErrShuttingDown = errors.New("scheduler is shutting down")
+
+// ErrMergedConfig is returned by RunNow for a config whose items are sent in
+// another config's digest
+var ErrMergedConfig = errors.New("config is merged into another digest")
+
 // RunStats contains detailed statistics from a feed fetch run
 type RunStats struct {
 	TotalFeeds   int
@@ -40,6 +85,114 @@ type RunStats struct {
 	FailedFeeds  int
 	NewItems     int
 	EmailSent    bool
+	Held         bool
+	Retrying     bool
+	Duration     time.Duration
+	FeedCounts   []FeedCount
+}
+
+// FeedCount is the number of new items found in a single feed during a run
+type FeedCount struct {
+	FeedName string
+	NewItems int
+}
+
+// Breakdown formats per-feed new item counts, e.g. "Feed A: 8, Feed B: 4"
+func (r *RunStats) Breakdown() string {
+	parts := make([]string, len(r.FeedCounts))
+	for i, fc := range r.FeedCounts {
+		parts[i] = fmt.Sprintf("%s: %d", fc.FeedName, fc.NewItems)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// newRunStats builds run statistics from fetch results and the collected feed groups
+func newRunStats(results []*FetchResult, feedGroups []email.FeedGroup, totalNew int, start time.Time) *RunStats {
+	stats := &RunStats{
+		TotalFeeds: len(results),
+		NewItems:   totalNew,
+		Duration:   time.Since(start),
+	}
+	for _, result := range results {
+		if result.Error != nil {
+			stats.FailedFeeds++
+		} else {
+			stats.FetchedFeeds++
+		}
+	}
+	for _, group := range feedGroups {
+		stats.FeedCounts = append(stats.FeedCounts, FeedCount{FeedName: group.FeedName, NewItems: len(group.Items)})
+	}
+	return stats
+}
+
+// runLogMessage summarizes a completed run for the config's activity log
+func runLogMessage(stats *RunStats, nextRun time.Time) string {
+	msg := fmt.Sprintf("Processed: %d new items from %d/%d feeds in %s", stats.NewItems, stats.FetchedFeeds, stats.TotalFeeds, stats.Duration.Round(100*time.Millisecond))
+	if stats.FailedFeeds > 0 {
+		msg += fmt.Sprintf(" (%d failed)", stats.FailedFeeds)
+	}
+	if len(stats.FeedCounts) > 0 {
+		msg += " [" + stats.Breakdown() + "]"
+	}
+	if stats.Held {
+		msg += ", held below min_send"
+	}
+	if stats.Retrying {
+		msg += ", retrying failed feeds"
+	}
+	return msg + ", next run: " + nextRun.Format(time.RFC3339)
+}
+
+// deliveryLogMessage is the config log line recording the SMTP server
+// accepting a digest
+func deliveryLogMessage(receipt email.SendReceipt, items int) string {
+	msg := fmt.Sprintf("Delivered %q to %s with %d items, accepted by %s", receipt.Subject, receipt.To, items, receipt.Server)
+	if receipt.Response != "" {
+		msg += ": " + receipt.Response
+	}
+	return msg
+}
+
+type Config struct {
+	Interval  time.Duration
+	OriginURL string
+	// MaxSeenItemsPerFeed caps stored seen items per feed; 0 disables the cap
+	MaxSeenItemsPerFeed int
+	// DigestWebhookURL receives a JSON POST after each digest is sent
+	DigestWebhookURL string
+	// StaleFeedThreshold flags feeds with no new items for this long; 0 disables
+	StaleFeedThreshold time.Duration
+	// FirstRunWindow limits a config's first run to items published this
+	// recently; 0 disables
+	FirstRunWindow time.Duration
+	// LogRetentionDays is how long config logs are kept; 0 uses the default
+	LogRetentionDays int
+	// KeepInactive disables deactivating configs that go unopened
+	KeepInactive bool
+	// MinFetchInterval is the shortest time between fetches of one feed URL
+	// across all configs; 0 disables
+	MinFetchInterval time.Duration
+	// AuditEmailBodies is config.AuditEmailHash or config.AuditEmailFull to
+	// keep a record of each sent body; empty disables
+	AuditEmailBodies string
+	// Feeds applies the instance's feed policy and proxy to fetches; nil
+	// allows any feed and uses the proxy settings from the environment
+	Feeds *config.FeedAccess
+	// MaxItemsPerFeed caps items kept from one fetch, newest first; 0 uses
+	// DefaultMaxItemsPerFeed
+	MaxItemsPerFeed int
+	// FetchRetries is how many times a fetch is retried after a network error
+	// or 5xx, waiting FetchRetryBackoff before the first retry and doubling
+	// after each; 0 disables retrying
+	FetchRetries      int
+	FetchRetryBackoff time.Duration
+	// BackfillPages is how many next-page links FetchFeedBackfill follows
+	// past the first page; 0 turns backfilling off
+	BackfillPages int
+	// FutureItems handles items dated in the future: config.FutureItemsClamp,
+	// FutureItemsSkip, or FutureItemsAllow; empty clamps
+	FutureItems string
 }
 
 type Scheduler struct {
@@ -48,18 +201,81 @@ type Scheduler struct {
 	logger      *log.Logger
 	interval    time.Duration
 	originURL   string
+	maxSeen     int
+	webhookURL  string
+	staleAfter  time.Duration
+	firstRun    time.Duration
+	fetchFloor  time.Duration
+	logDays     int
+	keepIdle    bool
+	audit       string
 	rateLimiter *ratelimit.Limiter
+	fetcher     *fetcher
+	fetchCache  *fetchCache
+	lastFetches *lastFetches
+	ticks       TickRecorder
+	favicons    *http.Client
+	running     *configLocks
+	inflight    sync.WaitGroup
+	// stopping is set once shutdown starts; runs check it under stopMu
+	// before joining inflight, so none start after drain begins waiting
+	stopMu   sync.Mutex
+	stopping bool
+	// now is the clock runs are scheduled by, normally time.Now
+	now func() time.Time
+	// send delivers a digest, normally mailer.Send
+	send func(to, subject, htmlBody, textBody, unsubToken, dashboardURL, keepAliveURL, footer string, meta email.DigestMeta) (email.SendReceipt, error)
 }
 
-func NewScheduler(st *store.DB, mailer *email.Mailer, logger *log.Logger, interval time.Duration, originURL string) *Scheduler {
-	return &Scheduler{
+// TickRecorder receives scheduler health after each tick, how many
+// fetches were answered 304 Not Modified, and each digest held back by the
+// email rate limit
+type TickRecorder interface {
+	RecordTick(duration time.Duration, processed, overdue int)
+	RecordNotModified(n int)
+	RecordRateLimited()
+}
+
+// SetTickRecorder reports tick duration and backlog to r
+func (s *Scheduler) SetTickRecorder(r TickRecorder) {
+	s.ticks = r
+}
+
+// FeedAccess returns the feed policy and proxy settings fetches go through
+func (s *Scheduler) FeedAccess() *config.FeedAccess {
+	return s.fetcher.access
+}
+
+func NewScheduler(cfg Config, st *store.DB, mailer *email.Mailer, logger *log.Logger) *Scheduler {
+	fetcher := newFetcher(cfg)
+	s := &Scheduler{
 		store:       st,
 		mailer:      mailer,
 		logger:      logger,
-		interval:    interval,
-		originURL:   originURL,
+		interval:    cfg.Interval,
+		originURL:   cfg.OriginURL,
+		maxSeen:     cfg.MaxSeenItemsPerFeed,
+		webhookURL:  cfg.DigestWebhookURL,
+		staleAfter:  cfg.StaleFeedThreshold,
+		logDays:     cfg.LogRetentionDays,
+		keepIdle:    cfg.KeepInactive,
+		firstRun:    cfg.FirstRunWindow,
+		audit:       cfg.AuditEmailBodies,
 		rateLimiter: ratelimit.New(emailsPerSecondPerUser, emailRateBurst),
+		fetcher:     fetcher,
+		fetchCache:  newFetchCache(fetcher.fetch),
+		favicons:    faviconClient,
+		running:     newConfigLocks(),
+		now:         time.Now,
+		send:        mailer.Send,
 	}
+
+	if cfg.MinFetchInterval > 0 {
+		s.fetchFloor = cfg.MinFetchInterval
+		s.lastFetches = newLastFetches()
+		s.fetchCache.fetchFn = s.fetchPolitely
+	}
+	return s
 }
 
 func (s *Scheduler) Start(ctx context.Context) {
@@ -79,10 +295,13 @@ func (s *Scheduler) Start(ctx context.Context) {
 	// Run cleanup on start
 	s.cleanupOldSeenItems(ctx)
 	s.cleanupOldEmailSends(ctx)
+	s.cleanupOldLogs(ctx)
+	s.cleanupGlobalFetches(ctx)
 
 	for {
 		select {
 		case <-ctx.Done():
+			s.drain(shutdownTimeout)
 			s.logger.Info("scheduler stopped")
 			return
 		case <-ticker.C:
@@ -90,12 +309,62 @@ func (s *Scheduler) Start(ctx context.Context) {
 		case <-cleanupTicker.C:
 			s.cleanupOldSeenItems(ctx)
 			s.cleanupOldEmailSends(ctx)
+			s.cleanupOldLogs(ctx)
+			s.cleanupGlobalFetches(ctx)
 		case <-engagementTicker.C:
 			s.checkAndDeactivateInactiveConfigs(ctx)
+			s.adjustAdaptiveSchedules(ctx)
+			s.checkStaleFeeds(ctx)
 		}
 	}
 }
 
+// beginRun registers an in-flight run, or reports false once shutdown has
+// started. Each successful call must be paired with s.inflight.Done.
+func (s *Scheduler) beginRun() bool {
+	s.stopMu.Lock()
+	defer s.stopMu.Unlock()
+	if s.stopping {
+		return false
+	}
+	s.inflight.Add(1)
+	return true
+}
+
+// runContext detaches a run from ctx so shutdown doesn't cancel it between
+// its send and commit, but still cancels it shutdownTimeout after ctx ends
+func runContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(shutdownTimeout, cancel)
+	})
+	return runCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// drain refuses new runs, then waits up to timeout for in-flight ones, such
+// as manual runs from SSH sessions, so a send isn't cut off between SMTP and
+// its commit
+func (s *Scheduler) drain(timeout time.Duration) {
+	s.stopMu.Lock()
+	s.stopping = true
+	s.stopMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		s.logger.Warn("shutdown timed out waiting for in-flight runs", "timeout", timeout)
+	}
+}
+
 func (s *Scheduler) cleanupOldSeenItems(ctx context.Context) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -111,6 +380,15 @@ func (s *Scheduler) cleanupOldSeenItems(ctx context.Context) {
 	if deleted > 0 {
 		s.logger.Info("cleaned up old seen items", "deleted", deleted)
 	}
+
+	deleted, err = s.store.CleanupOldFirstSeen(ctx, seenItemsRetention)
+	if err != nil {
+		s.logger.Error("failed to cleanup old first seen dates", "err", err)
+		return
+	}
+	if deleted > 0 {
+		s.logger.Info("cleaned up old first seen dates", "deleted", deleted)
+	}
 }
 
 func (s *Scheduler) cleanupOldEmailSends(ctx context.Context) {
@@ -130,6 +408,46 @@ func (s *Scheduler) cleanupOldEmailSends(ctx context.Context) {
 	}
 }
 
+func (s *Scheduler) cleanupOldLogs(ctx context.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("panic during logs cleanup", "panic", r)
+		}
+	}()
+
+	days := s.logDays
+	if days <= 0 {
+		days = logsRetention
+	}
+
+	deleted, err := s.store.CleanupOldLogs(ctx, days)
+	if err != nil {
+		s.logger.Error("failed to cleanup old logs", "err", err)
+		return
+	}
+	if deleted > 0 {
+		s.logger.Info("cleaned up old logs", "deleted", deleted)
+	}
+}
+
+// cleanupGlobalFetches drops fetch times that no longer hold back a fetch
+func (s *Scheduler) cleanupGlobalFetches(ctx context.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("panic during global fetch cleanup", "panic", r)
+		}
+	}()
+
+	deleted, err := s.store.CleanupGlobalFetches(ctx, max(s.fetchFloor, cleanupInterval))
+	if err != nil {
+		s.logger.Error("failed to cleanup global fetch times", "err", err)
+		return
+	}
+	if deleted > 0 {
+		s.logger.Info("cleaned up global fetch times", "deleted", deleted)
+	}
+}
+
 func (s *Scheduler) checkAndDeactivateInactiveConfigs(ctx context.Context) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -137,6 +455,10 @@ func (s *Scheduler) checkAndDeactivateInactiveConfigs(ctx context.Context) {
 		}
 	}()
 
+	if s.keepIdle {
+		return
+	}
+
 	inactiveConfigs, err := s.store.GetInactiveConfigs(inactivityThreshold, minSendsBeforeDeactivate)
 	if err != nil {
 		s.logger.Error("failed to get inactive configs", "err", err)
@@ -160,6 +482,9 @@ func (s *Scheduler) checkAndDeactivateInactiveConfigs(ctx context.Context) {
 		if !cfg.NextRun.Valid {
 			continue
 		}
+		if !s.configOptions(cfg).AutoDeactivate {
+			continue
+		}
 
 		// Deactivate by setting next_run to NULL
 		if err := s.store.UpdateNextRun(ctx, configID, nil); err != nil {
@@ -172,6 +497,41 @@ func (s *Scheduler) checkAndDeactivateInactiveConfigs(ctx context.Context) {
 	}
 }
 
+// checkStaleFeeds logs a notice on each config with feeds that have stopped
+// producing new items, so users can prune dead feeds.
+func (s *Scheduler) checkStaleFeeds(ctx context.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("panic during stale feed check", "panic", r)
+		}
+	}()
+
+	if s.staleAfter <= 0 {
+		return
+	}
+
+	staleFeeds, err := s.store.GetStaleFeeds(ctx, s.staleAfter)
+	if err != nil {
+		s.logger.Error("failed to get stale feeds", "err", err)
+		return
+	}
+
+	if len(staleFeeds) == 0 {
+		return
+	}
+
+	s.logger.Info("found stale feeds", "count", len(staleFeeds))
+
+	days := int(s.staleAfter.Hours() / 24)
+	for _, feed := range staleFeeds {
+		name := feed.URL
+		if feed.Name.Valid {
+			name = feed.Name.String
+		}
+		_ = s.store.AddLog(ctx, feed.ConfigID, "info", fmt.Sprintf("Feed %s has had no new items in %d days and may be dead", name, days))
+	}
+}
+
 func (s *Scheduler) tick(ctx context.Context) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -179,26 +539,83 @@ func (s *Scheduler) tick(ctx context.Context) {
 		}
 	}()
 
-	now := time.Now().UTC()
-	configs, err := s.store.GetDueConfigs(ctx, now)
+	start := time.Now()
+	configs, err := s.store.GetDueConfigs(ctx, s.now().UTC())
 	if err != nil {
 		s.logger.Error("failed to get due configs", "err", err)
 		return
 	}
 
-	for _, cfg := range configs {
-		if err := s.processConfig(ctx, cfg); err != nil {
-			s.logger.Error("failed to process config", "config_id", cfg.ID, "err", err)
-			_ = s.store.AddLog(ctx, cfg.ID, "error", fmt.Sprintf("Failed: %v", err))
+	// A config already being processed runs to completion on shutdown, so
+	// it isn't cancelled between its send and commit
+	runCtx, cancel := runContext(ctx)
+	defer cancel()
+	for i, cfg := range configs {
+		if ctx.Err() != nil || !s.beginRun() {
+			s.logger.Info("shutting down, leaving remaining configs", "remaining", len(configs)-i)
+			break
 		}
+		func() {
+			defer s.inflight.Done()
+			if err := s.processConfig(runCtx, cfg); err != nil {
+				s.logger.Error("failed to process config", "config_id", cfg.ID, "err", err)
+				_ = s.store.AddLog(runCtx, cfg.ID, "error", fmt.Sprintf("Failed: %v", err))
+			}
+		}()
+	}
+
+	s.recordTick(ctx, time.Since(start), len(configs))
+}
+
+// recordTick reports the tick duration and the remaining overdue backlog
+func (s *Scheduler) recordTick(ctx context.Context, duration time.Duration, processed int) {
+	if s.ticks == nil {
+		return
+	}
+
+	overdue, err := s.store.CountOverdueConfigs(ctx, overdueThreshold)
+	if err != nil {
+		s.logger.Warn("failed to count overdue configs", "err", err)
+	}
+	if overdue > 0 {
+		s.logger.Warn("scheduler is behind", "overdue_configs", overdue)
+	}
+	s.ticks.RecordTick(duration, processed, overdue)
+}
+
+// recordNotModified counts the feeds whose conditional request saved a
+// download, logging each so users can check caching works per feed
+func (s *Scheduler) recordNotModified(results []*FetchResult) {
+	n := 0
+	for _, result := range results {
+		if result.NotModified {
+			s.logger.Debug("feed not modified", "feed_id", result.FeedID, "url", result.FeedURL)
+			n++
+		}
+	}
+	if n > 0 && s.ticks != nil {
+		s.ticks.RecordNotModified(n)
 	}
 }
 
 func (s *Scheduler) RunNow(ctx context.Context, configID int64, progress *atomic.Int32) (*RunStats, error) {
+	if !s.beginRun() {
+		return nil, ErrShuttingDown
+	}
+	defer s.inflight.Done()
+	if !s.running.tryLock(configID) {
+		return nil, ErrConfigBusy
+	}
+	defer s.running.unlock(configID)
+
 	cfg, err := s.store.GetConfigByID(ctx, configID)
 	if err != nil {
 		return nil, fmt.Errorf("get config: %w", err)
 	}
+	opts := s.configOptions(cfg)
+	if target := s.mergeTarget(ctx, cfg, opts); target != nil {
+		return nil, fmt.Errorf("%w: run %s instead", ErrMergedConfig, target.Filename)
+	}
 
 	feeds, err := s.store.GetFeedsByConfig(ctx, cfg.ID)
 	if err != nil {
@@ -209,56 +626,59 @@ func (s *Scheduler) RunNow(ctx context.Context, configID int64, progress *atomic
 		return nil, fmt.Errorf("no feeds configured")
 	}
 
-	stats := &RunStats{
-		TotalFeeds: len(feeds),
-	}
-
-	results := FetchFeeds(ctx, feeds, progress)
+	start := time.Now()
+	results := fetchFeedsWith(ctx, feeds, progress, s.fetchCache.fetch)
 	s.logger.Debug("RunNow: fetching complete", "total", len(feeds))
-
-	// Count successful and failed fetches
-	for _, result := range results {
-		if result.Error != nil {
-			stats.FailedFeeds++
-		} else {
-			stats.FetchedFeeds++
-		}
+	s.recordNotModified(results)
+	if opts.Favicons {
+		s.attachFavicons(ctx, feeds, results)
+	}
+	if opts.FeedDescriptions {
+		s.attachDescriptions(ctx, feeds, results)
 	}
-	s.logger.Debug("RunNow: counting complete", "fetched", stats.FetchedFeeds, "failed", stats.FailedFeeds)
 
-	feedGroups, totalNew, err := s.collectNewItems(ctx, results)
+	feedGroups, totalNew, err := s.collectNewItems(ctx, cfg, opts, results)
 	s.logger.Debug("RunNow: collectNewItems complete", "totalNew", totalNew, "err", err)
 	if err != nil {
-		return stats, err
+		return newRunStats(results, feedGroups, totalNew, start), err
 	}
 
-	stats.NewItems = totalNew
+	allResults := results
+	sources := s.mergedSources(ctx, cfg, opts)
+	defer s.releaseMerged(sources)
+	if len(sources) > 0 {
+		merged, mergedNew, mergedResults := s.collectMerged(ctx, sources)
+		feedGroups = append(sectioned(feedGroups, cfg.Filename), merged...)
+		totalNew += mergedNew
+		allResults = append(append([]*FetchResult(nil), results...), mergedResults...)
+	}
+	stats := newRunStats(allResults, feedGroups, totalNew, start)
 
 	if totalNew > 0 {
 		s.logger.Debug("RunNow: starting email send")
-		if err := s.sendDigestAndMarkSeen(ctx, cfg, feedGroups, totalNew, results); err != nil {
+		receipt, err := s.sendDigestAndMarkSeen(ctx, cfg, opts, feedGroups, totalNew, allResults)
+		if err != nil {
 			s.logger.Error("RunNow: sendDigestAndMarkSeen failed", "err", err)
 			return stats, err
 		}
 		stats.EmailSent = true
-		s.logger.Info("email sent", "to", cfg.Email, "items", totalNew)
+		s.logger.Info("email sent", "to", cfg.Email, "items", totalNew, "response", receipt.Response)
+		_ = s.store.AddLog(ctx, cfg.ID, "info", deliveryLogMessage(receipt, totalNew))
+		s.trimSeenItems(ctx, allResults)
 	}
 	s.logger.Debug("RunNow: email phase complete")
 
 	// Update feed metadata
-	s.logger.Debug("RunNow: updating feed metadata", "count", len(results))
-	for _, result := range results {
-		if result.ETag != "" || result.LastModified != "" {
-			if err := s.store.UpdateFeedFetched(ctx, result.FeedID, result.ETag, result.LastModified); err != nil {
-				s.logger.Warn("failed to update feed fetched", "err", err)
-			}
-		}
-	}
+	s.logger.Debug("RunNow: updating feed metadata", "count", len(allResults))
+	s.recordFeedResults(ctx, allResults, false)
+	now := s.now().UTC()
+	s.finishMerged(ctx, cfg, sources, now, stats.EmailSent)
 	s.logger.Debug("RunNow: feed metadata updated")
 
+	// A manual run settles any pending retry the same way a scheduled run
+	// would, so the next scheduled run doesn't resume a stale attempt
 	s.logger.Debug("RunNow: calculating next run")
-	now := time.Now().UTC()
-	nextRun, err := gronx.NextTickAfter(cfg.CronExpr, now, true)
+	nextRun, attempt, err := s.nextRunAfterFailures(cfg, opts, results, now)
 	if err != nil {
 		return stats, fmt.Errorf("calculate next run: %w", err)
 	}
@@ -267,18 +687,34 @@ func (s *Scheduler) RunNow(ctx context.Context, configID int64, progress *atomic
 	if err := s.store.UpdateLastRun(ctx, cfg.ID, now, nextRun); err != nil {
 		return stats, fmt.Errorf("update last run: %w", err)
 	}
+	if attempt != cfg.RetryAttempt {
+		if err := s.store.SetRetryAttempt(ctx, cfg.ID, attempt); err != nil {
+			s.logger.Warn("failed to record retry attempt", "config_id", cfg.ID, "err", err)
+		}
+	}
+	stats.Retrying = attempt > 0
 
-	_ = s.store.AddLog(ctx, cfg.ID, "info", fmt.Sprintf("Processed: %d new items, next run: %s", totalNew, nextRun.Format(time.RFC3339)))
+	stats.Duration = time.Since(start)
+	_ = s.store.AddLog(ctx, cfg.ID, "info", runLogMessage(stats, nextRun))
 
 	s.logger.Debug("RunNow: complete")
 	return stats, nil
 }
 
-func (s *Scheduler) collectNewItems(ctx context.Context, results []*FetchResult) ([]email.FeedGroup, int, error) {
+func (s *Scheduler) collectNewItems(ctx context.Context, cfg *store.Config, opts *config.ParsedConfig, results []*FetchResult) ([]email.FeedGroup, int, error) {
 	var feedGroups []email.FeedGroup
 	totalNew := 0
-	maxAge := time.Now().UTC().Add(-itemMaxAge)
+	now := s.now().UTC()
+	maxAge := now.Add(-itemMaxAge)
 	feedErrors := 0
+	langs := languageSet(opts.Languages)
+
+	// A config that has never run only gets recent items, in case preseeding
+	// missed feeds that were down at upload time
+	var firstRunCutoff time.Time
+	if !cfg.LastRun.Valid && s.firstRun > 0 {
+		firstRunCutoff = now.Add(-s.firstRun)
+	}
 
 	for _, result := range results {
 		if result.Error != nil {
@@ -286,6 +722,16 @@ func (s *Scheduler) collectNewItems(ctx context.Context, results []*FetchResult)
 			feedErrors++
 			continue
 		}
+		if result.Truncated > 0 {
+			s.logger.Warn("feed truncated to newest items", "feed_id", result.FeedID, "url", result.FeedURL, "kept", len(result.Items), "dropped", result.Truncated)
+		}
+		if result.BadDates > 0 {
+			s.logger.Warn("feed has unparseable item dates", "feed_id", result.FeedID, "url", result.FeedURL, "items", result.BadDates)
+		}
+		if result.FutureDates > 0 {
+			s.logger.Warn("feed has future-dated items", "feed_id", result.FeedID, "url", result.FeedURL, "items", result.FutureDates)
+		}
+		s.dateUndatedItems(ctx, result)
 
 		// Collect all GUIDs for this feed to batch check
 		var guids []string
@@ -311,11 +757,26 @@ func (s *Scheduler) collectNewItems(ctx context.Context, results []*FetchResult)
 			}
 
 			if !seenSet[item.GUID] {
+				if !firstRunCutoff.IsZero() && item.Published.Before(firstRunCutoff) {
+					if err := s.store.MarkItemSeen(ctx, result.FeedID, item.GUID, item.Title, item.Link); err != nil {
+						s.logger.Warn("failed to mark backlog item seen", "feed_id", result.FeedID, "err", err)
+					}
+					continue
+				}
+				// Items in other languages are marked seen so they never resurface
+				if !acceptsLanguage(langs, item) {
+					if err := s.store.MarkItemSeen(ctx, result.FeedID, item.GUID, item.Title, item.Link); err != nil {
+						s.logger.Warn("failed to mark filtered item seen", "feed_id", result.FeedID, "err", err)
+					}
+					continue
+				}
 				newItems = append(newItems, email.FeedItem{
+					GUID:      item.GUID,
 					Title:     item.Title,
 					Link:      item.Link,
 					Content:   item.Content,
 					Published: item.Published,
+					PlainText: item.PlainText,
 				})
 			}
 		}
@@ -326,9 +787,12 @@ func (s *Scheduler) collectNewItems(ctx context.Context, results []*FetchResult)
 				feedName = result.FeedURL
 			}
 			feedGroups = append(feedGroups, email.FeedGroup{
-				FeedName: feedName,
-				FeedURL:  result.FeedURL,
-				Items:    newItems,
+				FeedName:    feedName,
+				FeedURL:     result.FeedURL,
+				Favicon:     result.Favicon,
+				Note:        result.Note,
+				Description: result.Description,
+				Items:       newItems,
 			})
 			totalNew += len(newItems)
 		}
@@ -341,12 +805,53 @@ func (s *Scheduler) collectNewItems(ctx context.Context, results []*FetchResult)
 	return feedGroups, totalNew, nil
 }
 
-func (s *Scheduler) sendDigestAndMarkSeen(ctx context.Context, cfg *store.Config, feedGroups []email.FeedGroup, totalNew int, results []*FetchResult) error {
+// dateUndatedItems gives items without a usable date the time they were first
+// fetched, so undated items still age out under itemMaxAge instead of
+// looking new forever
+func (s *Scheduler) dateUndatedItems(ctx context.Context, result *FetchResult) {
+	var guids []string
+	for _, item := range result.Items {
+		if item.Published.IsZero() {
+			guids = append(guids, item.GUID)
+		}
+	}
+	if len(guids) == 0 {
+		return
+	}
+
+	dates, err := s.store.FirstSeenDates(ctx, result.FeedID, guids, s.now().UTC())
+	if err != nil {
+		s.logger.Warn("failed to record undated items", "feed_id", result.FeedID, "err", err)
+		return
+	}
+
+	items := make([]FetchedItem, len(result.Items))
+	for i, item := range result.Items {
+		if item.Published.IsZero() {
+			item.Published = dates[item.GUID]
+		}
+		items[i] = item
+	}
+	result.Items = items
+}
+
+func (s *Scheduler) sendDigestAndMarkSeen(ctx context.Context, cfg *store.Config, opts *config.ParsedConfig, feedGroups []email.FeedGroup, totalNew int, results []*FetchResult) (email.SendReceipt, error) {
 	s.logger.Debug("sendDigestAndMarkSeen: start", "totalNew", totalNew)
+
+	// Generate tracking token before rendering (needed for click and keep-alive URLs)
+	trackingToken, err := s.store.GenerateTrackingToken()
+	if err != nil {
+		s.logger.Warn("failed to generate tracking token", "err", err)
+		trackingToken = ""
+	}
+	s.logger.Debug("sendDigestAndMarkSeen: generated tracking token")
+
 	digestData := &email.DigestData{
 		ConfigName: cfg.Filename,
 		TotalItems: totalNew,
-		FeedGroups: feedGroups,
+		FeedGroups: s.trackClicks(feedGroups, trackingToken),
+		Theme:      opts.Theme,
+		Style:      strings.Join(opts.Style, "\n"),
 	}
 
 	inline := cfg.InlineContent
@@ -363,7 +868,7 @@ func (s *Scheduler) sendDigestAndMarkSeen(ctx context.Context, cfg *store.Config
 	s.logger.Debug("sendDigestAndMarkSeen: rendering digest")
 	htmlBody, textBody, err := email.RenderDigest(digestData, inline, daysUntilExpiry, showUrgentBanner, showWarningBanner)
 	if err != nil {
-		return fmt.Errorf("render digest: %w", err)
+		return email.SendReceipt{}, fmt.Errorf("render digest: %w", err)
 	}
 	s.logger.Debug("sendDigestAndMarkSeen: digest rendered")
 
@@ -383,49 +888,59 @@ func (s *Scheduler) sendDigestAndMarkSeen(ctx context.Context, cfg *store.Config
 	}
 	s.logger.Debug("sendDigestAndMarkSeen: got dashboard URL")
 
-	// Rate limit email sending per user
-	if !s.rateLimiter.Allow(fmt.Sprintf("email:%d", cfg.UserID)) {
-		return fmt.Errorf("rate limit exceeded for email sending")
+	// Rate limit email sending per user, honoring any per-user override
+	perMinute := emailsPerMinutePerUser
+	if user != nil && user.EmailRateLimit > 0 {
+		perMinute = user.EmailRateLimit
+	}
+	if !s.rateLimiter.AllowRate(fmt.Sprintf("email:%d", cfg.UserID), float64(perMinute)/60.0, emailRateBurst) {
+		s.logger.Warn("digest held by email rate limit", "config_id", cfg.ID, "user_id", cfg.UserID, "per_minute", perMinute, "items", totalNew)
+		if s.ticks != nil {
+			s.ticks.RecordRateLimited()
+		}
+		return email.SendReceipt{}, ErrEmailRateLimited
 	}
 	s.logger.Debug("sendDigestAndMarkSeen: rate limit ok")
 
 	// Begin transaction to mark items seen
 	tx, err := s.store.BeginTx(ctx)
 	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
+		return email.SendReceipt{}, fmt.Errorf("begin transaction: %w", err)
 	}
 	defer func() { _ = tx.Rollback() }()
 	s.logger.Debug("sendDigestAndMarkSeen: transaction started")
 
-	// Mark items seen BEFORE sending email
+	// Record email send with tracking (within transaction)
+	subject := digestSubject(opts.Thread, s.now().UTC())
+	s.logger.Debug("sendDigestAndMarkSeen: recording email send")
+	bodyHash, bodyText := s.auditBody(textBody)
+	sendID, err := s.store.RecordEmailSendTx(tx, cfg.ID, cfg.Email, subject, trackingToken, bodyHash, bodyText)
+	if err != nil {
+		s.logger.Warn("failed to record email send", "err", err)
+	}
+	s.logger.Debug("sendDigestAndMarkSeen: recorded email send")
+
+	// Mark items seen BEFORE sending email, tying the ones in the digest to
+	// the send they went out in
+	delivered := deliveredGUIDs(feedGroups)
 	for _, result := range results {
 		if result.Error != nil {
 			continue
 		}
 		for _, item := range result.Items {
-			if err := s.store.MarkItemSeenTx(ctx, tx, result.FeedID, item.GUID, item.Title, item.Link); err != nil {
+			var err error
+			if delivered[result.FeedURL][item.GUID] {
+				err = s.store.MarkItemDeliveredTx(ctx, tx, result.FeedID, sendID, item.GUID, item.Title, item.Link)
+			} else {
+				err = s.store.MarkItemSeenTx(ctx, tx, result.FeedID, item.GUID, item.Title, item.Link)
+			}
+			if err != nil {
 				s.logger.Warn("failed to mark item seen", "err", err)
 			}
 		}
 	}
 	s.logger.Debug("sendDigestAndMarkSeen: items marked seen")
 
-	// Generate tracking token BEFORE recording (needed for keep-alive URL)
-	trackingToken, err := s.store.GenerateTrackingToken()
-	if err != nil {
-		s.logger.Warn("failed to generate tracking token", "err", err)
-		trackingToken = ""
-	}
-	s.logger.Debug("sendDigestAndMarkSeen: generated tracking token")
-
-	// Record email send with tracking (within transaction)
-	subject := "feed digest"
-	s.logger.Debug("sendDigestAndMarkSeen: recording email send")
-	if err := s.store.RecordEmailSendTx(tx, cfg.ID, cfg.Email, subject, trackingToken); err != nil {
-		s.logger.Warn("failed to record email send", "err", err)
-	}
-	s.logger.Debug("sendDigestAndMarkSeen: recorded email send")
-
 	// Build keep-alive URL
 	keepAliveURL := ""
 	if trackingToken != "" {
@@ -434,23 +949,418 @@ func (s *Scheduler) sendDigestAndMarkSeen(ctx context.Context, cfg *store.Config
 
 	// Send email - if this fails, transaction will rollback
 	s.logger.Debug("sendDigestAndMarkSeen: calling mailer.Send", "to", cfg.Email)
-	if err := s.mailer.Send(cfg.Email, subject, htmlBody, textBody, unsubToken, dashboardURL, keepAliveURL); err != nil {
+	meta := digestMeta(cfg, opts, len(feedGroups))
+	receipt, err := s.send(cfg.Email, subject, htmlBody, textBody, unsubToken, dashboardURL, keepAliveURL, strings.Join(opts.Footer, "\n"), meta)
+	if err != nil {
 		s.logger.Error("sendDigestAndMarkSeen: mailer.Send failed", "err", err)
-		return fmt.Errorf("send email: %w", err)
+		return email.SendReceipt{}, fmt.Errorf("send email: %w", err)
 	}
 	s.logger.Debug("sendDigestAndMarkSeen: mailer.Send returned successfully")
 
 	// Commit transaction only after successful email send
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit transaction: %w", err)
+		return email.SendReceipt{}, fmt.Errorf("commit transaction: %w", err)
 	}
 
-	return nil
+	s.notifyDigestWebhook(cfg, feedGroups, totalNew)
+
+	return receipt, nil
+}
+
+// deliveredGUIDs indexes the items in a digest's feed groups by feed URL
+func deliveredGUIDs(feedGroups []email.FeedGroup) map[string]map[string]bool {
+	delivered := make(map[string]map[string]bool, len(feedGroups))
+	for _, group := range feedGroups {
+		if delivered[group.FeedURL] == nil {
+			delivered[group.FeedURL] = make(map[string]bool, len(group.Items))
+		}
+		for _, item := range group.Items {
+			delivered[group.FeedURL][item.GUID] = true
+		}
+	}
+	return delivered
+}
+
+// auditBody returns what the audit mode keeps of a sent text body: its
+// SHA-256, and with full auditing the body itself
+func (s *Scheduler) auditBody(textBody string) (hash, text string) {
+	switch s.audit {
+	case config.AuditEmailHash:
+		sum := sha256.Sum256([]byte(textBody))
+		return hex.EncodeToString(sum[:]), ""
+	case config.AuditEmailFull:
+		sum := sha256.Sum256([]byte(textBody))
+		return hex.EncodeToString(sum[:]), textBody
+	default:
+		return "", ""
+	}
+}
+
+// digestMeta describes a digest of feedCount feeds for the mailer's headers
+func digestMeta(cfg *store.Config, opts *config.ParsedConfig, feedCount int) email.DigestMeta {
+	meta := email.DigestMeta{ConfigName: cfg.Filename, FeedCount: feedCount, AttachHTML: opts.AttachHTML}
+	if opts.Thread {
+		meta.ThreadID = fmt.Sprintf("herald.config.%d", cfg.ID)
+	}
+	return meta
+}
+
+// PreviewHeaders returns the header block of a digest for cfg exactly as it
+// would be sent now, DKIM signature included, without sending anything. The
+// feed count header assumes every enabled feed has new items.
+func (s *Scheduler) PreviewHeaders(ctx context.Context, cfg *store.Config) (string, error) {
+	if s.mailer == nil {
+		return "", errors.New("email is not configured")
+	}
+	opts := s.configOptions(cfg)
+
+	feeds, err := s.store.GetFeedsByConfig(ctx, cfg.ID)
+	if err != nil {
+		return "", fmt.Errorf("get feeds: %w", err)
+	}
+	unsubToken, err := s.store.GetOrCreateUnsubscribeToken(ctx, cfg.ID)
+	if err != nil {
+		return "", fmt.Errorf("get unsubscribe token: %w", err)
+	}
+	user, err := s.store.GetUserByID(ctx, cfg.UserID)
+	if err != nil {
+		return "", fmt.Errorf("get user: %w", err)
+	}
+	dashboardURL := s.originURL + "/" + user.PubkeyFP
+
+	htmlBody, textBody, err := email.RenderDigest(&email.DigestData{ConfigName: cfg.Filename, Theme: opts.Theme, Style: strings.Join(opts.Style, "\n")}, false, 0, false, false)
+	if err != nil {
+		return "", fmt.Errorf("render digest: %w", err)
+	}
+
+	subject := digestSubject(opts.Thread, s.now().UTC())
+	msg, err := s.mailer.BuildMessage(cfg.Email, subject, htmlBody, textBody, unsubToken, dashboardURL, "", strings.Join(opts.Footer, "\n"), digestMeta(cfg, opts, len(feeds)))
+	if err != nil {
+		return "", err
+	}
+	return email.HeaderBlock(msg), nil
+}
+
+// trackClicks returns a copy of feedGroups whose item links go through the
+// click redirect for the digest sent with token
+func (s *Scheduler) trackClicks(feedGroups []email.FeedGroup, token string) []email.FeedGroup {
+	if token == "" || s.originURL == "" {
+		return feedGroups
+	}
+
+	tracked := make([]email.FeedGroup, len(feedGroups))
+	for i, group := range feedGroups {
+		items := make([]email.FeedItem, len(group.Items))
+		for j, item := range group.Items {
+			if item.Link != "" && item.GUID != "" {
+				item.Link = s.originURL + "/r/" + token + "/" + store.ItemHash(item.GUID)
+			}
+			items[j] = item
+		}
+		group.Items = items
+		tracked[i] = group
+	}
+	return tracked
+}
+
+// digestSubject keeps the subject constant for threaded configs and dates it
+// otherwise, so each digest starts its own thread
+func digestSubject(thread bool, now time.Time) string {
+	if thread {
+		return "feed digest"
+	}
+	return "feed digest for " + now.Format("Jan 2, 2006")
+}
+
+// recordFeedResults stores fetch metadata and the last error for each feed.
+// With held set, new conditional request headers are not stored. Feeds whose
+// fetch was skipped for the minimum fetch interval are left untouched.
+func (s *Scheduler) recordFeedResults(ctx context.Context, results []*FetchResult, held bool) {
+	for _, result := range results {
+		var err error
+		switch {
+		case result.skipped:
+			continue
+		case result.Error != nil:
+			err = s.store.SetFeedError(ctx, result.FeedID, result.Error.Error())
+		case !held && (result.ETag != "" || result.LastModified != ""):
+			err = s.store.UpdateFeedFetched(ctx, result.FeedID, result.ETag, result.LastModified)
+		default:
+			err = s.store.TouchFeedFetched(ctx, result.FeedID)
+		}
+		if err != nil {
+			s.logger.Warn("failed to update feed fetched", "feed_id", result.FeedID, "err", err)
+		}
+	}
+}
+
+// shouldHold reports whether new items should wait for the config's min_send
+// threshold. Items are never held longer than max_hold since the last digest
+// (or since the config was created) so low-volume feeds still get delivered.
+func (s *Scheduler) shouldHold(cfg *store.Config, opts *config.ParsedConfig, totalNew int, now time.Time) bool {
+	if opts.MinSend <= 1 || totalNew >= opts.MinSend {
+		return false
+	}
+
+	maxHold := opts.MaxHold
+	if maxHold <= 0 {
+		maxHold = defaultMaxHold
+	}
+
+	if now.Sub(cfg.CreatedAt) < maxHold {
+		return true
+	}
+
+	sent, err := s.store.HasSentSince(cfg.ID, now.Add(-maxHold))
+	if err != nil {
+		s.logger.Warn("failed to check recent sends", "config_id", cfg.ID, "err", err)
+		return false
+	}
+	return sent
+}
+
+// failedFeeds returns the feeds whose most recent fetch failed
+func failedFeeds(feeds []*store.Feed) []*store.Feed {
+	var failed []*store.Feed
+	for _, feed := range feeds {
+		if feed.LastError.Valid {
+			failed = append(failed, feed)
+		}
+	}
+	return failed
+}
+
+// nextRunAfterFailures returns the next run and retry attempt after a run.
+// With retry_failed set and some feeds failing, the config runs again after
+// retryFailedDelay, up to maxFailedRetries times, unless the cron run is sooner.
+func (s *Scheduler) nextRunAfterFailures(cfg *store.Config, opts *config.ParsedConfig, results []*FetchResult, now time.Time) (time.Time, int, error) {
+	nextRun, err := cfg.NextRunAfter(now)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Error != nil {
+			failed++
+		}
+	}
+	if failed == 0 || cfg.RetryAttempt >= maxFailedRetries || !opts.RetryFailed {
+		return nextRun, 0, nil
+	}
+
+	retryAt := now.Add(retryFailedDelay)
+	if !retryAt.Before(nextRun) {
+		return nextRun, 0, nil
+	}
+	return retryAt, cfg.RetryAttempt + 1, nil
+}
+
+// adjustAdaptiveSchedules slows down adaptive configs whose digests are
+// rarely opened and restores them once opens pick up again. Open rates are
+// judged on the latest sends rather than a time window, so a config that was
+// slowed to a few sends a month can still earn its cadence back.
+func (s *Scheduler) adjustAdaptiveSchedules(ctx context.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("panic during adaptive schedule check", "panic", r)
+		}
+	}()
+
+	configs, err := s.store.ListActiveConfigs(ctx)
+	if err != nil {
+		s.logger.Error("failed to list active configs", "err", err)
+		return
+	}
+
+	for _, cfg := range configs {
+		adaptive := s.configOptions(cfg).Adaptive
+		if !adaptive && cfg.AdaptiveMultiplier <= 1 {
+			continue
+		}
+
+		multiplier := 1
+		rate := 0.0
+		if adaptive {
+			sends, opens, err := s.store.GetRecentEngagement(ctx, cfg.ID, adaptiveRecentSends)
+			if err != nil {
+				s.logger.Warn("failed to get engagement", "config_id", cfg.ID, "err", err)
+				continue
+			}
+			if sends < adaptiveMinSends {
+				continue
+			}
+			rate = float64(opens) / float64(sends)
+			multiplier = nextAdaptiveMultiplier(cfg.AdaptiveMultiplier, rate)
+		}
+		if multiplier == cfg.AdaptiveMultiplier {
+			continue
+		}
+
+		cfg.AdaptiveMultiplier = multiplier
+		nextRun, err := cfg.NextRunAfter(s.now().UTC())
+		if err != nil {
+			s.logger.Warn("failed to calculate next run", "config_id", cfg.ID, "err", err)
+			continue
+		}
+		if err := s.store.SetAdaptiveMultiplier(ctx, cfg.ID, multiplier, nextRun); err != nil {
+			s.logger.Error("failed to set adaptive multiplier", "config_id", cfg.ID, "err", err)
+			continue
+		}
+
+		s.logger.Info("adjusted adaptive schedule", "config_id", cfg.ID, "multiplier", multiplier, "open_rate", rate)
+		msg := "Adaptive schedule restored to every run"
+		if multiplier > 1 {
+			msg = fmt.Sprintf("Adaptive schedule: sending every %d runs (%.0f%% opened)", multiplier, rate*100)
+		}
+		_ = s.store.AddLog(ctx, cfg.ID, "info", msg)
+	}
+}
+
+// nextAdaptiveMultiplier doubles the multiplier while the open rate is low,
+// up to maxAdaptiveMultiplier, and resets it once the rate recovers.
+func nextAdaptiveMultiplier(current int, openRate float64) int {
+	if current < 1 {
+		current = 1
+	}
+	switch {
+	case openRate >= adaptiveRestoreRate:
+		return 1
+	case openRate < adaptiveLowOpenRate:
+		return min(current*2, maxAdaptiveMultiplier)
+	default:
+		return current
+	}
+}
+
+// languageSet builds a lookup of the lang directive's codes; nil accepts all
+func languageSet(codes []string) map[string]bool {
+	if len(codes) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		set[code] = true
+	}
+	return set
+}
+
+// acceptsLanguage reports whether an item passes the lang filter. Items whose
+// language can't be detected confidently are always accepted.
+func acceptsLanguage(langs map[string]bool, item FetchedItem) bool {
+	if langs == nil {
+		return true
+	}
+	code := lang.Detect(item.Title + "\n" + item.Content)
+	return code == "" || langs[code]
+}
+
+// quietUntil reports whether now is inside the config's quiet hours and, if
+// so, when the window ends.
+func (s *Scheduler) quietUntil(cfg *store.Config, opts *config.ParsedConfig, now time.Time) (time.Time, bool) {
+	if opts.QuietHours == "" {
+		return time.Time{}, false
+	}
+
+	quiet, err := config.ParseQuietHours(opts.QuietHours)
+	if err != nil {
+		s.logger.Warn("invalid stored quiet_hours", "config_id", cfg.ID, "err", err)
+		return time.Time{}, false
+	}
+	if !quiet.Contains(now) {
+		return time.Time{}, false
+	}
+	return quiet.EndAfter(now), true
+}
+
+// skippedDay reports whether t falls on a day ruled out by the skip_weekends
+// or skip_dates directives. Days are in UTC like cron.
+func skippedDay(opts *config.ParsedConfig, t time.Time) bool {
+	t = t.UTC()
+	if opts.SkipWeekends && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+		return true
+	}
+	day := t.Format(time.DateOnly)
+	for _, date := range opts.SkipDates {
+		if date == day {
+			return true
+		}
+	}
+	return false
+}
+
+// nextUnskippedRun returns the first cron tick after now that doesn't fall on
+// a skipped day
+func nextUnskippedRun(cfg *store.Config, opts *config.ParsedConfig, now time.Time) (time.Time, error) {
+	t := now.UTC()
+	for i := 0; i < maxSkipSearchDays; i++ {
+		next, err := cfg.NextCronTick(t)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if !skippedDay(opts, next) {
+			return next, nil
+		}
+		y, m, d := next.UTC().Date()
+		t = time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Time{}, fmt.Errorf("no run within %d days", maxSkipSearchDays)
+}
+
+// configOptions parses the stored config text for directives that don't have
+// their own column. Configs were validated on upload, so a parse failure only
+// falls back to defaults.
+func (s *Scheduler) configOptions(cfg *store.Config) *config.ParsedConfig {
+	parsed, err := config.Parse(cfg.RawText)
+	if err != nil {
+		s.logger.Warn("failed to parse stored config", "config_id", cfg.ID, "err", err)
+		return &config.ParsedConfig{Digest: cfg.Digest, Inline: cfg.InlineContent, AutoDeactivate: true}
+	}
+	return parsed
+}
+
+// trimSeenItems enforces the per-feed seen item cap after items were marked
+// seen. Items still present in the fetched feed are never trimmed, however
+// old, so a pinned item isn't forgotten and sent again.
+func (s *Scheduler) trimSeenItems(ctx context.Context, results []*FetchResult) {
+	if s.maxSeen <= 0 {
+		return
+	}
+
+	for _, result := range results {
+		// Without a body there's no telling what the feed still holds
+		if result.Error != nil || result.NotModified || result.skipped {
+			continue
+		}
+		current := make([]string, len(result.Items))
+		for i, item := range result.Items {
+			current[i] = item.GUID
+		}
+		deleted, err := s.store.TrimSeenItems(ctx, result.FeedID, s.maxSeen, current)
+		if err != nil {
+			s.logger.Warn("failed to trim seen items", "feed_id", result.FeedID, "err", err)
+			continue
+		}
+		if deleted > 0 {
+			s.logger.Debug("trimmed seen items", "feed_id", result.FeedID, "deleted", deleted)
+		}
+	}
 }
 
 func (s *Scheduler) processConfig(ctx context.Context, cfg *store.Config) error {
+	if !s.running.tryLock(cfg.ID) {
+		s.logger.Info("config already running, skipping", "config_id", cfg.ID)
+		return nil
+	}
+	defer s.running.unlock(cfg.ID)
+
 	s.logger.Info("processing config", "config_id", cfg.ID, "filename", cfg.Filename)
 
+	opts := s.configOptions(cfg)
+	if target := s.mergeTarget(ctx, cfg, opts); target != nil {
+		return s.deferToMergeTarget(ctx, cfg, target)
+	}
+
 	feeds, err := s.store.GetFeedsByConfig(ctx, cfg.ID)
 	if err != nil {
 		return fmt.Errorf("get feeds: %w", err)
@@ -461,33 +1371,102 @@ func (s *Scheduler) processConfig(ctx context.Context, cfg *store.Config) error
 		return nil
 	}
 
-	results := FetchFeeds(ctx, feeds, nil) // No progress tracking for background jobs
+	// A retry only re-fetches the feeds that failed last time; the others
+	// already delivered their items this cycle
+	if cfg.RetryAttempt > 0 {
+		if failed := failedFeeds(feeds); len(failed) > 0 {
+			feeds = failed
+		}
+	}
 
-	feedGroups, totalNew, err := s.collectNewItems(ctx, results)
+	// Inside quiet hours, leave items unseen and run again when the window
+	// ends. Nothing ran, so last_run keeps pointing at the last real run.
+	if until, quiet := s.quietUntil(cfg, opts, s.now()); quiet {
+		if err := s.store.UpdateNextRun(ctx, cfg.ID, &until); err != nil {
+			return fmt.Errorf("update next run: %w", err)
+		}
+		s.logger.Info("deferred by quiet hours", "config_id", cfg.ID, "until", until)
+		_ = s.store.AddLog(ctx, cfg.ID, "info", "Deferred by quiet hours until "+until.Format(time.RFC3339))
+		return nil
+	}
+
+	// On a skipped day, leave items unseen and run again on the next allowed
+	// day, without recording a run that never happened
+	if skippedDay(opts, s.now()) {
+		next, err := nextUnskippedRun(cfg, opts, s.now())
+		if err != nil {
+			s.logger.Warn("no run outside skipped days, running anyway", "config_id", cfg.ID, "err", err)
+		} else {
+			if err := s.store.UpdateNextRun(ctx, cfg.ID, &next); err != nil {
+				return fmt.Errorf("update next run: %w", err)
+			}
+			s.logger.Info("skipped day", "config_id", cfg.ID, "next_run", next)
+			_ = s.store.AddLog(ctx, cfg.ID, "info", "Skipped today, next run "+next.Format(time.RFC3339))
+			return nil
+		}
+	}
+
+	start := time.Now()
+	results := fetchFeedsWith(ctx, feeds, nil, s.fetchCache.fetch) // No progress tracking for background jobs
+	s.recordNotModified(results)
+	if opts.Favicons {
+		s.attachFavicons(ctx, feeds, results)
+	}
+	if opts.FeedDescriptions {
+		s.attachDescriptions(ctx, feeds, results)
+	}
+
+	feedGroups, totalNew, err := s.collectNewItems(ctx, cfg, opts, results)
 	if err != nil {
 		s.logger.Warn("failed to collect items", "config_id", cfg.ID, "err", err)
 	}
 
-	if totalNew > 0 {
-		if err := s.sendDigestAndMarkSeen(ctx, cfg, feedGroups, totalNew, results); err != nil {
+	// Configs merged into this one ride along in the same digest
+	allResults := results
+	sources := s.mergedSources(ctx, cfg, opts)
+	defer s.releaseMerged(sources)
+	if len(sources) > 0 {
+		merged, mergedNew, mergedResults := s.collectMerged(ctx, sources)
+		feedGroups = append(sectioned(feedGroups, cfg.Filename), merged...)
+		totalNew += mergedNew
+		allResults = append(append([]*FetchResult(nil), results...), mergedResults...)
+	}
+
+	held := totalNew > 0 && s.shouldHold(cfg, opts, totalNew, s.now())
+
+	switch {
+	case held:
+		s.logger.Info("holding items below min_send", "config_id", cfg.ID, "items", totalNew)
+	case totalNew > 0:
+		receipt, err := s.sendDigestAndMarkSeen(ctx, cfg, opts, feedGroups, totalNew, allResults)
+		if errors.Is(err, ErrEmailRateLimited) {
+			// Leave next_run, the items, and the feeds' conditional headers
+			// alone so the next tick fetches and sends the same digest
+			_ = s.store.AddLog(ctx, cfg.ID, "info", "Held by the email rate limit, retrying on the next tick")
+			return nil
+		}
+		if err != nil {
 			return fmt.Errorf("send digest: %w", err)
 		}
-		s.logger.Info("email sent", "to", cfg.Email, "items", totalNew)
-	} else {
+		s.logger.Info("email sent", "to", cfg.Email, "items", totalNew, "response", receipt.Response)
+		_ = s.store.AddLog(ctx, cfg.ID, "info", deliveryLogMessage(receipt, totalNew))
+		s.trimSeenItems(ctx, allResults)
+	default:
 		s.logger.Info("no new items", "config_id", cfg.ID)
 	}
 
-	// Update feed metadata
-	for _, result := range results {
-		if result.ETag != "" || result.LastModified != "" {
-			if err := s.store.UpdateFeedFetched(ctx, result.FeedID, result.ETag, result.LastModified); err != nil {
-				s.logger.Warn("failed to update feed fetched", "err", err)
-			}
-		}
+	// Update feed metadata. Held items are still unseen, so keep the old
+	// conditional headers to make sure the next fetch returns them again.
+	s.recordFeedResults(ctx, allResults, held)
+	s.finishMerged(ctx, cfg, sources, s.now().UTC(), totalNew > 0 && !held)
+
+	// A leftover multiplier only applies while the config still opts in
+	if cfg.AdaptiveMultiplier > 1 && !opts.Adaptive {
+		cfg.AdaptiveMultiplier = 1
 	}
 
-	now := time.Now().UTC()
-	nextRun, err := gronx.NextTickAfter(cfg.CronExpr, now, true)
+	now := s.now().UTC()
+	nextRun, attempt, err := s.nextRunAfterFailures(cfg, opts, results, now)
 	if err != nil {
 		return fmt.Errorf("calculate next run: %w", err)
 	}
@@ -495,8 +1474,17 @@ func (s *Scheduler) processConfig(ctx context.Context, cfg *store.Config) error
 	if err := s.store.UpdateLastRun(ctx, cfg.ID, now, nextRun); err != nil {
 		return fmt.Errorf("update last run: %w", err)
 	}
+	if attempt != cfg.RetryAttempt {
+		if err := s.store.SetRetryAttempt(ctx, cfg.ID, attempt); err != nil {
+			s.logger.Warn("failed to record retry attempt", "config_id", cfg.ID, "err", err)
+		}
+	}
 
-	_ = s.store.AddLog(ctx, cfg.ID, "info", fmt.Sprintf("Processed: %d new items, next run: %s", totalNew, nextRun.Format(time.RFC3339)))
+	stats := newRunStats(allResults, feedGroups, totalNew, start)
+	stats.Held = held
+	stats.Retrying = attempt > 0
+	s.logger.Info("config processed", "config_id", cfg.ID, "new_items", totalNew, "failed_feeds", stats.FailedFeeds, "duration", stats.Duration)
+	_ = s.store.AddLog(ctx, cfg.ID, "info", runLogMessage(stats, nextRun))
 
 	return nil
 }
@@ -0,0 +1,1021 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/kierank/herald/config"
+	"github.com/kierank/herald/email"
+	"github.com/kierank/herald/store"
+)
+
+// setupTestScheduler returns a scheduler built from cfg over an in-memory
+// store, along with the store and a user to own test configs
+func setupTestScheduler(t *testing.T, cfg Config) (*Scheduler, *store.DB, *store.User) {
+	t.Helper()
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	user, err := db.GetOrCreateUser(context.Background(), "test-fp", "test-pubkey")
+	if err != nil {
+		t.Fatalf("GetOrCreateUser failed: %v", err)
+	}
+	return NewScheduler(cfg, db, nil, log.New(io.Discard)), db, user
+}
+
+func TestNewRunStats(t *testing.T) {
+	results := []*FetchResult{
+		{FeedID: 1},
+		{FeedID: 2},
+		{FeedID: 3, Error: errors.New("boom")},
+	}
+	groups := []email.FeedGroup{
+		{FeedName: "Feed A", Items: make([]email.FeedItem, 8)},
+		{FeedName: "Feed B", Items: make([]email.FeedItem, 4)},
+	}
+
+	stats := newRunStats(results, groups, 12, time.Now().Add(-3*time.Second))
+
+	if stats.TotalFeeds != 3 || stats.FetchedFeeds != 2 || stats.FailedFeeds != 1 {
+		t.Errorf("unexpected feed counts: %+v", stats)
+	}
+	if stats.Duration < 3*time.Second {
+		t.Errorf("expected duration >= 3s, got %s", stats.Duration)
+	}
+	if got := stats.Breakdown(); got != "Feed A: 8, Feed B: 4" {
+		t.Errorf("unexpected breakdown: %q", got)
+	}
+
+	msg := runLogMessage(stats, time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC))
+	for _, want := range []string{"12 new items", "2/3 feeds", "(1 failed)", "Feed A: 8", "2026-01-01T08:00:00Z"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("log message %q missing %q", msg, want)
+		}
+	}
+}
+
+func TestShouldHold(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{})
+
+	ctx := context.Background()
+
+	raw := "=: email user@example.com\n=: cron 0 8 * * *\n=: min_send 3\n=: max_hold 1d\n=> https://example.com/feed.xml"
+	cfg, err := db.CreateConfig(ctx, user.ID, "hold.txt", "user@example.com", "0 8 * * *", true, false, raw, time.Now())
+	if err != nil {
+		t.Fatalf("CreateConfig failed: %v", err)
+	}
+
+	now := time.Now()
+	opts := s.configOptions(cfg)
+	if !s.shouldHold(cfg, opts, 2, now) {
+		t.Error("expected items below min_send to be held for a new config")
+	}
+	if s.shouldHold(cfg, opts, 3, now) {
+		t.Error("expected items at min_send to be sent")
+	}
+
+	// Past max_hold with no recent digest, held items go out anyway
+	cfg.CreatedAt = now.Add(-48 * time.Hour)
+	if s.shouldHold(cfg, opts, 1, now) {
+		t.Error("expected items to be released after max_hold")
+	}
+
+	if _, err := db.RecordEmailSend(cfg.ID, cfg.Email, "Subject", false); err != nil {
+		t.Fatalf("RecordEmailSend failed: %v", err)
+	}
+	if !s.shouldHold(cfg, opts, 1, now) {
+		t.Error("expected items to be held after a recent digest")
+	}
+	if s.shouldHold(cfg, opts, 1, now.Add(48*time.Hour)) {
+		t.Error("expected items to be released once max_hold has passed since the digest")
+	}
+}
+
+func TestProcessConfigDefersDuringQuietHours(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{})
+
+	ctx := context.Background()
+
+	// A run due at 23:00 falls inside an overnight window and waits for its end
+	now := time.Date(2026, 3, 4, 23, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	raw := "=: email user@example.com\n=: cron 0 8 * * *\n=: quiet_hours 22:00-07:00\n=> https://example.invalid/feed.xml"
+	cfg, _ := db.CreateConfig(ctx, user.ID, "quiet.txt", "user@example.com", "0 8 * * *", true, false, raw, now)
+	if _, err := db.CreateFeed(ctx, cfg.ID, "https://example.invalid/feed.xml", "", store.FeedOptions{}); err != nil {
+		t.Fatalf("CreateFeed failed: %v", err)
+	}
+
+	if err := s.processConfig(ctx, cfg); err != nil {
+		t.Fatalf("processConfig failed: %v", err)
+	}
+
+	updated, err := db.GetConfigByID(ctx, cfg.ID)
+	if err != nil {
+		t.Fatalf("GetConfigByID failed: %v", err)
+	}
+	want := time.Date(2026, 3, 5, 7, 0, 0, 0, time.UTC)
+	if !updated.NextRun.Valid || !updated.NextRun.Time.Equal(want) {
+		t.Errorf("expected next run deferred to %s, got %v", want, updated.NextRun)
+	}
+	if updated.LastRun.Valid {
+		t.Errorf("expected last run untouched by a deferral, got %v", updated.LastRun.Time)
+	}
+
+	feeds, _ := db.GetFeedsByConfig(ctx, cfg.ID)
+	if feeds[0].LastFetched.Valid {
+		t.Error("expected feeds not to be fetched during quiet hours")
+	}
+}
+
+type tickRecord struct {
+	duration           time.Duration
+	processed, overdue int
+	notModified        int
+	rateLimited        int
+}
+
+func (r *tickRecord) RecordTick(duration time.Duration, processed, overdue int) {
+	r.duration, r.processed, r.overdue = duration, processed, overdue
+}
+
+func (r *tickRecord) RecordNotModified(n int) {
+	r.notModified += n
+}
+
+func (r *tickRecord) RecordRateLimited() {
+	r.rateLimited++
+}
+
+func TestTickRecordsMetrics(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{})
+
+	ctx := context.Background()
+	rec := &tickRecord{processed: -1}
+	s.SetTickRecorder(rec)
+
+	s.tick(ctx)
+	if rec.processed != 0 || rec.overdue != 0 {
+		t.Errorf("expected empty tick, got %+v", rec)
+	}
+
+	// A config with no feeds is processed without touching next_run, so it
+	// stays overdue and shows up as backlog
+	_, _ = db.CreateConfig(ctx, user.ID, "empty.txt", "user@example.com", "0 8 * * *", true, false, "", time.Now().Add(-time.Hour))
+
+	s.tick(ctx)
+	if rec.processed != 1 {
+		t.Errorf("expected 1 processed config, got %d", rec.processed)
+	}
+	if rec.overdue != 1 {
+		t.Errorf("expected 1 overdue config, got %d", rec.overdue)
+	}
+}
+
+func TestCollectNewItemsLanguageFilter(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{})
+
+	ctx := context.Background()
+	raw := "=: email user@example.com\n=: cron 0 8 * * *\n=: lang en\n=> https://example.com/feed.xml"
+	cfg, _ := db.CreateConfig(ctx, user.ID, "lang.txt", "user@example.com", "0 8 * * *", true, false, raw, time.Now())
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", store.FeedOptions{})
+
+	results := []*FetchResult{{
+		FeedID:  feed.ID,
+		FeedURL: feed.URL,
+		Items: []FetchedItem{
+			{GUID: "en", Title: "English", Content: "The release is out and it has been a long wait for all of the users."},
+			{GUID: "es", Title: "Español", Content: "El equipo publicó una nueva versión del programa con muchas mejoras para los usuarios."},
+			{GUID: "unknown", Title: "Changelog v1.2"},
+		},
+	}}
+
+	groups, total, err := s.collectNewItems(ctx, cfg, s.configOptions(cfg), results)
+	if err != nil {
+		t.Fatalf("collectNewItems failed: %v", err)
+	}
+	if total != 2 || len(groups) != 1 {
+		t.Fatalf("expected 2 items in 1 group, got %d in %d", total, len(groups))
+	}
+	for _, item := range groups[0].Items {
+		if item.Title == "Español" {
+			t.Error("expected Spanish item to be filtered")
+		}
+	}
+
+	if seen, _ := db.IsItemSeen(ctx, feed.ID, "es"); !seen {
+		t.Error("expected filtered item to be marked seen")
+	}
+	if seen, _ := db.IsItemSeen(ctx, feed.ID, "en"); seen {
+		t.Error("expected kept item to stay unseen until sent")
+	}
+}
+
+func TestCollectNewItemsFirstRunWindow(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{FirstRunWindow: 48 * time.Hour})
+
+	ctx := context.Background()
+	cfg, _ := db.CreateConfig(ctx, user.ID, "new.txt", "user@example.com", "0 8 * * *", true, false, "", time.Now())
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", store.FeedOptions{})
+
+	now := time.Now().UTC()
+	fetch := func() []*FetchResult {
+		return []*FetchResult{{
+			FeedID:  feed.ID,
+			FeedURL: feed.URL,
+			Items: []FetchedItem{
+				{GUID: "recent", Title: "Recent", Published: now.Add(-time.Hour)},
+				{GUID: "backlog", Title: "Backlog", Published: now.Add(-10 * 24 * time.Hour)},
+			},
+		}}
+	}
+
+	groups, total, err := s.collectNewItems(ctx, cfg, s.configOptions(cfg), fetch())
+	if err != nil {
+		t.Fatalf("collectNewItems failed: %v", err)
+	}
+	if total != 1 || groups[0].Items[0].GUID != "recent" {
+		t.Fatalf("expected only the recent item on the first run, got %d", total)
+	}
+	if seen, _ := db.IsItemSeen(ctx, feed.ID, "backlog"); !seen {
+		t.Error("expected backlog item to be marked seen")
+	}
+
+	// Later runs aren't limited by the window
+	_, _ = db.DeleteSeenItemsByConfig(ctx, cfg.ID)
+	cfg.LastRun = sql.NullTime{Time: now, Valid: true}
+	if _, total, _ := s.collectNewItems(ctx, cfg, s.configOptions(cfg), fetch()); total != 2 {
+		t.Errorf("expected both items after the first run, got %d", total)
+	}
+}
+
+func TestNextAdaptiveMultiplier(t *testing.T) {
+	tests := []struct {
+		current  int
+		rate     float64
+		expected int
+	}{
+		{1, 0.0, 2},
+		{2, 0.05, 4},
+		{4, 0.0, 7},
+		{7, 0.0, 7},
+		{4, 0.2, 4},
+		{4, 0.5, 1},
+		{0, 0.5, 1},
+	}
+
+	for _, tt := range tests {
+		if got := nextAdaptiveMultiplier(tt.current, tt.rate); got != tt.expected {
+			t.Errorf("nextAdaptiveMultiplier(%d, %.2f) = %d, expected %d", tt.current, tt.rate, got, tt.expected)
+		}
+	}
+}
+
+func TestAdjustAdaptiveSchedules(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{})
+
+	ctx := context.Background()
+
+	raw := "=: email user@example.com\n=: cron 0 8 * * *\n=: adaptive true\n=> https://example.com/feed.xml"
+	adaptive, _ := db.CreateConfig(ctx, user.ID, "adaptive.txt", "user@example.com", "0 8 * * *", true, false, raw, time.Now())
+	plain, _ := db.CreateConfig(ctx, user.ID, "plain.txt", "user@example.com", "0 8 * * *", true, false, "=: cron 0 8 * * *", time.Now())
+	for i := 0; i < 4; i++ {
+		_, _ = db.RecordEmailSend(adaptive.ID, "user@example.com", "Digest", true)
+		_, _ = db.RecordEmailSend(plain.ID, "user@example.com", "Digest", true)
+	}
+
+	s.adjustAdaptiveSchedules(ctx)
+
+	got, _ := db.GetConfigByID(ctx, adaptive.ID)
+	if got.AdaptiveMultiplier != 2 {
+		t.Errorf("expected unopened adaptive config to slow down, got multiplier %d", got.AdaptiveMultiplier)
+	}
+	if !got.NextRun.Time.After(time.Now().Add(24 * time.Hour)) {
+		t.Errorf("expected next run to skip a tick, got %s", got.NextRun.Time)
+	}
+	if got, _ := db.GetConfigByID(ctx, plain.ID); got.AdaptiveMultiplier != 1 {
+		t.Errorf("expected non-adaptive config to keep its cadence, got %d", got.AdaptiveMultiplier)
+	}
+
+	// Opening digests restores the normal cadence, even when a slowed config
+	// has sent nothing recently
+	if _, err := db.Exec(`UPDATE email_sends SET opened = TRUE, opened_at = CURRENT_TIMESTAMP, sent_at = datetime('now', '-60 days') WHERE config_id = ?`, adaptive.ID); err != nil {
+		t.Fatalf("mark opened: %v", err)
+	}
+	s.adjustAdaptiveSchedules(ctx)
+	if got, _ := db.GetConfigByID(ctx, adaptive.ID); got.AdaptiveMultiplier != 1 {
+		t.Errorf("expected opens to restore cadence, got multiplier %d", got.AdaptiveMultiplier)
+	}
+}
+
+func TestCheckAndDeactivateInactiveConfigs(t *testing.T) {
+	_, db, user := setupTestScheduler(t, Config{})
+
+	ctx := context.Background()
+
+	idle, _ := db.CreateConfig(ctx, user.ID, "idle.txt", "user@example.com", "0 8 * * *", true, false, "=: cron 0 8 * * *", time.Now())
+	optOut, _ := db.CreateConfig(ctx, user.ID, "opt-out.txt", "user@example.com", "0 8 * * *", true, false, "=: cron 0 8 * * *\n=: auto_deactivate false", time.Now())
+	for _, cfg := range []*store.Config{idle, optOut} {
+		for i := 0; i < minSendsBeforeDeactivate; i++ {
+			_, _ = db.RecordEmailSend(cfg.ID, "user@example.com", "Digest", true)
+		}
+	}
+	if _, err := db.Exec(`UPDATE configs SET created_at = datetime('now', '-100 days')`); err != nil {
+		t.Fatalf("age configs: %v", err)
+	}
+
+	NewScheduler(Config{KeepInactive: true}, db, nil, log.New(io.Discard)).checkAndDeactivateInactiveConfigs(ctx)
+	if got, _ := db.GetConfigByID(ctx, idle.ID); !got.NextRun.Valid {
+		t.Error("expected KeepInactive to leave configs active")
+	}
+
+	NewScheduler(Config{}, db, nil, log.New(io.Discard)).checkAndDeactivateInactiveConfigs(ctx)
+	if got, _ := db.GetConfigByID(ctx, idle.ID); got.NextRun.Valid {
+		t.Error("expected unopened config to be deactivated")
+	}
+	if got, _ := db.GetConfigByID(ctx, optOut.ID); !got.NextRun.Valid {
+		t.Error("expected auto_deactivate false to keep config active")
+	}
+}
+
+func TestProcessConfigRetriesFailedFeeds(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{})
+
+	var goodHits, badHits atomic.Int32
+	var badUp atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/good":
+			goodHits.Add(1)
+		case "/bad":
+			badHits.Add(1)
+			if !badUp.Load() {
+				http.Error(w, "down", http.StatusBadGateway)
+				return
+			}
+		}
+		_, _ = w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>Empty</title></channel></rss>`))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	raw := "=: email user@example.com\n=: cron 0 8 * * *\n=: retry_failed true\n=> " + srv.URL + "/good\n=> " + srv.URL + "/bad"
+	cfg, _ := db.CreateConfig(ctx, user.ID, "retry.txt", "user@example.com", "0 8 * * *", true, false, raw, time.Now())
+	for _, path := range []string{"/good", "/bad"} {
+		if _, err := db.CreateFeed(ctx, cfg.ID, srv.URL+path, "", store.FeedOptions{}); err != nil {
+			t.Fatalf("CreateFeed failed: %v", err)
+		}
+	}
+
+	run := func() *store.Config {
+		t.Helper()
+		current, err := db.GetConfigByID(ctx, cfg.ID)
+		if err != nil {
+			t.Fatalf("GetConfigByID failed: %v", err)
+		}
+		if err := s.processConfig(ctx, current); err != nil {
+			t.Fatalf("processConfig failed: %v", err)
+		}
+		updated, _ := db.GetConfigByID(ctx, cfg.ID)
+		return updated
+	}
+
+	before := time.Now().UTC()
+	updated := run()
+	if updated.RetryAttempt != 1 {
+		t.Errorf("expected retry attempt 1, got %d", updated.RetryAttempt)
+	}
+	if d := updated.NextRun.Time.Sub(before); d < retryFailedDelay || d > retryFailedDelay+time.Minute {
+		t.Errorf("expected retry in %s, got next run %s", retryFailedDelay, updated.NextRun.Time)
+	}
+
+	// The retry only fetches the feed that failed
+	badUp.Store(true)
+	s.fetchCache = newFetchCache(s.fetcher.fetch)
+	updated = run()
+	if goodHits.Load() != 1 || badHits.Load() != 2 {
+		t.Errorf("expected only the failed feed to be retried, got good=%d bad=%d", goodHits.Load(), badHits.Load())
+	}
+	if updated.RetryAttempt != 0 {
+		t.Errorf("expected retry attempt reset, got %d", updated.RetryAttempt)
+	}
+	want, _ := updated.NextRunAfter(time.Now().UTC())
+	if !updated.NextRun.Time.Equal(want) {
+		t.Errorf("expected regular next run %s, got %s", want, updated.NextRun.Time)
+	}
+}
+
+func TestRunNowSettlesPendingRetry(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{})
+
+	var badUp atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bad" && !badUp.Load() {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>Empty</title></channel></rss>`))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	raw := "=: email user@example.com\n=: cron 0 8 * * *\n=: retry_failed true\n=> " + srv.URL + "/good\n=> " + srv.URL + "/bad"
+	cfg, _ := db.CreateConfig(ctx, user.ID, "retry.txt", "user@example.com", "0 8 * * *", true, false, raw, time.Now())
+	for _, path := range []string{"/good", "/bad"} {
+		if _, err := db.CreateFeed(ctx, cfg.ID, srv.URL+path, "", store.FeedOptions{}); err != nil {
+			t.Fatalf("CreateFeed failed: %v", err)
+		}
+	}
+
+	if err := s.processConfig(ctx, cfg); err != nil {
+		t.Fatalf("processConfig failed: %v", err)
+	}
+	if updated, _ := db.GetConfigByID(ctx, cfg.ID); updated.RetryAttempt != 1 {
+		t.Fatalf("expected a pending retry, got attempt %d", updated.RetryAttempt)
+	}
+
+	badUp.Store(true)
+	s.fetchCache = newFetchCache(s.fetcher.fetch)
+	stats, err := s.RunNow(ctx, cfg.ID, nil)
+	if err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+	if stats.Retrying {
+		t.Error("expected a clean manual run not to report a retry")
+	}
+	updated, _ := db.GetConfigByID(ctx, cfg.ID)
+	if updated.RetryAttempt != 0 {
+		t.Errorf("expected the manual run to clear the retry attempt, got %d", updated.RetryAttempt)
+	}
+	want, _ := updated.NextRunAfter(time.Now().UTC())
+	if !updated.NextRun.Time.Equal(want) {
+		t.Errorf("expected regular next run %s, got %s", want, updated.NextRun.Time)
+	}
+}
+
+func TestNextRunAfterFailuresGivesUp(t *testing.T) {
+	s := NewScheduler(Config{}, nil, nil, log.New(io.Discard))
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	failed := []*FetchResult{{Error: errors.New("down")}}
+	raw := "=: retry_failed true"
+
+	tests := []struct {
+		name        string
+		cron        string
+		raw         string
+		attempt     int
+		results     []*FetchResult
+		wantAttempt int
+		wantRetry   bool
+	}{
+		{"first failure", "0 8 * * *", raw, 0, failed, 1, true},
+		{"last retry", "0 8 * * *", raw, maxFailedRetries - 1, failed, maxFailedRetries, true},
+		{"retries exhausted", "0 8 * * *", raw, maxFailedRetries, failed, 0, false},
+		{"no failures", "0 8 * * *", raw, 1, []*FetchResult{{}}, 0, false},
+		{"not enabled", "0 8 * * *", "", 0, failed, 0, false},
+		{"cron sooner", "*/5 * * * *", raw, 0, failed, 0, false},
+	}
+	for _, tt := range tests {
+		cfg := &store.Config{CronExpr: tt.cron, RawText: tt.raw, RetryAttempt: tt.attempt}
+		next, attempt, err := s.nextRunAfterFailures(cfg, s.configOptions(cfg), tt.results, now)
+		if err != nil {
+			t.Fatalf("%s: nextRunAfterFailures failed: %v", tt.name, err)
+		}
+		if attempt != tt.wantAttempt {
+			t.Errorf("%s: expected attempt %d, got %d", tt.name, tt.wantAttempt, attempt)
+		}
+		if retried := next.Equal(now.Add(retryFailedDelay)); retried != tt.wantRetry {
+			t.Errorf("%s: expected retry %v, got next run %s", tt.name, tt.wantRetry, next)
+		}
+	}
+}
+
+func TestDigestSubject(t *testing.T) {
+	now := time.Date(2026, 1, 9, 8, 0, 0, 0, time.UTC)
+	if got := digestSubject(true, now); got != "feed digest" {
+		t.Errorf("expected constant threaded subject, got %q", got)
+	}
+	if got := digestSubject(false, now); got != "feed digest for Jan 9, 2026" {
+		t.Errorf("expected dated subject, got %q", got)
+	}
+	if digestSubject(false, now) == digestSubject(false, now.AddDate(0, 0, 1)) {
+		t.Error("expected subjects on different days to differ")
+	}
+}
+
+func TestAuditBody(t *testing.T) {
+	const body = "feed digest"
+	const sum = "305af05c12ae086fd2ffd522e9fd2efd55d78b3c6bd327d063eebda492776f04"
+
+	if hash, text := (&Scheduler{}).auditBody(body); hash != "" || text != "" {
+		t.Errorf("expected no audit record by default, got %q %q", hash, text)
+	}
+	hash, text := (&Scheduler{audit: config.AuditEmailHash}).auditBody(body)
+	if hash != sum || text != "" {
+		t.Errorf("expected only a hash, got %q %q", hash, text)
+	}
+	fullHash, text := (&Scheduler{audit: config.AuditEmailFull}).auditBody(body)
+	if fullHash != hash || text != body {
+		t.Errorf("expected hash and body, got %q %q", fullHash, text)
+	}
+}
+
+func TestTrackClicks(t *testing.T) {
+	s := &Scheduler{originURL: "https://herald.example.com"}
+	groups := []email.FeedGroup{{
+		FeedName: "Example",
+		Items: []email.FeedItem{
+			{GUID: "guid-a", Link: "https://example.com/a"},
+			{GUID: "guid-b"},
+		},
+	}}
+
+	tracked := s.trackClicks(groups, "tok")
+	want := "https://herald.example.com/r/tok/" + store.ItemHash("guid-a")
+	if got := tracked[0].Items[0].Link; got != want {
+		t.Errorf("tracked link = %q, want %q", got, want)
+	}
+	if got := tracked[0].Items[1].Link; got != "" {
+		t.Errorf("items without links should stay empty, got %q", got)
+	}
+	if groups[0].Items[0].Link != "https://example.com/a" {
+		t.Error("trackClicks should not modify the original groups")
+	}
+
+	if untracked := s.trackClicks(groups, ""); untracked[0].Items[0].Link != "https://example.com/a" {
+		t.Error("links should be unchanged without a tracking token")
+	}
+}
+
+func TestCollectNewItemsPrefersFeedName(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Replace(testRSS, "Test Feed", "RSS Feed", 1)))
+	}))
+	defer srv.Close()
+
+	s, db, user := setupTestScheduler(t, Config{})
+
+	ctx := context.Background()
+	cfg, _ := db.CreateConfig(ctx, user.ID, "named.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now())
+	named, _ := db.CreateFeed(ctx, cfg.ID, srv.URL+"/a", "My Blog", store.FeedOptions{})
+	blank, _ := db.CreateFeed(ctx, cfg.ID, srv.URL+"/b", "  ", store.FeedOptions{})
+	feeds := []*store.Feed{named, blank}
+
+	results := fetchFeedsWith(ctx, feeds, nil, s.fetchCache.fetch)
+	groups, _, err := s.collectNewItems(ctx, cfg, s.configOptions(cfg), results)
+	if err != nil {
+		t.Fatalf("collectNewItems failed: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+	if groups[0].FeedName != "My Blog" {
+		t.Errorf("expected user-set name in group header, got %q", groups[0].FeedName)
+	}
+	if groups[1].FeedName != "RSS Feed" {
+		t.Errorf("expected fetched title for a blank name, got %q", groups[1].FeedName)
+	}
+}
+
+func TestFeedDisplayName(t *testing.T) {
+	tests := []struct {
+		name  sql.NullString
+		title string
+		want  string
+	}{
+		{sql.NullString{String: "Mine", Valid: true}, "RSS Feed", "Mine"},
+		{sql.NullString{String: " ", Valid: true}, "RSS Feed", "RSS Feed"},
+		{sql.NullString{}, "  RSS Feed ", "RSS Feed"},
+		{sql.NullString{}, "", "https://example.com/feed.xml"},
+	}
+
+	for _, tt := range tests {
+		feed := &store.Feed{URL: "https://example.com/feed.xml", Name: tt.name}
+		if got := feedDisplayName(feed, tt.title); got != tt.want {
+			t.Errorf("feedDisplayName(%q, %q) = %q, want %q", tt.name.String, tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestCollectNewItemsDatesUndatedItems(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{})
+
+	ctx := context.Background()
+	cfg, _ := db.CreateConfig(ctx, user.ID, "undated.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now())
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", store.FeedOptions{})
+
+	// An undated item first fetched long ago has aged out
+	if _, err := db.FirstSeenDates(ctx, feed.ID, []string{"old"}, time.Now().Add(-2*itemMaxAge)); err != nil {
+		t.Fatalf("FirstSeenDates failed: %v", err)
+	}
+
+	results := []*FetchResult{{
+		FeedID:  feed.ID,
+		FeedURL: feed.URL,
+		Items:   []FetchedItem{{GUID: "old", Title: "Old"}, {GUID: "new", Title: "New"}},
+	}}
+
+	groups, total, err := s.collectNewItems(ctx, cfg, s.configOptions(cfg), results)
+	if err != nil {
+		t.Fatalf("collectNewItems failed: %v", err)
+	}
+	if total != 1 || groups[0].Items[0].Title != "New" {
+		t.Fatalf("expected only the newly seen undated item, got %d items", total)
+	}
+	if groups[0].Items[0].Published.IsZero() {
+		t.Error("expected undated item to get its first seen date")
+	}
+}
+
+func TestNextUnskippedRun(t *testing.T) {
+	cfg := &store.Config{CronExpr: "0 8 * * *"}
+	// Friday Dec 25, 2026 is a holiday and the weekend follows it
+	opts := &config.ParsedConfig{SkipWeekends: true, SkipDates: []string{"2026-12-25"}}
+	now := time.Date(2026, 12, 25, 8, 0, 0, 0, time.UTC)
+
+	if !skippedDay(opts, now) {
+		t.Fatal("expected the holiday to be skipped")
+	}
+	next, err := nextUnskippedRun(cfg, opts, now)
+	if err != nil {
+		t.Fatalf("nextUnskippedRun failed: %v", err)
+	}
+	if want := time.Date(2026, 12, 28, 8, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("expected next run %s, got %s", want, next)
+	}
+
+	// A weekend-only cron never runs on a weekday
+	weekendOnly := &store.Config{CronExpr: "0 8 * * 6"}
+	if _, err := nextUnskippedRun(weekendOnly, &config.ParsedConfig{SkipWeekends: true}, now); err == nil {
+		t.Error("expected an error when every run is skipped")
+	}
+}
+
+func TestProcessConfigSkipsDates(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{})
+
+	ctx := context.Background()
+
+	today := time.Date(2026, 3, 4, 8, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return today }
+	raw := "=: email user@example.com\n=: cron 0 8 * * *\n=: skip_dates 2026-03-04\n=> https://example.invalid/feed.xml"
+	cfg, _ := db.CreateConfig(ctx, user.ID, "skip.txt", "user@example.com", "0 8 * * *", true, false, raw, today)
+	if _, err := db.CreateFeed(ctx, cfg.ID, "https://example.invalid/feed.xml", "", store.FeedOptions{}); err != nil {
+		t.Fatalf("CreateFeed failed: %v", err)
+	}
+
+	if err := s.processConfig(ctx, cfg); err != nil {
+		t.Fatalf("processConfig failed: %v", err)
+	}
+
+	updated, err := db.GetConfigByID(ctx, cfg.ID)
+	if err != nil {
+		t.Fatalf("GetConfigByID failed: %v", err)
+	}
+	want := time.Date(2026, 3, 5, 8, 0, 0, 0, time.UTC)
+	if !updated.NextRun.Valid || !updated.NextRun.Time.Equal(want) {
+		t.Errorf("expected next run %s, got %v", want, updated.NextRun)
+	}
+	if updated.LastRun.Valid {
+		t.Errorf("expected last run untouched on a skipped day, got %v", updated.LastRun.Time)
+	}
+
+	feeds, _ := db.GetFeedsByConfig(ctx, cfg.ID)
+	if feeds[0].LastFetched.Valid {
+		t.Error("expected feeds not to be fetched on a skipped day")
+	}
+}
+
+func TestPreviewHeaders(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{OriginURL: "https://herald.example.com"})
+
+	ctx := context.Background()
+	raw := "=: email user@example.com\n=: cron 0 8 * * *\n=: thread true\n=> https://example.com/feed.xml"
+	cfg, _ := db.CreateConfig(ctx, user.ID, "feeds.txt", "user@example.com", "0 8 * * *", true, false, raw, time.Now())
+	_, _ = db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", store.FeedOptions{})
+
+	if _, err := s.PreviewHeaders(ctx, cfg); err == nil {
+		t.Error("expected an error without a mailer")
+	}
+
+	mailer, err := email.NewMailer(email.SMTPConfig{Host: "smtp.example.com", From: "herald@example.com", SendTimeout: time.Second}, "https://herald.example.com")
+	if err != nil {
+		t.Fatalf("NewMailer failed: %v", err)
+	}
+	s.mailer = mailer
+
+	headers, err := s.PreviewHeaders(ctx, cfg)
+	if err != nil {
+		t.Fatalf("PreviewHeaders failed: %v", err)
+	}
+	token, _ := db.GetOrCreateUnsubscribeToken(ctx, cfg.ID)
+	for _, want := range []string{
+		"To: user@example.com",
+		"Subject: feed digest",
+		"List-Archive: <https://herald.example.com/test-fp>",
+		"List-Unsubscribe: <https://herald.example.com/unsubscribe/" + token + ">",
+		"References: <herald.config.",
+		"X-Herald-Feed-Count: 1",
+	} {
+		if !strings.Contains(headers, want) {
+			t.Errorf("expected %q in headers:\n%s", want, headers)
+		}
+	}
+}
+
+func TestMergedConfigs(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	target, _ := db.CreateConfig(ctx, user.ID, "daily.txt", "user@example.com", "0 8 * * *", true, false,
+		"=: email user@example.com\n=: cron 0 8 * * *\n=> "+srv.URL+"/daily", now)
+	source, _ := db.CreateConfig(ctx, user.ID, "work.txt", "user@example.com", "0 9 * * *", true, false,
+		"=: email user@example.com\n=: cron 0 9 * * *\n=: merge_into daily.txt\n=> "+srv.URL+"/work", now)
+	if _, err := db.CreateFeed(ctx, source.ID, srv.URL+"/work", "Work", store.FeedOptions{}); err != nil {
+		t.Fatalf("CreateFeed failed: %v", err)
+	}
+
+	if got := s.mergeTarget(ctx, source, s.configOptions(source)); got == nil || got.ID != target.ID {
+		t.Fatalf("expected work.txt to merge into daily.txt, got %+v", got)
+	}
+	if got := s.mergeTarget(ctx, target, s.configOptions(target)); got != nil {
+		t.Errorf("expected daily.txt to send its own digest, got %+v", got)
+	}
+
+	// The merged config's own schedule leaves its feeds alone
+	if err := s.processConfig(ctx, source); err != nil {
+		t.Fatalf("processConfig failed: %v", err)
+	}
+	feeds, _ := db.GetFeedsByConfig(ctx, source.ID)
+	if feeds[0].LastFetched.Valid {
+		t.Error("expected the merged config not to fetch on its own schedule")
+	}
+	updated, _ := db.GetConfigByID(ctx, source.ID)
+	if !updated.NextRun.Valid || !updated.NextRun.Time.After(now) {
+		t.Errorf("expected next run to move forward, got %v", updated.NextRun)
+	}
+
+	sources := s.mergedSources(ctx, target, s.configOptions(target))
+	if len(sources) != 1 || sources[0].ID != source.ID {
+		t.Fatalf("expected work.txt as the only merged source, got %d", len(sources))
+	}
+	groups, total, results := s.collectMerged(ctx, sources)
+	if total != 2 || len(groups) != 1 || len(results) != 1 {
+		t.Fatalf("expected 2 items in 1 group, got %d items in %d groups", total, len(groups))
+	}
+	if groups[0].Section != "work.txt" || groups[0].FeedName != "Work" {
+		t.Errorf("expected Work group under work.txt, got %q under %q", groups[0].FeedName, groups[0].Section)
+	}
+
+	// The source is claimed until the target's run is done, so a manual run
+	// of it can't send the same items
+	if s.running.tryLock(source.ID) {
+		t.Error("expected the merged source to be locked during the target's run")
+	}
+	s.releaseMerged(sources)
+	if _, err := s.RunNow(ctx, source.ID, nil); !errors.Is(err, ErrMergedConfig) {
+		t.Errorf("expected ErrMergedConfig running a merged config, got %v", err)
+	}
+
+	// A source that is already running is left for the next digest
+	s.running.tryLock(source.ID)
+	if busy := s.mergedSources(ctx, target, s.configOptions(target)); len(busy) != 0 {
+		t.Errorf("expected a running source to be left out, got %d sources", len(busy))
+	}
+	s.running.unlock(source.ID)
+
+	// Without an active target, the config sends on its own again
+	if err := db.DeactivateConfig(ctx, target.ID); err != nil {
+		t.Fatalf("DeactivateConfig failed: %v", err)
+	}
+	if got := s.mergeTarget(ctx, source, s.configOptions(source)); got != nil {
+		t.Errorf("expected no merge target once daily.txt is inactive, got %+v", got)
+	}
+}
+
+func TestRunNowLogsDeliveryReceipt(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	cfg, _ := db.CreateConfig(ctx, user.ID, "feeds.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now().Add(time.Hour))
+	feed, err := db.CreateFeed(ctx, cfg.ID, srv.URL, "", store.FeedOptions{})
+	if err != nil {
+		t.Fatalf("CreateFeed failed: %v", err)
+	}
+
+	s.send = func(to, subject, htmlBody, textBody, unsubToken, dashboardURL, keepAliveURL, footer string, meta email.DigestMeta) (email.SendReceipt, error) {
+		return email.SendReceipt{To: to, Subject: subject, Server: "smtp.example.com", Response: "2.0.0 Ok: queued as ABC123"}, nil
+	}
+
+	stats, err := s.RunNow(ctx, cfg.ID, nil)
+	if err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+
+	if delivered, _ := db.GetDeliveredItems(ctx, feed.ID, 100); len(delivered) != stats.NewItems {
+		t.Errorf("expected %d delivered items, got %d", stats.NewItems, len(delivered))
+	}
+
+	logs, err := db.GetLogs(ctx, cfg.ID, 10)
+	if err != nil {
+		t.Fatalf("GetLogs failed: %v", err)
+	}
+	want := fmt.Sprintf("to user@example.com with %d items, accepted by smtp.example.com: 2.0.0 Ok: queued as ABC123", stats.NewItems)
+	for _, l := range logs {
+		if strings.Contains(l.Message, want) {
+			return
+		}
+	}
+	t.Errorf("expected a delivery log containing %q, got %+v", want, logs)
+}
+
+func TestRunNowConcurrentSendsOnce(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	cfg, _ := db.CreateConfig(ctx, user.ID, "feeds.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now().Add(time.Hour))
+	if _, err := db.CreateFeed(ctx, cfg.ID, srv.URL, "", store.FeedOptions{}); err != nil {
+		t.Fatalf("CreateFeed failed: %v", err)
+	}
+
+	var sends atomic.Int32
+	sending := make(chan struct{})
+	release := make(chan struct{})
+	s.send = func(to, subject, htmlBody, textBody, unsubToken, dashboardURL, keepAliveURL, footer string, meta email.DigestMeta) (email.SendReceipt, error) {
+		if sends.Add(1) == 1 {
+			close(sending)
+			<-release
+		}
+		return email.SendReceipt{}, nil
+	}
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := s.RunNow(ctx, cfg.ID, nil)
+		first <- err
+	}()
+	<-sending
+
+	// A second run while the first is still sending is turned away
+	if _, err := s.RunNow(ctx, cfg.ID, nil); !errors.Is(err, ErrConfigBusy) {
+		t.Errorf("expected ErrConfigBusy, got %v", err)
+	}
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+
+	if n := sends.Load(); n != 1 {
+		t.Errorf("expected exactly one send, got %d", n)
+	}
+}
+
+func TestDrainWaitsForInFlightRuns(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	cfg, _ := db.CreateConfig(ctx, user.ID, "feeds.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now().Add(time.Hour))
+	if _, err := db.CreateFeed(ctx, cfg.ID, srv.URL, "", store.FeedOptions{}); err != nil {
+		t.Fatalf("CreateFeed failed: %v", err)
+	}
+
+	sending := make(chan struct{})
+	release := make(chan struct{})
+	s.send = func(to, subject, htmlBody, textBody, unsubToken, dashboardURL, keepAliveURL, footer string, meta email.DigestMeta) (email.SendReceipt, error) {
+		close(sending)
+		<-release
+		return email.SendReceipt{}, nil
+	}
+
+	runDone := make(chan error, 1)
+	go func() {
+		_, err := s.RunNow(ctx, cfg.ID, nil)
+		runDone <- err
+	}()
+	<-sending
+
+	// A short timeout gives up on the stuck send
+	start := time.Now()
+	s.drain(20 * time.Millisecond)
+	if time.Since(start) > time.Second {
+		t.Error("expected drain to give up after its timeout")
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		s.drain(5 * time.Second)
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		t.Fatal("expected drain to wait for the in-flight send")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-runDone; err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+	select {
+	case <-drained:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected drain to return once the send finished")
+	}
+
+	// Once shutdown has started, new manual runs are refused
+	if _, err := s.RunNow(ctx, cfg.ID, nil); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("expected ErrShuttingDown after drain, got %v", err)
+	}
+}
+
+func TestRunContextOutlivesShutdownBriefly(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runCtx, stop := runContext(ctx)
+	defer stop()
+
+	cancel()
+	select {
+	case <-runCtx.Done():
+		t.Fatal("expected the run context to outlive its parent for the shutdown timeout")
+	case <-time.After(50 * time.Millisecond):
+	}
+	stop()
+	if runCtx.Err() == nil {
+		t.Error("expected stop to cancel the run context")
+	}
+}
+
+func TestProcessConfigRateLimitedRetriesNextTick(t *testing.T) {
+	s, db, user := setupTestScheduler(t, Config{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	rec := &tickRecord{}
+	s.SetTickRecorder(rec)
+
+	raw := "=: email user@example.com\n=: cron 0 8 * * *\n=> " + srv.URL
+	due := time.Now().Add(-time.Minute).UTC()
+	cfg, _ := db.CreateConfig(ctx, user.ID, "limited.txt", "user@example.com", "0 8 * * *", true, false, raw, due)
+	feed, _ := db.CreateFeed(ctx, cfg.ID, srv.URL, "", store.FeedOptions{})
+
+	// Use up the user's burst so the digest is held back
+	s.rateLimiter.AllowRate(fmt.Sprintf("email:%d", user.ID), float64(emailsPerMinutePerUser)/60.0, emailRateBurst)
+
+	if err := s.processConfig(ctx, cfg); err != nil {
+		t.Fatalf("expected a rate-limited run not to fail, got %v", err)
+	}
+	if rec.rateLimited != 1 {
+		t.Errorf("expected 1 rate-limited digest recorded, got %d", rec.rateLimited)
+	}
+
+	updated, _ := db.GetConfigByID(ctx, cfg.ID)
+	if !updated.NextRun.Valid || updated.NextRun.Time.After(time.Now()) {
+		t.Errorf("expected next_run to stay due, got %v", updated.NextRun)
+	}
+	if seen, _ := db.IsItemSeen(ctx, feed.ID, "1"); seen {
+		t.Error("expected items to stay unseen")
+	}
+	feeds, _ := db.GetFeedsByConfig(ctx, cfg.ID)
+	if feeds[0].ETag.Valid {
+		t.Errorf("expected the feed's ETag not to be stored, got %q", feeds[0].ETag.String)
+	}
+}
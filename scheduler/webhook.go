@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/kierank/herald/email"
+	"github.com/kierank/herald/store"
+)
+
+const webhookTimeout = 5 * time.Second
+
+// digestWebhookPayload is the JSON body POSTed to the digest webhook
+type digestWebhookPayload struct {
+	Config    string    `json:"config"`
+	Recipient string    `json:"recipient"`
+	Items     int       `json:"items"`
+	Feeds     []string  `json:"feeds"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// notifyDigestWebhook posts a summary of a sent digest to the instance webhook.
+// It runs in the background and only logs failures.
+func (s *Scheduler) notifyDigestWebhook(cfg *store.Config, feedGroups []email.FeedGroup, totalNew int) {
+	if s.webhookURL == "" {
+		return
+	}
+
+	feeds := make([]string, len(feedGroups))
+	for i, group := range feedGroups {
+		feeds[i] = group.FeedName
+	}
+
+	payload := digestWebhookPayload{
+		Config:    cfg.Filename,
+		Recipient: cfg.Email,
+		Items:     totalNew,
+		Feeds:     feeds,
+		SentAt:    time.Now().UTC(),
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		s.logger.Warn("failed to encode digest webhook payload", "err", err)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(body))
+		if err != nil {
+			s.logger.Warn("failed to build digest webhook request", "err", err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "Herald/1.0 (RSS Aggregator)")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			s.logger.Warn("digest webhook failed", "err", err)
+			return
+		}
+		_ = resp.Body.Close()
+
+		if resp.StatusCode >= 300 {
+			s.logger.Warn("digest webhook returned error status", "status", resp.StatusCode)
+		}
+	}()
+}
@@ -1,9 +1,14 @@
 package ssh
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/mail"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -11,10 +16,17 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/log"
 	"github.com/charmbracelet/ssh"
+	"github.com/kierank/herald/config"
+	"github.com/kierank/herald/ratelimit"
 	"github.com/kierank/herald/scheduler"
 	"github.com/kierank/herald/store"
 )
 
+const (
+	minBoostInterval = 10 * time.Minute
+	maxBoostDuration = 48 * time.Hour
+)
+
 var (
 	titleStyle = lipgloss.NewStyle().
 			Bold(true).
@@ -28,6 +40,11 @@ var (
 
 	errorStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("9"))
+
+	motdStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("12")).
+			Padding(0, 1)
 )
 
 // print writes to the session, ignoring errors (connection drops are expected)
@@ -45,23 +62,51 @@ func println(w io.Writer, args ...interface{}) {
 	_, _ = fmt.Fprintln(w, args...)
 }
 
-func HandleCommand(sess ssh.Session, user *store.User, st *store.DB, sched *scheduler.Scheduler, logger *log.Logger) {
+func HandleCommand(sess ssh.Session, user *store.User, st *store.DB, sched *scheduler.Scheduler, fetchLimiter *ratelimit.Limiter, policy CommandPolicy, logger *log.Logger) {
 	cmd := sess.Command()
 	if len(cmd) == 0 {
 		return
 	}
+	if KnownCommand(cmd[0]) && !policy.Permits(cmd[0]) {
+		println(sess, errorStyle.Render("Command disabled on this server: "+cmd[0]))
+		return
+	}
 
 	ctx := context.Background()
 
 	switch cmd[0] {
 	case "ls":
 		handleLs(ctx, sess, user, st)
+	case "feeds":
+		if len(cmd) < 2 {
+			println(sess, errorStyle.Render("Usage: feeds <filename>"))
+			return
+		}
+		handleFeeds(ctx, sess, user, st, cmd[1])
+	case "allfeeds":
+		handleAllFeeds(ctx, sess, user, st)
+	case "fetch":
+		if len(cmd) < 2 {
+			println(sess, errorStyle.Render("Usage: fetch <url>"))
+			return
+		}
+		if !fetchLimiter.Allow(fmt.Sprintf("fetch:%s", user.PubkeyFP)) {
+			println(sess, errorStyle.Render("Too many fetches, try again in a few seconds"))
+			return
+		}
+		handleFetch(ctx, sess, sched, cmd[1])
+	case "upload":
+		if len(cmd) < 2 {
+			println(sess, errorStyle.Render("Usage: upload <filename> < feeds.txt"))
+			return
+		}
+		handleUpload(ctx, sess, user, st, sched, logger, cmd[1])
 	case "cat":
 		if len(cmd) < 2 {
 			println(sess, errorStyle.Render("Usage: cat <filename>"))
 			return
 		}
-		handleCat(ctx, sess, user, st, cmd[1])
+		handleCat(ctx, sess, user, st, sched, cmd[1])
 	case "rm":
 		if len(cmd) < 2 {
 			println(sess, errorStyle.Render("Usage: rm <filename>"))
@@ -86,11 +131,73 @@ func HandleCommand(sess ssh.Session, user *store.User, st *store.DB, sched *sche
 			return
 		}
 		handleRun(ctx, sess, user, st, sched, cmd[1])
+	case "run-all":
+		handleRunAll(ctx, sess, user, st, sched)
 	case "logs":
 		handleLogs(ctx, sess, user, st)
+	case "search":
+		if len(cmd) < 2 {
+			println(sess, errorStyle.Render("Usage: search <query>"))
+			return
+		}
+		handleSearch(ctx, sess, user, st, strings.Join(cmd[1:], " "))
+	case "clear-logs":
+		if len(cmd) < 2 {
+			println(sess, errorStyle.Render("Usage: clear-logs <filename>"))
+			return
+		}
+		handleClearLogs(ctx, sess, user, st, cmd[1])
+	case "boost":
+		if len(cmd) == 3 && cmd[2] == "off" {
+			handleBoostOff(ctx, sess, user, st, cmd[1])
+			return
+		}
+		if len(cmd) < 4 {
+			println(sess, errorStyle.Render("Usage: boost <filename> <interval> <duration> | boost <filename> off"))
+			return
+		}
+		handleBoost(ctx, sess, user, st, cmd[1], cmd[2], cmd[3])
+	case "reset":
+		if len(cmd) < 2 {
+			println(sess, errorStyle.Render("Usage: reset <filename> --yes"))
+			return
+		}
+		confirmed := len(cmd) > 2 && cmd[2] == "--yes"
+		handleReset(ctx, sess, user, st, sched, cmd[1], confirmed)
+	case "headers":
+		if len(cmd) < 2 {
+			println(sess, errorStyle.Render("Usage: headers <filename>"))
+			return
+		}
+		handleHeaders(ctx, sess, user, st, sched, cmd[1])
+	case "export":
+		withSeen := len(cmd) > 1 && cmd[1] == "--seen"
+		handleExport(ctx, sess, user, st, withSeen)
+	case "import":
+		handleImport(ctx, sess, user, st, sched, logger)
+	case "set-email":
+		if len(cmd) < 3 {
+			println(sess, errorStyle.Render("Usage: set-email <filename> <address>"))
+			return
+		}
+		handleSetEmail(ctx, sess, user, st, logger, cmd[1], strings.Join(cmd[2:], " "))
+	case "rotate-token":
+		if len(cmd) < 2 {
+			println(sess, errorStyle.Render("Usage: rotate-token <filename>"))
+			return
+		}
+		handleRotateToken(ctx, sess, user, st, cmd[1])
+	case "defaults":
+		handleDefaults(ctx, sess, user, st, logger, cmd[1:])
+	case "explain-cron":
+		if len(cmd) < 2 {
+			println(sess, errorStyle.Render("Usage: explain-cron <expr>"))
+			return
+		}
+		handleExplainCron(sess, strings.Join(cmd[1:], " "))
 	default:
 		printf(sess, errorStyle.Render("Unknown command: %s\n"), cmd[0])
-		println(sess, "Available commands: ls, cat, rm, activate, deactivate, run, logs")
+		println(sess, "Available commands: ls, feeds, allfeeds, fetch, upload, cat, rm, activate, deactivate, run, run-all, logs, search, clear-logs, boost, reset, headers, set-email, rotate-token, defaults, explain-cron, export, import")
 	}
 }
 
@@ -125,10 +232,172 @@ func handleLs(ctx context.Context, sess ssh.Session, user *store.User, st *store
 			dimStyle.Render(fmt.Sprintf("%d feed(s)", feedCount)),
 			nextRunStr,
 		)
+
+		if cfg.Boosted(time.Now().UTC()) {
+			printf(sess, "  %-20s %s\n", "", dimStyle.Render(fmt.Sprintf("boosted: every %s until %s",
+				shortDuration(time.Duration(cfg.BoostInterval.Int64)*time.Second),
+				cfg.BoostUntil.Time.Format("Jan 02 15:04 MST"))))
+		}
 	}
 }
 
-func handleCat(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, filename string) {
+func handleFeeds(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, filename string) {
+	cfg, err := st.GetConfig(ctx, user.ID, filename)
+	if err != nil {
+		println(sess, errorStyle.Render("Config not found: "+filename))
+		return
+	}
+
+	feeds, err := st.GetAllFeedsByConfig(ctx, cfg.ID)
+	if err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+
+	if len(feeds) == 0 {
+		println(sess, dimStyle.Render("No feeds in "+filename))
+		return
+	}
+
+	println(sess, titleStyle.Render("Feeds in "+filename+":"))
+
+	for _, feed := range feeds {
+		name := feed.URL
+		if feed.Name.Valid {
+			name = feed.Name.String
+		}
+
+		status := successStyle.Render("✓")
+		switch {
+		case feed.Disabled:
+			status = dimStyle.Render("-")
+		case feed.LastError.Valid:
+			status = errorStyle.Render("✗")
+		case !feed.LastFetched.Valid:
+			status = dimStyle.Render("·")
+		}
+
+		fetched := "never"
+		if feed.LastFetched.Valid {
+			fetched = formatTimeAgo(feed.LastFetched.Time)
+		}
+
+		detail := "fetched: " + fetched
+		if feed.Disabled {
+			detail = "disabled"
+		}
+		printf(sess, "  %s %-24s %s\n", status, name, dimStyle.Render(detail))
+		if feed.Name.Valid {
+			printf(sess, "    %s\n", dimStyle.Render(feed.URL))
+		}
+		if feed.LastError.Valid {
+			printf(sess, "    %s\n", errorStyle.Render("error: "+feed.LastError.String))
+		}
+	}
+}
+
+// handleAllFeeds lists each distinct feed URL across the user's configs, so
+// feeds subscribed to in several configs stand out
+func handleAllFeeds(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB) {
+	feeds, err := st.ListUserFeeds(ctx, user.ID)
+	if err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+
+	if len(feeds) == 0 {
+		println(sess, dimStyle.Render("No feeds found. Upload a config with: scp feeds.txt <host>:"))
+		return
+	}
+
+	println(sess, titleStyle.Render("All feeds:"))
+
+	shared := 0
+	for _, feed := range feeds {
+		configs := "1 config"
+		if feed.Configs != 1 {
+			configs = fmt.Sprintf("%d configs", feed.Configs)
+			shared++
+		}
+
+		fetched := "never"
+		if feed.LastFetched.Valid {
+			fetched = formatTimeAgo(feed.LastFetched.Time)
+		}
+
+		printf(sess, "  %-10s %s\n", configs, feed.URL)
+		printf(sess, "  %-10s %s\n", "", dimStyle.Render("fetched: "+fetched))
+	}
+
+	if shared > 0 {
+		println(sess)
+		println(sess, dimStyle.Render(fmt.Sprintf("%d feed(s) appear in more than one config; see which with: feeds <file>", shared)))
+	}
+}
+
+func handleFetch(ctx context.Context, sess ssh.Session, sched *scheduler.Scheduler, rawURL string) {
+	result, err := sched.ProbeFeed(ctx, rawURL)
+	if result == nil {
+		println(sess, errorStyle.Render("Fetch failed: "+err.Error()))
+		return
+	}
+
+	println(sess, titleStyle.Render("Fetched "+rawURL))
+	printf(sess, "  %-14s %s\n", "Status:", result.Status)
+	printf(sess, "  %-14s %s\n", "Content-Type:", result.ContentType)
+	if err != nil {
+		println(sess, errorStyle.Render("  "+err.Error()))
+		return
+	}
+	if result.StatusCode != http.StatusOK {
+		return
+	}
+
+	printf(sess, "  %-14s %s\n", "Title:", result.Title)
+	printf(sess, "  %-14s %d\n", "Items:", result.ItemCount)
+
+	if len(result.Items) == 0 {
+		return
+	}
+	println(sess)
+	println(sess, titleStyle.Render("First items:"))
+	for _, item := range result.Items {
+		printf(sess, "  %s\n", item.Title)
+		printf(sess, "    %s\n", dimStyle.Render("guid: "+item.GUID))
+	}
+}
+
+func handleUpload(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, sched *scheduler.Scheduler, logger *log.Logger, filename string) {
+	if !strings.HasSuffix(filename, ".txt") {
+		println(sess, errorStyle.Render("Error: only .txt files are supported"))
+		return
+	}
+
+	content, err := io.ReadAll(io.LimitReader(sess, maxConfigSize+1))
+	if err != nil {
+		println(sess, errorStyle.Render("Error: failed to read config: "+err.Error()))
+		return
+	}
+	if len(content) > maxConfigSize {
+		println(sess, errorStyle.Render("Error: file too large (max 1MB)"))
+		return
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		println(sess, errorStyle.Render("Error: no config received on stdin"))
+		return
+	}
+
+	parsed, nextRun, err := saveConfig(ctx, st, sched, logger, user.ID, filename, content)
+	if err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+
+	logger.Info("config uploaded via stdin", "user_id", user.ID, "filename", filename, "feeds", len(parsed.Feeds), "next_run", nextRun)
+	println(sess, successStyle.Render(fmt.Sprintf("Uploaded %s: %d feed(s), next run %s", filename, len(parsed.Feeds), nextRun.Format("2006-01-02 15:04 MST"))))
+}
+
+func handleCat(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, sched *scheduler.Scheduler, filename string) {
 	cfg, err := st.GetConfig(ctx, user.ID, filename)
 	if err != nil {
 		println(sess, errorStyle.Render("Config not found: "+filename))
@@ -137,6 +406,47 @@ func handleCat(ctx context.Context, sess ssh.Session, user *store.User, st *stor
 
 	println(sess, titleStyle.Render("# "+filename))
 	println(sess, cfg.RawText)
+	println(sess, "")
+	printConfigSummary(sess, cfg, sched.FeedAccess().Policy())
+}
+
+// printConfigSummary checks a stored config against the current rules and
+// shows what it does, below the raw text so that stays copyable
+func printConfigSummary(sess ssh.Session, cfg *store.Config, policy config.FeedPolicy) {
+	parsed, err := config.Parse(cfg.RawText)
+	if err != nil {
+		println(sess, errorStyle.Render("✗ Invalid: "+err.Error()))
+		return
+	}
+	// A config relying on the account's default cron was saved with it
+	parsed.ApplyDefaults(config.Defaults{CronExpr: cfg.CronExpr})
+
+	disabled := 0
+	for _, feed := range parsed.Feeds {
+		if feed.Disabled {
+			disabled++
+		}
+	}
+	feeds := fmt.Sprintf("%d feed(s)", len(parsed.Feeds))
+	if disabled > 0 {
+		feeds += fmt.Sprintf(", %d disabled", disabled)
+	}
+
+	printf(sess, "%s %s\n", dimStyle.Render("email:"), parsed.Email)
+	printf(sess, "%s %s\n", dimStyle.Render("schedule:"), describeCron(parsed.CronExpr))
+	printf(sess, "%s %s\n", dimStyle.Render("feeds:"), feeds)
+	if !cfg.NextRun.Valid {
+		printf(sess, "%s %s\n", dimStyle.Render("status:"), "inactive")
+	}
+	if cfg.PublicSlug.Valid {
+		printf(sess, "%s %s\n", dimStyle.Render("archive:"), "/archive/"+cfg.PublicSlug.String)
+	}
+
+	if err := config.Validate(parsed, policy); err != nil {
+		println(sess, errorStyle.Render("✗ No longer valid: "+err.Error()))
+		return
+	}
+	println(sess, successStyle.Render("✓ Valid"))
 }
 
 func handleRm(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, filename string) {
@@ -176,12 +486,102 @@ func handleRun(ctx context.Context, sess ssh.Session, user *store.User, st *stor
 		return
 	}
 
-	// Get feed count for progress display
-	feeds, err := st.GetFeedsByConfig(ctx, cfg.ID)
+	stats, err := runWithProgress(ctx, sess, st, sched, cfg, "")
 	if err != nil {
 		println(sess, errorStyle.Render("Error: "+err.Error()))
 		return
 	}
+
+	// Display detailed stats
+	if stats != nil {
+		icon := successStyle.Render("✓")
+		if stats.FailedFeeds > 0 {
+			icon = dimStyle.Render("⚠")
+		}
+		summary := fmt.Sprintf("Fetched %d/%d feeds in %s, %d new item(s)",
+			stats.FetchedFeeds,
+			stats.TotalFeeds,
+			stats.Duration.Round(100*time.Millisecond),
+			stats.NewItems)
+		if len(stats.FeedCounts) > 0 {
+			summary += " (" + stats.Breakdown() + ")"
+		}
+		printf(sess, "%s %s\n", icon, summary)
+		if stats.FailedFeeds > 0 {
+			println(sess, dimStyle.Render(fmt.Sprintf("%d feed(s) failed to fetch", stats.FailedFeeds)))
+		}
+
+		if stats.NewItems == 0 {
+			println(sess, dimStyle.Render("No new items found."))
+		} else {
+			if stats.EmailSent {
+				println(sess, successStyle.Render(fmt.Sprintf("Sent %d new item(s) to %s", stats.NewItems, cfg.Email)))
+			} else {
+				println(sess, dimStyle.Render(fmt.Sprintf("Found %d new item(s) but did not send email", stats.NewItems)))
+			}
+		}
+	}
+}
+
+// handleRunAll runs each of the user's active configs in turn. Digests held
+// back by the email rate limit are reported and go out on the next run.
+func handleRunAll(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, sched *scheduler.Scheduler) {
+	configs, err := st.ListConfigs(ctx, user.ID)
+	if err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+	if len(configs) == 0 {
+		println(sess, dimStyle.Render("No configs found."))
+		return
+	}
+
+	var sent, limited, failed int
+	for i, cfg := range configs {
+		label := fmt.Sprintf("[%d/%d] %s: ", i+1, len(configs), cfg.Filename)
+
+		if !cfg.NextRun.Valid {
+			println(sess, dimStyle.Render(label+"skipped (inactive)"))
+			continue
+		}
+
+		stats, err := runWithProgress(ctx, sess, st, sched, cfg, label)
+		switch {
+		case errors.Is(err, scheduler.ErrEmailRateLimited):
+			limited++
+			newItems := 0
+			if stats != nil {
+				newItems = stats.NewItems
+			}
+			printf(sess, "%s %s\n", dimStyle.Render("⚠"), label+fmt.Sprintf("%d new item(s), rate-limited", newItems))
+		case err != nil:
+			failed++
+			println(sess, errorStyle.Render("✗ "+label+err.Error()))
+		case stats.EmailSent:
+			sent++
+			printf(sess, "%s %s\n", successStyle.Render("✓"), label+fmt.Sprintf("sent %d new item(s)", stats.NewItems))
+		case stats.NewItems > 0:
+			printf(sess, "%s %s\n", successStyle.Render("✓"), label+fmt.Sprintf("%d new item(s), not sent", stats.NewItems))
+		default:
+			printf(sess, "%s %s\n", successStyle.Render("✓"), label+"no new items")
+		}
+	}
+
+	println(sess)
+	println(sess, fmt.Sprintf("%d sent, %d rate-limited, %d failed", sent, limited, failed))
+	if limited > 0 {
+		println(sess, dimStyle.Render("Rate-limited items stay unsent and go out on each config's next scheduled run."))
+	}
+}
+
+// runWithProgress runs a config now, showing a spinner with feed progress
+// prefixed by label until the run finishes
+func runWithProgress(ctx context.Context, sess ssh.Session, st *store.DB, sched *scheduler.Scheduler, cfg *store.Config, label string) (*scheduler.RunStats, error) {
+	// Get feed count for progress display
+	feeds, err := st.GetFeedsByConfig(ctx, cfg.ID)
+	if err != nil {
+		return nil, err
+	}
 	totalFeeds := len(feeds)
 
 	// Progress tracking
@@ -202,7 +602,7 @@ func handleRun(ctx context.Context, sess ssh.Session, user *store.User, st *stor
 				return
 			default:
 				completed := progress.Load()
-				printf(sess, "\r%s Fetching feeds... %d/%d", spinChars[i%len(spinChars)], completed, totalFeeds)
+				printf(sess, "\r%s %sFetching feeds... %d/%d", spinChars[i%len(spinChars)], label, completed, totalFeeds)
 				i++
 				time.Sleep(80 * time.Millisecond)
 			}
@@ -223,38 +623,172 @@ func handleRun(ctx context.Context, sess ssh.Session, user *store.User, st *stor
 	close(done)
 	print(sess, "\r\033[K") // Clear the spinner line
 
-	if res.err != nil {
-		println(sess, errorStyle.Render("Error: "+res.err.Error()))
+	return res.stats, res.err
+}
+
+func handleBoost(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, filename, intervalStr, durationStr string) {
+	cfg, err := st.GetConfig(ctx, user.ID, filename)
+	if err != nil {
+		println(sess, errorStyle.Render("Config not found: "+filename))
 		return
 	}
 
-	// Display detailed stats
-	if res.stats != nil {
-		if res.stats.FailedFeeds > 0 {
-			printf(sess, "%s Fetched %d/%d feeds (%d failed)\n",
-				dimStyle.Render("⚠"),
-				res.stats.FetchedFeeds,
-				res.stats.TotalFeeds,
-				res.stats.FailedFeeds)
-		} else {
-			printf(sess, "%s Fetched %d/%d feeds\n",
-				successStyle.Render("✓"),
-				res.stats.FetchedFeeds,
-				res.stats.TotalFeeds)
+	if !cfg.NextRun.Valid {
+		println(sess, errorStyle.Render("Config is inactive. Activate it first: activate "+filename))
+		return
+	}
+
+	interval, err := time.ParseDuration(intervalStr)
+	if err != nil {
+		println(sess, errorStyle.Render("Invalid interval: "+intervalStr+" (e.g. 30m, 1h)"))
+		return
+	}
+	duration, err := time.ParseDuration(durationStr)
+	if err != nil {
+		println(sess, errorStyle.Render("Invalid duration: "+durationStr+" (e.g. 6h)"))
+		return
+	}
+
+	if interval < minBoostInterval {
+		println(sess, errorStyle.Render("Interval must be at least "+shortDuration(minBoostInterval)))
+		return
+	}
+	if duration <= 0 || duration > maxBoostDuration {
+		println(sess, errorStyle.Render("Duration must be between 0 and "+shortDuration(maxBoostDuration)))
+		return
+	}
+	if interval > duration {
+		println(sess, errorStyle.Render("Interval must not exceed the boost duration"))
+		return
+	}
+
+	now := time.Now().UTC()
+	until := now.Add(duration)
+	nextRun := now.Add(interval)
+	if cfg.NextRun.Time.Before(nextRun) {
+		nextRun = cfg.NextRun.Time
+	}
+
+	if err := st.SetBoost(ctx, cfg.ID, interval, until, nextRun); err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+
+	_ = st.AddLog(ctx, cfg.ID, "info", fmt.Sprintf("Boosted: every %s until %s", shortDuration(interval), until.Format(time.RFC3339)))
+	println(sess, successStyle.Render(fmt.Sprintf("Boosted %s: every %s for %s (until %s)",
+		filename, shortDuration(interval), shortDuration(duration), until.Format("Jan 02 15:04 MST"))))
+}
+
+func handleBoostOff(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, filename string) {
+	cfg, err := st.GetConfig(ctx, user.ID, filename)
+	if err != nil {
+		println(sess, errorStyle.Render("Config not found: "+filename))
+		return
+	}
+
+	if !cfg.BoostUntil.Valid {
+		println(sess, dimStyle.Render("Config is not boosted: "+filename))
+		return
+	}
+
+	if err := st.ClearBoost(ctx, cfg.ID); err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+
+	// Pull next_run back onto the cron schedule if the config is active
+	if cfg.NextRun.Valid {
+		nextRun, err := cfg.NextCronTick(time.Now().UTC())
+		if err != nil {
+			println(sess, errorStyle.Render("Error: "+err.Error()))
+			return
+		}
+		if err := st.UpdateNextRun(ctx, cfg.ID, &nextRun); err != nil {
+			println(sess, errorStyle.Render("Error: "+err.Error()))
+			return
 		}
+	}
 
-		if res.stats.NewItems == 0 {
-			println(sess, dimStyle.Render("No new items found."))
-		} else {
-			if res.stats.EmailSent {
-				println(sess, successStyle.Render(fmt.Sprintf("Sent %d new item(s) to %s", res.stats.NewItems, cfg.Email)))
-			} else {
-				println(sess, dimStyle.Render(fmt.Sprintf("Found %d new item(s) but did not send email", res.stats.NewItems)))
-			}
+	println(sess, successStyle.Render("Boost removed: "+filename))
+}
+
+func handleReset(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, sched *scheduler.Scheduler, filename string, confirmed bool) {
+	cfg, err := st.GetConfig(ctx, user.ID, filename)
+	if err != nil {
+		println(sess, errorStyle.Render("Config not found: "+filename))
+		return
+	}
+
+	if !confirmed {
+		println(sess, errorStyle.Render("This clears all seen items for "+filename+" and re-marks current feed items as seen."))
+		println(sess, dimStyle.Render("Run again with: reset "+filename+" --yes"))
+		return
+	}
+
+	print(sess, dimStyle.Render("Re-fetching feeds..."))
+	stats, err := sched.ResetSeen(ctx, cfg)
+	print(sess, "\r\033[K")
+	if err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+
+	_ = st.AddLog(ctx, cfg.ID, "info", fmt.Sprintf("Reset: cleared %d seen items, re-seeded %d", stats.Cleared, stats.Seeded))
+
+	println(sess, successStyle.Render(fmt.Sprintf("Reset %s: cleared %d seen item(s), re-seeded %d from %d feed(s)", filename, stats.Cleared, stats.Seeded, stats.FetchedFeeds)))
+	if stats.FailedFeeds > 0 {
+		println(sess, dimStyle.Render(fmt.Sprintf("%d feed(s) failed to fetch; their seen items were kept", stats.FailedFeeds)))
+	}
+}
+
+func handleSearch(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, query string) {
+	matches, err := st.SearchSeenItems(ctx, user.ID, query, 25)
+	if err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+
+	if len(matches) == 0 {
+		println(sess, dimStyle.Render("No seen items match "+strconv.Quote(query)+"."))
+		return
+	}
+
+	println(sess, titleStyle.Render(fmt.Sprintf("Seen items matching %q:", query)))
+
+	for _, m := range matches {
+		title := m.Title.String
+		if title == "" {
+			title = m.Link.String
+		}
+		feed := m.FeedURL
+		if m.FeedName.Valid && m.FeedName.String != "" {
+			feed = m.FeedName.String
+		}
+
+		printf(sess, "  %s  %s\n", dimStyle.Render(m.SeenAt.Format("Jan 02 2006 15:04")), title)
+		printf(sess, "    %s\n", dimStyle.Render(m.ConfigFilename+" / "+feed))
+		if m.Link.Valid && m.Link.String != title {
+			printf(sess, "    %s\n", dimStyle.Render(m.Link.String))
 		}
 	}
 }
 
+func handleClearLogs(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, filename string) {
+	cfg, err := st.GetConfig(ctx, user.ID, filename)
+	if err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+
+	deleted, err := st.DeleteLogsByConfig(ctx, cfg.ID)
+	if err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+
+	println(sess, successStyle.Render(fmt.Sprintf("Cleared %d log entry(s) for %s", deleted, filename)))
+}
+
 func handleLogs(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB) {
 	logs, err := st.GetRecentLogs(ctx, user.ID, 20)
 	if err != nil {
@@ -287,6 +821,34 @@ func handleLogs(ctx context.Context, sess ssh.Session, user *store.User, st *sto
 	}
 }
 
+// shortDuration formats a duration without trailing zero units, e.g. 30m or 6h
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// formatTimeAgo formats a past time relative to now, e.g. 5 min ago
+func formatTimeAgo(t time.Time) string {
+	diff := time.Since(t)
+
+	if diff < time.Minute {
+		return "just now"
+	}
+	if diff < time.Hour {
+		return fmt.Sprintf("%d min ago", int(diff.Minutes()))
+	}
+	if diff < 24*time.Hour {
+		return fmt.Sprintf("%d hr ago", int(diff.Hours()))
+	}
+	return fmt.Sprintf("%d day(s) ago", int(diff.Hours()/24))
+}
+
 func formatRelativeTime(t time.Time) string {
 	now := time.Now().UTC()
 	diff := t.Sub(now)
@@ -310,3 +872,62 @@ func formatRelativeTime(t time.Time) string {
 	days := int(diff.Hours() / 24)
 	return fmt.Sprintf("%d day(s)", days)
 }
+
+func handleRotateToken(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, filename string) {
+	cfg, err := st.GetConfig(ctx, user.ID, filename)
+	if err != nil {
+		println(sess, errorStyle.Render("Config not found: "+filename))
+		return
+	}
+
+	if _, err := st.RotateUnsubscribeToken(ctx, cfg.ID); err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+
+	_ = st.AddLog(ctx, cfg.ID, "info", "Rotated unsubscribe token")
+
+	println(sess, successStyle.Render("Rotated unsubscribe token for "+filename))
+	println(sess, dimStyle.Render("Unsubscribe links in earlier digests no longer work; new digests use the new link."))
+}
+
+func handleSetEmail(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, logger *log.Logger, filename, address string) {
+	addr, err := mail.ParseAddress(address)
+	if err != nil {
+		println(sess, errorStyle.Render("Invalid email address: "+address))
+		return
+	}
+
+	cfg, err := st.GetConfig(ctx, user.ID, filename)
+	if err != nil {
+		println(sess, errorStyle.Render("Config not found: "+filename))
+		return
+	}
+
+	if err := st.UpdateConfigEmail(ctx, cfg.ID, addr.Address, config.SetEmail(cfg.RawText, addr.Address)); err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+
+	logger.Info("config email changed", "user_id", user.ID, "config_id", cfg.ID, "filename", filename)
+	_ = st.AddLog(ctx, cfg.ID, "info", fmt.Sprintf("Email changed from %s to %s", cfg.Email, addr.Address))
+
+	println(sess, successStyle.Render(fmt.Sprintf("Digests for %s now go to %s", filename, addr.Address)))
+}
+
+func handleHeaders(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, sched *scheduler.Scheduler, filename string) {
+	cfg, err := st.GetConfig(ctx, user.ID, filename)
+	if err != nil {
+		println(sess, errorStyle.Render("Config not found: "+filename))
+		return
+	}
+
+	headers, err := sched.PreviewHeaders(ctx, cfg)
+	if err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+
+	println(sess, dimStyle.Render("Headers for the next digest from "+filename+" (not sent):"))
+	println(sess, strings.ReplaceAll(headers, "\r\n", "\n"))
+}
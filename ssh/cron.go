@@ -0,0 +1,198 @@
+package ssh
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adhocore/gronx"
+	"github.com/charmbracelet/ssh"
+	"github.com/kierank/herald/config"
+)
+
+const (
+	// explainCronRuns is how many upcoming runs explain-cron lists
+	explainCronRuns = 5
+	// maxCronTimes is the most times of day a description lists
+	maxCronTimes = 6
+)
+
+var cronAliases = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// describeCron puts common cron schedules in words, like "daily at 08:00 UTC"
+// or "every 15 minutes on weekdays", falling back to the expression itself
+func describeCron(expr string) string {
+	spec := strings.TrimSpace(expr)
+	if alias, ok := cronAliases[strings.ToLower(spec)]; ok {
+		spec = alias
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return expr
+	}
+	minute, hour, dom, month, dow := fields[0], fields[1], fields[2], fields[3], fields[4]
+	if month != "*" {
+		return expr
+	}
+	lead, trail, ok := describeCronDays(dom, dow)
+	if !ok {
+		return expr
+	}
+
+	// Fixed times of day, e.g. "weekdays at 08:00 and 17:00 UTC"
+	if m, ok := cronNumber(minute, 59); ok {
+		if hours, ok := cronList(hour, 0, 23); ok && len(hours) <= maxCronTimes {
+			times := make([]string, len(hours))
+			for i, h := range hours {
+				times[i] = fmt.Sprintf("%02d:%02d", h, m)
+			}
+			return lead + " at " + joinWords(times) + " UTC"
+		}
+	}
+
+	// Repeating through the day, e.g. "every 15 minutes on weekdays"
+	every := ""
+	switch {
+	case hour == "*":
+		if m, ok := cronNumber(minute, 59); ok {
+			every = fmt.Sprintf("hourly at :%02d", m)
+		} else if n, ok := cronStep(minute, 59); ok {
+			every = fmt.Sprintf("every %d minutes", n)
+		}
+	default:
+		m, okMinute := cronNumber(minute, 59)
+		if n, ok := cronStep(hour, 23); ok && okMinute {
+			every = fmt.Sprintf("every %d hours at :%02d", n, m)
+		}
+	}
+	if every == "" {
+		return expr
+	}
+	if trail == "" {
+		return every
+	}
+	return every + " " + trail
+}
+
+// describeCronDays words the day-of-month and day-of-week fields, both to
+// lead a description ("weekdays") and to follow one ("on weekdays")
+func describeCronDays(dom, dow string) (lead, trail string, ok bool) {
+	switch {
+	case dom == "*" && dow == "*":
+		return "daily", "", true
+	case dom == "*":
+		days, ok := cronList(dow, 0, 7)
+		if !ok {
+			return "", "", false
+		}
+		set := map[int]bool{}
+		for _, d := range days {
+			set[d%7] = true
+		}
+		names := []string{}
+		for d := range 7 {
+			if set[d] {
+				names = append(names, time.Weekday(d).String()+"s")
+			}
+		}
+		switch {
+		case len(set) == 5 && !set[0] && !set[6]:
+			return "weekdays", "on weekdays", true
+		case len(set) == 2 && set[0] && set[6]:
+			return "weekends", "on weekends", true
+		}
+		words := joinWords(names)
+		return words, "on " + words, true
+	case dow == "*":
+		if d, ok := cronNumber(dom, 31); ok && d > 0 {
+			return fmt.Sprintf("monthly on day %d", d), fmt.Sprintf("on day %d of the month", d), true
+		}
+	}
+	return "", "", false
+}
+
+// cronNumber parses a plain cron field value no greater than limit
+func cronNumber(field string, limit int) (int, bool) {
+	n, err := strconv.Atoi(field)
+	if err != nil || n < 0 || n > limit {
+		return 0, false
+	}
+	return n, true
+}
+
+// cronStep parses a "*/n" field with 0 < n <= limit
+func cronStep(field string, limit int) (int, bool) {
+	step, ok := strings.CutPrefix(field, "*/")
+	if !ok {
+		return 0, false
+	}
+	n, ok := cronNumber(step, limit)
+	return n, ok && n > 0
+}
+
+// cronList parses a field of values and ranges like "1-3,5" into sorted
+// values between lo and hi
+func cronList(field string, lo, hi int) ([]int, bool) {
+	var values []int
+	for _, part := range strings.Split(field, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		a, ok := cronNumber(from, hi)
+		if !ok || a < lo {
+			return nil, false
+		}
+		b := a
+		if isRange {
+			if b, ok = cronNumber(to, hi); !ok || b < a {
+				return nil, false
+			}
+		}
+		for v := a; v <= b; v++ {
+			values = append(values, v)
+		}
+	}
+	slices.Sort(values)
+	return slices.Compact(values), true
+}
+
+// joinWords lists words as "a", "a and b", or "a, b and c"
+func joinWords(words []string) string {
+	if len(words) <= 1 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
+
+// handleExplainCron describes a cron expression in words and lists its next
+// runs, so a schedule can be checked before it goes into a config
+func handleExplainCron(sess ssh.Session, expr string) {
+	if !config.ValidCron(expr) {
+		println(sess, errorStyle.Render("Invalid cron expression: "+expr))
+		return
+	}
+
+	if desc := describeCron(expr); desc != expr {
+		printf(sess, "%s %s\n", dimStyle.Render("schedule:"), desc)
+	} else {
+		println(sess, dimStyle.Render("No plain description for this expression; check the runs below."))
+	}
+
+	println(sess, dimStyle.Render("next runs:"))
+	next := time.Now().UTC()
+	for range explainCronRuns {
+		var err error
+		next, err = gronx.NextTickAfter(expr, next, false)
+		if err != nil {
+			println(sess, errorStyle.Render("Error: "+err.Error()))
+			return
+		}
+		println(sess, "  "+next.Format("Mon 2006-01-02 15:04 MST"))
+	}
+}
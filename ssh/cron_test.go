@@ -0,0 +1,52 @@
+package ssh
+
+import "testing"
+
+func TestDescribeCron(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"0 8 * * *", "daily at 08:00 UTC"},
+		{"@daily", "daily at 00:00 UTC"},
+		{"0 8,17 * * 1-5", "weekdays at 08:00 and 17:00 UTC"},
+		{"30 9 * * 0,6", "weekends at 09:30 UTC"},
+		{"0 9 * * 1,3", "Mondays and Wednesdays at 09:00 UTC"},
+		{"0 8 * * 7", "Sundays at 08:00 UTC"},
+		{"0 7 1 * *", "monthly on day 1 at 07:00 UTC"},
+		{"5 * * * *", "hourly at :05"},
+		{"*/15 * * * *", "every 15 minutes"},
+		{"*/15 * * * 1-5", "every 15 minutes on weekdays"},
+		{"0 */6 * * *", "every 6 hours at :00"},
+		{"0 */6 1 * *", "every 6 hours at :00 on day 1 of the month"},
+
+		// Anything else falls back to the expression itself
+		{"0 8 * 1 *", "0 8 * 1 *"},
+		{"0 8 1 * 1", "0 8 1 * 1"},
+		{"0 0,2,4,6,8,10,12 * * *", "0 0,2,4,6,8,10,12 * * *"},
+		{"0 8-20/2 * * *", "0 8-20/2 * * *"},
+		{"not a cron", "not a cron"},
+	}
+	for _, tt := range tests {
+		if got := describeCron(tt.expr); got != tt.want {
+			t.Errorf("describeCron(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestJoinWords(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"a"}, "a"},
+		{[]string{"a", "b"}, "a and b"},
+		{[]string{"a", "b", "c"}, "a, b and c"},
+	}
+	for _, tt := range tests {
+		if got := joinWords(tt.words); got != tt.want {
+			t.Errorf("joinWords(%q) = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package ssh
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/ssh"
+	"github.com/kierank/herald/config"
+	"github.com/kierank/herald/store"
+)
+
+const defaultsUsage = "Usage: defaults [cron <expr> | digest <bool> | inline <bool> | <key> clear | clear]"
+
+// configDefaults converts a user's stored defaults for config.ApplyDefaults
+func configDefaults(d store.UserDefaults) config.Defaults {
+	out := config.Defaults{CronExpr: d.CronExpr.String}
+	if d.Digest.Valid {
+		out.Digest = &d.Digest.Bool
+	}
+	if d.Inline.Valid {
+		out.Inline = &d.Inline.Bool
+	}
+	return out
+}
+
+// handleDefaults shows the user's config defaults, or changes one of them.
+// Defaults apply when a config is uploaded, so existing configs keep their
+// values until they are uploaded again.
+func handleDefaults(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, logger *log.Logger, args []string) {
+	defaults, err := st.GetUserDefaults(ctx, user.ID)
+	if err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+
+	if len(args) == 0 {
+		printDefaults(sess, defaults)
+		return
+	}
+
+	key := strings.ToLower(args[0])
+	value := strings.TrimSpace(strings.Join(args[1:], " "))
+	switch {
+	case key == "clear" && value == "":
+		defaults = store.UserDefaults{}
+	case value == "":
+		println(sess, errorStyle.Render(defaultsUsage))
+		return
+	case key == "cron" && value == "clear":
+		defaults.CronExpr = sql.NullString{}
+	case key == "cron":
+		if !config.ValidCron(value) {
+			println(sess, errorStyle.Render("Invalid cron expression: "+value))
+			return
+		}
+		defaults.CronExpr = sql.NullString{String: value, Valid: true}
+	case key == "digest" || key == "inline":
+		field := &defaults.Digest
+		if key == "inline" {
+			field = &defaults.Inline
+		}
+		if value == "clear" {
+			*field = sql.NullBool{}
+			break
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			println(sess, errorStyle.Render(fmt.Sprintf("Invalid %s value: %s (use true or false)", key, value)))
+			return
+		}
+		*field = sql.NullBool{Bool: b, Valid: true}
+	default:
+		println(sess, errorStyle.Render(defaultsUsage))
+		return
+	}
+
+	if err := st.SetUserDefaults(ctx, user.ID, defaults); err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+
+	logger.Info("config defaults changed", "user_id", user.ID, "key", key)
+	println(sess, successStyle.Render("Defaults updated; they apply to configs uploaded from now on"))
+	printDefaults(sess, defaults)
+}
+
+func printDefaults(sess ssh.Session, d store.UserDefaults) {
+	cron := "not set"
+	if d.CronExpr.Valid {
+		cron = fmt.Sprintf("%s (%s)", d.CronExpr.String, describeCron(d.CronExpr.String))
+	}
+	digest, inline := "true (built-in)", "false (built-in)"
+	if d.Digest.Valid {
+		digest = strconv.FormatBool(d.Digest.Bool)
+	}
+	if d.Inline.Valid {
+		inline = strconv.FormatBool(d.Inline.Bool)
+	}
+
+	printf(sess, "%s %s\n", dimStyle.Render("cron:"), cron)
+	printf(sess, "%s %s\n", dimStyle.Render("digest:"), digest)
+	printf(sess, "%s %s\n", dimStyle.Render("inline:"), inline)
+}
@@ -0,0 +1,181 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/ssh"
+	"github.com/kierank/herald/scheduler"
+	"github.com/kierank/herald/store"
+)
+
+const (
+	exportVersion = 1
+	// maxImportSize bounds an import bundle, which may carry seen items
+	maxImportSize = 32 * 1024 * 1024
+	// maxExportSeenItems is how many recent seen items are exported per feed
+	maxExportSeenItems = 5000
+)
+
+// exportBundle is the JSON written by export and read by import
+type exportBundle struct {
+	Version int            `json:"version"`
+	Configs []exportConfig `json:"configs"`
+}
+
+type exportConfig struct {
+	Filename string       `json:"filename"`
+	Config   string       `json:"config"`
+	Feeds    []exportFeed `json:"feeds,omitempty"`
+}
+
+type exportFeed struct {
+	URL  string           `json:"url"`
+	Seen []exportSeenItem `json:"seen,omitempty"`
+}
+
+type exportSeenItem struct {
+	GUID   string    `json:"guid"`
+	Title  string    `json:"title,omitempty"`
+	Link   string    `json:"link,omitempty"`
+	SeenAt time.Time `json:"seen_at"`
+}
+
+// buildExport collects the user's configs, and each feed's seen items if
+// withSeen is set
+func buildExport(ctx context.Context, st *store.DB, userID int64, withSeen bool) (*exportBundle, error) {
+	configs, err := st.ListConfigs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &exportBundle{Version: exportVersion, Configs: []exportConfig{}}
+	for _, cfg := range configs {
+		entry := exportConfig{Filename: cfg.Filename, Config: cfg.RawText}
+		if withSeen {
+			// Disabled feeds keep their history so re-enabling them after an
+			// import doesn't resend it
+			feeds, err := st.GetAllFeedsByConfig(ctx, cfg.ID)
+			if err != nil {
+				return nil, fmt.Errorf("get feeds for %s: %w", cfg.Filename, err)
+			}
+			for _, feed := range feeds {
+				items, err := st.GetSeenItems(ctx, feed.ID, maxExportSeenItems)
+				if err != nil {
+					return nil, fmt.Errorf("get seen items for %s: %w", feed.URL, err)
+				}
+				ef := exportFeed{URL: feed.URL}
+				for _, item := range items {
+					ef.Seen = append(ef.Seen, exportSeenItem{
+						GUID:   item.GUID,
+						Title:  item.Title.String,
+						Link:   item.Link.String,
+						SeenAt: item.SeenAt,
+					})
+				}
+				entry.Feeds = append(entry.Feeds, ef)
+			}
+		}
+		bundle.Configs = append(bundle.Configs, entry)
+	}
+	return bundle, nil
+}
+
+func handleExport(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, withSeen bool) {
+	bundle, err := buildExport(ctx, st, user.ID, withSeen)
+	if err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+
+	enc := json.NewEncoder(sess)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(bundle)
+}
+
+// importBundle saves every config in the bundle under userID and restores
+// the seen items exported with it. It stops at the first config that fails.
+func importBundle(ctx context.Context, st *store.DB, sched *scheduler.Scheduler, logger *log.Logger, userID int64, bundle *exportBundle) (configs, seen int, err error) {
+	for _, cfg := range bundle.Configs {
+		if len(cfg.Config) > maxConfigSize {
+			return configs, seen, fmt.Errorf("%s: file too large (max 1MB)", cfg.Filename)
+		}
+		if _, _, err := saveConfig(ctx, st, sched, logger, userID, cfg.Filename, []byte(cfg.Config)); err != nil {
+			return configs, seen, fmt.Errorf("%s: %w", cfg.Filename, err)
+		}
+		configs++
+
+		for _, feed := range cfg.Feeds {
+			if len(feed.Seen) == 0 {
+				continue
+			}
+			items := make([]store.SeenItem, len(feed.Seen))
+			for i, item := range feed.Seen {
+				items[i] = store.SeenItem{GUID: item.GUID, SeenAt: item.SeenAt}
+				items[i].Title.String, items[i].Title.Valid = item.Title, item.Title != ""
+				items[i].Link.String, items[i].Link.Valid = item.Link, item.Link != ""
+			}
+			n, err := st.ImportSeenItems(ctx, userID, cfg.Filename, feed.URL, items)
+			if err != nil {
+				logger.Warn("failed to import seen items", "filename", cfg.Filename, "feed_url", feed.URL, "err", err)
+				continue
+			}
+			seen += n
+		}
+	}
+	return configs, seen, nil
+}
+
+// parseBundle decodes and checks an export bundle
+func parseBundle(data []byte) (*exportBundle, error) {
+	var bundle exportBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid export bundle: %w", err)
+	}
+	if bundle.Version != exportVersion {
+		return nil, fmt.Errorf("unsupported export version %d", bundle.Version)
+	}
+	for _, cfg := range bundle.Configs {
+		if !strings.HasSuffix(cfg.Filename, ".txt") {
+			return nil, fmt.Errorf("%q: only .txt files are supported", cfg.Filename)
+		}
+	}
+	return &bundle, nil
+}
+
+func handleImport(ctx context.Context, sess ssh.Session, user *store.User, st *store.DB, sched *scheduler.Scheduler, logger *log.Logger) {
+	data, err := io.ReadAll(io.LimitReader(sess, maxImportSize+1))
+	if err != nil {
+		println(sess, errorStyle.Render("Error: failed to read bundle: "+err.Error()))
+		return
+	}
+	if len(data) > maxImportSize {
+		println(sess, errorStyle.Render("Error: bundle too large (max 32MB)"))
+		return
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		println(sess, errorStyle.Render("Error: no export bundle received on stdin"))
+		return
+	}
+
+	bundle, err := parseBundle(data)
+	if err != nil {
+		println(sess, errorStyle.Render("Error: "+err.Error()))
+		return
+	}
+
+	configs, seen, err := importBundle(ctx, st, sched, logger, user.ID, bundle)
+	if err != nil {
+		println(sess, errorStyle.Render(fmt.Sprintf("Error: imported %d config(s) before %v", configs, err)))
+		return
+	}
+
+	logger.Info("configs imported", "user_id", user.ID, "configs", configs, "seen_items", seen)
+	println(sess, successStyle.Render(fmt.Sprintf("Imported %d config(s) and %d seen item(s)", configs, seen)))
+}
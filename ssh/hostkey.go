@@ -0,0 +1,89 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// HostKeyRotation describes a rotated host key
+type HostKeyRotation struct {
+	// BackupPath holds the old key, or is empty if there was none
+	BackupPath     string
+	OldFingerprint string
+	NewFingerprint string
+}
+
+// RotateHostKey replaces the host key at path with a newly generated one,
+// first copying the old key next to it with a timestamped .bak suffix. The
+// new key is written to a temporary file and renamed into place, so path
+// always holds a whole key. A running server keeps using the old key until
+// it restarts.
+func RotateHostKey(path string) (*HostKeyRotation, error) {
+	rotation := &HostKeyRotation{}
+
+	old, err := os.ReadFile(path) // #nosec G304 -- path comes from the operator's config
+	switch {
+	case err == nil:
+		signer, err := gossh.ParsePrivateKey(old)
+		if err != nil {
+			return nil, fmt.Errorf("parse current host key: %w", err)
+		}
+		rotation.OldFingerprint = gossh.FingerprintSHA256(signer.PublicKey())
+		rotation.BackupPath = path + ".bak-" + time.Now().UTC().Format("20060102T150405Z")
+		if err := os.WriteFile(rotation.BackupPath, old, 0600); err != nil {
+			return nil, fmt.Errorf("back up host key: %w", err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("read current host key: %w", err)
+	}
+
+	key, err := newHostKey()
+	if err != nil {
+		return nil, err
+	}
+	signer, err := gossh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("parse new host key: %w", err)
+	}
+	rotation.NewFingerprint = gossh.FingerprintSHA256(signer.PublicKey())
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".host_key-*")
+	if err != nil {
+		return nil, fmt.Errorf("create temporary host key: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(key); err != nil {
+		_ = tmp.Close()
+		return nil, fmt.Errorf("write new host key: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("write new host key: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("replace host key: %w", err)
+	}
+	return rotation, nil
+}
+
+// newHostKey generates an ed25519 host key in PEM form
+func newHostKey() ([]byte, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	privBytes, err := gossh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+
+	return pem.EncodeToMemory(privBytes), nil
+}
@@ -0,0 +1,35 @@
+package ssh
+
+import (
+	"slices"
+	"strings"
+)
+
+// commandNames lists every command HandleCommand dispatches
+var commandNames = []string{
+	"ls", "feeds", "allfeeds", "fetch", "upload", "cat", "rm", "activate", "deactivate",
+	"run", "run-all", "logs", "search", "clear-logs", "boost", "reset",
+	"headers", "set-email", "rotate-token", "defaults", "explain-cron",
+	"export", "import",
+}
+
+// KnownCommand reports whether name is a command users can run over SSH
+func KnownCommand(name string) bool {
+	return slices.Contains(commandNames, strings.ToLower(name))
+}
+
+// CommandPolicy limits which commands users may run. When Allowed is set only
+// those commands run; Disabled commands never run.
+type CommandPolicy struct {
+	Allowed  []string
+	Disabled []string
+}
+
+// Permits reports whether the policy lets users run the named command
+func (p CommandPolicy) Permits(name string) bool {
+	match := func(s string) bool { return strings.EqualFold(strings.TrimSpace(s), name) }
+	if slices.ContainsFunc(p.Disabled, match) {
+		return false
+	}
+	return len(p.Allowed) == 0 || slices.ContainsFunc(p.Allowed, match)
+}
@@ -0,0 +1,37 @@
+package ssh
+
+import "testing"
+
+func TestCommandPolicyPermits(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  CommandPolicy
+		command string
+		want    bool
+	}{
+		{"empty policy allows everything", CommandPolicy{}, "rm", true},
+		{"allowlisted", CommandPolicy{Allowed: []string{"ls", "cat"}}, "ls", true},
+		{"not on the allowlist", CommandPolicy{Allowed: []string{"ls", "cat"}}, "rm", false},
+		{"disabled", CommandPolicy{Disabled: []string{"rm"}}, "rm", false},
+		{"others stay allowed when one is disabled", CommandPolicy{Disabled: []string{"rm"}}, "ls", true},
+		{"disabled wins over allowed", CommandPolicy{Allowed: []string{"ls", "rm"}, Disabled: []string{"rm"}}, "rm", false},
+		{"entries ignore case and spaces", CommandPolicy{Allowed: []string{" LS "}}, "ls", true},
+		{"disabled ignores case", CommandPolicy{Disabled: []string{"Reset"}}, "reset", false},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Permits(tt.command); got != tt.want {
+			t.Errorf("%s: Permits(%q) = %v, want %v", tt.name, tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestKnownCommand(t *testing.T) {
+	for _, name := range []string{"ls", "RUN-ALL", "explain-cron"} {
+		if !KnownCommand(name) {
+			t.Errorf("expected %q to be known", name)
+		}
+	}
+	if KnownCommand("shutdown") {
+		t.Error("expected shutdown to be unknown")
+	}
+}
@@ -2,8 +2,6 @@ package ssh
 
 import (
 	"bytes"
-	"context"
-	"database/sql"
 	"fmt"
 	"io"
 	"io/fs"
@@ -11,11 +9,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/adhocore/gronx"
 	"github.com/charmbracelet/log"
 	"github.com/charmbracelet/ssh"
 	"github.com/charmbracelet/wish/scp"
-	"github.com/kierank/herald/config"
 	"github.com/kierank/herald/ratelimit"
 	"github.com/kierank/herald/scheduler"
 	"github.com/kierank/herald/store"
@@ -115,8 +111,7 @@ func (h *scpHandler) Write(s ssh.Session, entry *scp.FileEntry) (int64, error) {
 		return 0, fmt.Errorf("rate limit exceeded, please try again later")
 	}
 
-	// Max file size: 1MB
-	if entry.Size > 1024*1024 {
+	if entry.Size > maxConfigSize {
 		return 0, fmt.Errorf("file too large (max 1MB)")
 	}
 
@@ -125,128 +120,26 @@ func (h *scpHandler) Write(s ssh.Session, entry *scp.FileEntry) (int64, error) {
 		return 0, fmt.Errorf("only .txt files are supported")
 	}
 
-	content, err := io.ReadAll(io.LimitReader(entry.Reader, 1024*1024))
+	content, err := io.ReadAll(io.LimitReader(entry.Reader, maxConfigSize))
 	if err != nil {
 		return 0, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	parsed, err := config.Parse(string(content))
+	parsed, nextRun, err := saveConfig(s.Context(), h.store, h.scheduler, h.logger, user.ID, name, content)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse config: %w", err)
-	}
-
-	if err := config.Validate(parsed); err != nil {
-		return 0, fmt.Errorf("invalid config: %w", err)
-	}
-
-	ctx := s.Context()
-
-	// Validate feed URLs by attempting to fetch them
-	if err := config.ValidateFeedURLs(ctx, parsed); err != nil {
-		return 0, fmt.Errorf("feed validation failed: %w", err)
-	}
-
-	nextRun, err := calculateNextRun(parsed.CronExpr)
-	if err != nil {
-		return 0, fmt.Errorf("failed to calculate next run: %w", err)
-	}
-
-	// Use transaction for config update
-	tx, err := h.store.BeginTx(ctx)
-	if err != nil {
-		return 0, fmt.Errorf("begin transaction: %w", err)
-	}
-	defer func() { _ = tx.Rollback() }()
-
-	// Try to get existing config
-	existingCfg, err := h.store.GetConfigTx(ctx, tx, user.ID, name)
-	var cfg *store.Config
-
-	if err == nil {
-		// Config exists - update it
-		if err := h.store.UpdateConfigTx(ctx, tx, existingCfg.ID, parsed.Email, parsed.CronExpr, parsed.Digest, parsed.Inline, string(content), nextRun); err != nil {
-			return 0, fmt.Errorf("failed to update config: %w", err)
-		}
-		cfg = existingCfg
-		cfg.Email = parsed.Email
-		cfg.CronExpr = parsed.CronExpr
-		cfg.Digest = parsed.Digest
-		cfg.InlineContent = parsed.Inline
-		cfg.RawText = string(content)
-
-		// Sync feeds: match by URL, update/delete/add as needed
-		existingFeeds, err := h.store.GetFeedsByConfigTx(ctx, tx, cfg.ID)
-		if err != nil {
-			return 0, fmt.Errorf("failed to get existing feeds: %w", err)
-		}
-
-		// Build maps for comparison
-		existingByURL := make(map[string]*store.Feed)
-		for _, f := range existingFeeds {
-			existingByURL[f.URL] = f
-		}
-
-		newByURL := make(map[string]struct{ URL, Name string })
-		for _, f := range parsed.Feeds {
-			newByURL[f.URL] = struct{ URL, Name string }{URL: f.URL, Name: f.Name}
-		}
-
-		// Update existing feeds that are still present
-		for _, newFeed := range parsed.Feeds {
-			if existingFeed, exists := existingByURL[newFeed.URL]; exists {
-				// Feed still exists - update name if changed
-				if err := h.store.UpdateFeedTx(ctx, tx, existingFeed.ID, newFeed.Name); err != nil {
-					return 0, fmt.Errorf("failed to update feed: %w", err)
-				}
-			} else {
-				// New feed - create it and mark existing items as seen
-				newFeedRecord, err := h.store.CreateFeedTx(ctx, tx, cfg.ID, newFeed.URL, newFeed.Name)
-				if err != nil {
-					return 0, fmt.Errorf("failed to create feed: %w", err)
-				}
-				// Pre-seed seen items so we don't send old posts
-				if err := h.preseedSeenItems(ctx, tx, newFeedRecord); err != nil {
-					h.logger.Warn("failed to preseed seen items", "feed_url", newFeed.URL, "err", err)
-				}
-			}
-		}
-
-		// Delete feeds that are no longer present
-		for _, existingFeed := range existingFeeds {
-			if _, stillExists := newByURL[existingFeed.URL]; !stillExists {
-				if err := h.store.DeleteFeedTx(ctx, tx, existingFeed.ID); err != nil {
-					return 0, fmt.Errorf("failed to delete feed: %w", err)
-				}
-			}
-		}
-
-		h.logger.Debug("updated existing config", "filename", name)
-	} else {
-		// Config doesn't exist - create new one
-		cfg, err = h.store.CreateConfigTx(ctx, tx, user.ID, name, parsed.Email, parsed.CronExpr, parsed.Digest, parsed.Inline, string(content), nextRun)
-		if err != nil {
-			return 0, fmt.Errorf("failed to create config: %w", err)
-		}
-
-		for _, feed := range parsed.Feeds {
-			if _, err := h.store.CreateFeedTx(ctx, tx, cfg.ID, feed.URL, feed.Name); err != nil {
-				return 0, fmt.Errorf("failed to create feed: %w", err)
-			}
-		}
-
-		h.logger.Debug("created new config", "filename", name)
-	}
-
-	if err := tx.Commit(); err != nil {
-		return 0, fmt.Errorf("commit transaction: %w", err)
+		return 0, err
 	}
 
 	h.logger.Info("config uploaded", "user_id", user.ID, "filename", name, "feeds", len(parsed.Feeds), "next_run", nextRun)
 	return int64(len(content)), nil
 }
 
-func calculateNextRun(cronExpr string) (time.Time, error) {
-	return gronx.NextTickAfter(cronExpr, time.Now().UTC(), true)
+// calculateNextRun returns the next cron tick, shifted by the config's
+// schedule offset within st's jitter window. New configs pass 0 until they
+// have an ID.
+func calculateNextRun(st *store.DB, configID int64, cronExpr string) (time.Time, error) {
+	cfg := &store.Config{ID: configID, CronExpr: cronExpr, JitterWindow: st.ScheduleJitter()}
+	return cfg.NextCronTick(time.Now().UTC())
 }
 
 type configFileInfo struct {
@@ -268,21 +161,3 @@ func (e *configDirEntry) Name() string               { return e.info.Name() }
 func (e *configDirEntry) IsDir() bool                { return false }
 func (e *configDirEntry) Type() fs.FileMode          { return e.info.Mode() }
 func (e *configDirEntry) Info() (fs.FileInfo, error) { return e.info, nil }
-
-// preseedSeenItems fetches the feed and marks all current items as seen,
-// so that adding a new feed doesn't trigger emails for old posts.
-func (h *scpHandler) preseedSeenItems(ctx context.Context, tx *sql.Tx, feed *store.Feed) error {
-	result := scheduler.FetchFeed(ctx, feed)
-	if result.Error != nil {
-		return result.Error
-	}
-
-	for _, item := range result.Items {
-		if err := h.store.MarkItemSeenTx(ctx, tx, feed.ID, item.GUID, item.Title, item.Link); err != nil {
-			return err
-		}
-	}
-
-	h.logger.Debug("preseeded seen items for new feed", "feed_url", feed.URL, "count", len(result.Items))
-	return nil
-}
@@ -3,9 +3,6 @@ package ssh
 
 import (
 	"context"
-	"crypto/ed25519"
-	"crypto/rand"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	"os"
@@ -27,6 +24,12 @@ type Config struct {
 	HostKeyPath  string
 	AllowAllKeys bool
 	AllowedKeys  []string
+	// AllowedCommands, when set, are the only commands users may run
+	AllowedCommands []string
+	// DisabledCommands are refused even if AllowedCommands lists them
+	DisabledCommands []string
+	// MOTD is an operator message shown after the welcome text
+	MOTD string
 }
 
 type Server struct {
@@ -35,18 +38,25 @@ type Server struct {
 	scheduler   *scheduler.Scheduler
 	logger      *log.Logger
 	rateLimiter *ratelimit.Limiter
+	// fetchLimiter throttles the fetch command, which reaches arbitrary URLs
+	fetchLimiter *ratelimit.Limiter
 }
 
 func NewServer(cfg Config, st *store.DB, sched *scheduler.Scheduler, logger *log.Logger) *Server {
 	return &Server{
-		cfg:         cfg,
-		store:       st,
-		scheduler:   sched,
-		logger:      logger,
-		rateLimiter: ratelimit.New(5, 10), // 5 req/sec, burst of 10 for SSH/SCP
+		cfg:          cfg,
+		store:        st,
+		scheduler:    sched,
+		logger:       logger,
+		rateLimiter:  ratelimit.New(5, 10),  // 5 req/sec, burst of 10 for SSH/SCP
+		fetchLimiter: ratelimit.New(0.1, 3), // 1 fetch per 10s, burst of 3
 	}
 }
 
+func (s *Server) commandPolicy() CommandPolicy {
+	return CommandPolicy{Allowed: s.cfg.AllowedCommands, Disabled: s.cfg.DisabledCommands}
+}
+
 func (s *Server) ListenAndServe(ctx context.Context) error {
 	if err := s.ensureHostKey(); err != nil {
 		return fmt.Errorf("failed to ensure host key: %w", err)
@@ -158,7 +168,7 @@ func (s *Server) commandMiddleware(next ssh.Handler) ssh.Handler {
 		}
 
 		// Handle our custom commands (ls, cat, rm, run, logs)
-		HandleCommand(sess, user, s.store, s.scheduler, s.logger)
+		HandleCommand(sess, user, s.store, s.scheduler, s.fetchLimiter, s.commandPolicy(), s.logger)
 	}
 }
 
@@ -166,16 +176,47 @@ func (s *Server) handleWelcome(sess ssh.Session, user *store.User) {
 	fp := sess.Context().Value("fingerprint").(string)
 	printf(sess, "Welcome to Herald!\n\n")
 	printf(sess, "Your fingerprint: %s\n\n", fp)
+	s.printMOTD(sess)
 	printf(sess, "Upload a config with:\n")
 	printf(sess, "  scp feeds.txt %s:\n\n", sess.User())
 	printf(sess, "Commands:\n")
 	printf(sess, "  ls                   List your configs\n")
+	printf(sess, "  feeds <file>         List feeds and fetch status\n")
+	printf(sess, "  allfeeds             List feeds across all configs\n")
+	printf(sess, "  fetch <url>          Debug fetch a feed URL\n")
+	printf(sess, "  upload <file>        Upload a config from stdin\n")
 	printf(sess, "  cat <file>           Show config contents\n")
 	printf(sess, "  rm <file>            Delete a config\n")
 	printf(sess, "  activate <file>      Enable a config\n")
 	printf(sess, "  deactivate <file>    Disable a config\n")
 	printf(sess, "  run <file>           Run a config now\n")
+	printf(sess, "  run-all              Run all active configs now\n")
 	printf(sess, "  logs                 Show recent activity\n")
+	printf(sess, "  search <query>       Find seen items by title or link\n")
+	printf(sess, "  clear-logs <file>    Clear a config's logs\n")
+	printf(sess, "  boost <file> <i> <d> Run every <i> for <d> (e.g. 30m 6h)\n")
+	printf(sess, "  reset <file> --yes   Clear and re-seed seen items\n")
+	printf(sess, "  headers <file>       Show the email headers a digest is sent with\n")
+	printf(sess, "  set-email <file> <a> Send a config's digests to another address\n")
+	printf(sess, "  rotate-token <file>  Replace the unsubscribe link\n")
+	printf(sess, "  defaults [<k> <v>]   Show or set cron/digest/inline for new uploads\n")
+	printf(sess, "  explain-cron <expr>  Describe a cron expression and list its next runs\n")
+	printf(sess, "  export [--seen]      Print all configs as JSON, with seen items\n")
+	printf(sess, "  import               Restore configs from an export on stdin\n")
+}
+
+// printMOTD shows the operator's message, boxed when the session has a
+// terminal
+func (s *Server) printMOTD(sess ssh.Session) {
+	if s.cfg.MOTD == "" {
+		return
+	}
+	if _, _, isPty := sess.Pty(); isPty {
+		println(sess, motdStyle.Render(s.cfg.MOTD))
+	} else {
+		println(sess, s.cfg.MOTD)
+	}
+	println(sess)
 }
 
 func (s *Server) ensureHostKey() error {
@@ -187,18 +228,11 @@ func (s *Server) ensureHostKey() error {
 
 	s.logger.Info("generating new host key", "path", s.cfg.HostKeyPath)
 
-	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	key, err := newHostKey()
 	if err != nil {
-		return fmt.Errorf("failed to generate key: %w", err)
-	}
-
-	privBytes, err := gossh.MarshalPrivateKey(priv, "")
-	if err != nil {
-		return fmt.Errorf("failed to marshal private key: %w", err)
+		return err
 	}
-
-	pemBlock := pem.EncodeToMemory(privBytes)
-	if err := os.WriteFile(s.cfg.HostKeyPath, pemBlock, 0600); err != nil {
+	if err := os.WriteFile(s.cfg.HostKeyPath, key, 0600); err != nil {
 		return fmt.Errorf("failed to write host key: %w", err)
 	}
 
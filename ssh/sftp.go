@@ -167,23 +167,32 @@ func (w *configWriter) Close() error {
 		return fmt.Errorf("failed to parse config: %w", err)
 	}
 
-	if err := config.Validate(parsed); err != nil {
+	ctx := w.handler.session.Context()
+
+	defaults, err := w.handler.store.GetUserDefaults(ctx, w.handler.user.ID)
+	if err != nil {
+		return err
+	}
+	parsed.ApplyDefaults(configDefaults(defaults))
+
+	if err := config.Validate(parsed, w.handler.scheduler.FeedAccess().Policy()); err != nil {
 		return fmt.Errorf("invalid config: %w", err)
 	}
 
-	nextRun, err := calculateNextRun(parsed.CronExpr)
+	nextRun, err := calculateNextRun(w.handler.store, 0, parsed.CronExpr)
 	if err != nil {
 		return fmt.Errorf("failed to calculate next run: %w", err)
 	}
 
-	ctx := w.handler.session.Context()
-
 	// Try to get existing config
 	existingCfg, err := w.handler.store.GetConfig(ctx, w.handler.user.ID, w.filename)
 	var cfg *store.Config
 
 	if err == nil {
 		// Config exists - update it
+		if nextRun, err = calculateNextRun(w.handler.store, existingCfg.ID, parsed.CronExpr); err != nil {
+			return fmt.Errorf("failed to calculate next run: %w", err)
+		}
 		if err := w.handler.store.UpdateConfig(ctx, existingCfg.ID, parsed.Email, parsed.CronExpr, parsed.Digest, parsed.Inline, content, nextRun); err != nil {
 			return fmt.Errorf("failed to update config: %w", err)
 		}
@@ -195,7 +204,7 @@ func (w *configWriter) Close() error {
 		cfg.RawText = content
 
 		// Sync feeds: match by URL, update/delete/add as needed
-		existingFeeds, err := w.handler.store.GetFeedsByConfig(ctx, cfg.ID)
+		existingFeeds, err := w.handler.store.GetAllFeedsByConfig(ctx, cfg.ID)
 		if err != nil {
 			return fmt.Errorf("failed to get existing feeds: %w", err)
 		}
@@ -215,12 +224,12 @@ func (w *configWriter) Close() error {
 		for _, newFeed := range parsed.Feeds {
 			if existingFeed, exists := existingByURL[newFeed.URL]; exists {
 				// Feed still exists - update name if changed
-				if err := w.handler.store.UpdateFeed(ctx, existingFeed.ID, newFeed.Name); err != nil {
+				if err := w.handler.store.UpdateFeed(ctx, existingFeed.ID, newFeed.Name, feedOptions(newFeed)); err != nil {
 					return fmt.Errorf("failed to update feed: %w", err)
 				}
 			} else {
 				// New feed - create it and mark existing items as seen
-				newFeedRecord, err := w.handler.store.CreateFeed(ctx, cfg.ID, newFeed.URL, newFeed.Name)
+				newFeedRecord, err := w.handler.store.CreateFeed(ctx, cfg.ID, newFeed.URL, newFeed.Name, feedOptions(newFeed))
 				if err != nil {
 					return fmt.Errorf("failed to create feed: %w", err)
 				}
@@ -248,8 +257,18 @@ func (w *configWriter) Close() error {
 			return fmt.Errorf("failed to create config: %w", err)
 		}
 
+		// The schedule offset depends on the ID, which only exists now
+		if cfg.ScheduleOffset() > 0 {
+			if nextRun, err = calculateNextRun(w.handler.store, cfg.ID, parsed.CronExpr); err != nil {
+				return fmt.Errorf("failed to calculate next run: %w", err)
+			}
+			if err := w.handler.store.UpdateNextRun(ctx, cfg.ID, &nextRun); err != nil {
+				return fmt.Errorf("failed to update next run: %w", err)
+			}
+		}
+
 		for _, feed := range parsed.Feeds {
-			if _, err := w.handler.store.CreateFeed(ctx, cfg.ID, feed.URL, feed.Name); err != nil {
+			if _, err := w.handler.store.CreateFeed(ctx, cfg.ID, feed.URL, feed.Name, feedOptions(feed)); err != nil {
 				return fmt.Errorf("failed to create feed: %w", err)
 			}
 		}
@@ -257,6 +276,10 @@ func (w *configWriter) Close() error {
 		w.handler.logger.Debug("created new config via SFTP", "filename", w.filename)
 	}
 
+	if _, err := w.handler.store.SetConfigPublic(ctx, cfg.ID, parsed.Public); err != nil {
+		return fmt.Errorf("failed to update public archive: %w", err)
+	}
+
 	w.handler.logger.Info("config uploaded via SFTP", "user_id", w.handler.user.ID, "filename", w.filename, "feeds", len(parsed.Feeds))
 	return nil
 }
@@ -264,7 +287,7 @@ func (w *configWriter) Close() error {
 // preseedSeenItems fetches the feed and marks all current items as seen,
 // so that adding a new feed doesn't trigger emails for old posts.
 func (w *configWriter) preseedSeenItems(ctx context.Context, feed *store.Feed) error {
-	result := scheduler.FetchFeed(ctx, feed)
+	result := w.handler.scheduler.FetchFeedBackfill(ctx, feed)
 	if result.Error != nil {
 		return result.Error
 	}
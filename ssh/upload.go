@@ -0,0 +1,221 @@
+package ssh
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/kierank/herald/config"
+	"github.com/kierank/herald/scheduler"
+	"github.com/kierank/herald/store"
+)
+
+// maxConfigSize is the largest config accepted over SCP or upload
+const maxConfigSize = 1024 * 1024 // 1MB
+
+// saveConfig parses and validates config text, then creates or updates the
+// named config in one transaction. Feeds are synced by URL so existing feeds
+// keep their seen history and new feeds are pre-seeded.
+func saveConfig(ctx context.Context, st *store.DB, sched *scheduler.Scheduler, logger *log.Logger, userID int64, name string, content []byte) (*config.ParsedConfig, time.Time, error) {
+	parsed, err := config.Parse(string(content))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	defaults, err := st.GetUserDefaults(ctx, userID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	parsed.ApplyDefaults(configDefaults(defaults))
+
+	if err := config.Validate(parsed, sched.FeedAccess().Policy()); err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid config: %w", err)
+	}
+
+	// Validate feed URLs by attempting to fetch them
+	if err := config.ValidateFeedURLs(ctx, parsed, sched.FeedAccess()); err != nil {
+		return nil, time.Time{}, fmt.Errorf("feed validation failed: %w", err)
+	}
+
+	nextRun, err := calculateNextRun(st, 0, parsed.CronExpr)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to calculate next run: %w", err)
+	}
+
+	// Fetch items to pre-seed before the transaction, so slow feeds don't
+	// hold the database's write lock
+	seeds := fetchNewFeedSeeds(ctx, st, sched, logger, userID, name, parsed.Feeds)
+
+	// Use transaction for config update
+	tx, err := st.BeginTx(ctx)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Try to get existing config
+	existingCfg, err := st.GetConfigTx(ctx, tx, userID, name)
+	var cfg *store.Config
+
+	if err == nil {
+		// Config exists - update it
+		if nextRun, err = calculateNextRun(st, existingCfg.ID, parsed.CronExpr); err != nil {
+			return nil, time.Time{}, fmt.Errorf("failed to calculate next run: %w", err)
+		}
+		if err := st.UpdateConfigTx(ctx, tx, existingCfg.ID, parsed.Email, parsed.CronExpr, parsed.Digest, parsed.Inline, string(content), nextRun); err != nil {
+			return nil, time.Time{}, fmt.Errorf("failed to update config: %w", err)
+		}
+		cfg = existingCfg
+
+		// Sync feeds: match by URL, update/delete/add as needed
+		existingFeeds, err := st.GetFeedsByConfigTx(ctx, tx, cfg.ID)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("failed to get existing feeds: %w", err)
+		}
+
+		// Build maps for comparison
+		existingByURL := make(map[string]*store.Feed)
+		for _, f := range existingFeeds {
+			existingByURL[f.URL] = f
+		}
+
+		newByURL := make(map[string]struct{ URL, Name string })
+		for _, f := range parsed.Feeds {
+			newByURL[f.URL] = struct{ URL, Name string }{URL: f.URL, Name: f.Name}
+		}
+
+		// Update existing feeds that are still present
+		for _, newFeed := range parsed.Feeds {
+			if existingFeed, exists := existingByURL[newFeed.URL]; exists {
+				// Feed still exists - update name if changed
+				if err := st.UpdateFeedTx(ctx, tx, existingFeed.ID, newFeed.Name, feedOptions(newFeed)); err != nil {
+					return nil, time.Time{}, fmt.Errorf("failed to update feed: %w", err)
+				}
+			} else {
+				// New feed - create it and mark existing items as seen
+				newFeedRecord, err := st.CreateFeedTx(ctx, tx, cfg.ID, newFeed.URL, newFeed.Name, feedOptions(newFeed))
+				if err != nil {
+					return nil, time.Time{}, fmt.Errorf("failed to create feed: %w", err)
+				}
+				// Pre-seed seen items so we don't send old posts
+				if err := preseedSeenItems(ctx, st, tx, newFeedRecord.ID, seeds[newFeed.URL]); err != nil {
+					logger.Warn("failed to preseed seen items", "feed_url", newFeed.URL, "err", err)
+				}
+			}
+		}
+
+		// Delete feeds that are no longer present
+		for _, existingFeed := range existingFeeds {
+			if _, stillExists := newByURL[existingFeed.URL]; !stillExists {
+				if err := st.DeleteFeedTx(ctx, tx, existingFeed.ID); err != nil {
+					return nil, time.Time{}, fmt.Errorf("failed to delete feed: %w", err)
+				}
+			}
+		}
+
+		logger.Debug("updated existing config", "filename", name)
+	} else {
+		// Config doesn't exist - create new one
+		cfg, err = st.CreateConfigTx(ctx, tx, userID, name, parsed.Email, parsed.CronExpr, parsed.Digest, parsed.Inline, string(content), nextRun)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("failed to create config: %w", err)
+		}
+
+		// The schedule offset depends on the ID, which only exists now
+		if cfg.ScheduleOffset() > 0 {
+			if nextRun, err = calculateNextRun(st, cfg.ID, parsed.CronExpr); err != nil {
+				return nil, time.Time{}, fmt.Errorf("failed to calculate next run: %w", err)
+			}
+			if err := st.UpdateNextRunTx(ctx, tx, cfg.ID, nextRun); err != nil {
+				return nil, time.Time{}, fmt.Errorf("failed to update next run: %w", err)
+			}
+		}
+
+		for _, feed := range parsed.Feeds {
+			if _, err := st.CreateFeedTx(ctx, tx, cfg.ID, feed.URL, feed.Name, feedOptions(feed)); err != nil {
+				return nil, time.Time{}, fmt.Errorf("failed to create feed: %w", err)
+			}
+		}
+
+		logger.Debug("created new config", "filename", name)
+	}
+
+	if _, err := st.SetConfigPublicTx(ctx, tx, cfg.ID, parsed.Public); err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to update public archive: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, time.Time{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return parsed, nextRun, nil
+}
+
+// feedOptions maps a parsed feed line to its stored request settings
+func feedOptions(feed config.FeedEntry) store.FeedOptions {
+	return store.FeedOptions{
+		Headers:  feed.Headers,
+		Method:   feed.Method,
+		Body:     feed.Body,
+		Note:     feed.Note,
+		Proxy:    feed.Proxy,
+		Warmup:   feed.Warmup,
+		Disabled: feed.Disabled,
+	}
+}
+
+// fetchNewFeedSeeds fetches the current items of each feed the named config
+// doesn't have yet, keyed by URL. A new config gets no seeds, so its first
+// digest carries what its feeds have now. Feeds that fail to fetch are
+// logged and left out.
+func fetchNewFeedSeeds(ctx context.Context, st *store.DB, sched *scheduler.Scheduler, logger *log.Logger, userID int64, name string, feeds []config.FeedEntry) map[string][]scheduler.FetchedItem {
+	existingCfg, err := st.GetConfig(ctx, userID, name)
+	if err != nil {
+		return nil
+	}
+	existingFeeds, err := st.GetAllFeedsByConfig(ctx, existingCfg.ID)
+	if err != nil {
+		logger.Warn("failed to get existing feeds", "config_id", existingCfg.ID, "err", err)
+		return nil
+	}
+	existing := make(map[string]bool, len(existingFeeds))
+	for _, f := range existingFeeds {
+		existing[f.URL] = true
+	}
+
+	seeds := make(map[string][]scheduler.FetchedItem)
+	for _, feed := range feeds {
+		if existing[feed.URL] {
+			continue
+		}
+		opts := feedOptions(feed)
+		result := sched.FetchFeedBackfill(ctx, &store.Feed{
+			URL:     feed.URL,
+			Headers: opts.Headers,
+			Method:  opts.Method,
+			Body:    opts.Body,
+			Proxy:   opts.Proxy,
+			Warmup:  opts.Warmup,
+		})
+		if result.Error != nil {
+			logger.Warn("failed to preseed seen items", "feed_url", feed.URL, "err", result.Error)
+			continue
+		}
+		seeds[feed.URL] = result.Items
+		logger.Debug("fetched items to preseed for new feed", "feed_url", feed.URL, "count", len(result.Items))
+	}
+	return seeds
+}
+
+// preseedSeenItems marks a new feed's current items as seen, so that adding
+// it doesn't trigger emails for old posts
+func preseedSeenItems(ctx context.Context, st *store.DB, tx *sql.Tx, feedID int64, items []scheduler.FetchedItem) error {
+	for _, item := range items {
+		if err := st.MarkItemSeenTx(ctx, tx, feedID, item.GUID, item.Title, item.Link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
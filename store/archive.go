@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+// ArchivedDigest is a past digest rebuilt from its send time and the items
+// marked seen with it
+type ArchivedDigest struct {
+	SentAt time.Time
+	Items  []ArchivedItem
+}
+
+type ArchivedItem struct {
+	FeedName string
+	Title    string
+	Link     string
+}
+
+func newPublicSlug() (string, error) {
+	b := make([]byte, 9)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate slug: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// SetConfigPublicTx gives a config a public archive slug, keeping the one it
+// has so shared links stay stable, or removes it when public is false
+func (db *DB) SetConfigPublicTx(ctx context.Context, tx *sql.Tx, configID int64, public bool) (string, error) {
+	if !public {
+		if _, err := tx.ExecContext(ctx, `UPDATE configs SET public_slug = NULL WHERE id = ?`, configID); err != nil {
+			return "", fmt.Errorf("clear public slug: %w", err)
+		}
+		return "", nil
+	}
+
+	var slug sql.NullString
+	if err := tx.QueryRowContext(ctx, `SELECT public_slug FROM configs WHERE id = ?`, configID).Scan(&slug); err != nil {
+		return "", fmt.Errorf("get public slug: %w", err)
+	}
+	if slug.Valid {
+		return slug.String, nil
+	}
+
+	newSlug, err := newPublicSlug()
+	if err != nil {
+		return "", err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE configs SET public_slug = ? WHERE id = ?`, newSlug, configID); err != nil {
+		return "", fmt.Errorf("set public slug: %w", err)
+	}
+	return newSlug, nil
+}
+
+// SetConfigPublic is SetConfigPublicTx in a transaction of its own
+func (db *DB) SetConfigPublic(ctx context.Context, configID int64, public bool) (string, error) {
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		return "", fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	slug, err := db.SetConfigPublicTx(ctx, tx, configID, public)
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("commit transaction: %w", err)
+	}
+	return slug, nil
+}
+
+// GetConfigByPublicSlug returns the public config with the given archive slug
+func (db *DB) GetConfigByPublicSlug(ctx context.Context, slug string) (*Config, error) {
+	var cfg Config
+	err := db.QueryRowContext(ctx,
+		`SELECT `+configColumns+`
+		 FROM configs WHERE public_slug = ?`,
+		slug,
+	).Scan(db.configDest(&cfg)...)
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// GetDigestArchive rebuilds a config's most recent digests, newest first.
+// Items are matched to the send they were delivered in, so items seen
+// without a send, like those pre-seeded on upload, are left out, as are
+// sends whose items have since been pruned.
+func (db *DB) GetDigestArchive(ctx context.Context, configID int64, limit int) ([]*ArchivedDigest, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT e.id, e.sent_at, COALESCE(f.name, f.url), COALESCE(s.title, ''), COALESCE(s.link, '')
+		 FROM (SELECT id, sent_at FROM email_sends WHERE config_id = ? ORDER BY sent_at DESC, id DESC LIMIT ?) e
+		 JOIN seen_items s ON s.email_send_id = e.id
+		 JOIN feeds f ON f.id = s.feed_id
+		 ORDER BY e.sent_at DESC, e.id DESC, f.id, s.id`,
+		configID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query digest archive: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var digests []*ArchivedDigest
+	var lastSend int64
+	for rows.Next() {
+		var sendID int64
+		var sentAt time.Time
+		var item ArchivedItem
+		if err := rows.Scan(&sendID, &sentAt, &item.FeedName, &item.Title, &item.Link); err != nil {
+			return nil, fmt.Errorf("scan digest archive: %w", err)
+		}
+		if sendID != lastSend {
+			digests = append(digests, &ArchivedDigest{SentAt: sentAt})
+			lastSend = sendID
+		}
+		last := digests[len(digests)-1]
+		last.Items = append(last.Items, item)
+	}
+	return digests, rows.Err()
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDigestArchive(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, _ := db.CreateConfig(ctx, user.ID, "news.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now().Add(time.Hour))
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "Example", FeedOptions{})
+
+	// Pre-seeded long before any send
+	old := SeenItem{GUID: "old", SeenAt: time.Now().Add(-48 * time.Hour)}
+	if _, err := db.ImportSeenItems(ctx, user.ID, "news.txt", feed.URL, []SeenItem{old}); err != nil {
+		t.Fatalf("ImportSeenItems failed: %v", err)
+	}
+
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("begin tx: %v", err)
+	}
+	sendID, err := db.RecordEmailSendTx(tx, cfg.ID, "user@example.com", "feed digest", "", "", "")
+	if err != nil {
+		t.Fatalf("RecordEmailSendTx failed: %v", err)
+	}
+	_ = db.MarkItemDeliveredTx(ctx, tx, feed.ID, sendID, "a", "First post", "https://example.com/a")
+	_ = db.MarkItemDeliveredTx(ctx, tx, feed.ID, sendID, "b", "Second post", "https://example.com/b")
+	// Seen in the same transaction but filtered out of the digest
+	_ = db.MarkItemSeenTx(ctx, tx, feed.ID, "c", "Filtered post", "https://example.com/c")
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	digests, err := db.GetDigestArchive(ctx, cfg.ID, 10)
+	if err != nil {
+		t.Fatalf("GetDigestArchive failed: %v", err)
+	}
+	if len(digests) != 1 {
+		t.Fatalf("expected 1 digest, got %d", len(digests))
+	}
+	items := digests[0].Items
+	if len(items) != 2 || items[0].Title != "First post" || items[1].FeedName != "Example" {
+		t.Errorf("expected the two sent items, got %+v", items)
+	}
+	if digests[0].SentAt.IsZero() {
+		t.Error("expected a send time")
+	}
+}
+
+func TestSetConfigPublic(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, _ := db.CreateConfig(ctx, user.ID, "news.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now().Add(time.Hour))
+
+	slug, err := db.SetConfigPublic(ctx, cfg.ID, true)
+	if err != nil || slug == "" {
+		t.Fatalf("expected a slug, got %q, %v", slug, err)
+	}
+	if again, _ := db.SetConfigPublic(ctx, cfg.ID, true); again != slug {
+		t.Errorf("expected the slug to stay %q, got %q", slug, again)
+	}
+
+	found, err := db.GetConfigByPublicSlug(ctx, slug)
+	if err != nil || found.ID != cfg.ID || found.PublicSlug.String != slug {
+		t.Fatalf("expected config by slug, got %+v, %v", found, err)
+	}
+
+	if _, err := db.SetConfigPublic(ctx, cfg.ID, false); err != nil {
+		t.Fatalf("SetConfigPublic failed: %v", err)
+	}
+	if _, err := db.GetConfigByPublicSlug(ctx, slug); err == nil {
+		t.Error("expected the slug to be gone once the config is private")
+	}
+}
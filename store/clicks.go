@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrItemNotFound is returned when a click link doesn't match a sent item
+var ErrItemNotFound = errors.New("item not found")
+
+// ItemHash returns the short hash that identifies an item in click links
+func ItemHash(guid string) string {
+	sum := sha256.Sum256([]byte(guid))
+	return hex.EncodeToString(sum[:8])
+}
+
+// RecordItemClick records a click on an item in the digest sent with
+// trackingToken and returns the item's link. The item is one delivered in that
+// digest, which covers configs merged into it, or else one of the digest's
+// own config's items. A click also counts as an open.
+func (db *DB) RecordItemClick(ctx context.Context, trackingToken, itemHash string) (string, error) {
+	var sendID, configID int64
+	err := db.QueryRowContext(ctx,
+		`SELECT id, config_id FROM email_sends WHERE tracking_token = ?`,
+		trackingToken,
+	).Scan(&sendID, &configID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("tracking token not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("query email send: %w", err)
+	}
+
+	var (
+		feedID int64
+		guid   string
+		link   string
+	)
+	err = db.QueryRowContext(ctx,
+		`SELECT s.feed_id, s.guid, s.link
+		 FROM seen_items s
+		 JOIN feeds f ON f.id = s.feed_id
+		 WHERE s.guid_hash = ? AND s.link IS NOT NULL
+		 AND (s.email_send_id = ? OR f.config_id = ?)
+		 ORDER BY s.email_send_id IS ? DESC
+		 LIMIT 1`,
+		itemHash, sendID, configID, sendID,
+	).Scan(&feedID, &guid, &link)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrItemNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("query seen item: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx,
+		`INSERT INTO item_clicks (tracking_token, feed_id, guid) VALUES (?, ?, ?)`,
+		trackingToken, feedID, guid,
+	); err != nil {
+		return "", fmt.Errorf("insert item click: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx,
+		`UPDATE email_sends SET opened = TRUE, opened_at = CURRENT_TIMESTAMP
+		 WHERE tracking_token = ? AND opened = FALSE`,
+		trackingToken,
+	); err != nil {
+		return "", fmt.Errorf("update email opened: %w", err)
+	}
+
+	return link, nil
+}
+
+// CountItemClicks returns how many item clicks a config's digests got in the
+// last days
+func (db *DB) CountItemClicks(ctx context.Context, configID int64, days int) (int, error) {
+	var clicks int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM item_clicks c
+		 JOIN feeds f ON f.id = c.feed_id
+		 WHERE f.config_id = ?
+		 AND c.clicked_at > datetime('now', '-' || ? || ' days')`,
+		configID, days,
+	).Scan(&clicks)
+	if err != nil {
+		return 0, fmt.Errorf("count item clicks: %w", err)
+	}
+	return clicks, nil
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecordItemClick(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now().Add(time.Hour))
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", FeedOptions{})
+	_ = db.MarkItemSeen(ctx, feed.ID, "guid-a", "A", "https://example.com/a")
+	_ = db.MarkItemSeen(ctx, feed.ID, "guid-b", "B", "https://example.com/b")
+
+	token, err := db.RecordEmailSend(cfg.ID, "user@example.com", "feed digest", true)
+	if err != nil {
+		t.Fatalf("record email send: %v", err)
+	}
+
+	link, err := db.RecordItemClick(ctx, token, ItemHash("guid-b"))
+	if err != nil {
+		t.Fatalf("RecordItemClick failed: %v", err)
+	}
+	if link != "https://example.com/b" {
+		t.Errorf("expected link for guid-b, got %q", link)
+	}
+
+	if _, err := db.RecordItemClick(ctx, token, ItemHash("missing")); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("expected ErrItemNotFound, got %v", err)
+	}
+	if _, err := db.RecordItemClick(ctx, "bad-token", ItemHash("guid-a")); err == nil {
+		t.Error("expected error for unknown tracking token")
+	}
+
+	clicks, err := db.CountItemClicks(ctx, cfg.ID, 30)
+	if err != nil {
+		t.Fatalf("CountItemClicks failed: %v", err)
+	}
+	if clicks != 1 {
+		t.Errorf("expected 1 click, got %d", clicks)
+	}
+
+	_, opens, _, _, _ := db.GetConfigEngagement(cfg.ID, 30)
+	if opens != 1 {
+		t.Errorf("expected click to count as an open, got %d opens", opens)
+	}
+
+	// Resetting keeps clicked items seen so they never resurface
+	deleted, err := db.DeleteSeenItemsByConfig(ctx, cfg.ID)
+	if err != nil {
+		t.Fatalf("DeleteSeenItemsByConfig failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 deleted, got %d", deleted)
+	}
+	if seen, _ := db.IsItemSeen(ctx, feed.ID, "guid-b"); !seen {
+		t.Error("clicked item should stay seen after reset")
+	}
+}
+
+func TestRecordItemClickMergedItem(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	target, _ := db.CreateConfig(ctx, user.ID, "daily.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now().Add(time.Hour))
+	source, _ := db.CreateConfig(ctx, user.ID, "work.txt", "user@example.com", "0 9 * * *", true, false, "raw", time.Now().Add(time.Hour))
+	feed, _ := db.CreateFeed(ctx, source.ID, "https://example.com/work.xml", "", FeedOptions{})
+	other, _ := db.CreateFeed(ctx, source.ID, "https://example.com/other.xml", "", FeedOptions{})
+
+	// work.txt's item went out in daily.txt's digest
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	sendID, err := db.RecordEmailSendTx(tx, target.ID, "user@example.com", "feed digest", "merged-token", "", "")
+	if err != nil {
+		t.Fatalf("RecordEmailSendTx failed: %v", err)
+	}
+	if err := db.MarkItemDeliveredTx(ctx, tx, feed.ID, sendID, "guid-w", "W", "https://example.com/w"); err != nil {
+		t.Fatalf("MarkItemDeliveredTx failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	_ = db.MarkItemSeen(ctx, other.ID, "guid-x", "X", "https://example.com/x")
+
+	link, err := db.RecordItemClick(ctx, "merged-token", ItemHash("guid-w"))
+	if err != nil {
+		t.Fatalf("RecordItemClick failed: %v", err)
+	}
+	if link != "https://example.com/w" {
+		t.Errorf("expected link for the merged item, got %q", link)
+	}
+
+	// Items of the merged config that weren't in the digest stay unreachable
+	if _, err := db.RecordItemClick(ctx, "merged-token", ItemHash("guid-x")); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("expected ErrItemNotFound for an undelivered item, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// rawTextMagic is the gzip header, which never starts a plain text config, so
+// compressed and uncompressed raw_text rows can live side by side.
+const rawTextMagic = "\x1f\x8b"
+
+// SetCompressRawText toggles gzip compression of config raw_text on write.
+// Reads always detect compressed rows, so existing rows stay readable either
+// way and are rewritten in the new format on their next update.
+func (db *DB) SetCompressRawText(enabled bool) {
+	db.compressRawText = enabled
+}
+
+// encodeRawText returns the value to store for a config's raw text
+func (db *DB) encodeRawText(text string) any {
+	if !db.compressRawText {
+		return text
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(text)); err != nil {
+		return text
+	}
+	if err := zw.Close(); err != nil {
+		return text
+	}
+	return buf.Bytes()
+}
+
+// rawTextScanner decodes raw_text into a string, decompressing gzip rows
+type rawTextScanner struct {
+	dst *string
+}
+
+func (r rawTextScanner) Scan(src any) error {
+	var raw []byte
+	switch v := src.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	case nil:
+		*r.dst = ""
+		return nil
+	default:
+		return fmt.Errorf("unsupported raw_text type %T", src)
+	}
+
+	if !bytes.HasPrefix(raw, []byte(rawTextMagic)) {
+		*r.dst = string(raw)
+		return nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return fmt.Errorf("open compressed raw_text: %w", err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	text, err := io.ReadAll(zr)
+	if err != nil {
+		return fmt.Errorf("decompress raw_text: %w", err)
+	}
+	*r.dst = string(text)
+	return nil
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompressedRawTextRoundTrip(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	nextRun := time.Now().Add(time.Hour)
+	rawText := "=: email user@example.com\n" + strings.Repeat("=> https://example.com/feed.xml\n", 50)
+
+	// Rows written before compression was enabled must stay readable
+	plain, err := db.CreateConfig(ctx, user.ID, "plain.txt", "user@example.com", "0 8 * * *", true, false, rawText, nextRun)
+	if err != nil {
+		t.Fatalf("CreateConfig failed: %v", err)
+	}
+
+	db.SetCompressRawText(true)
+	compressed, err := db.CreateConfig(ctx, user.ID, "compressed.txt", "user@example.com", "0 8 * * *", true, false, rawText, nextRun)
+	if err != nil {
+		t.Fatalf("CreateConfig failed: %v", err)
+	}
+
+	var stored []byte
+	if err := db.QueryRowContext(ctx, `SELECT raw_text FROM configs WHERE id = ?`, compressed.ID).Scan(&stored); err != nil {
+		t.Fatalf("read raw_text: %v", err)
+	}
+	if !strings.HasPrefix(string(stored), rawTextMagic) || len(stored) >= len(rawText) {
+		t.Errorf("expected compressed raw_text, got %d bytes", len(stored))
+	}
+
+	for _, id := range []int64{plain.ID, compressed.ID} {
+		cfg, err := db.GetConfigByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetConfigByID failed: %v", err)
+		}
+		if cfg.RawText != rawText {
+			t.Errorf("config %d: raw text did not round-trip", id)
+		}
+	}
+}
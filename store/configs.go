@@ -3,7 +3,9 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/binary"
 	"fmt"
+	"hash/fnv"
 	"time"
 
 	"github.com/adhocore/gronx"
@@ -22,13 +24,112 @@ type Config struct {
 	NextRun       sql.NullTime
 	CreatedAt     time.Time
 	LastActiveAt  sql.NullTime
+	BoostInterval sql.NullInt64 // seconds
+	BoostUntil    sql.NullTime
+	// AdaptiveMultiplier runs the config on every Nth cron tick; 1 is normal
+	AdaptiveMultiplier int
+	// RetryAttempt counts retries of failed feeds in the current cycle; 0 is a regular run
+	RetryAttempt int
+	// PublicSlug names the config's public archive; it is set only while
+	// the config has public turned on
+	PublicSlug sql.NullString
+	// JitterWindow is the schedule jitter window of the DB the config came
+	// from; zero disables the schedule offset
+	JitterWindow time.Duration
+}
+
+// configColumns lists the configs columns in the order scanned by scanDest
+const configColumns = `id, user_id, filename, email, cron_expr, digest, inline_content, raw_text, last_run, next_run, created_at, last_active_at, boost_interval, boost_until, adaptive_multiplier, retry_attempt, public_slug`
+
+func (cfg *Config) scanDest() []any {
+	return []any{&cfg.ID, &cfg.UserID, &cfg.Filename, &cfg.Email, &cfg.CronExpr, &cfg.Digest, &cfg.InlineContent, rawTextScanner{&cfg.RawText}, &cfg.LastRun, &cfg.NextRun, &cfg.CreatedAt, &cfg.LastActiveAt, &cfg.BoostInterval, &cfg.BoostUntil, &cfg.AdaptiveMultiplier, &cfg.RetryAttempt, &cfg.PublicSlug}
+}
+
+// Boosted reports whether a temporary schedule boost is in effect at t
+func (cfg *Config) Boosted(t time.Time) bool {
+	return cfg.BoostInterval.Valid && cfg.BoostInterval.Int64 > 0 &&
+		cfg.BoostUntil.Valid && t.Before(cfg.BoostUntil.Time)
+}
+
+// configDest is scanDest for a config loaded from db, which also stamps it
+// with db's schedule jitter window
+func (db *DB) configDest(cfg *Config) []any {
+	cfg.JitterWindow = db.scheduleJitter
+	return cfg.scanDest()
+}
+
+// SetScheduleJitter spreads cron runs over a window of the given width so
+// configs sharing a schedule don't all send in the same tick. Zero disables
+// it. It applies to configs loaded after the call.
+func (db *DB) SetScheduleJitter(window time.Duration) {
+	db.scheduleJitter = window
+	if r := db.ReadOnly(); r != db {
+		r.scheduleJitter = window
+	}
+}
+
+// ScheduleJitter returns the window set with SetScheduleJitter
+func (db *DB) ScheduleJitter() time.Duration {
+	return db.scheduleJitter
+}
+
+// ScheduleOffset is the config's stable delay within its jitter window,
+// derived from a hash of its ID so every run lands on the same offset
+func (cfg *Config) ScheduleOffset() time.Duration {
+	window := int64(cfg.JitterWindow / time.Minute)
+	if window <= 0 || cfg.ID <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	_ = binary.Write(h, binary.LittleEndian, cfg.ID)
+	return time.Duration(h.Sum64()%uint64(window)) * time.Minute
+}
+
+// NextCronTick returns the next cron tick after t, shifted by the schedule offset
+func (cfg *Config) NextCronTick(t time.Time) (time.Time, error) {
+	return cfg.cronTickAfter(t, true)
+}
+
+func (cfg *Config) cronTickAfter(t time.Time, inclusive bool) (time.Time, error) {
+	offset := cfg.ScheduleOffset()
+	next, err := gronx.NextTickAfter(cfg.CronExpr, t.Add(-offset), inclusive)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return next.Add(offset), nil
+}
+
+// NextRunAfter calculates the next run from the cron expression, skipping
+// ticks for an adaptive multiplier and pulled in by an active boost. Once the
+// boost expires the cron schedule takes over.
+func (cfg *Config) NextRunAfter(t time.Time) (time.Time, error) {
+	next, err := cfg.cronTickAfter(t, true)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for i := 1; i < cfg.AdaptiveMultiplier; i++ {
+		next, err = cfg.cronTickAfter(next, false)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if cfg.Boosted(t) {
+		boosted := t.Add(time.Duration(cfg.BoostInterval.Int64) * time.Second)
+		if boosted.Before(next) && !boosted.After(cfg.BoostUntil.Time) {
+			next = boosted
+		}
+	}
+
+	return next, nil
 }
 
 func (db *DB) CreateConfig(ctx context.Context, userID int64, filename, email, cronExpr string, digest, inline bool, rawText string, nextRun time.Time) (*Config, error) {
 	result, err := db.ExecContext(ctx,
 		`INSERT INTO configs (user_id, filename, email, cron_expr, digest, inline_content, raw_text, next_run)
 		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		userID, filename, email, cronExpr, digest, inline, rawText, nextRun,
+		userID, filename, email, cronExpr, digest, inline, db.encodeRawText(rawText), nextRun,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("insert config: %w", err)
@@ -50,6 +151,9 @@ func (db *DB) CreateConfig(ctx context.Context, userID int64, filename, email, c
 		RawText:       rawText,
 		NextRun:       sql.NullTime{Time: nextRun, Valid: true},
 		CreatedAt:     time.Now().UTC(),
+		JitterWindow:  db.scheduleJitter,
+
+		AdaptiveMultiplier: 1,
 	}, nil
 }
 
@@ -57,7 +161,7 @@ func (db *DB) CreateConfigTx(ctx context.Context, tx *sql.Tx, userID int64, file
 	result, err := tx.ExecContext(ctx,
 		`INSERT INTO configs (user_id, filename, email, cron_expr, digest, inline_content, raw_text, next_run)
 		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		userID, filename, email, cronExpr, digest, inline, rawText, nextRun,
+		userID, filename, email, cronExpr, digest, inline, db.encodeRawText(rawText), nextRun,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("insert config: %w", err)
@@ -79,13 +183,16 @@ func (db *DB) CreateConfigTx(ctx context.Context, tx *sql.Tx, userID int64, file
 		RawText:       rawText,
 		NextRun:       sql.NullTime{Time: nextRun, Valid: true},
 		CreatedAt:     time.Now().UTC(),
+		JitterWindow:  db.scheduleJitter,
+
+		AdaptiveMultiplier: 1,
 	}, nil
 }
 
 func (db *DB) UpdateConfigTx(ctx context.Context, tx *sql.Tx, configID int64, email, cronExpr string, digest, inline bool, rawText string, nextRun time.Time) error {
 	_, err := tx.ExecContext(ctx,
 		`UPDATE configs SET email = ?, cron_expr = ?, digest = ?, inline_content = ?, raw_text = ?, next_run = ? WHERE id = ?`,
-		email, cronExpr, digest, inline, rawText, nextRun, configID,
+		email, cronExpr, digest, inline, db.encodeRawText(rawText), nextRun, configID,
 	)
 	if err != nil {
 		return fmt.Errorf("update config: %w", err)
@@ -93,6 +200,17 @@ func (db *DB) UpdateConfigTx(ctx context.Context, tx *sql.Tx, configID int64, em
 	return nil
 }
 
+func (db *DB) UpdateNextRunTx(ctx context.Context, tx *sql.Tx, configID int64, nextRun time.Time) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE configs SET next_run = ? WHERE id = ?`,
+		nextRun, configID,
+	)
+	if err != nil {
+		return fmt.Errorf("update next run: %w", err)
+	}
+	return nil
+}
+
 func (db *DB) DeleteConfigTx(ctx context.Context, tx *sql.Tx, userID int64, filename string) error {
 	result, err := tx.ExecContext(ctx,
 		`DELETE FROM configs WHERE user_id = ? AND filename = ?`,
@@ -114,7 +232,7 @@ func (db *DB) DeleteConfigTx(ctx context.Context, tx *sql.Tx, userID int64, file
 
 func (db *DB) GetConfig(ctx context.Context, userID int64, filename string) (*Config, error) {
 	var cfg Config
-	err := db.stmts.getConfig.QueryRowContext(ctx, userID, filename).Scan(&cfg.ID, &cfg.UserID, &cfg.Filename, &cfg.Email, &cfg.CronExpr, &cfg.Digest, &cfg.InlineContent, &cfg.RawText, &cfg.LastRun, &cfg.NextRun, &cfg.CreatedAt, &cfg.LastActiveAt)
+	err := db.stmts.getConfig.QueryRowContext(ctx, userID, filename).Scan(db.configDest(&cfg)...)
 	if err != nil {
 		return nil, err
 	}
@@ -124,10 +242,10 @@ func (db *DB) GetConfig(ctx context.Context, userID int64, filename string) (*Co
 func (db *DB) GetConfigTx(ctx context.Context, tx *sql.Tx, userID int64, filename string) (*Config, error) {
 	var cfg Config
 	err := tx.QueryRowContext(ctx,
-		`SELECT id, user_id, filename, email, cron_expr, digest, inline_content, raw_text, last_run, next_run, created_at, last_active_at
+		`SELECT `+configColumns+`
 		 FROM configs WHERE user_id = ? AND filename = ?`,
 		userID, filename,
-	).Scan(&cfg.ID, &cfg.UserID, &cfg.Filename, &cfg.Email, &cfg.CronExpr, &cfg.Digest, &cfg.InlineContent, &cfg.RawText, &cfg.LastRun, &cfg.NextRun, &cfg.CreatedAt, &cfg.LastActiveAt)
+	).Scan(db.configDest(&cfg)...)
 	if err != nil {
 		return nil, err
 	}
@@ -137,10 +255,10 @@ func (db *DB) GetConfigTx(ctx context.Context, tx *sql.Tx, userID int64, filenam
 func (db *DB) GetConfigByID(ctx context.Context, id int64) (*Config, error) {
 	var cfg Config
 	err := db.QueryRowContext(ctx,
-		`SELECT id, user_id, filename, email, cron_expr, digest, inline_content, raw_text, last_run, next_run, created_at, last_active_at
+		`SELECT `+configColumns+`
 		 FROM configs WHERE id = ?`,
 		id,
-	).Scan(&cfg.ID, &cfg.UserID, &cfg.Filename, &cfg.Email, &cfg.CronExpr, &cfg.Digest, &cfg.InlineContent, &cfg.RawText, &cfg.LastRun, &cfg.NextRun, &cfg.CreatedAt, &cfg.LastActiveAt)
+	).Scan(db.configDest(&cfg)...)
 	if err != nil {
 		return nil, err
 	}
@@ -149,7 +267,7 @@ func (db *DB) GetConfigByID(ctx context.Context, id int64) (*Config, error) {
 
 func (db *DB) ListConfigs(ctx context.Context, userID int64) ([]*Config, error) {
 	rows, err := db.QueryContext(ctx,
-		`SELECT id, user_id, filename, email, cron_expr, digest, inline_content, raw_text, last_run, next_run, created_at, last_active_at
+		`SELECT `+configColumns+`
 		 FROM configs WHERE user_id = ? ORDER BY filename`,
 		userID,
 	)
@@ -161,7 +279,7 @@ func (db *DB) ListConfigs(ctx context.Context, userID int64) ([]*Config, error)
 	var configs []*Config
 	for rows.Next() {
 		var cfg Config
-		if err := rows.Scan(&cfg.ID, &cfg.UserID, &cfg.Filename, &cfg.Email, &cfg.CronExpr, &cfg.Digest, &cfg.InlineContent, &cfg.RawText, &cfg.LastRun, &cfg.NextRun, &cfg.CreatedAt, &cfg.LastActiveAt); err != nil {
+		if err := rows.Scan(db.configDest(&cfg)...); err != nil {
 			return nil, fmt.Errorf("scan config: %w", err)
 		}
 		configs = append(configs, &cfg)
@@ -172,7 +290,7 @@ func (db *DB) ListConfigs(ctx context.Context, userID int64) ([]*Config, error)
 func (db *DB) UpdateConfig(ctx context.Context, configID int64, email, cronExpr string, digest, inline bool, rawText string, nextRun time.Time) error {
 	_, err := db.ExecContext(ctx,
 		`UPDATE configs SET email = ?, cron_expr = ?, digest = ?, inline_content = ?, raw_text = ?, next_run = ? WHERE id = ?`,
-		email, cronExpr, digest, inline, rawText, nextRun, configID,
+		email, cronExpr, digest, inline, db.encodeRawText(rawText), nextRun, configID,
 	)
 	if err != nil {
 		return fmt.Errorf("update config: %w", err)
@@ -180,6 +298,18 @@ func (db *DB) UpdateConfig(ctx context.Context, configID int64, email, cronExpr
 	return nil
 }
 
+// UpdateConfigEmail changes a config's recipient along with its stored text
+func (db *DB) UpdateConfigEmail(ctx context.Context, configID int64, email, rawText string) error {
+	_, err := db.ExecContext(ctx,
+		`UPDATE configs SET email = ?, raw_text = ? WHERE id = ?`,
+		email, db.encodeRawText(rawText), configID,
+	)
+	if err != nil {
+		return fmt.Errorf("update config email: %w", err)
+	}
+	return nil
+}
+
 func (db *DB) DeleteConfig(ctx context.Context, userID int64, filename string) error {
 	result, err := db.ExecContext(ctx,
 		`DELETE FROM configs WHERE user_id = ? AND filename = ?`,
@@ -209,7 +339,7 @@ func (db *DB) UpdateLastRun(ctx context.Context, configID int64, lastRun, nextRu
 
 func (db *DB) GetDueConfigs(ctx context.Context, now time.Time) ([]*Config, error) {
 	rows, err := db.QueryContext(ctx,
-		`SELECT id, user_id, filename, email, cron_expr, digest, inline_content, raw_text, last_run, next_run, created_at, last_active_at
+		`SELECT `+configColumns+`
 		 FROM configs WHERE next_run IS NOT NULL AND next_run <= ? ORDER BY next_run`,
 		now,
 	)
@@ -221,7 +351,7 @@ func (db *DB) GetDueConfigs(ctx context.Context, now time.Time) ([]*Config, erro
 	var configs []*Config
 	for rows.Next() {
 		var cfg Config
-		if err := rows.Scan(&cfg.ID, &cfg.UserID, &cfg.Filename, &cfg.Email, &cfg.CronExpr, &cfg.Digest, &cfg.InlineContent, &cfg.RawText, &cfg.LastRun, &cfg.NextRun, &cfg.CreatedAt, &cfg.LastActiveAt); err != nil {
+		if err := rows.Scan(db.configDest(&cfg)...); err != nil {
 			return nil, fmt.Errorf("scan config: %w", err)
 		}
 		configs = append(configs, &cfg)
@@ -229,6 +359,20 @@ func (db *DB) GetDueConfigs(ctx context.Context, now time.Time) ([]*Config, erro
 	return configs, rows.Err()
 }
 
+// CountOverdueConfigs counts active configs whose next run is more than
+// threshold in the past, i.e. the scheduler is falling behind.
+func (db *DB) CountOverdueConfigs(ctx context.Context, threshold time.Duration) (int, error) {
+	var count int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM configs WHERE next_run IS NOT NULL AND next_run < ?`,
+		time.Now().UTC().Add(-threshold),
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count overdue configs: %w", err)
+	}
+	return count, nil
+}
+
 func (db *DB) DeactivateConfig(ctx context.Context, configID int64) error {
 	_, err := db.ExecContext(ctx,
 		`UPDATE configs SET next_run = NULL WHERE id = ?`,
@@ -254,7 +398,7 @@ func (db *DB) ActivateConfig(ctx context.Context, userID int64, filename string)
 		return err
 	}
 
-	nextRun, err := gronx.NextTickAfter(cfg.CronExpr, time.Now().UTC(), true)
+	nextRun, err := cfg.NextCronTick(time.Now().UTC())
 	if err != nil {
 		return fmt.Errorf("calculate next run: %w", err)
 	}
@@ -289,3 +433,75 @@ func (db *DB) UpdateNextRun(ctx context.Context, configID int64, nextRun *time.T
 	}
 	return nil
 }
+
+// SetRetryAttempt records which retry of failed feeds the next run is; 0
+// means the next run is a regular one
+func (db *DB) SetRetryAttempt(ctx context.Context, configID int64, attempt int) error {
+	_, err := db.ExecContext(ctx,
+		`UPDATE configs SET retry_attempt = ? WHERE id = ?`,
+		attempt, configID,
+	)
+	if err != nil {
+		return fmt.Errorf("set retry attempt: %w", err)
+	}
+	return nil
+}
+
+// SetAdaptiveMultiplier sets how many cron ticks pass between runs and the
+// next run that results from it
+func (db *DB) SetAdaptiveMultiplier(ctx context.Context, configID int64, multiplier int, nextRun time.Time) error {
+	_, err := db.ExecContext(ctx,
+		`UPDATE configs SET adaptive_multiplier = ?, next_run = ? WHERE id = ?`,
+		multiplier, nextRun, configID,
+	)
+	if err != nil {
+		return fmt.Errorf("set adaptive multiplier: %w", err)
+	}
+	return nil
+}
+
+// ListActiveConfigs returns every config that has a next run scheduled
+func (db *DB) ListActiveConfigs(ctx context.Context) ([]*Config, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT `+configColumns+`
+		 FROM configs WHERE next_run IS NOT NULL ORDER BY id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query active configs: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var configs []*Config
+	for rows.Next() {
+		var cfg Config
+		if err := rows.Scan(db.configDest(&cfg)...); err != nil {
+			return nil, fmt.Errorf("scan config: %w", err)
+		}
+		configs = append(configs, &cfg)
+	}
+	return configs, rows.Err()
+}
+
+// SetBoost temporarily runs a config every interval until the given time
+func (db *DB) SetBoost(ctx context.Context, configID int64, interval time.Duration, until, nextRun time.Time) error {
+	_, err := db.ExecContext(ctx,
+		`UPDATE configs SET boost_interval = ?, boost_until = ?, next_run = ? WHERE id = ?`,
+		int64(interval/time.Second), until, nextRun, configID,
+	)
+	if err != nil {
+		return fmt.Errorf("set boost: %w", err)
+	}
+	return nil
+}
+
+// ClearBoost removes a config's schedule boost
+func (db *DB) ClearBoost(ctx context.Context, configID int64) error {
+	_, err := db.ExecContext(ctx,
+		`UPDATE configs SET boost_interval = NULL, boost_until = NULL WHERE id = ?`,
+		configID,
+	)
+	if err != nil {
+		return fmt.Errorf("clear boost: %w", err)
+	}
+	return nil
+}
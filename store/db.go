@@ -4,15 +4,27 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type DB struct {
 	*sql.DB
-	stmts *preparedStmts
+	stmts           *preparedStmts
+	compressRawText bool
+	// scheduleJitter is stamped on loaded configs as their JitterWindow
+	scheduleJitter time.Duration
+
+	// reader is a read-only pool for queries that shouldn't wait behind the
+	// single writer connection; it is the DB itself for in-memory databases
+	reader *DB
 }
 
+// defaultReadConns is the size of the read-only connection pool
+const defaultReadConns = 4
+
 type preparedStmts struct {
 	markItemSeen     *sql.Stmt
 	isItemSeen       *sql.Stmt
@@ -46,96 +58,65 @@ func Open(path string) (*DB, error) {
 		return nil, fmt.Errorf("prepare statements: %w", err)
 	}
 
+	reader, err := openReader(path)
+	if err != nil {
+		_ = store.Close()
+		return nil, err
+	}
+	if reader == nil {
+		reader = store
+	}
+	store.reader = reader
+
 	return store, nil
 }
 
-func (db *DB) migrate() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY,
-		pubkey_fp TEXT UNIQUE NOT NULL,
-		pubkey TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS configs (
-		id INTEGER PRIMARY KEY,
-		user_id INTEGER NOT NULL REFERENCES users(id),
-		filename TEXT NOT NULL,
-		email TEXT NOT NULL,
-		cron_expr TEXT NOT NULL,
-		digest BOOLEAN DEFAULT TRUE,
-		inline_content BOOLEAN DEFAULT FALSE,
-		raw_text TEXT NOT NULL,
-		last_run DATETIME,
-		next_run DATETIME,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		last_active_at DATETIME,
-		UNIQUE(user_id, filename)
-	);
-
-	CREATE TABLE IF NOT EXISTS feeds (
-		id INTEGER PRIMARY KEY,
-		config_id INTEGER NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
-		url TEXT NOT NULL,
-		name TEXT,
-		last_fetched DATETIME,
-		etag TEXT,
-		last_modified TEXT
-	);
-
-	CREATE TABLE IF NOT EXISTS seen_items (
-		id INTEGER PRIMARY KEY,
-		feed_id INTEGER NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
-		guid TEXT NOT NULL,
-		title TEXT,
-		link TEXT,
-		seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		UNIQUE(feed_id, guid)
-	);
-
-	CREATE TABLE IF NOT EXISTS logs (
-		id INTEGER PRIMARY KEY,
-		config_id INTEGER NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
-		message TEXT NOT NULL,
-		level TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS unsubscribe_tokens (
-		id INTEGER PRIMARY KEY,
-		token TEXT UNIQUE NOT NULL,
-		config_id INTEGER NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS email_sends (
-		id INTEGER PRIMARY KEY,
-		config_id INTEGER NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
-		recipient TEXT NOT NULL,
-		subject TEXT NOT NULL,
-		tracking_token TEXT UNIQUE,
-		sent_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		bounced BOOLEAN DEFAULT FALSE,
-		bounce_reason TEXT,
-		opened BOOLEAN DEFAULT FALSE,
-		opened_at DATETIME
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_configs_user_id ON configs(user_id);
-	CREATE INDEX IF NOT EXISTS idx_configs_active_next_run ON configs(next_run) WHERE next_run IS NOT NULL;
-	CREATE INDEX IF NOT EXISTS idx_feeds_config_id ON feeds(config_id);
-	CREATE INDEX IF NOT EXISTS idx_seen_items_feed_id ON seen_items(feed_id);
-	CREATE INDEX IF NOT EXISTS idx_logs_config_id ON logs(config_id);
-	CREATE INDEX IF NOT EXISTS idx_logs_created_at ON logs(created_at);
-	CREATE INDEX IF NOT EXISTS idx_unsubscribe_tokens_token ON unsubscribe_tokens(token);
-	CREATE INDEX IF NOT EXISTS idx_email_sends_config_id ON email_sends(config_id);
-	CREATE INDEX IF NOT EXISTS idx_email_sends_tracking_token ON email_sends(tracking_token);
-	CREATE INDEX IF NOT EXISTS idx_email_sends_sent_at ON email_sends(sent_at);
-	`
-
-	_, err := db.Exec(schema)
-	return err
+// openReader opens a read-only pool on the same database file. In-memory
+// databases can't be shared between connections, so it returns nil for them.
+func openReader(path string) (*DB, error) {
+	if path == ":memory:" || strings.Contains(path, "mode=memory") {
+		return nil, nil
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro&_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("open read-only database: %w", err)
+	}
+	db.SetMaxOpenConns(defaultReadConns)
+	db.SetMaxIdleConns(defaultReadConns)
+
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("ping read-only database: %w", err)
+	}
+
+	reader := &DB{DB: db}
+	if err := reader.prepareStatements(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("prepare read-only statements: %w", err)
+	}
+	reader.reader = reader
+	return reader, nil
+}
+
+// ReadOnly returns a handle whose queries use the read-only pool. Writes
+// through it fail, so use it only for pages and reports that never write.
+func (db *DB) ReadOnly() *DB {
+	if db.reader == nil {
+		return db
+	}
+	return db.reader
+}
+
+// SetReadConns sets the maximum number of read-only connections
+func (db *DB) SetReadConns(n int) {
+	if n <= 0 {
+		n = defaultReadConns
+	}
+	if r := db.ReadOnly(); r != db {
+		r.SetMaxOpenConns(n)
+		r.SetMaxIdleConns(n)
+	}
 }
 
 func (db *DB) Close() error {
@@ -148,6 +129,9 @@ func (db *DB) Close() error {
 		_ = db.stmts.updateFeedMeta.Close()
 		_ = db.stmts.cleanupSeenItems.Close()
 	}
+	if db.reader != nil && db.reader != db {
+		_ = db.reader.Close()
+	}
 	return db.DB.Close()
 }
 
@@ -157,7 +141,7 @@ func (db *DB) prepareStatements() error {
 	var err error
 
 	db.stmts.markItemSeen, err = db.Prepare(
-		`INSERT INTO seen_items (feed_id, guid, title, link) VALUES (?, ?, ?, ?)
+		`INSERT INTO seen_items (feed_id, guid, title, link, guid_hash) VALUES (?, ?, ?, ?, ?)
 		 ON CONFLICT(feed_id, guid) DO UPDATE SET title = excluded.title, link = excluded.link`)
 	if err != nil {
 		return fmt.Errorf("prepare markItemSeen: %w", err)
@@ -177,7 +161,7 @@ func (db *DB) prepareStatements() error {
 	}
 
 	db.stmts.getConfig, err = db.Prepare(
-		`SELECT id, user_id, filename, email, cron_expr, digest, inline_content, raw_text, last_run, next_run, created_at, last_active_at
+		`SELECT ` + configColumns + `
 		 FROM configs WHERE user_id = ? AND filename = ?`)
 	if err != nil {
 		return fmt.Errorf("prepare getConfig: %w", err)
@@ -190,7 +174,7 @@ func (db *DB) prepareStatements() error {
 	}
 
 	db.stmts.updateFeedMeta, err = db.Prepare(
-		`UPDATE feeds SET last_fetched = ?, etag = ?, last_modified = ? WHERE id = ?`)
+		`UPDATE feeds SET last_fetched = ?, etag = ?, last_modified = ?, last_error = NULL WHERE id = ?`)
 	if err != nil {
 		return fmt.Errorf("prepare updateFeedMeta: %w", err)
 	}
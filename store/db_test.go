@@ -3,6 +3,9 @@ package store
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -111,6 +114,57 @@ func TestGetUserByID(t *testing.T) {
 	}
 }
 
+func TestSetUserEmailRateLimit(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	created, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	if created.EmailRateLimit != 0 {
+		t.Errorf("expected default rate limit 0, got %d", created.EmailRateLimit)
+	}
+
+	if err := db.SetUserEmailRateLimit(ctx, "test-fp", 5); err != nil {
+		t.Fatalf("SetUserEmailRateLimit failed: %v", err)
+	}
+	user, _ := db.GetUserByID(ctx, created.ID)
+	if user.EmailRateLimit != 5 {
+		t.Errorf("expected rate limit 5, got %d", user.EmailRateLimit)
+	}
+
+	if err := db.SetUserEmailRateLimit(ctx, "missing-fp", 5); err != sql.ErrNoRows {
+		t.Errorf("expected sql.ErrNoRows for unknown user, got %v", err)
+	}
+	if err := db.SetUserEmailRateLimit(ctx, "test-fp", -1); err == nil {
+		t.Error("expected error for negative rate limit")
+	}
+}
+
+func TestUserDefaults(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	defaults, err := db.GetUserDefaults(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserDefaults failed: %v", err)
+	}
+	if defaults.CronExpr.Valid || defaults.Digest.Valid || defaults.Inline.Valid {
+		t.Errorf("expected no defaults for a new user, got %+v", defaults)
+	}
+
+	want := UserDefaults{
+		CronExpr: sql.NullString{String: "0 8 * * *", Valid: true},
+		Digest:   sql.NullBool{Bool: false, Valid: true},
+	}
+	if err := db.SetUserDefaults(ctx, user.ID, want); err != nil {
+		t.Fatalf("SetUserDefaults failed: %v", err)
+	}
+	defaults, _ = db.GetUserDefaults(ctx, user.ID)
+	if defaults != want {
+		t.Errorf("expected %+v, got %+v", want, defaults)
+	}
+}
+
 func TestDeleteUser(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
@@ -206,6 +260,29 @@ func TestGetConfig(t *testing.T) {
 	}
 }
 
+func TestUpdateConfigEmail(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	created, _ := db.CreateConfig(ctx, user.ID, "test.txt", "old@example.com", "0 8 * * *", true, false, "=: email old@example.com", time.Now().Add(time.Hour))
+
+	if err := db.UpdateConfigEmail(ctx, created.ID, "new@example.com", "=: email new@example.com"); err != nil {
+		t.Fatalf("UpdateConfigEmail failed: %v", err)
+	}
+
+	cfg, err := db.GetConfigByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetConfigByID failed: %v", err)
+	}
+	if cfg.Email != "new@example.com" || cfg.RawText != "=: email new@example.com" {
+		t.Errorf("expected updated email and text, got %q and %q", cfg.Email, cfg.RawText)
+	}
+	if cfg.CronExpr != "0 8 * * *" || !cfg.NextRun.Valid {
+		t.Errorf("expected schedule untouched, got %q %v", cfg.CronExpr, cfg.NextRun)
+	}
+}
+
 func TestDeleteConfig(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
@@ -225,6 +302,26 @@ func TestDeleteConfig(t *testing.T) {
 	}
 }
 
+func TestCountOverdueConfigs(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	_, _ = db.CreateConfig(ctx, user.ID, "late.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now().Add(-time.Hour))
+	_, _ = db.CreateConfig(ctx, user.ID, "due.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now().Add(-time.Minute))
+	_, _ = db.CreateConfig(ctx, user.ID, "future.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now().Add(time.Hour))
+	inactive, _ := db.CreateConfig(ctx, user.ID, "off.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now().Add(-time.Hour))
+	_ = db.DeactivateConfig(ctx, inactive.ID)
+
+	count, err := db.CountOverdueConfigs(ctx, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("CountOverdueConfigs failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 overdue config, got %d", count)
+	}
+}
+
 func TestCreateFeed(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
@@ -233,7 +330,7 @@ func TestCreateFeed(t *testing.T) {
 	nextRun := time.Now().Add(time.Hour)
 	cfg, _ := db.CreateConfig(ctx, user.ID, "test.herald", "user@example.com", "0 8 * * *", true, false, "raw", nextRun)
 
-	feed, err := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "Example Feed")
+	feed, err := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "Example Feed", FeedOptions{})
 	if err != nil {
 		t.Fatalf("CreateFeed failed: %v", err)
 	}
@@ -246,6 +343,56 @@ func TestCreateFeed(t *testing.T) {
 	}
 }
 
+func TestFeedNote(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.herald", "user@example.com", "0 8 * * *", true, false, "raw", time.Now())
+
+	feed, err := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", FeedOptions{Note: "Must read"})
+	if err != nil {
+		t.Fatalf("CreateFeed failed: %v", err)
+	}
+	feeds, _ := db.GetFeedsByConfig(ctx, cfg.ID)
+	if len(feeds) != 1 || feeds[0].Note != "Must read" {
+		t.Fatalf("expected stored note, got %+v", feeds)
+	}
+
+	if err := db.UpdateFeed(ctx, feed.ID, "", FeedOptions{}); err != nil {
+		t.Fatalf("UpdateFeed failed: %v", err)
+	}
+	feeds, _ = db.GetFeedsByConfig(ctx, cfg.ID)
+	if feeds[0].Note != "" {
+		t.Errorf("expected note to be cleared, got %q", feeds[0].Note)
+	}
+}
+
+func TestFeedProxy(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.herald", "user@example.com", "0 8 * * *", true, false, "raw", time.Now())
+
+	feed, err := db.CreateFeed(ctx, cfg.ID, "http://example.onion/feed", "", FeedOptions{Proxy: "socks5://127.0.0.1:9050"})
+	if err != nil {
+		t.Fatalf("CreateFeed failed: %v", err)
+	}
+	feeds, _ := db.GetFeedsByConfig(ctx, cfg.ID)
+	if len(feeds) != 1 || feeds[0].Proxy != "socks5://127.0.0.1:9050" {
+		t.Fatalf("expected stored proxy, got %+v", feeds)
+	}
+
+	if err := db.UpdateFeed(ctx, feed.ID, "", FeedOptions{}); err != nil {
+		t.Fatalf("UpdateFeed failed: %v", err)
+	}
+	feeds, _ = db.GetFeedsByConfig(ctx, cfg.ID)
+	if feeds[0].Proxy != "" {
+		t.Errorf("expected proxy to be cleared, got %q", feeds[0].Proxy)
+	}
+}
+
 func TestGetFeedsByConfig(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
@@ -253,8 +400,8 @@ func TestGetFeedsByConfig(t *testing.T) {
 	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
 	nextRun := time.Now().Add(time.Hour)
 	cfg, _ := db.CreateConfig(ctx, user.ID, "test.herald", "user@example.com", "0 8 * * *", true, false, "raw", nextRun)
-	_, _ = db.CreateFeed(ctx, cfg.ID, "https://feed1.com/rss", "Feed 1")
-	_, _ = db.CreateFeed(ctx, cfg.ID, "https://feed2.com/atom", "Feed 2")
+	_, _ = db.CreateFeed(ctx, cfg.ID, "https://feed1.com/rss", "Feed 1", FeedOptions{})
+	_, _ = db.CreateFeed(ctx, cfg.ID, "https://feed2.com/atom", "Feed 2", FeedOptions{})
 
 	feeds, err := db.GetFeedsByConfig(ctx, cfg.ID)
 	if err != nil {
@@ -266,6 +413,84 @@ func TestGetFeedsByConfig(t *testing.T) {
 	}
 }
 
+func TestListUserFeeds(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	other, _ := db.GetOrCreateUser(ctx, "other-fp", "other-pubkey")
+	daily, _ := db.CreateConfig(ctx, user.ID, "daily.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now())
+	weekly, _ := db.CreateConfig(ctx, user.ID, "weekly.txt", "user@example.com", "0 8 * * 1", true, false, "raw", time.Now())
+	theirs, _ := db.CreateConfig(ctx, other.ID, "daily.txt", "other@example.com", "0 8 * * *", true, false, "raw", time.Now())
+
+	shared := "https://example.com/shared.xml"
+	_, _ = db.CreateFeed(ctx, daily.ID, shared, "", FeedOptions{})
+	fetched, _ := db.CreateFeed(ctx, weekly.ID, shared, "", FeedOptions{})
+	_, _ = db.CreateFeed(ctx, weekly.ID, "https://example.com/only.xml", "", FeedOptions{})
+	_, _ = db.CreateFeed(ctx, theirs.ID, shared, "", FeedOptions{})
+	if err := db.TouchFeedFetched(ctx, fetched.ID); err != nil {
+		t.Fatalf("TouchFeedFetched failed: %v", err)
+	}
+
+	feeds, err := db.ListUserFeeds(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("ListUserFeeds failed: %v", err)
+	}
+	if len(feeds) != 2 {
+		t.Fatalf("expected 2 distinct feeds, got %d", len(feeds))
+	}
+	if feeds[0].URL != shared || feeds[0].Configs != 2 {
+		t.Errorf("expected the shared feed first in 2 configs, got %s in %d", feeds[0].URL, feeds[0].Configs)
+	}
+	if !feeds[0].LastFetched.Valid {
+		t.Error("expected the shared feed's latest fetch time")
+	}
+	if feeds[1].Configs != 1 || feeds[1].LastFetched.Valid {
+		t.Errorf("expected an unfetched feed in 1 config, got %+v", feeds[1])
+	}
+}
+
+func TestImportSeenItems(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, _ := db.CreateConfig(ctx, user.ID, "feeds.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now())
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", FeedOptions{})
+	_ = db.MarkItemSeen(ctx, feed.ID, "existing", "Existing", "")
+
+	seenAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	items := []SeenItem{
+		{GUID: "existing"},
+		{GUID: "old", Title: sql.NullString{String: "Old post", Valid: true}, SeenAt: seenAt},
+		{GUID: "undated"},
+		{GUID: ""},
+	}
+	added, err := db.ImportSeenItems(ctx, user.ID, "feeds.txt", "https://example.com/feed.xml", items)
+	if err != nil {
+		t.Fatalf("ImportSeenItems failed: %v", err)
+	}
+	if added != 2 {
+		t.Errorf("expected 2 items added, got %d", added)
+	}
+
+	seen, _ := db.GetSeenGUIDs(ctx, feed.ID, []string{"existing", "old", "undated"})
+	if len(seen) != 3 {
+		t.Errorf("expected all 3 items seen, got %v", seen)
+	}
+	stored, _ := db.GetSeenItems(ctx, feed.ID, 10)
+	for _, item := range stored {
+		if item.GUID == "old" && (!item.SeenAt.Equal(seenAt) || item.Title.String != "Old post") {
+			t.Errorf("expected original title and seen time, got %q at %v", item.Title.String, item.SeenAt)
+		}
+	}
+
+	other, _ := db.GetOrCreateUser(ctx, "other-fp", "other-pubkey")
+	if _, err := db.ImportSeenItems(ctx, other.ID, "feeds.txt", "https://example.com/feed.xml", items); err == nil {
+		t.Error("expected an error importing into another user's config")
+	}
+}
+
 func TestMarkItemSeen(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
@@ -273,7 +498,7 @@ func TestMarkItemSeen(t *testing.T) {
 	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
 	nextRun := time.Now().Add(time.Hour)
 	cfg, _ := db.CreateConfig(ctx, user.ID, "test.herald", "user@example.com", "0 8 * * *", true, false, "raw", nextRun)
-	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "")
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", FeedOptions{})
 
 	err := db.MarkItemSeen(ctx, feed.ID, "item-guid-123", "Item Title", "https://example.com/item")
 	if err != nil {
@@ -290,6 +515,147 @@ func TestMarkItemSeen(t *testing.T) {
 	}
 }
 
+func TestMarkItemDelivered(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.herald", "user@example.com", "0 8 * * *", true, false, "raw", time.Now().Add(time.Hour))
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", FeedOptions{})
+
+	_ = db.MarkItemSeen(ctx, feed.ID, "preseeded", "Old", "https://example.com/old")
+	_ = db.MarkItemSeen(ctx, feed.ID, "sent", "New", "https://example.com/new")
+
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	if err := db.MarkItemDeliveredTx(ctx, tx, feed.ID, 0, "sent", "New", "https://example.com/new"); err != nil {
+		t.Fatalf("MarkItemDeliveredTx failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	// Seeing a delivered item again doesn't clear the flag
+	_ = db.MarkItemSeen(ctx, feed.ID, "sent", "New", "https://example.com/new")
+
+	items, err := db.GetDeliveredItems(ctx, feed.ID, 10)
+	if err != nil {
+		t.Fatalf("GetDeliveredItems failed: %v", err)
+	}
+	if len(items) != 1 || items[0].GUID != "sent" {
+		t.Errorf("expected only the sent item, got %+v", items)
+	}
+	if all, _ := db.GetSeenItems(ctx, feed.ID, 10); len(all) != 2 {
+		t.Errorf("expected both items to stay seen, got %d", len(all))
+	}
+}
+
+func TestGetSeenItemsByConfig(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, _ := db.CreateConfig(ctx, user.ID, "feeds.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now())
+	other, _ := db.CreateConfig(ctx, user.ID, "other.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now())
+	feedA, _ := db.CreateFeed(ctx, cfg.ID, "https://a.example.com/feed.xml", "", FeedOptions{})
+	feedB, _ := db.CreateFeed(ctx, cfg.ID, "https://b.example.com/feed.xml", "", FeedOptions{})
+	feedC, _ := db.CreateFeed(ctx, other.ID, "https://c.example.com/feed.xml", "", FeedOptions{})
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	seen := func(filename, url string, guids ...string) {
+		items := make([]SeenItem, len(guids))
+		for i, guid := range guids {
+			items[i] = SeenItem{GUID: guid, SeenAt: base.Add(time.Duration(guid[len(guid)-1]-'0') * time.Hour)}
+		}
+		if _, err := db.ImportSeenItems(ctx, user.ID, filename, url, items); err != nil {
+			t.Fatalf("ImportSeenItems failed: %v", err)
+		}
+	}
+	seen("feeds.txt", feedA.URL, "a1", "a3", "a5")
+	seen("feeds.txt", feedB.URL, "b2", "b4")
+	seen("other.txt", feedC.URL, "c9")
+
+	guids := func(items []*SeenItem) string {
+		var out []string
+		for _, item := range items {
+			out = append(out, item.GUID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	items, err := db.GetSeenItemsByConfig(ctx, cfg.ID, 10, SeenItemsFilter{})
+	if err != nil {
+		t.Fatalf("GetSeenItemsByConfig failed: %v", err)
+	}
+	if got := guids(items); got != "a5,b4,a3,b2,a1" {
+		t.Errorf("expected the config's items newest first, got %s", got)
+	}
+
+	items, _ = db.GetSeenItemsByConfig(ctx, cfg.ID, 10, SeenItemsFilter{PerFeed: 1})
+	if got := guids(items); got != "a5,b4" {
+		t.Errorf("expected one item per feed, got %s", got)
+	}
+
+	items, _ = db.GetSeenItemsByConfig(ctx, cfg.ID, 2, SeenItemsFilter{})
+	if got := guids(items); got != "a5,b4" {
+		t.Errorf("expected the limit to apply, got %s", got)
+	}
+
+	tx, _ := db.BeginTx(ctx)
+	_ = db.MarkItemDeliveredTx(ctx, tx, feedA.ID, 0, "a1", "", "")
+	_ = tx.Commit()
+	items, _ = db.GetSeenItemsByConfig(ctx, cfg.ID, 10, SeenItemsFilter{DeliveredOnly: true})
+	if got := guids(items); got != "a1" {
+		t.Errorf("expected only the delivered item, got %s", got)
+	}
+}
+
+// BenchmarkGetSeenItemsByConfig compares the feed endpoints' single batched
+// query against reading each feed's items separately.
+func BenchmarkGetSeenItemsByConfig(b *testing.B) {
+	db := openFileDB(b)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, _ := db.CreateConfig(ctx, user.ID, "feeds.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now())
+	for i := 0; i < 20; i++ {
+		feed, _ := db.CreateFeed(ctx, cfg.ID, fmt.Sprintf("https://example.com/%d.xml", i), "", FeedOptions{})
+		for k := 0; k < 100; k++ {
+			_ = db.MarkItemSeen(ctx, feed.ID, fmt.Sprintf("guid-%d", k), "Title", "https://example.com/item")
+		}
+	}
+	const limit, perFeed = 100, 50
+
+	b.Run("batched", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := db.GetSeenItemsByConfig(ctx, cfg.ID, limit, SeenItemsFilter{PerFeed: perFeed}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("per-feed", func(b *testing.B) {
+		for b.Loop() {
+			feeds, err := db.GetFeedsByConfig(ctx, cfg.ID)
+			if err != nil {
+				b.Fatal(err)
+			}
+			var items []*SeenItem
+			for _, f := range feeds {
+				feedItems, err := db.GetSeenItems(ctx, f.ID, perFeed)
+				if err != nil {
+					b.Fatal(err)
+				}
+				items = append(items, feedItems...)
+			}
+			sort.Slice(items, func(i, j int) bool { return items[i].SeenAt.After(items[j].SeenAt) })
+			if len(items) > limit {
+				items = items[:limit]
+			}
+		}
+	})
+}
+
 func TestIsItemSeen_NotSeen(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
@@ -297,7 +663,7 @@ func TestIsItemSeen_NotSeen(t *testing.T) {
 	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
 	nextRun := time.Now().Add(time.Hour)
 	cfg, _ := db.CreateConfig(ctx, user.ID, "test.herald", "user@example.com", "0 8 * * *", true, false, "raw", nextRun)
-	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "")
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", FeedOptions{})
 
 	seen, err := db.IsItemSeen(ctx, feed.ID, "nonexistent-guid")
 	if err != nil {
@@ -315,7 +681,7 @@ func TestGetSeenGUIDs(t *testing.T) {
 	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
 	nextRun := time.Now().Add(time.Hour)
 	cfg, _ := db.CreateConfig(ctx, user.ID, "test.herald", "user@example.com", "0 8 * * *", true, false, "raw", nextRun)
-	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "")
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", FeedOptions{})
 
 	// Mark some items as seen
 	_ = db.MarkItemSeen(ctx, feed.ID, "guid1", "Title 1", "link1")
@@ -345,7 +711,7 @@ func TestCleanupOldSeenItems(t *testing.T) {
 	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
 	nextRun := time.Now().Add(time.Hour)
 	cfg, _ := db.CreateConfig(ctx, user.ID, "test.herald", "user@example.com", "0 8 * * *", true, false, "raw", nextRun)
-	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "")
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", FeedOptions{})
 
 	// Mark item as seen
 	_ = db.MarkItemSeen(ctx, feed.ID, "old-item", "Old Item", "link")
@@ -363,3 +729,519 @@ func TestCleanupOldSeenItems(t *testing.T) {
 		t.Log("No items deleted - this test may be timing-sensitive")
 	}
 }
+
+func TestCreateFeedWithOptions(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	nextRun := time.Now().Add(time.Hour)
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.herald", "user@example.com", "0 8 * * *", true, false, "raw", nextRun)
+
+	opts := FeedOptions{
+		Headers: map[string]string{"Accept": "application/rss+xml"},
+		Method:  "POST",
+		Body:    `{"limit":50}`,
+	}
+	if _, err := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", opts); err != nil {
+		t.Fatalf("CreateFeed failed: %v", err)
+	}
+
+	feeds, err := db.GetFeedsByConfig(ctx, cfg.ID)
+	if err != nil {
+		t.Fatalf("GetFeedsByConfig failed: %v", err)
+	}
+	if len(feeds) != 1 {
+		t.Fatalf("expected 1 feed, got %d", len(feeds))
+	}
+	if feeds[0].Headers["Accept"] != "application/rss+xml" {
+		t.Errorf("expected Accept header to round-trip, got %v", feeds[0].Headers)
+	}
+	if feeds[0].Method != "POST" || feeds[0].Body != `{"limit":50}` {
+		t.Errorf("expected method and body to round-trip, got %q %q", feeds[0].Method, feeds[0].Body)
+	}
+}
+
+func TestTrimSeenItems(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	nextRun := time.Now().Add(time.Hour)
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.herald", "user@example.com", "0 8 * * *", true, false, "raw", nextRun)
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", FeedOptions{})
+
+	for _, guid := range []string{"a", "b", "c", "d", "e"} {
+		if err := db.MarkItemSeen(ctx, feed.ID, guid, "", ""); err != nil {
+			t.Fatalf("MarkItemSeen failed: %v", err)
+		}
+	}
+
+	// a is the oldest row but still in the feed, so it survives the trim
+	deleted, err := db.TrimSeenItems(ctx, feed.ID, 2, []string{"a", "e"})
+	if err != nil {
+		t.Fatalf("TrimSeenItems failed: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("expected 2 deleted, got %d", deleted)
+	}
+
+	seen, err := db.GetSeenGUIDs(ctx, feed.ID, []string{"a", "b", "c", "d", "e"})
+	if err != nil {
+		t.Fatalf("GetSeenGUIDs failed: %v", err)
+	}
+	if len(seen) != 3 || !seen["a"] || !seen["d"] || !seen["e"] {
+		t.Errorf("expected a and newest items d and e to remain, got %v", seen)
+	}
+
+	if deleted, _ := db.TrimSeenItems(ctx, feed.ID, 2, nil); deleted != 1 {
+		t.Errorf("expected a trimmed once it left the feed, got %d deleted", deleted)
+	}
+}
+
+func TestDeleteSeenItemsByConfig(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	nextRun := time.Now().Add(time.Hour)
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.herald", "user@example.com", "0 8 * * *", true, false, "raw", nextRun)
+	other, _ := db.CreateConfig(ctx, user.ID, "other.herald", "user@example.com", "0 8 * * *", true, false, "raw", nextRun)
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", FeedOptions{})
+	otherFeed, _ := db.CreateFeed(ctx, other.ID, "https://example.com/feed.xml", "", FeedOptions{})
+
+	_ = db.MarkItemSeen(ctx, feed.ID, "a", "", "")
+	_ = db.MarkItemSeen(ctx, feed.ID, "b", "", "")
+	_ = db.MarkItemSeen(ctx, otherFeed.ID, "a", "", "")
+
+	deleted, err := db.DeleteSeenItemsByConfig(ctx, cfg.ID)
+	if err != nil {
+		t.Fatalf("DeleteSeenItemsByConfig failed: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("expected 2 deleted, got %d", deleted)
+	}
+
+	seen, _ := db.IsItemSeen(ctx, otherFeed.ID, "a")
+	if !seen {
+		t.Error("items from other configs should not be deleted")
+	}
+}
+
+func TestSearchSeenItems(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	stranger, _ := db.GetOrCreateUser(ctx, "other-fp", "other-pubkey")
+	nextRun := time.Now().Add(time.Hour)
+	cfg, _ := db.CreateConfig(ctx, user.ID, "news.txt", "user@example.com", "0 8 * * *", true, false, "raw", nextRun)
+	otherCfg, _ := db.CreateConfig(ctx, stranger.ID, "news.txt", "other@example.com", "0 8 * * *", true, false, "raw", nextRun)
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "Example", FeedOptions{})
+	otherFeed, _ := db.CreateFeed(ctx, otherCfg.ID, "https://example.com/feed.xml", "", FeedOptions{})
+
+	_ = db.MarkItemSeen(ctx, feed.ID, "1", "Go 1.24 released", "https://example.com/go-124")
+	_ = db.MarkItemSeen(ctx, feed.ID, "2", "Rust news", "https://example.com/golang-tips")
+	_ = db.MarkItemSeen(ctx, feed.ID, "3", "100% done", "https://example.com/done")
+	_ = db.MarkItemSeen(ctx, otherFeed.ID, "1", "Go 1.24 released", "https://example.com/go-124")
+	if _, err := db.Exec(`UPDATE seen_items SET seen_at = datetime('now', '-1 day') WHERE guid = '1'`); err != nil {
+		t.Fatalf("backdate item: %v", err)
+	}
+
+	matches, err := db.SearchSeenItems(ctx, user.ID, "go", 10)
+	if err != nil {
+		t.Fatalf("SearchSeenItems failed: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches for user, got %d", len(matches))
+	}
+	if matches[0].Title.String != "Rust news" {
+		t.Errorf("expected most recent match first, got %q", matches[0].Title.String)
+	}
+	if matches[1].ConfigFilename != "news.txt" || matches[1].FeedName.String != "Example" {
+		t.Errorf("unexpected match metadata: %+v", matches[1])
+	}
+
+	matches, _ = db.SearchSeenItems(ctx, user.ID, "0%", 10)
+	if len(matches) != 1 || matches[0].Title.String != "100% done" {
+		t.Errorf("expected wildcard to match literally, got %d matches", len(matches))
+	}
+
+	matches, _ = db.SearchSeenItems(ctx, user.ID, "go", 1)
+	if len(matches) != 1 {
+		t.Errorf("expected limit to apply, got %d matches", len(matches))
+	}
+}
+
+func TestConfigNextRunAfterBoost(t *testing.T) {
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	cfg := &Config{CronExpr: "0 8 * * *"}
+
+	next, err := cfg.NextRunAfter(now)
+	if err != nil {
+		t.Fatalf("NextRunAfter failed: %v", err)
+	}
+	if !next.Equal(time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected cron next run, got %s", next)
+	}
+
+	cfg.BoostInterval = sql.NullInt64{Int64: 1800, Valid: true}
+	cfg.BoostUntil = sql.NullTime{Time: now.Add(6 * time.Hour), Valid: true}
+
+	next, err = cfg.NextRunAfter(now)
+	if err != nil {
+		t.Fatalf("NextRunAfter failed: %v", err)
+	}
+	if !next.Equal(now.Add(30 * time.Minute)) {
+		t.Errorf("expected boosted next run, got %s", next)
+	}
+
+	// After the boost expires the cron schedule takes over again
+	next, err = cfg.NextRunAfter(now.Add(7 * time.Hour))
+	if err != nil {
+		t.Fatalf("NextRunAfter failed: %v", err)
+	}
+	if !next.Equal(time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected cron next run after boost expiry, got %s", next)
+	}
+}
+
+func TestConfigNextRunAfterAdaptive(t *testing.T) {
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	cfg := &Config{CronExpr: "0 8 * * *", AdaptiveMultiplier: 7}
+
+	next, err := cfg.NextRunAfter(now)
+	if err != nil {
+		t.Fatalf("NextRunAfter failed: %v", err)
+	}
+	if !next.Equal(time.Date(2026, 1, 8, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected every 7th daily tick, got %s", next)
+	}
+
+	db := setupTestDB(t)
+	ctx := context.Background()
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	created, _ := db.CreateConfig(ctx, user.ID, "test.txt", "user@example.com", "0 8 * * *", true, false, "raw", now)
+	if created.AdaptiveMultiplier != 1 {
+		t.Errorf("expected default multiplier 1, got %d", created.AdaptiveMultiplier)
+	}
+
+	if err := db.SetAdaptiveMultiplier(ctx, created.ID, 4, next); err != nil {
+		t.Fatalf("SetAdaptiveMultiplier failed: %v", err)
+	}
+	active, err := db.ListActiveConfigs(ctx)
+	if err != nil {
+		t.Fatalf("ListActiveConfigs failed: %v", err)
+	}
+	if len(active) != 1 || active[0].AdaptiveMultiplier != 4 || !active[0].NextRun.Time.Equal(next) {
+		t.Errorf("expected stored multiplier and next run, got %+v", active)
+	}
+}
+
+func TestSetAndClearBoost(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	nextRun := time.Now().Add(time.Hour)
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.herald", "user@example.com", "0 8 * * *", true, false, "raw", nextRun)
+
+	until := time.Now().Add(6 * time.Hour).UTC()
+	if err := db.SetBoost(ctx, cfg.ID, 30*time.Minute, until, time.Now().Add(30*time.Minute)); err != nil {
+		t.Fatalf("SetBoost failed: %v", err)
+	}
+
+	got, err := db.GetConfigByID(ctx, cfg.ID)
+	if err != nil {
+		t.Fatalf("GetConfigByID failed: %v", err)
+	}
+	if !got.Boosted(time.Now().UTC()) {
+		t.Error("expected config to be boosted")
+	}
+	if got.BoostInterval.Int64 != 1800 {
+		t.Errorf("expected boost interval 1800s, got %d", got.BoostInterval.Int64)
+	}
+
+	if err := db.ClearBoost(ctx, cfg.ID); err != nil {
+		t.Fatalf("ClearBoost failed: %v", err)
+	}
+	got, _ = db.GetConfigByID(ctx, cfg.ID)
+	if got.Boosted(time.Now().UTC()) {
+		t.Error("expected boost to be cleared")
+	}
+}
+
+func TestFeedLastError(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	nextRun := time.Now().Add(time.Hour)
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.herald", "user@example.com", "0 8 * * *", true, false, "raw", nextRun)
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", FeedOptions{})
+
+	if err := db.SetFeedError(ctx, feed.ID, "status 404"); err != nil {
+		t.Fatalf("SetFeedError failed: %v", err)
+	}
+	feeds, _ := db.GetFeedsByConfig(ctx, cfg.ID)
+	if !feeds[0].LastError.Valid || feeds[0].LastError.String != "status 404" {
+		t.Errorf("expected last error to be stored, got %v", feeds[0].LastError)
+	}
+
+	if err := db.TouchFeedFetched(ctx, feed.ID); err != nil {
+		t.Fatalf("TouchFeedFetched failed: %v", err)
+	}
+	feeds, _ = db.GetFeedsByConfig(ctx, cfg.ID)
+	if feeds[0].LastError.Valid {
+		t.Errorf("expected last error to be cleared, got %v", feeds[0].LastError)
+	}
+	if !feeds[0].LastFetched.Valid {
+		t.Error("expected last fetched to be set")
+	}
+}
+
+func TestGetStaleFeeds(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	nextRun := time.Now().Add(time.Hour)
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.herald", "user@example.com", "0 8 * * *", true, false, "raw", nextRun)
+
+	stale, _ := db.CreateFeed(ctx, cfg.ID, "https://stale.example.com/feed.xml", "Stale", FeedOptions{})
+	fresh, _ := db.CreateFeed(ctx, cfg.ID, "https://fresh.example.com/feed.xml", "Fresh", FeedOptions{})
+	_, _ = db.CreateFeed(ctx, cfg.ID, "https://empty.example.com/feed.xml", "Empty", FeedOptions{})
+
+	_ = db.MarkItemSeen(ctx, stale.ID, "old", "Old", "https://stale.example.com/old")
+	_ = db.MarkItemSeen(ctx, fresh.ID, "new", "New", "https://fresh.example.com/new")
+	if _, err := db.ExecContext(ctx, `UPDATE seen_items SET seen_at = ? WHERE feed_id = ?`, time.Now().Add(-100*24*time.Hour), stale.ID); err != nil {
+		t.Fatalf("backdate seen item: %v", err)
+	}
+
+	feeds, err := db.GetStaleFeeds(ctx, 90*24*time.Hour)
+	if err != nil {
+		t.Fatalf("GetStaleFeeds failed: %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].FeedID != stale.ID {
+		t.Fatalf("expected only the stale feed, got %+v", feeds)
+	}
+
+	// Feeds on inactive configs are ignored
+	if err := db.UpdateNextRun(ctx, cfg.ID, nil); err != nil {
+		t.Fatalf("UpdateNextRun failed: %v", err)
+	}
+	feeds, _ = db.GetStaleFeeds(ctx, 90*24*time.Hour)
+	if len(feeds) != 0 {
+		t.Errorf("expected no stale feeds for inactive config, got %d", len(feeds))
+	}
+}
+
+func TestDisabledFeedsSkipped(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	nextRun := time.Now().Add(time.Hour)
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.herald", "user@example.com", "0 8 * * *", true, false, "raw", nextRun)
+
+	_, _ = db.CreateFeed(ctx, cfg.ID, "https://on.example.com/feed.xml", "", FeedOptions{})
+	off, _ := db.CreateFeed(ctx, cfg.ID, "https://off.example.com/feed.xml", "", FeedOptions{Disabled: true})
+
+	feeds, _ := db.GetFeedsByConfig(ctx, cfg.ID)
+	if len(feeds) != 1 || feeds[0].URL != "https://on.example.com/feed.xml" {
+		t.Errorf("expected only the enabled feed, got %d feeds", len(feeds))
+	}
+
+	all, _ := db.GetAllFeedsByConfig(ctx, cfg.ID)
+	if len(all) != 2 || !all[1].Disabled {
+		t.Errorf("expected both feeds with the second disabled, got %d feeds", len(all))
+	}
+
+	if err := db.UpdateFeed(ctx, off.ID, "", FeedOptions{}); err != nil {
+		t.Fatalf("UpdateFeed failed: %v", err)
+	}
+	feeds, _ = db.GetFeedsByConfig(ctx, cfg.ID)
+	if len(feeds) != 2 {
+		t.Errorf("expected re-enabled feed to be returned, got %d feeds", len(feeds))
+	}
+}
+
+func TestConfigScheduleOffset(t *testing.T) {
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	spread := make(map[time.Duration]bool)
+	for id := int64(1); id <= 50; id++ {
+		cfg := &Config{ID: id, CronExpr: "0 8 * * *", JitterWindow: 10 * time.Minute}
+		offset := cfg.ScheduleOffset()
+		if offset < 0 || offset >= 10*time.Minute || offset%time.Minute != 0 {
+			t.Fatalf("config %d: offset %s outside 10m window", id, offset)
+		}
+		if again := (&Config{ID: id, JitterWindow: 10 * time.Minute}).ScheduleOffset(); again != offset {
+			t.Errorf("config %d: offset not stable, got %s then %s", id, offset, again)
+		}
+		spread[offset] = true
+
+		next, err := cfg.NextRunAfter(now)
+		if err != nil {
+			t.Fatalf("NextRunAfter failed: %v", err)
+		}
+		want := time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC).Add(offset)
+		if !next.Equal(want) {
+			t.Errorf("config %d: expected %s, got %s", id, want, next)
+		}
+
+		// Running at the offset time moves on to the next day's slot
+		next, err = cfg.NextRunAfter(want.Add(time.Second))
+		if err != nil {
+			t.Fatalf("NextRunAfter failed: %v", err)
+		}
+		if !next.Equal(want.AddDate(0, 0, 1)) {
+			t.Errorf("config %d: expected following day %s, got %s", id, want.AddDate(0, 0, 1), next)
+		}
+	}
+	if len(spread) < 5 {
+		t.Errorf("expected offsets spread across the window, got %d distinct", len(spread))
+	}
+
+	// Frequent schedules keep their spacing rather than skipping ticks
+	cfg := &Config{ID: 3, CronExpr: "*/5 * * * *", JitterWindow: 60 * time.Minute}
+	first, _ := cfg.NextRunAfter(now)
+	second, _ := cfg.NextRunAfter(first.Add(time.Second))
+	if second.Sub(first) != 5*time.Minute {
+		t.Errorf("expected 5m between runs, got %s", second.Sub(first))
+	}
+
+	cfg.JitterWindow = 0
+	if offset := cfg.ScheduleOffset(); offset != 0 {
+		t.Errorf("expected no offset when disabled, got %s", offset)
+	}
+}
+
+func TestScheduleJitterStampedOnConfigs(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	db.SetScheduleJitter(10 * time.Minute)
+
+	user, err := db.GetOrCreateUser(ctx, "fp-jitter", "ssh-ed25519 AAAA")
+	if err != nil {
+		t.Fatalf("GetOrCreateUser failed: %v", err)
+	}
+	created, err := db.CreateConfig(ctx, user.ID, "jitter.txt", "user@example.com", "0 8 * * *", true, false, "", time.Now())
+	if err != nil {
+		t.Fatalf("CreateConfig failed: %v", err)
+	}
+	loaded, err := db.GetConfigByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetConfigByID failed: %v", err)
+	}
+	for _, cfg := range []*Config{created, loaded} {
+		if cfg.JitterWindow != 10*time.Minute {
+			t.Errorf("expected a 10m jitter window, got %s", cfg.JitterWindow)
+		}
+	}
+}
+
+func TestSetFeedFavicon(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now())
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", FeedOptions{})
+
+	feeds, _ := db.GetFeedsByConfig(ctx, cfg.ID)
+	if feeds[0].Favicon != "" || feeds[0].FaviconCheckedAt.Valid {
+		t.Fatalf("expected no favicon on a new feed, got %+v", feeds[0])
+	}
+
+	const icon = "data:image/png;base64,AAAA"
+	if err := db.SetFeedFavicon(ctx, feed.ID, icon); err != nil {
+		t.Fatalf("SetFeedFavicon failed: %v", err)
+	}
+	feeds, _ = db.GetFeedsByConfig(ctx, cfg.ID)
+	if feeds[0].Favicon != icon || !feeds[0].FaviconCheckedAt.Valid {
+		t.Errorf("expected stored favicon, got %q (checked %v)", feeds[0].Favicon, feeds[0].FaviconCheckedAt)
+	}
+
+	// A failed lookup clears the favicon but still records the check
+	if err := db.SetFeedFavicon(ctx, feed.ID, ""); err != nil {
+		t.Fatalf("SetFeedFavicon failed: %v", err)
+	}
+	feeds, _ = db.GetFeedsByConfig(ctx, cfg.ID)
+	if feeds[0].Favicon != "" || !feeds[0].FaviconCheckedAt.Valid {
+		t.Errorf("expected cleared favicon with check time, got %q (checked %v)", feeds[0].Favicon, feeds[0].FaviconCheckedAt)
+	}
+}
+
+func TestFirstSeenDates(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now())
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", FeedOptions{})
+
+	first := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	dates, err := db.FirstSeenDates(ctx, feed.ID, []string{"a"}, first)
+	if err != nil {
+		t.Fatalf("FirstSeenDates failed: %v", err)
+	}
+	if !dates["a"].Equal(first) {
+		t.Errorf("expected %v, got %v", first, dates["a"])
+	}
+
+	later := first.Add(24 * time.Hour)
+	dates, err = db.FirstSeenDates(ctx, feed.ID, []string{"a", "b"}, later)
+	if err != nil {
+		t.Fatalf("FirstSeenDates failed: %v", err)
+	}
+	if !dates["a"].Equal(first) {
+		t.Errorf("expected first sight to be kept, got %v", dates["a"])
+	}
+	if !dates["b"].Equal(later) {
+		t.Errorf("expected new item to be dated now, got %v", dates["b"])
+	}
+
+	deleted, err := db.CleanupOldFirstSeen(ctx, time.Since(first)-12*time.Hour)
+	if err != nil {
+		t.Fatalf("CleanupOldFirstSeen failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 deleted, got %d", deleted)
+	}
+}
+
+func TestGlobalFetchTimes(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	const url = "https://example.com/feed.xml"
+
+	last, err := db.LastGlobalFetch(ctx, url)
+	if err != nil || !last.IsZero() {
+		t.Fatalf("expected no fetch recorded, got %v, %v", last, err)
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	if err := db.RecordGlobalFetch(ctx, url, old); err != nil {
+		t.Fatalf("RecordGlobalFetch failed: %v", err)
+	}
+	now := time.Now()
+	if err := db.RecordGlobalFetch(ctx, url, now); err != nil {
+		t.Fatalf("RecordGlobalFetch failed: %v", err)
+	}
+	last, err = db.LastGlobalFetch(ctx, url)
+	if err != nil {
+		t.Fatalf("LastGlobalFetch failed: %v", err)
+	}
+	if !last.Equal(now.UTC()) {
+		t.Errorf("expected latest fetch %s, got %s", now.UTC(), last)
+	}
+
+	_ = db.RecordGlobalFetch(ctx, "https://example.com/old.xml", old)
+	deleted, err := db.CleanupGlobalFetches(ctx, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("CleanupGlobalFetches failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 stale fetch time deleted, got %d", deleted)
+	}
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -15,17 +16,70 @@ type Feed struct {
 	LastFetched  sql.NullTime
 	ETag         sql.NullString
 	LastModified sql.NullString
+	Headers      map[string]string
+	Method       string
+	Body         string
+	Note         string
+	Proxy        string
+	// Warmup is a page fetched first so the feed request carries its cookies
+	Warmup    string
+	LastError sql.NullString
+	Disabled  bool
+	// Favicon is an inlined data: URI; empty if none was found
+	Favicon          string
+	FaviconCheckedAt sql.NullTime
+	// Description is the feed's own description from its last full fetch
+	Description string
 }
 
-func (db *DB) CreateFeed(ctx context.Context, configID int64, url, name string) (*Feed, error) {
+// FeedOptions holds the per-feed settings from the config
+type FeedOptions struct {
+	Headers  map[string]string
+	Method   string
+	Body     string
+	Note     string
+	Proxy    string
+	Warmup   string
+	Disabled bool
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// encodeHeaders serializes custom request headers for storage
+func encodeHeaders(headers map[string]string) sql.NullString {
+	if len(headers) == 0 {
+		return sql.NullString{}
+	}
+	b, err := json.Marshal(headers)
+	if err != nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(b), Valid: true}
+}
+
+// decodeHeaders parses stored custom request headers, ignoring malformed data
+func decodeHeaders(raw sql.NullString) map[string]string {
+	if !raw.Valid || raw.String == "" {
+		return nil
+	}
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(raw.String), &headers); err != nil {
+		return nil
+	}
+	return headers
+}
+
+func (db *DB) CreateFeed(ctx context.Context, configID int64, url, name string, opts FeedOptions) (*Feed, error) {
 	var nameVal sql.NullString
 	if name != "" {
 		nameVal = sql.NullString{String: name, Valid: true}
 	}
 
 	result, err := db.ExecContext(ctx,
-		`INSERT INTO feeds (config_id, url, name) VALUES (?, ?, ?)`,
-		configID, url, nameVal,
+		`INSERT INTO feeds (config_id, url, name, headers, method, body, note, proxy, warmup, enabled) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		configID, url, nameVal, encodeHeaders(opts.Headers), nullIfEmpty(opts.Method), nullIfEmpty(opts.Body), nullIfEmpty(opts.Note), nullIfEmpty(opts.Proxy), nullIfEmpty(opts.Warmup), !opts.Disabled,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("insert feed: %w", err)
@@ -41,18 +95,25 @@ func (db *DB) CreateFeed(ctx context.Context, configID int64, url, name string)
 		ConfigID: configID,
 		URL:      url,
 		Name:     nameVal,
+		Headers:  opts.Headers,
+		Method:   opts.Method,
+		Body:     opts.Body,
+		Note:     opts.Note,
+		Proxy:    opts.Proxy,
+		Warmup:   opts.Warmup,
+		Disabled: opts.Disabled,
 	}, nil
 }
 
-func (db *DB) CreateFeedTx(ctx context.Context, tx *sql.Tx, configID int64, url, name string) (*Feed, error) {
+func (db *DB) CreateFeedTx(ctx context.Context, tx *sql.Tx, configID int64, url, name string, opts FeedOptions) (*Feed, error) {
 	var nameVal sql.NullString
 	if name != "" {
 		nameVal = sql.NullString{String: name, Valid: true}
 	}
 
 	result, err := tx.ExecContext(ctx,
-		`INSERT INTO feeds (config_id, url, name) VALUES (?, ?, ?)`,
-		configID, url, nameVal,
+		`INSERT INTO feeds (config_id, url, name, headers, method, body, note, proxy, warmup, enabled) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		configID, url, nameVal, encodeHeaders(opts.Headers), nullIfEmpty(opts.Method), nullIfEmpty(opts.Body), nullIfEmpty(opts.Note), nullIfEmpty(opts.Proxy), nullIfEmpty(opts.Warmup), !opts.Disabled,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("insert feed: %w", err)
@@ -68,18 +129,25 @@ func (db *DB) CreateFeedTx(ctx context.Context, tx *sql.Tx, configID int64, url,
 		ConfigID: configID,
 		URL:      url,
 		Name:     nameVal,
+		Headers:  opts.Headers,
+		Method:   opts.Method,
+		Body:     opts.Body,
+		Note:     opts.Note,
+		Proxy:    opts.Proxy,
+		Warmup:   opts.Warmup,
+		Disabled: opts.Disabled,
 	}, nil
 }
 
-func (db *DB) UpdateFeedTx(ctx context.Context, tx *sql.Tx, feedID int64, name string) error {
+func (db *DB) UpdateFeedTx(ctx context.Context, tx *sql.Tx, feedID int64, name string, opts FeedOptions) error {
 	var nameVal sql.NullString
 	if name != "" {
 		nameVal = sql.NullString{String: name, Valid: true}
 	}
 
 	_, err := tx.ExecContext(ctx,
-		`UPDATE feeds SET name = ? WHERE id = ?`,
-		nameVal, feedID,
+		`UPDATE feeds SET name = ?, headers = ?, method = ?, body = ?, note = ?, proxy = ?, warmup = ?, enabled = ? WHERE id = ?`,
+		nameVal, encodeHeaders(opts.Headers), nullIfEmpty(opts.Method), nullIfEmpty(opts.Body), nullIfEmpty(opts.Note), nullIfEmpty(opts.Proxy), nullIfEmpty(opts.Warmup), !opts.Disabled, feedID,
 	)
 	if err != nil {
 		return fmt.Errorf("update feed: %w", err)
@@ -98,51 +166,80 @@ func (db *DB) DeleteFeedTx(ctx context.Context, tx *sql.Tx, feedID int64) error
 	return nil
 }
 
+// feedColumns is the column list scanned by scanFeed
+const feedColumns = `id, config_id, url, name, last_fetched, etag, last_modified, headers, method, body, note, proxy, warmup, last_error, enabled, favicon, favicon_checked_at, description`
+
+func scanFeed(rows *sql.Rows) (*Feed, error) {
+	var f Feed
+	var headers, method, body, note, proxy, warmup, favicon, description sql.NullString
+	var enabled bool
+	if err := rows.Scan(&f.ID, &f.ConfigID, &f.URL, &f.Name, &f.LastFetched, &f.ETag, &f.LastModified, &headers, &method, &body, &note, &proxy, &warmup, &f.LastError, &enabled, &favicon, &f.FaviconCheckedAt, &description); err != nil {
+		return nil, fmt.Errorf("scan feed: %w", err)
+	}
+	f.Headers = decodeHeaders(headers)
+	f.Method = method.String
+	f.Body = body.String
+	f.Note = note.String
+	f.Proxy = proxy.String
+	f.Warmup = warmup.String
+	f.Disabled = !enabled
+	f.Favicon = favicon.String
+	f.Description = description.String
+	return &f, nil
+}
+
+func collectFeeds(rows *sql.Rows) ([]*Feed, error) {
+	defer func() { _ = rows.Close() }()
+
+	var feeds []*Feed
+	for rows.Next() {
+		f, err := scanFeed(rows)
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, rows.Err()
+}
+
+// GetFeedsByConfig returns the enabled feeds for a config
 func (db *DB) GetFeedsByConfig(ctx context.Context, configID int64) ([]*Feed, error) {
 	rows, err := db.QueryContext(ctx,
-		`SELECT id, config_id, url, name, last_fetched, etag, last_modified
-		 FROM feeds WHERE config_id = ? ORDER BY id`,
+		`SELECT `+feedColumns+` FROM feeds WHERE config_id = ? AND enabled = 1 ORDER BY id`,
 		configID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("query feeds: %w", err)
 	}
-	defer func() { _ = rows.Close() }()
+	return collectFeeds(rows)
+}
 
-	var feeds []*Feed
-	for rows.Next() {
-		var f Feed
-		if err := rows.Scan(&f.ID, &f.ConfigID, &f.URL, &f.Name, &f.LastFetched, &f.ETag, &f.LastModified); err != nil {
-			return nil, fmt.Errorf("scan feed: %w", err)
-		}
-		feeds = append(feeds, &f)
+// GetAllFeedsByConfig returns every feed for a config, including disabled ones
+func (db *DB) GetAllFeedsByConfig(ctx context.Context, configID int64) ([]*Feed, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT `+feedColumns+` FROM feeds WHERE config_id = ? ORDER BY id`,
+		configID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query feeds: %w", err)
 	}
-	return feeds, rows.Err()
+	return collectFeeds(rows)
 }
 
+// GetFeedsByConfigTx returns every feed for a config, including disabled ones,
+// so uploads can sync against the full stored set.
 func (db *DB) GetFeedsByConfigTx(ctx context.Context, tx *sql.Tx, configID int64) ([]*Feed, error) {
 	rows, err := tx.QueryContext(ctx,
-		`SELECT id, config_id, url, name, last_fetched, etag, last_modified
-		 FROM feeds WHERE config_id = ? ORDER BY id`,
+		`SELECT `+feedColumns+` FROM feeds WHERE config_id = ? ORDER BY id`,
 		configID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("query feeds: %w", err)
 	}
-	defer func() { _ = rows.Close() }()
-
-	var feeds []*Feed
-	for rows.Next() {
-		var f Feed
-		if err := rows.Scan(&f.ID, &f.ConfigID, &f.URL, &f.Name, &f.LastFetched, &f.ETag, &f.LastModified); err != nil {
-			return nil, fmt.Errorf("scan feed: %w", err)
-		}
-		feeds = append(feeds, &f)
-	}
-	return feeds, rows.Err()
+	return collectFeeds(rows)
 }
 
-// GetFeedsByConfigs returns a map of configID to feeds for multiple configs in a single query
+// GetFeedsByConfigs returns a map of configID to enabled feeds for multiple configs in a single query
 func (db *DB) GetFeedsByConfigs(ctx context.Context, configIDs []int64) (map[int64][]*Feed, error) {
 	if len(configIDs) == 0 {
 		return make(map[int64][]*Feed), nil
@@ -159,8 +256,7 @@ func (db *DB) GetFeedsByConfigs(ctx context.Context, configIDs []int64) (map[int
 	}
 
 	query := fmt.Sprintf(
-		`SELECT id, config_id, url, name, last_fetched, etag, last_modified
-		 FROM feeds WHERE config_id IN (%s) ORDER BY config_id, id`,
+		`SELECT `+feedColumns+` FROM feeds WHERE config_id IN (%s) AND enabled = 1 ORDER BY config_id, id`,
 		placeholders,
 	)
 
@@ -172,25 +268,25 @@ func (db *DB) GetFeedsByConfigs(ctx context.Context, configIDs []int64) (map[int
 
 	feedMap := make(map[int64][]*Feed)
 	for rows.Next() {
-		var f Feed
-		if err := rows.Scan(&f.ID, &f.ConfigID, &f.URL, &f.Name, &f.LastFetched, &f.ETag, &f.LastModified); err != nil {
-			return nil, fmt.Errorf("scan feed: %w", err)
+		f, err := scanFeed(rows)
+		if err != nil {
+			return nil, err
 		}
-		feedMap[f.ConfigID] = append(feedMap[f.ConfigID], &f)
+		feedMap[f.ConfigID] = append(feedMap[f.ConfigID], f)
 	}
 
 	return feedMap, rows.Err()
 }
 
-func (db *DB) UpdateFeed(ctx context.Context, feedID int64, name string) error {
+func (db *DB) UpdateFeed(ctx context.Context, feedID int64, name string, opts FeedOptions) error {
 	var nameVal sql.NullString
 	if name != "" {
 		nameVal = sql.NullString{String: name, Valid: true}
 	}
 
 	_, err := db.ExecContext(ctx,
-		`UPDATE feeds SET name = ? WHERE id = ?`,
-		nameVal, feedID,
+		`UPDATE feeds SET name = ?, headers = ?, method = ?, body = ?, note = ?, proxy = ?, warmup = ?, enabled = ? WHERE id = ?`,
+		nameVal, encodeHeaders(opts.Headers), nullIfEmpty(opts.Method), nullIfEmpty(opts.Body), nullIfEmpty(opts.Note), nullIfEmpty(opts.Proxy), nullIfEmpty(opts.Warmup), !opts.Disabled, feedID,
 	)
 	if err != nil {
 		return fmt.Errorf("update feed: %w", err)
@@ -225,6 +321,57 @@ func (db *DB) UpdateFeedFetched(ctx context.Context, feedID int64, etag, lastMod
 	return nil
 }
 
+// TouchFeedFetched records a successful fetch without changing the stored
+// conditional request headers, e.g. after a 304 response.
+func (db *DB) TouchFeedFetched(ctx context.Context, feedID int64) error {
+	_, err := db.ExecContext(ctx,
+		`UPDATE feeds SET last_fetched = ?, last_error = NULL WHERE id = ?`,
+		time.Now(), feedID,
+	)
+	if err != nil {
+		return fmt.Errorf("touch feed fetched: %w", err)
+	}
+	return nil
+}
+
+// SetFeedError records the error from the most recent failed fetch
+func (db *DB) SetFeedError(ctx context.Context, feedID int64, msg string) error {
+	_, err := db.ExecContext(ctx,
+		`UPDATE feeds SET last_error = ? WHERE id = ?`,
+		msg, feedID,
+	)
+	if err != nil {
+		return fmt.Errorf("set feed error: %w", err)
+	}
+	return nil
+}
+
+// SetFeedFavicon stores the feed's inlined favicon, or clears it when none
+// was found, and records when it was checked
+func (db *DB) SetFeedFavicon(ctx context.Context, feedID int64, favicon string) error {
+	_, err := db.ExecContext(ctx,
+		`UPDATE feeds SET favicon = ?, favicon_checked_at = ? WHERE id = ?`,
+		nullIfEmpty(favicon), time.Now().UTC(), feedID,
+	)
+	if err != nil {
+		return fmt.Errorf("set feed favicon: %w", err)
+	}
+	return nil
+}
+
+// SetFeedDescription stores the feed's own description, or clears it when
+// the feed no longer has one
+func (db *DB) SetFeedDescription(ctx context.Context, feedID int64, description string) error {
+	_, err := db.ExecContext(ctx,
+		`UPDATE feeds SET description = ? WHERE id = ?`,
+		nullIfEmpty(description), feedID,
+	)
+	if err != nil {
+		return fmt.Errorf("set feed description: %w", err)
+	}
+	return nil
+}
+
 func (db *DB) DeleteFeedsByConfig(ctx context.Context, configID int64) error {
 	_, err := db.ExecContext(ctx,
 		`DELETE FROM feeds WHERE config_id = ?`,
@@ -235,3 +382,130 @@ func (db *DB) DeleteFeedsByConfig(ctx context.Context, configID int64) error {
 	}
 	return nil
 }
+
+// StaleFeed is a feed on an active config that has stopped producing new items
+type StaleFeed struct {
+	FeedID   int64
+	ConfigID int64
+	URL      string
+	Name     sql.NullString
+}
+
+// GetStaleFeeds returns feeds on active configs that have seen items but none
+// newer than the threshold. Feeds that have never produced an item are skipped
+// since they may simply not have been fetched yet.
+func (db *DB) GetStaleFeeds(ctx context.Context, threshold time.Duration) ([]*StaleFeed, error) {
+	cutoff := time.Now().Add(-threshold)
+	rows, err := db.QueryContext(ctx,
+		`SELECT f.id, f.config_id, f.url, f.name
+		 FROM feeds f
+		 INNER JOIN configs c ON c.id = f.config_id
+		 WHERE c.next_run IS NOT NULL
+		 AND EXISTS (SELECT 1 FROM seen_items s WHERE s.feed_id = f.id)
+		 AND NOT EXISTS (SELECT 1 FROM seen_items s WHERE s.feed_id = f.id AND s.seen_at >= ?)
+		 ORDER BY f.config_id, f.id`,
+		cutoff,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query stale feeds: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var feeds []*StaleFeed
+	for rows.Next() {
+		f := &StaleFeed{}
+		if err := rows.Scan(&f.FeedID, &f.ConfigID, &f.URL, &f.Name); err != nil {
+			return nil, fmt.Errorf("scan stale feed: %w", err)
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, rows.Err()
+}
+
+// UserFeed is one feed URL across all of a user's configs
+type UserFeed struct {
+	URL string
+	// Configs is how many of the user's configs list the URL
+	Configs int
+	// LastFetched is the latest fetch of the URL by any of those configs
+	LastFetched sql.NullTime
+}
+
+// ListUserFeeds returns every distinct feed URL in a user's configs, the
+// most widely referenced first
+func (db *DB) ListUserFeeds(ctx context.Context, userID int64) ([]*UserFeed, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT latest.url, refs.configs, latest.last_fetched
+		 FROM (
+		   SELECT f.url, f.last_fetched,
+		          ROW_NUMBER() OVER (PARTITION BY f.url ORDER BY f.last_fetched DESC) AS rn
+		   FROM feeds f
+		   INNER JOIN configs c ON c.id = f.config_id
+		   WHERE c.user_id = ?
+		 ) latest
+		 INNER JOIN (
+		   SELECT f.url, COUNT(DISTINCT f.config_id) AS configs
+		   FROM feeds f
+		   INNER JOIN configs c ON c.id = f.config_id
+		   WHERE c.user_id = ?
+		   GROUP BY f.url
+		 ) refs ON refs.url = latest.url
+		 WHERE latest.rn = 1
+		 ORDER BY refs.configs DESC, latest.url`,
+		userID, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query user feeds: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var feeds []*UserFeed
+	for rows.Next() {
+		f := &UserFeed{}
+		if err := rows.Scan(&f.URL, &f.Configs, &f.LastFetched); err != nil {
+			return nil, fmt.Errorf("scan user feed: %w", err)
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, rows.Err()
+}
+
+// LastGlobalFetch returns when any config last fetched url, or the zero time
+// if it hasn't been recorded
+func (db *DB) LastGlobalFetch(ctx context.Context, url string) (time.Time, error) {
+	var fetchedAt time.Time
+	err := db.QueryRowContext(ctx,
+		`SELECT last_global_fetch FROM feed_url_fetches WHERE url = ?`,
+		url,
+	).Scan(&fetchedAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get last global fetch: %w", err)
+	}
+	return fetchedAt, nil
+}
+
+// RecordGlobalFetch records that url was fetched at the given time
+func (db *DB) RecordGlobalFetch(ctx context.Context, url string, at time.Time) error {
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO feed_url_fetches (url, last_global_fetch) VALUES (?, ?)
+		 ON CONFLICT(url) DO UPDATE SET last_global_fetch = excluded.last_global_fetch`,
+		url, at.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("record global fetch: %w", err)
+	}
+	return nil
+}
+
+// CleanupGlobalFetches deletes fetch times older than the specified duration
+func (db *DB) CleanupGlobalFetches(ctx context.Context, olderThan time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-olderThan).UTC()
+	result, err := db.ExecContext(ctx, `DELETE FROM feed_url_fetches WHERE last_global_fetch < ?`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("cleanup global fetches: %w", err)
+	}
+	return result.RowsAffected()
+}
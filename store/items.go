@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -26,7 +27,7 @@ func (db *DB) MarkItemSeen(ctx context.Context, feedID int64, guid, title, link
 		linkVal = sql.NullString{String: link, Valid: true}
 	}
 
-	_, err := db.stmts.markItemSeen.ExecContext(ctx, feedID, guid, titleVal, linkVal)
+	_, err := db.stmts.markItemSeen.ExecContext(ctx, feedID, guid, titleVal, linkVal, ItemHash(guid))
 	if err != nil {
 		return fmt.Errorf("mark item seen: %w", err)
 	}
@@ -55,9 +56,9 @@ func (db *DB) MarkItemSeenTx(ctx context.Context, tx *sql.Tx, feedID int64, guid
 	}
 
 	_, err := tx.ExecContext(ctx,
-		`INSERT INTO seen_items (feed_id, guid, title, link) VALUES (?, ?, ?, ?)
+		`INSERT INTO seen_items (feed_id, guid, title, link, guid_hash) VALUES (?, ?, ?, ?, ?)
 		 ON CONFLICT(feed_id, guid) DO UPDATE SET title = excluded.title, link = excluded.link`,
-		feedID, guid, titleVal, linkVal,
+		feedID, guid, titleVal, linkVal, ItemHash(guid),
 	)
 	if err != nil {
 		return fmt.Errorf("mark item seen: %w", err)
@@ -65,6 +66,30 @@ func (db *DB) MarkItemSeenTx(ctx context.Context, tx *sql.Tx, feedID int64, guid
 	return nil
 }
 
+// MarkItemDeliveredTx marks an item seen and records that it went out in the
+// digest sendID, as opposed to being seen at upload or filtered out. A zero
+// sendID means the send wasn't recorded.
+func (db *DB) MarkItemDeliveredTx(ctx context.Context, tx *sql.Tx, feedID, sendID int64, guid, title, link string) error {
+	var titleVal, linkVal sql.NullString
+	if title != "" {
+		titleVal = sql.NullString{String: title, Valid: true}
+	}
+	if link != "" {
+		linkVal = sql.NullString{String: link, Valid: true}
+	}
+
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO seen_items (feed_id, guid, title, link, guid_hash, delivered, email_send_id) VALUES (?, ?, ?, ?, ?, 1, ?)
+		 ON CONFLICT(feed_id, guid) DO UPDATE SET title = excluded.title, link = excluded.link,
+		   delivered = 1, email_send_id = excluded.email_send_id`,
+		feedID, guid, titleVal, linkVal, ItemHash(guid), sql.NullInt64{Int64: sendID, Valid: sendID != 0},
+	)
+	if err != nil {
+		return fmt.Errorf("mark item delivered: %w", err)
+	}
+	return nil
+}
+
 func (db *DB) GetSeenItems(ctx context.Context, feedID int64, limit int) ([]*SeenItem, error) {
 	rows, err := db.stmts.getSeenItems.QueryContext(ctx, feedID, limit)
 	if err != nil {
@@ -83,6 +108,70 @@ func (db *DB) GetSeenItems(ctx context.Context, feedID int64, limit int) ([]*See
 	return items, rows.Err()
 }
 
+// GetDeliveredItems is GetSeenItems limited to items that went out in a digest
+func (db *DB) GetDeliveredItems(ctx context.Context, feedID int64, limit int) ([]*SeenItem, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, feed_id, guid, title, link, seen_at
+		 FROM seen_items WHERE feed_id = ? AND delivered = 1 ORDER BY seen_at DESC LIMIT ?`,
+		feedID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query delivered items: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var items []*SeenItem
+	for rows.Next() {
+		var item SeenItem
+		if err := rows.Scan(&item.ID, &item.FeedID, &item.GUID, &item.Title, &item.Link, &item.SeenAt); err != nil {
+			return nil, fmt.Errorf("scan seen item: %w", err)
+		}
+		items = append(items, &item)
+	}
+	return items, rows.Err()
+}
+
+// SeenItemsFilter narrows GetSeenItemsByConfig. PerFeed caps how many recent
+// items each feed contributes (0 means no cap); DeliveredOnly keeps only items
+// that went out in a digest.
+type SeenItemsFilter struct {
+	PerFeed       int
+	DeliveredOnly bool
+}
+
+// GetSeenItemsByConfig returns the newest seen items across all of a config's
+// feeds in one query, newest first
+func (db *DB) GetSeenItemsByConfig(ctx context.Context, configID int64, limit int, filter SeenItemsFilter) ([]*SeenItem, error) {
+	delivered := ""
+	if filter.DeliveredOnly {
+		delivered = " AND s.delivered = 1"
+	}
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, feed_id, guid, title, link, seen_at FROM (
+		   SELECT s.id, s.feed_id, s.guid, s.title, s.link, s.seen_at,
+		          ROW_NUMBER() OVER (PARTITION BY s.feed_id ORDER BY s.seen_at DESC, s.id DESC) AS rn
+		   FROM seen_items s
+		   JOIN feeds f ON f.id = s.feed_id
+		   WHERE f.config_id = ?`+delivered+`
+		 )
+		 WHERE ? <= 0 OR rn <= ?
+		 ORDER BY seen_at DESC, id DESC LIMIT ?`,
+		configID, filter.PerFeed, filter.PerFeed, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query seen items by config: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var items []*SeenItem
+	for rows.Next() {
+		var item SeenItem
+		if err := rows.Scan(&item.ID, &item.FeedID, &item.GUID, &item.Title, &item.Link, &item.SeenAt); err != nil {
+			return nil, fmt.Errorf("scan seen item: %w", err)
+		}
+		items = append(items, &item)
+	}
+	return items, rows.Err()
+}
+
 // GetSeenGUIDs returns a set of GUIDs that have been seen for a given feed
 func (db *DB) GetSeenGUIDs(ctx context.Context, feedID int64, guids []string) (map[string]bool, error) {
 	if len(guids) == 0 {
@@ -124,6 +213,50 @@ func (db *DB) GetSeenGUIDs(ctx context.Context, feedID int64, guids []string) (m
 	return seenSet, rows.Err()
 }
 
+// FirstSeenDates returns when each of a feed's undated items was first
+// fetched, recording now for any GUID not fetched before
+func (db *DB) FirstSeenDates(ctx context.Context, feedID int64, guids []string, now time.Time) (map[string]time.Time, error) {
+	dates := make(map[string]time.Time, len(guids))
+	if len(guids) == 0 {
+		return dates, nil
+	}
+
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, guid := range guids {
+		var firstSeen time.Time
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO item_first_seen (feed_id, guid, first_seen_at) VALUES (?, ?, ?)
+			 ON CONFLICT(feed_id, guid) DO UPDATE SET first_seen_at = first_seen_at
+			 RETURNING first_seen_at`,
+			feedID, guid, now.UTC(),
+		).Scan(&firstSeen)
+		if err != nil {
+			return nil, fmt.Errorf("record first seen: %w", err)
+		}
+		dates[guid] = firstSeen
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit first seen: %w", err)
+	}
+	return dates, nil
+}
+
+// CleanupOldFirstSeen deletes first-seen dates older than the specified duration
+func (db *DB) CleanupOldFirstSeen(ctx context.Context, olderThan time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-olderThan).UTC()
+	result, err := db.ExecContext(ctx, `DELETE FROM item_first_seen WHERE first_seen_at < ?`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("cleanup old first seen: %w", err)
+	}
+	return result.RowsAffected()
+}
+
 // CleanupOldSeenItems deletes seen items older than the specified duration
 func (db *DB) CleanupOldSeenItems(ctx context.Context, olderThan time.Duration) (int64, error) {
 	cutoff := time.Now().Add(-olderThan)
@@ -139,3 +272,175 @@ func (db *DB) CleanupOldSeenItems(ctx context.Context, olderThan time.Duration)
 
 	return deleted, nil
 }
+
+// TrimSeenItems deletes the oldest seen items for a feed beyond the newest keep
+// rows. Items whose GUID is in current, the feed's present contents, are never
+// deleted, so an old item still in the feed isn't sent again.
+func (db *DB) TrimSeenItems(ctx context.Context, feedID int64, keep int, current []string) (int64, error) {
+	args := []any{feedID, feedID, keep}
+	query := `DELETE FROM seen_items WHERE feed_id = ? AND id NOT IN (
+			SELECT id FROM seen_items WHERE feed_id = ? ORDER BY seen_at DESC, id DESC LIMIT ?
+		)`
+	if len(current) > 0 {
+		query += ` AND guid NOT IN (?` + strings.Repeat(",?", len(current)-1) + `)`
+		for _, guid := range current {
+			args = append(args, guid)
+		}
+	}
+
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("trim seen items: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// DeleteFeedSeenItemsTx deletes a feed's seen items within a transaction,
+// keeping items that were clicked through from a digest
+func (db *DB) DeleteFeedSeenItemsTx(ctx context.Context, tx *sql.Tx, feedID int64) (int64, error) {
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM seen_items WHERE feed_id = ?
+		 AND NOT EXISTS (
+		     SELECT 1 FROM item_clicks c WHERE c.feed_id = seen_items.feed_id AND c.guid = seen_items.guid
+		 )`,
+		feedID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("delete seen items: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// DeleteSeenItemsByConfig deletes the seen items for every feed in a config,
+// keeping items that were clicked through from a digest
+func (db *DB) DeleteSeenItemsByConfig(ctx context.Context, configID int64) (int64, error) {
+	result, err := db.ExecContext(ctx,
+		`DELETE FROM seen_items WHERE feed_id IN (SELECT id FROM feeds WHERE config_id = ?)
+		 AND NOT EXISTS (
+		     SELECT 1 FROM item_clicks c WHERE c.feed_id = seen_items.feed_id AND c.guid = seen_items.guid
+		 )`,
+		configID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("delete seen items: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// maxSearchResults bounds SearchSeenItems regardless of the requested limit
+const maxSearchResults = 100
+
+// SeenItemMatch is a seen item found by SearchSeenItems with its config and feed
+type SeenItemMatch struct {
+	ConfigFilename string
+	FeedURL        string
+	FeedName       sql.NullString
+	Title          sql.NullString
+	Link           sql.NullString
+	SeenAt         time.Time
+}
+
+// SearchSeenItems finds a user's seen items whose title or link contains the
+// query, most recently seen first.
+func (db *DB) SearchSeenItems(ctx context.Context, userID int64, query string, limit int) ([]*SeenItemMatch, error) {
+	if limit <= 0 || limit > maxSearchResults {
+		limit = maxSearchResults
+	}
+
+	pattern := "%" + escapeLike(query) + "%"
+	rows, err := db.QueryContext(ctx,
+		`SELECT c.filename, f.url, f.name, s.title, s.link, s.seen_at
+		 FROM seen_items s
+		 JOIN feeds f ON s.feed_id = f.id
+		 JOIN configs c ON f.config_id = c.id
+		 WHERE c.user_id = ?
+		   AND (s.title LIKE ? ESCAPE '\' OR s.link LIKE ? ESCAPE '\')
+		 ORDER BY s.seen_at DESC, s.id DESC LIMIT ?`,
+		userID, pattern, pattern, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("search seen items: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var matches []*SeenItemMatch
+	for rows.Next() {
+		var m SeenItemMatch
+		if err := rows.Scan(&m.ConfigFilename, &m.FeedURL, &m.FeedName, &m.Title, &m.Link, &m.SeenAt); err != nil {
+			return nil, fmt.Errorf("scan seen item match: %w", err)
+		}
+		matches = append(matches, &m)
+	}
+	return matches, rows.Err()
+}
+
+// escapeLike escapes LIKE wildcards so the query matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// ImportSeenItems marks items seen on the feed with feedURL in the user's
+// named config, keeping their titles, links, and seen times when given.
+// Items already seen are left alone. It returns how many were added.
+func (db *DB) ImportSeenItems(ctx context.Context, userID int64, configFilename, feedURL string, items []SeenItem) (int, error) {
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var feedID int64
+	err = tx.QueryRowContext(ctx,
+		`SELECT f.id FROM feeds f JOIN configs c ON c.id = f.config_id
+		 WHERE c.user_id = ? AND c.filename = ? AND f.url = ?`,
+		userID, configFilename, feedURL,
+	).Scan(&feedID)
+	if err != nil {
+		return 0, fmt.Errorf("find feed: %w", err)
+	}
+
+	added := 0
+	for _, item := range items {
+		if item.GUID == "" {
+			continue
+		}
+		var seenAt sql.NullTime
+		if !item.SeenAt.IsZero() {
+			seenAt = sql.NullTime{Time: item.SeenAt.UTC(), Valid: true}
+		}
+		result, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO seen_items (feed_id, guid, title, link, guid_hash, seen_at)
+			 VALUES (?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP))`,
+			feedID, item.GUID, item.Title, item.Link, ItemHash(item.GUID), seenAt,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("import seen item: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			added++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return added, nil
+}
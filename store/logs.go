@@ -26,10 +26,16 @@ func (db *DB) AddLog(ctx context.Context, configID int64, level, message string)
 }
 
 func (db *DB) GetLogs(ctx context.Context, configID int64, limit int) ([]*Log, error) {
+	return db.GetLogsByLevel(ctx, configID, "", limit)
+}
+
+// GetLogsByLevel is GetLogs limited to one level; an empty level returns all
+func (db *DB) GetLogsByLevel(ctx context.Context, configID int64, level string, limit int) ([]*Log, error) {
 	rows, err := db.QueryContext(ctx,
 		`SELECT id, config_id, message, level, created_at
-		 FROM logs WHERE config_id = ? ORDER BY created_at DESC LIMIT ?`,
-		configID, limit,
+		 FROM logs WHERE config_id = ? AND (? = '' OR level = ?)
+		 ORDER BY created_at DESC LIMIT ?`,
+		configID, level, level, limit,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("query logs: %w", err)
@@ -71,3 +77,24 @@ func (db *DB) GetRecentLogs(ctx context.Context, userID int64, limit int) ([]*Lo
 	}
 	return logs, rows.Err()
 }
+
+// CleanupOldLogs removes log entries older than the given number of days
+func (db *DB) CleanupOldLogs(ctx context.Context, daysToKeep int) (int64, error) {
+	result, err := db.ExecContext(ctx,
+		`DELETE FROM logs WHERE created_at < datetime('now', '-' || ? || ' days')`,
+		daysToKeep,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("cleanup old logs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteLogsByConfig removes all log entries for a config
+func (db *DB) DeleteLogsByConfig(ctx context.Context, configID int64) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM logs WHERE config_id = ?`, configID)
+	if err != nil {
+		return 0, fmt.Errorf("delete logs: %w", err)
+	}
+	return result.RowsAffected()
+}
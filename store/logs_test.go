@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLogCleanup(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.txt", "test@example.com", "0 8 * * *", true, false, "", time.Now())
+	other, _ := db.CreateConfig(ctx, user.ID, "other.txt", "test@example.com", "0 8 * * *", true, false, "", time.Now())
+
+	for _, id := range []int64{cfg.ID, cfg.ID, other.ID} {
+		if err := db.AddLog(ctx, id, "info", "message"); err != nil {
+			t.Fatalf("AddLog failed: %v", err)
+		}
+	}
+	if _, err := db.Exec(`UPDATE logs SET created_at = datetime('now', '-40 days') WHERE id = (SELECT MIN(id) FROM logs)`); err != nil {
+		t.Fatalf("backdate log: %v", err)
+	}
+
+	deleted, err := db.CleanupOldLogs(ctx, 30)
+	if err != nil {
+		t.Fatalf("CleanupOldLogs failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 old log deleted, got %d", deleted)
+	}
+
+	deleted, err = db.DeleteLogsByConfig(ctx, cfg.ID)
+	if err != nil {
+		t.Fatalf("DeleteLogsByConfig failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 log deleted for config, got %d", deleted)
+	}
+
+	logs, err := db.GetRecentLogs(ctx, user.ID, 10)
+	if err != nil {
+		t.Fatalf("GetRecentLogs failed: %v", err)
+	}
+	if len(logs) != 1 || logs[0].ConfigID != other.ID {
+		t.Errorf("expected only other config's log to remain, got %+v", logs)
+	}
+}
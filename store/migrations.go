@@ -0,0 +1,353 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migration is a single versioned schema change. Migrations run in order
+// inside a transaction and are recorded in schema_migrations once applied.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations must only ever be appended to; applied versions are never rerun.
+var migrations = []migration{
+	{1, "initial schema", execSQL(initialSchema)},
+	{2, "feed request headers", addColumns(
+		column{"feeds", "headers", "TEXT"},
+	)},
+	{3, "config boost", addColumns(
+		column{"configs", "boost_interval", "INTEGER"},
+		column{"configs", "boost_until", "DATETIME"},
+	)},
+	{4, "feed request method and body", addColumns(
+		column{"feeds", "method", "TEXT"},
+		column{"feeds", "body", "TEXT"},
+	)},
+	{5, "feed last error", addColumns(
+		column{"feeds", "last_error", "TEXT"},
+	)},
+	{6, "feed enabled flag", addColumns(
+		column{"feeds", "enabled", "INTEGER NOT NULL DEFAULT 1"},
+	)},
+	{7, "user email rate limit", addColumns(
+		column{"users", "email_rate_limit", "INTEGER NOT NULL DEFAULT 0"},
+	)},
+	{8, "config adaptive multiplier", addColumns(
+		column{"configs", "adaptive_multiplier", "INTEGER NOT NULL DEFAULT 1"},
+	)},
+	{9, "feed favicon", addColumns(
+		column{"feeds", "favicon", "TEXT"},
+		column{"feeds", "favicon_checked_at", "DATETIME"},
+	)},
+	{10, "config retry attempt", addColumns(
+		column{"configs", "retry_attempt", "INTEGER NOT NULL DEFAULT 0"},
+	)},
+	{11, "item clicks", execSQL(`
+CREATE TABLE IF NOT EXISTS item_clicks (
+	id INTEGER PRIMARY KEY,
+	tracking_token TEXT NOT NULL,
+	feed_id INTEGER NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+	guid TEXT NOT NULL,
+	clicked_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_item_clicks_tracking_token ON item_clicks(tracking_token);
+CREATE INDEX IF NOT EXISTS idx_item_clicks_feed_guid ON item_clicks(feed_id, guid);
+`)},
+	{12, "undated item first seen", execSQL(`
+CREATE TABLE IF NOT EXISTS item_first_seen (
+	feed_id INTEGER NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+	guid TEXT NOT NULL,
+	first_seen_at DATETIME NOT NULL,
+	PRIMARY KEY (feed_id, guid)
+);
+CREATE INDEX IF NOT EXISTS idx_item_first_seen_at ON item_first_seen(first_seen_at);
+`)},
+	{13, "feed note", addColumns(
+		column{"feeds", "note", "TEXT"},
+	)},
+	{14, "global feed fetch times", execSQL(`
+CREATE TABLE IF NOT EXISTS feed_url_fetches (
+	url TEXT PRIMARY KEY,
+	last_global_fetch DATETIME NOT NULL
+);
+`)},
+	{15, "per-feed proxy", addColumns(
+		column{"feeds", "proxy", "TEXT"},
+	)},
+	{16, "email body audit", addColumns(
+		column{"email_sends", "body_sha256", "TEXT"},
+		column{"email_sends", "body_text", "TEXT"},
+	)},
+	{17, "public archive slug", func(tx *sql.Tx) error {
+		if err := addColumns(column{"configs", "public_slug", "TEXT"})(tx); err != nil {
+			return err
+		}
+		return execSQL(`CREATE UNIQUE INDEX IF NOT EXISTS idx_configs_public_slug ON configs(public_slug)`)(tx)
+	}},
+	{18, "user config defaults", addColumns(
+		column{"users", "default_cron", "TEXT"},
+		column{"users", "default_digest", "INTEGER"},
+		column{"users", "default_inline", "INTEGER"},
+	)},
+	{19, "feed description", addColumns(column{"feeds", "description", "TEXT"})},
+	{20, "feed warmup", addColumns(column{"feeds", "warmup", "TEXT"})},
+	{21, "delivered seen items", addColumns(column{"seen_items", "delivered", "INTEGER NOT NULL DEFAULT 0"})},
+	{22, "seen item digest send", func(tx *sql.Tx) error {
+		if err := addColumns(column{"seen_items", "email_send_id", "INTEGER REFERENCES email_sends(id) ON DELETE SET NULL"})(tx); err != nil {
+			return err
+		}
+		return execSQL(`CREATE INDEX IF NOT EXISTS idx_seen_items_email_send ON seen_items(email_send_id)`)(tx)
+	}},
+	{23, "seen item guid hash", func(tx *sql.Tx) error {
+		if err := addColumns(column{"seen_items", "guid_hash", "TEXT"})(tx); err != nil {
+			return err
+		}
+		if err := backfillGUIDHashes(tx); err != nil {
+			return err
+		}
+		return execSQL(`CREATE INDEX IF NOT EXISTS idx_seen_items_guid_hash ON seen_items(guid_hash)`)(tx)
+	}},
+}
+
+const initialSchema = `
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY,
+	pubkey_fp TEXT UNIQUE NOT NULL,
+	pubkey TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS configs (
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	filename TEXT NOT NULL,
+	email TEXT NOT NULL,
+	cron_expr TEXT NOT NULL,
+	digest BOOLEAN DEFAULT TRUE,
+	inline_content BOOLEAN DEFAULT FALSE,
+	raw_text TEXT NOT NULL,
+	last_run DATETIME,
+	next_run DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_active_at DATETIME,
+	UNIQUE(user_id, filename)
+);
+
+CREATE TABLE IF NOT EXISTS feeds (
+	id INTEGER PRIMARY KEY,
+	config_id INTEGER NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
+	url TEXT NOT NULL,
+	name TEXT,
+	last_fetched DATETIME,
+	etag TEXT,
+	last_modified TEXT
+);
+
+CREATE TABLE IF NOT EXISTS seen_items (
+	id INTEGER PRIMARY KEY,
+	feed_id INTEGER NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+	guid TEXT NOT NULL,
+	title TEXT,
+	link TEXT,
+	seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(feed_id, guid)
+);
+
+CREATE TABLE IF NOT EXISTS logs (
+	id INTEGER PRIMARY KEY,
+	config_id INTEGER NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
+	message TEXT NOT NULL,
+	level TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS unsubscribe_tokens (
+	id INTEGER PRIMARY KEY,
+	token TEXT UNIQUE NOT NULL,
+	config_id INTEGER NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS email_sends (
+	id INTEGER PRIMARY KEY,
+	config_id INTEGER NOT NULL REFERENCES configs(id) ON DELETE CASCADE,
+	recipient TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	tracking_token TEXT UNIQUE,
+	sent_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	bounced BOOLEAN DEFAULT FALSE,
+	bounce_reason TEXT,
+	opened BOOLEAN DEFAULT FALSE,
+	opened_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_configs_user_id ON configs(user_id);
+CREATE INDEX IF NOT EXISTS idx_configs_active_next_run ON configs(next_run) WHERE next_run IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_feeds_config_id ON feeds(config_id);
+CREATE INDEX IF NOT EXISTS idx_seen_items_feed_id ON seen_items(feed_id);
+CREATE INDEX IF NOT EXISTS idx_logs_config_id ON logs(config_id);
+CREATE INDEX IF NOT EXISTS idx_logs_created_at ON logs(created_at);
+CREATE INDEX IF NOT EXISTS idx_unsubscribe_tokens_token ON unsubscribe_tokens(token);
+CREATE INDEX IF NOT EXISTS idx_email_sends_config_id ON email_sends(config_id);
+CREATE INDEX IF NOT EXISTS idx_email_sends_tracking_token ON email_sends(tracking_token);
+CREATE INDEX IF NOT EXISTS idx_email_sends_sent_at ON email_sends(sent_at);
+`
+
+func (db *DB) migrate() error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		if err := db.applyMigration(m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (db *DB) appliedMigrations() (map[int]bool, error) {
+	rows, err := db.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("query schema_migrations: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("scan migration version: %w", err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+func (db *DB) applyMigration(m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.version, m.name); err != nil {
+		return fmt.Errorf("record migration: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func execSQL(query string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
+type column struct {
+	table, name, definition string
+}
+
+// addColumns adds columns that are missing. Databases created before
+// versioned migrations may already have some of them, and SQLite has no
+// ADD COLUMN IF NOT EXISTS.
+func addColumns(columns ...column) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, c := range columns {
+			exists, err := hasColumn(tx, c.table, c.name)
+			if err != nil {
+				return fmt.Errorf("check column %s.%s: %w", c.table, c.name, err)
+			}
+			if exists {
+				continue
+			}
+			if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.name, c.definition)); err != nil {
+				return fmt.Errorf("add column %s.%s: %w", c.table, c.name, err)
+			}
+		}
+		return nil
+	}
+}
+
+// backfillGUIDHashes sets guid_hash on seen items stored before it existed.
+// SQLite has no SHA-256, so the hashes are computed here.
+func backfillGUIDHashes(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT id, guid FROM seen_items WHERE guid_hash IS NULL`)
+	if err != nil {
+		return fmt.Errorf("query seen items: %w", err)
+	}
+	hashes := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var guid string
+		if err := rows.Scan(&id, &guid); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("scan seen item: %w", err)
+		}
+		hashes[id] = ItemHash(guid)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate seen items: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`UPDATE seen_items SET guid_hash = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("prepare guid hash update: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+	for id, hash := range hashes {
+		if _, err := stmt.Exec(hash, id); err != nil {
+			return fmt.Errorf("update guid hash: %w", err)
+		}
+	}
+	return nil
+}
+
+func hasColumn(tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   bool
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateOldSchemaForward(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+
+	// Build a database the way it looked before versioned migrations
+	raw, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("open raw db: %v", err)
+	}
+	if _, err := raw.Exec(initialSchema); err != nil {
+		t.Fatalf("create old schema: %v", err)
+	}
+	if _, err := raw.Exec(`ALTER TABLE feeds ADD COLUMN headers TEXT`); err != nil {
+		t.Fatalf("add legacy column: %v", err)
+	}
+	if _, err := raw.Exec(`INSERT INTO users (pubkey_fp, pubkey) VALUES ('fp', 'key')`); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	if _, err := raw.Exec(`INSERT INTO configs (user_id, filename, email, cron_expr, raw_text) VALUES (1, 'a.txt', 'a@example.com', '0 8 * * *', 'raw')`); err != nil {
+		t.Fatalf("insert config: %v", err)
+	}
+	if _, err := raw.Exec(`INSERT INTO feeds (config_id, url) VALUES (1, 'https://example.com/feed.xml')`); err != nil {
+		t.Fatalf("insert feed: %v", err)
+	}
+	if _, err := raw.Exec(`INSERT INTO seen_items (feed_id, guid, link) VALUES (1, 'old-guid', 'https://example.com/old')`); err != nil {
+		t.Fatalf("insert seen item: %v", err)
+	}
+	_ = raw.Close()
+
+	ctx := context.Background()
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&count); err != nil {
+		t.Fatalf("count migrations: %v", err)
+	}
+	if count != len(migrations) {
+		t.Errorf("expected %d applied migrations, got %d", len(migrations), count)
+	}
+
+	feeds, err := db.GetAllFeedsByConfig(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetAllFeedsByConfig failed: %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].Disabled {
+		t.Fatalf("expected existing feed to survive as enabled, got %+v", feeds)
+	}
+
+	cfg, err := db.GetConfigByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetConfigByID failed: %v", err)
+	}
+	if cfg.RawText != "raw" || cfg.BoostInterval.Valid {
+		t.Errorf("unexpected migrated config: %+v", cfg)
+	}
+
+	var hash string
+	if err := db.QueryRow(`SELECT guid_hash FROM seen_items WHERE guid = 'old-guid'`).Scan(&hash); err != nil {
+		t.Fatalf("query guid hash: %v", err)
+	}
+	if hash != ItemHash("old-guid") {
+		t.Errorf("expected existing seen items to get a guid hash, got %q", hash)
+	}
+
+	// Re-running is a no-op
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("second Migrate failed: %v", err)
+	}
+}
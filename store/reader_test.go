@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openFileDB(tb testing.TB) *DB {
+	tb.Helper()
+	db, err := Open(filepath.Join(tb.TempDir(), "herald.db"))
+	if err != nil {
+		tb.Fatalf("Open failed: %v", err)
+	}
+	tb.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestReadOnly(t *testing.T) {
+	db := openFileDB(t)
+	ctx := context.Background()
+
+	reader := db.ReadOnly()
+	if reader == db {
+		t.Fatal("expected a separate reader for file databases")
+	}
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now())
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "", FeedOptions{})
+	_ = db.MarkItemSeen(ctx, feed.ID, "a", "Title", "https://example.com/a")
+
+	// Committed writes are visible through the reader
+	got, err := reader.GetConfig(ctx, user.ID, "test.txt")
+	if err != nil {
+		t.Fatalf("reader GetConfig failed: %v", err)
+	}
+	if got.RawText != "raw" {
+		t.Errorf("expected raw text, got %q", got.RawText)
+	}
+	items, err := reader.GetSeenItems(ctx, feed.ID, 10)
+	if err != nil || len(items) != 1 {
+		t.Errorf("expected 1 seen item through reader, got %d (%v)", len(items), err)
+	}
+
+	// Writes through the reader are rejected
+	if _, err := reader.GetOrCreateUser(ctx, "other-fp", "other-pubkey"); err == nil {
+		t.Error("expected write through reader to fail")
+	}
+}
+
+func TestReadOnlyInMemory(t *testing.T) {
+	db := setupTestDB(t)
+	if db.ReadOnly() != db {
+		t.Error("expected in-memory databases to read through the writer")
+	}
+}
+
+// BenchmarkConcurrentReads compares dashboard-style reads through the single
+// writer connection against the read-only pool while a background writer
+// keeps short transactions open, as the scheduler does when marking items seen.
+func BenchmarkConcurrentReads(b *testing.B) {
+	db := openFileDB(b)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	var configIDs []int64
+	for i := 0; i < 20; i++ {
+		cfg, _ := db.CreateConfig(ctx, user.ID, fmt.Sprintf("c%d.txt", i), "user@example.com", "0 8 * * *", true, false, "raw", time.Now())
+		configIDs = append(configIDs, cfg.ID)
+		for j := 0; j < 10; j++ {
+			feed, _ := db.CreateFeed(ctx, cfg.ID, fmt.Sprintf("https://example.com/%d/%d.xml", i, j), "", FeedOptions{})
+			for k := 0; k < 50; k++ {
+				_ = db.MarkItemSeen(ctx, feed.ID, fmt.Sprintf("guid-%d", k), "Title", "https://example.com/item")
+			}
+		}
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			tx, err := db.BeginTx(ctx)
+			if err != nil {
+				continue
+			}
+			_ = db.MarkItemSeenTx(ctx, tx, 1, fmt.Sprintf("bench-%d", i), "Title", "")
+			time.Sleep(time.Millisecond)
+			_ = tx.Commit()
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	read := func(h *DB) func(*testing.PB) {
+		return func(pb *testing.PB) {
+			for pb.Next() {
+				feeds, err := h.GetFeedsByConfigs(ctx, configIDs)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for _, f := range feeds[configIDs[0]] {
+					if _, err := h.GetSeenItems(ctx, f.ID, 50); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		}
+	}
+
+	b.Run("writer", func(b *testing.B) {
+		b.SetParallelism(4)
+		b.RunParallel(read(db))
+	})
+	b.Run("reader", func(b *testing.B) {
+		b.SetParallelism(4)
+		b.RunParallel(read(db.ReadOnly()))
+	})
+}
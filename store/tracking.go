@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"crypto/rand"
 	"database/sql"
 	"encoding/base64"
@@ -42,15 +43,17 @@ func (db *DB) RecordEmailSend(configID int64, recipient, subject string, include
 	return trackingToken, nil
 }
 
-// RecordEmailSendTx records an email send within an existing transaction
-func (db *DB) RecordEmailSendTx(tx *sql.Tx, configID int64, recipient, subject, trackingToken string) error {
-	query := `INSERT INTO email_sends (config_id, recipient, subject, tracking_token)
-	          VALUES (?, ?, ?, ?)`
-	_, err := tx.Exec(query, configID, recipient, subject, sql.NullString{String: trackingToken, Valid: trackingToken != ""})
+// RecordEmailSendTx records an email send within an existing transaction and
+// returns its id. bodyHash and bodyText are the audit record of the body and
+// may be empty.
+func (db *DB) RecordEmailSendTx(tx *sql.Tx, configID int64, recipient, subject, trackingToken, bodyHash, bodyText string) (int64, error) {
+	query := `INSERT INTO email_sends (config_id, recipient, subject, tracking_token, body_sha256, body_text)
+	          VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := tx.Exec(query, configID, recipient, subject, sql.NullString{String: trackingToken, Valid: trackingToken != ""}, nullIfEmpty(bodyHash), nullIfEmpty(bodyText))
 	if err != nil {
-		return fmt.Errorf("insert email send: %w", err)
+		return 0, fmt.Errorf("insert email send: %w", err)
 	}
-	return nil
+	return result.LastInsertId()
 }
 
 // MarkEmailBounced marks an email as bounced
@@ -125,6 +128,21 @@ func (db *DB) GetInactiveConfigs(daysWithoutActivity int, minSends int) ([]int64
 	return configIDs, rows.Err()
 }
 
+// GetRecentEngagement returns how many of a config's last n sends there are,
+// up to n, and how many of them were opened. Unlike GetConfigEngagement it
+// doesn't depend on how often the config sends.
+func (db *DB) GetRecentEngagement(ctx context.Context, configID int64, n int) (sends, opens int, err error) {
+	err = db.QueryRowContext(ctx,
+		`SELECT COUNT(*), COALESCE(SUM(CASE WHEN opened = TRUE THEN 1 ELSE 0 END), 0)
+		 FROM (SELECT opened FROM email_sends WHERE config_id = ? ORDER BY sent_at DESC, id DESC LIMIT ?)`,
+		configID, n,
+	).Scan(&sends, &opens)
+	if err != nil {
+		return 0, 0, fmt.Errorf("query recent engagement: %w", err)
+	}
+	return sends, opens, nil
+}
+
 // GetConfigEngagement returns engagement stats for a config
 func (db *DB) GetConfigEngagement(configID int64, days int) (totalSends, opens, bounces int, lastOpen *time.Time, err error) {
 	// First get counts
@@ -170,6 +188,58 @@ func (db *DB) GetConfigEngagement(configID int64, days int) (totalSends, opens,
 	return totalSends, opens, bounces, lastOpen, nil
 }
 
+// HasSentSince reports whether a digest was sent for the config after since
+func (db *DB) HasSentSince(configID int64, since time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM email_sends
+			WHERE config_id = ?
+			AND sent_at > ?
+		)
+	`
+
+	// sent_at holds SQLite's CURRENT_TIMESTAMP, UTC in time.DateTime layout
+	var sent bool
+	if err := db.QueryRow(query, configID, since.UTC().Format(time.DateTime)).Scan(&sent); err != nil {
+		return false, fmt.Errorf("query recent sends: %w", err)
+	}
+	return sent, nil
+}
+
+// EmailAudit is a sent digest with its audit record, if one was kept
+type EmailAudit struct {
+	ID         int64
+	Recipient  string
+	Subject    string
+	SentAt     time.Time
+	BodySHA256 sql.NullString
+	BodyText   sql.NullString
+}
+
+// GetEmailAudit returns a config's most recent sends, newest first
+func (db *DB) GetEmailAudit(ctx context.Context, configID int64, limit int) ([]*EmailAudit, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, recipient, subject, sent_at, body_sha256, body_text
+		 FROM email_sends WHERE config_id = ?
+		 ORDER BY sent_at DESC, id DESC LIMIT ?`,
+		configID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query email audit: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var sends []*EmailAudit
+	for rows.Next() {
+		var a EmailAudit
+		if err := rows.Scan(&a.ID, &a.Recipient, &a.Subject, &a.SentAt, &a.BodySHA256, &a.BodyText); err != nil {
+			return nil, fmt.Errorf("scan email audit: %w", err)
+		}
+		sends = append(sends, &a)
+	}
+	return sends, rows.Err()
+}
+
 // CleanupOldSends removes email send records older than specified days
 func (db *DB) CleanupOldSends(daysToKeep int) (int64, error) {
 	query := `DELETE FROM email_sends WHERE sent_at < datetime('now', '-' || ? || ' days')`
@@ -220,3 +290,32 @@ func (db *DB) UpdateLastActive(trackingToken string) error {
 
 	return nil
 }
+
+// TouchConfigActivity records reading a config's feed as activity, so configs
+// read on the web instead of by email aren't auto-deactivated. Activity within
+// the last hour is left as is.
+func (db *DB) TouchConfigActivity(ctx context.Context, configID int64) error {
+	_, err := db.ExecContext(ctx, `UPDATE configs
+		SET last_active_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+		AND (last_active_at IS NULL OR last_active_at < datetime('now', '-1 hour'))`,
+		configID)
+	if err != nil {
+		return fmt.Errorf("touch config activity: %w", err)
+	}
+	return nil
+}
+
+// TouchUserActivity records a dashboard visit as activity on all of the
+// user's configs
+func (db *DB) TouchUserActivity(ctx context.Context, userID int64) error {
+	_, err := db.ExecContext(ctx, `UPDATE configs
+		SET last_active_at = CURRENT_TIMESTAMP
+		WHERE user_id = ?
+		AND (last_active_at IS NULL OR last_active_at < datetime('now', '-1 hour'))`,
+		userID)
+	if err != nil {
+		return fmt.Errorf("touch user activity: %w", err)
+	}
+	return nil
+}
@@ -148,3 +148,123 @@ func TestEmailTracking(t *testing.T) {
 		}
 	})
 }
+
+func TestHasSentSince(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.txt", "test@example.com", "0 8 * * *", true, false, "", time.Now())
+
+	dayAgo := time.Now().Add(-24 * time.Hour)
+	sent, err := db.HasSentSince(cfg.ID, dayAgo)
+	if err != nil {
+		t.Fatalf("HasSentSince failed: %v", err)
+	}
+	if sent {
+		t.Error("expected no recent sends")
+	}
+
+	if _, err := db.RecordEmailSend(cfg.ID, "test@example.com", "Subject", false); err != nil {
+		t.Fatalf("RecordEmailSend failed: %v", err)
+	}
+	if sent, _ := db.HasSentSince(cfg.ID, dayAgo); !sent {
+		t.Error("expected a recent send")
+	}
+
+	if _, err := db.Exec(`UPDATE email_sends SET sent_at = datetime('now', '-2 days')`); err != nil {
+		t.Fatalf("backdate send: %v", err)
+	}
+	if sent, _ := db.HasSentSince(cfg.ID, dayAgo); sent {
+		t.Error("expected send older than window to be ignored")
+	}
+	if sent, _ := db.HasSentSince(cfg.ID, time.Now().Add(-72*time.Hour)); !sent {
+		t.Error("expected send inside an earlier window to count")
+	}
+}
+
+func TestTouchConfigActivity(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.txt", "test@example.com", "0 8 * * *", true, false, "", time.Now())
+	other, _ := db.CreateConfig(ctx, user.ID, "other.txt", "test@example.com", "0 8 * * *", true, false, "", time.Now())
+
+	if err := db.TouchConfigActivity(ctx, cfg.ID); err != nil {
+		t.Fatalf("TouchConfigActivity failed: %v", err)
+	}
+	got, _ := db.GetConfigByID(ctx, cfg.ID)
+	if !got.LastActiveAt.Valid {
+		t.Fatal("expected last_active_at to be set")
+	}
+	if got, _ := db.GetConfigByID(ctx, other.ID); got.LastActiveAt.Valid {
+		t.Error("expected other config to be untouched")
+	}
+
+	// Recent activity isn't rewritten
+	if _, err := db.Exec(`UPDATE configs SET last_active_at = datetime('now', '-30 minutes') WHERE id = ?`, cfg.ID); err != nil {
+		t.Fatalf("backdate: %v", err)
+	}
+	before, _ := db.GetConfigByID(ctx, cfg.ID)
+	_ = db.TouchConfigActivity(ctx, cfg.ID)
+	if after, _ := db.GetConfigByID(ctx, cfg.ID); !after.LastActiveAt.Time.Equal(before.LastActiveAt.Time) {
+		t.Errorf("expected last_active_at to stay %s, got %s", before.LastActiveAt.Time, after.LastActiveAt.Time)
+	}
+
+	if err := db.TouchUserActivity(ctx, user.ID); err != nil {
+		t.Fatalf("TouchUserActivity failed: %v", err)
+	}
+	if got, _ := db.GetConfigByID(ctx, other.ID); !got.LastActiveAt.Valid {
+		t.Error("expected dashboard visit to mark every config active")
+	}
+}
+
+func TestGetEmailAudit(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, err := db.CreateConfig(ctx, user.ID, "test.txt", "test@example.com", "0 0 * * *", true, false, "test config", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("create config: %v", err)
+	}
+
+	if _, err := db.RecordEmailSend(cfg.ID, "test@example.com", "Plain", false); err != nil {
+		t.Fatalf("record email send: %v", err)
+	}
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("begin tx: %v", err)
+	}
+	if _, err := db.RecordEmailSendTx(tx, cfg.ID, "test@example.com", "Audited", "", "abc123", "body text"); err != nil {
+		t.Fatalf("record email send tx: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	sends, err := db.GetEmailAudit(ctx, cfg.ID, 10)
+	if err != nil {
+		t.Fatalf("get email audit: %v", err)
+	}
+	if len(sends) != 2 {
+		t.Fatalf("expected 2 sends, got %d", len(sends))
+	}
+	audited := sends[0]
+	if audited.Subject != "Audited" || audited.BodySHA256.String != "abc123" || audited.BodyText.String != "body text" {
+		t.Errorf("unexpected audited send: %+v", audited)
+	}
+	if sends[1].BodySHA256.Valid || sends[1].BodyText.Valid {
+		t.Errorf("expected no audit record on plain send: %+v", sends[1])
+	}
+
+	sends, err = db.GetEmailAudit(ctx, cfg.ID, 1)
+	if err != nil {
+		t.Fatalf("get email audit: %v", err)
+	}
+	if len(sends) != 1 {
+		t.Errorf("expected limit to apply, got %d sends", len(sends))
+	}
+}
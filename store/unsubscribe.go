@@ -8,15 +8,21 @@ import (
 	"fmt"
 )
 
-func (db *DB) CreateUnsubscribeToken(ctx context.Context, configID int64) (string, error) {
-	// Generate random token
+func newUnsubscribeToken() (string, error) {
 	tokenBytes := make([]byte, 32)
 	if _, err := rand.Read(tokenBytes); err != nil {
 		return "", fmt.Errorf("generate token: %w", err)
 	}
-	token := base64.URLEncoding.EncodeToString(tokenBytes)
+	return base64.URLEncoding.EncodeToString(tokenBytes), nil
+}
 
-	_, err := db.ExecContext(ctx,
+func (db *DB) CreateUnsubscribeToken(ctx context.Context, configID int64) (string, error) {
+	token, err := newUnsubscribeToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = db.ExecContext(ctx,
 		`INSERT INTO unsubscribe_tokens (token, config_id) VALUES (?, ?)`,
 		token, configID,
 	)
@@ -67,3 +73,33 @@ func (db *DB) GetOrCreateUnsubscribeToken(ctx context.Context, configID int64) (
 	// Create new token
 	return db.CreateUnsubscribeToken(ctx, configID)
 }
+
+// RotateUnsubscribeToken replaces a config's unsubscribe tokens with a new one.
+// Unsubscribe links in digests already sent stop working.
+func (db *DB) RotateUnsubscribeToken(ctx context.Context, configID int64) (string, error) {
+	token, err := newUnsubscribeToken()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		return "", fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM unsubscribe_tokens WHERE config_id = ?`, configID); err != nil {
+		return "", fmt.Errorf("delete tokens: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO unsubscribe_tokens (token, config_id) VALUES (?, ?)`,
+		token, configID,
+	); err != nil {
+		return "", fmt.Errorf("insert token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("commit token rotation: %w", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRotateUnsubscribeToken(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := db.GetOrCreateUser(ctx, "test-fp", "test-pubkey")
+	cfg, _ := db.CreateConfig(ctx, user.ID, "test.txt", "test@example.com", "0 8 * * *", true, false, "", time.Now())
+
+	old, err := db.GetOrCreateUnsubscribeToken(ctx, cfg.ID)
+	if err != nil {
+		t.Fatalf("GetOrCreateUnsubscribeToken failed: %v", err)
+	}
+
+	rotated, err := db.RotateUnsubscribeToken(ctx, cfg.ID)
+	if err != nil {
+		t.Fatalf("RotateUnsubscribeToken failed: %v", err)
+	}
+	if rotated == old {
+		t.Fatal("expected a new token")
+	}
+
+	if _, err := db.GetConfigByToken(ctx, old); err == nil {
+		t.Error("expected the old token to be invalidated")
+	}
+	if got, err := db.GetConfigByToken(ctx, rotated); err != nil || got.ID != cfg.ID {
+		t.Errorf("expected the new token to resolve to config %d, got %v, %v", cfg.ID, got, err)
+	}
+	if current, _ := db.GetOrCreateUnsubscribeToken(ctx, cfg.ID); current != rotated {
+		t.Errorf("expected later digests to use %q, got %q", rotated, current)
+	}
+}
@@ -14,6 +14,8 @@ type User struct {
 	PubkeyFP  string
 	Pubkey    string
 	CreatedAt time.Time
+	// EmailRateLimit overrides the default emails per minute; 0 uses the default
+	EmailRateLimit int
 }
 
 func (db *DB) GetOrCreateUser(ctx context.Context, pubkeyFP, pubkey string) (*User, error) {
@@ -49,9 +51,9 @@ func (db *DB) GetOrCreateUser(ctx context.Context, pubkeyFP, pubkey string) (*Us
 func (db *DB) GetUserByFingerprint(ctx context.Context, fp string) (*User, error) {
 	var user User
 	err := db.QueryRowContext(ctx,
-		`SELECT id, pubkey_fp, pubkey, created_at FROM users WHERE pubkey_fp = ?`,
+		`SELECT id, pubkey_fp, pubkey, created_at, email_rate_limit FROM users WHERE pubkey_fp = ?`,
 		fp,
-	).Scan(&user.ID, &user.PubkeyFP, &user.Pubkey, &user.CreatedAt)
+	).Scan(&user.ID, &user.PubkeyFP, &user.Pubkey, &user.CreatedAt, &user.EmailRateLimit)
 	if err != nil {
 		return nil, err
 	}
@@ -61,15 +63,71 @@ func (db *DB) GetUserByFingerprint(ctx context.Context, fp string) (*User, error
 func (db *DB) GetUserByID(ctx context.Context, userID int64) (*User, error) {
 	var user User
 	err := db.QueryRowContext(ctx,
-		`SELECT id, pubkey_fp, pubkey, created_at FROM users WHERE id = ?`,
+		`SELECT id, pubkey_fp, pubkey, created_at, email_rate_limit FROM users WHERE id = ?`,
 		userID,
-	).Scan(&user.ID, &user.PubkeyFP, &user.Pubkey, &user.CreatedAt)
+	).Scan(&user.ID, &user.PubkeyFP, &user.Pubkey, &user.CreatedAt, &user.EmailRateLimit)
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
+// SetUserEmailRateLimit sets a user's emails-per-minute override; 0 restores the default
+func (db *DB) SetUserEmailRateLimit(ctx context.Context, fp string, perMinute int) error {
+	if perMinute < 0 {
+		return fmt.Errorf("email rate limit must not be negative")
+	}
+	result, err := db.ExecContext(ctx,
+		`UPDATE users SET email_rate_limit = ? WHERE pubkey_fp = ?`,
+		perMinute, fp,
+	)
+	if err != nil {
+		return fmt.Errorf("set email rate limit: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UserDefaults fill in directives that a user's configs leave out. Unset
+// fields fall back to the usual defaults.
+type UserDefaults struct {
+	CronExpr sql.NullString
+	Digest   sql.NullBool
+	Inline   sql.NullBool
+}
+
+// GetUserDefaults returns the defaults applied to userID's uploads
+func (db *DB) GetUserDefaults(ctx context.Context, userID int64) (UserDefaults, error) {
+	var d UserDefaults
+	err := db.QueryRowContext(ctx,
+		`SELECT default_cron, default_digest, default_inline FROM users WHERE id = ?`,
+		userID,
+	).Scan(&d.CronExpr, &d.Digest, &d.Inline)
+	if err != nil {
+		return UserDefaults{}, fmt.Errorf("get user defaults: %w", err)
+	}
+	return d, nil
+}
+
+// SetUserDefaults replaces userID's defaults. Configs already uploaded keep
+// the values they were saved with until they are uploaded again.
+func (db *DB) SetUserDefaults(ctx context.Context, userID int64, d UserDefaults) error {
+	_, err := db.ExecContext(ctx,
+		`UPDATE users SET default_cron = ?, default_digest = ?, default_inline = ? WHERE id = ?`,
+		d.CronExpr, d.Digest, d.Inline, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("set user defaults: %w", err)
+	}
+	return nil
+}
+
 func (db *DB) DeleteUser(ctx context.Context, userID int64) error {
 	_, err := db.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, userID)
 	return err
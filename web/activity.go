@@ -0,0 +1,63 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// activityInterval is how often reads of one config's feed or dashboard are
+// written to last_active_at. Inactivity is measured in days, so an hour is
+// plenty and keeps polling feed readers from writing on every request.
+const activityInterval = time.Hour
+
+// activityThrottle remembers when each key last recorded activity
+type activityThrottle struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newActivityThrottle() *activityThrottle {
+	return &activityThrottle{last: make(map[string]time.Time)}
+}
+
+// allow reports whether key hasn't recorded activity within activityInterval,
+// and if so marks it as recorded now
+func (t *activityThrottle) allow(key string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if last, ok := t.last[key]; ok && now.Sub(last) < activityInterval {
+		return false
+	}
+	t.last[key] = now
+
+	// Drop expired keys so configs that stop being read don't pile up
+	for k, last := range t.last {
+		if now.Sub(last) >= activityInterval {
+			delete(t.last, k)
+		}
+	}
+	return true
+}
+
+// touchConfig records a read of a config's feed as engagement
+func (s *Server) touchConfig(ctx context.Context, configID int64) {
+	if !s.activity.allow(fmt.Sprintf("config:%d", configID), time.Now()) {
+		return
+	}
+	if err := s.store.TouchConfigActivity(ctx, configID); err != nil {
+		s.logger.Warn("touch config activity", "config_id", configID, "err", err)
+	}
+}
+
+// touchUser records a dashboard visit as engagement on all of a user's configs
+func (s *Server) touchUser(ctx context.Context, userID int64) {
+	if !s.activity.allow(fmt.Sprintf("user:%d", userID), time.Now()) {
+		return
+	}
+	if err := s.store.TouchUserActivity(ctx, userID); err != nil {
+		s.logger.Warn("touch user activity", "user_id", userID, "err", err)
+	}
+}
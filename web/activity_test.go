@@ -0,0 +1,24 @@
+package web
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActivityThrottle(t *testing.T) {
+	th := newActivityThrottle()
+	now := time.Now()
+
+	if !th.allow("config:1", now) {
+		t.Error("expected first read to record activity")
+	}
+	if th.allow("config:1", now.Add(30*time.Minute)) {
+		t.Error("expected a second read within the hour to be skipped")
+	}
+	if !th.allow("config:2", now.Add(30*time.Minute)) {
+		t.Error("expected other configs to be tracked separately")
+	}
+	if !th.allow("config:1", now.Add(activityInterval)) {
+		t.Error("expected activity to be recorded again after an hour")
+	}
+}
@@ -0,0 +1,98 @@
+package web
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+// SetAdminToken enables the /admin endpoints, which require the token as a
+// bearer credential. They stay disabled while it is empty.
+func (s *Server) SetAdminToken(token string) {
+	s.adminToken = token
+}
+
+// authorizedAdmin reports whether r carries the admin token
+func (s *Server) authorizedAdmin(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1
+}
+
+type emailAuditEntry struct {
+	ID         int64     `json:"id"`
+	Recipient  string    `json:"recipient"`
+	Subject    string    `json:"subject"`
+	SentAt     time.Time `json:"sent_at"`
+	BodySHA256 string    `json:"body_sha256,omitempty"`
+	BodyText   string    `json:"body_text,omitempty"`
+}
+
+// handleEmailAudit serves /admin/audit/{configID}, the audit trail of a
+// config's sent digests
+func (s *Server) handleEmailAudit(w http.ResponseWriter, r *http.Request) {
+	if s.adminToken == "" {
+		httpError(w, r, "Not Found", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		httpError(w, r, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorizedAdmin(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+		return
+	}
+
+	configID, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/admin/audit/"), 10, 64)
+	if err != nil || configID <= 0 {
+		httpError(w, r, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultAuditLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			httpError(w, r, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxAuditLimit)
+	}
+
+	sends, err := s.reader.GetEmailAudit(r.Context(), configID, limit)
+	if err != nil {
+		s.logger.Error("failed to get email audit", "config_id", configID, "err", err)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	entries := make([]emailAuditEntry, len(sends))
+	for i, send := range sends {
+		entries[i] = emailAuditEntry{
+			ID:         send.ID,
+			Recipient:  send.Recipient,
+			Subject:    send.Subject,
+			SentAt:     send.SentAt,
+			BodySHA256: send.BodySHA256.String,
+			BodyText:   send.BodyText.String,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		s.logger.Warn("failed to encode email audit", "error", err)
+	}
+}
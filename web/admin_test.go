@@ -0,0 +1,55 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleEmailAudit(t *testing.T) {
+	s, db, cfg := newTestServer(t)
+
+	ctx := context.Background()
+	tx, _ := db.BeginTx(ctx)
+	_, _ = db.RecordEmailSendTx(tx, cfg.ID, "user@example.com", "feed digest", "", "deadbeef", "")
+	_ = tx.Commit()
+
+	path := fmt.Sprintf("/admin/audit/%d", cfg.ID)
+
+	get := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		s.handleEmailAudit(rec, req)
+		return rec
+	}
+
+	if rec := get("Bearer secret"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without an admin token, got %d", rec.Code)
+	}
+
+	s.SetAdminToken("secret")
+	if rec := get(""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without credentials, got %d", rec.Code)
+	}
+	if rec := get("Bearer wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 with a wrong token, got %d", rec.Code)
+	}
+
+	rec := get("Bearer secret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var entries []emailAuditEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("decode audit: %v", err)
+	}
+	if len(entries) != 1 || entries[0].BodySHA256 != "deadbeef" || entries[0].Subject != "feed digest" {
+		t.Errorf("unexpected audit entries: %+v", entries)
+	}
+}
@@ -0,0 +1,63 @@
+package web
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kierank/herald/store"
+)
+
+// archiveLimit is how many past digests the public archive shows
+const archiveLimit = 50
+
+type archivePageData struct {
+	Title   string
+	Digests []archiveDigest
+}
+
+type archiveDigest struct {
+	SentAt string
+	Items  []store.ArchivedItem
+}
+
+// handleArchive serves /archive/{slug}, the public digest history of a
+// config with public turned on
+func (s *Server) handleArchive(w http.ResponseWriter, r *http.Request, slug string) {
+	ctx := r.Context()
+
+	cfg, err := s.reader.GetConfigByPublicSlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.handle404(w, r)
+			return
+		}
+		s.logger.Warn("get config by public slug", "err", err)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	digests, err := s.reader.GetDigestArchive(ctx, cfg.ID, archiveLimit)
+	if err != nil {
+		s.logger.Warn("get digest archive", "config_id", cfg.ID, "err", err)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := archivePageData{Title: strings.TrimSuffix(cfg.Filename, ".txt")}
+	for _, d := range digests {
+		data.Digests = append(data.Digests, archiveDigest{
+			SentAt: d.SentAt.UTC().Format("Jan 2, 2006 15:04 MST"),
+			Items:  d.Items,
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", feedCacheMaxAge))
+	if err := s.tmpl.ExecuteTemplate(w, "archive.html", data); err != nil {
+		s.logger.Warn("render archive", "err", err)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
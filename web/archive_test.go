@@ -0,0 +1,46 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kierank/herald/store"
+)
+
+func TestHandleArchive(t *testing.T) {
+	s, db, cfg := newTestServer(t)
+
+	ctx := context.Background()
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/feed.xml", "Example", store.FeedOptions{})
+
+	tx, _ := db.BeginTx(ctx)
+	sendID, _ := db.RecordEmailSendTx(tx, cfg.ID, "user@example.com", "feed digest", "", "", "")
+	_ = db.MarkItemDeliveredTx(ctx, tx, feed.ID, sendID, "a", "First post", "https://example.com/a")
+	_ = tx.Commit()
+
+	rec := httptest.NewRecorder()
+	s.routeHandler(rec, httptest.NewRequest(http.MethodGet, "/archive/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown slug, got %d", rec.Code)
+	}
+
+	slug, err := db.SetConfigPublic(ctx, cfg.ID, true)
+	if err != nil {
+		t.Fatalf("SetConfigPublic failed: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	s.routeHandler(rec, httptest.NewRequest(http.MethodGet, "/archive/"+slug, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "First post") {
+		t.Error("expected the archive to list the sent item")
+	}
+	if rec.Header().Get("X-Robots-Tag") != "" {
+		t.Error("expected the archive to be indexable")
+	}
+}
@@ -0,0 +1,59 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// errorResponse is the JSON body sent for errors to clients that want JSON
+type errorResponse struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// writeJSONError writes a JSON error envelope like
+// {"error":{"code":"not_found","message":"Not Found"}}
+func writeJSONError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorResponse{Error: errorDetail{Code: code, Message: msg}})
+}
+
+// wantsJSON reports whether the client asked for JSON, either with an Accept
+// header or by requesting a .json endpoint
+func wantsJSON(r *http.Request) bool {
+	return strings.HasSuffix(r.URL.Path, ".json") || strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// errorCode is the machine-readable code for an HTTP error status
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "bad_request"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusMethodNotAllowed:
+		return "method_not_allowed"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	case http.StatusServiceUnavailable:
+		return "unavailable"
+	default:
+		return "internal_error"
+	}
+}
+
+// httpError is http.Error, but answers in JSON when the client wants JSON
+func httpError(w http.ResponseWriter, r *http.Request, msg string, status int) {
+	if wantsJSON(r) {
+		writeJSONError(w, status, errorCode(status), msg)
+		return
+	}
+	http.Error(w, msg, status)
+}
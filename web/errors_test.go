@@ -0,0 +1,64 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSONErrors(t *testing.T) {
+	s, _, _ := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		status int
+		code   string
+	}{
+		{"json feed", "/SHA256:missing/feeds.json", "", http.StatusNotFound, "not_found"},
+		{"stats", "/SHA256:missing/feeds.txt/stats.json", "", http.StatusNotFound, "not_found"},
+		{"logs", "/SHA256:missing/feeds.txt/logs.json", "", http.StatusNotFound, "not_found"},
+		{"accept header", "/SHA256:missing/feeds.xml", "application/json", http.StatusNotFound, "not_found"},
+		{"unsubscribe", "/unsubscribe/bogus", "application/json", http.StatusNotFound, "not_found"},
+		{"method", "/unsubscribe/bogus", "application/json", http.StatusMethodNotAllowed, "method_not_allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := http.MethodGet
+			if tt.status == http.StatusMethodNotAllowed {
+				method = http.MethodDelete
+			}
+			req := httptest.NewRequest(method, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			s.routeHandler(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected %d, got %d", tt.status, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("expected JSON content type, got %q", ct)
+			}
+			var body errorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode error body: %v", err)
+			}
+			if body.Error.Code != tt.code || body.Error.Message == "" {
+				t.Errorf("unexpected error body: %+v", body)
+			}
+		})
+	}
+
+	// Browsers still get the HTML page
+	rec := httptest.NewRecorder()
+	s.routeHandler(rec, httptest.NewRequest(http.MethodGet, "/SHA256:missing/feeds.xml", nil))
+	if rec.Code != http.StatusNotFound || strings.Contains(rec.Header().Get("Content-Type"), "json") {
+		t.Errorf("expected an HTML 404, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
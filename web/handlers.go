@@ -7,10 +7,16 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/kierank/herald/store"
 )
 
 const (
@@ -18,6 +24,12 @@ const (
 	shortFingerprintLen = 8
 	recentItemsLimit    = 50
 	feedCacheMaxAge     = 300 // 5 minutes
+	statsCacheMaxAge    = 60
+	defaultLogsLimit    = 50
+	maxLogsLimit        = 200
+	engagementDays      = 90 // window for sends, opens, and clicks
+
+	maxUnsubscribeFormSize = 1 << 16
 )
 
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
@@ -29,41 +41,59 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 		shortHash = shortHash[:7]
 	}
 
+	// Missing or unreadable host keys just hide the fingerprint section
+	fingerprint, keyType, _ := s.hostKeyFingerprint()
+
 	data := struct {
 		Origin          string
 		OriginHost      string
 		SSHHost         string
+		SCPHost         string
 		SSHPort         int
 		CommitHash      string
 		ShortCommitHash string
+		HostKeyType     string
+		HostKeySHA256   string
 	}{
 		Origin:          s.origin,
 		OriginHost:      stripProtocol(s.origin),
 		SSHHost:         host,
+		SCPHost:         scpHost(host),
 		SSHPort:         s.sshPort,
 		CommitHash:      s.commitHash,
 		ShortCommitHash: shortHash,
+		HostKeyType:     keyType,
+		HostKeySHA256:   fingerprint,
 	}
 	if err := s.tmpl.ExecuteTemplate(w, "index.html", data); err != nil {
 		s.logger.Warn("render index", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
 func (s *Server) handleStyleCSS(w http.ResponseWriter, r *http.Request) {
 	css, err := templatesFS.ReadFile("templates/style.css")
 	if err != nil {
-		http.Error(w, "Not Found", http.StatusNotFound)
+		httpError(w, r, "Not Found", http.StatusNotFound)
 		return
 	}
 	w.Header().Set("Content-Type", "text/css; charset=utf-8")
 	_, _ = w.Write(css)
 }
 
+// robotsTXT keeps crawlers off every page, since user pages are only as
+// private as their fingerprint URLs
+const robotsTXT = "User-agent: *\nDisallow: /\n"
+
+func (s *Server) handleRobotsTXT(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(robotsTXT))
+}
+
 func (s *Server) handleFaviconSVG(w http.ResponseWriter, r *http.Request) {
 	svg, err := publicFS.ReadFile("public/favicon.svg")
 	if err != nil {
-		http.Error(w, "Not Found", http.StatusNotFound)
+		httpError(w, r, "Not Found", http.StatusNotFound)
 		return
 	}
 	w.Header().Set("Content-Type", "image/svg+xml")
@@ -77,6 +107,7 @@ type userPageData struct {
 	Status           string
 	NextRun          string
 	Origin           string
+	GroupByDomain    bool
 }
 
 type configInfo struct {
@@ -85,16 +116,60 @@ type configInfo struct {
 	URL             string
 	FeedXMLURL      string
 	FeedJSONURL     string
+	ArchiveURL      string
 	IsActive        bool
 	TotalSends      int
+	Clicks          int
 	LastActiveDays  int
 	DaysUntilExpiry int
+	Domains         []domainGroup
+}
+
+// domainGroup lists a config's feeds that share a source host
+type domainGroup struct {
+	Domain string
+	Count  int
+	Feeds  []string
+}
+
+// groupFeedsByDomain buckets feeds by URL host, largest groups first
+func groupFeedsByDomain(feeds []*store.Feed) []domainGroup {
+	index := make(map[string]int)
+	var groups []domainGroup
+	for _, feed := range feeds {
+		domain := feed.URL
+		if u, err := url.Parse(feed.URL); err == nil && u.Hostname() != "" {
+			domain = strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+		}
+
+		name := feed.URL
+		if feed.Name.Valid && feed.Name.String != "" {
+			name = feed.Name.String
+		}
+
+		i, ok := index[domain]
+		if !ok {
+			i = len(groups)
+			index[domain] = i
+			groups = append(groups, domainGroup{Domain: domain})
+		}
+		groups[i].Count++
+		groups[i].Feeds = append(groups[i].Feeds, name)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Domain < groups[j].Domain
+	})
+	return groups
 }
 
 func (s *Server) handleUser(w http.ResponseWriter, r *http.Request, fingerprint string) {
 	ctx := r.Context()
 
-	user, err := s.store.GetUserByFingerprint(ctx, fingerprint)
+	user, err := s.reader.GetUserByFingerprint(ctx, fingerprint)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			s.handle404(w, r)
@@ -104,14 +179,16 @@ func (s *Server) handleUser(w http.ResponseWriter, r *http.Request, fingerprint
 			return // Client disconnected, don't log as error
 		}
 		s.logger.Warn("get user", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	configs, err := s.store.ListConfigs(ctx, user.ID)
+	s.touchUser(ctx, user.ID)
+
+	configs, err := s.reader.ListConfigs(ctx, user.ID)
 	if err != nil {
 		s.logger.Warn("list configs", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
@@ -120,13 +197,15 @@ func (s *Server) handleUser(w http.ResponseWriter, r *http.Request, fingerprint
 	for i, cfg := range configs {
 		configIDs[i] = cfg.ID
 	}
-	feedsByConfig, err := s.store.GetFeedsByConfigs(ctx, configIDs)
+	feedsByConfig, err := s.reader.GetFeedsByConfigs(ctx, configIDs)
 	if err != nil {
 		s.logger.Warn("get feeds by configs", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
+	groupByDomain := r.URL.Query().Get("group") == "domain"
+
 	var configInfos []configInfo
 	var earliestNextRun time.Time
 	hasAnyActive := false
@@ -143,11 +222,15 @@ func (s *Server) handleUser(w http.ResponseWriter, r *http.Request, fingerprint
 		feedBaseName := strings.TrimSuffix(cfg.Filename, ".txt")
 
 		// Get engagement stats (last 90 days)
-		totalSends, _, _, _, err := s.store.GetConfigEngagement(cfg.ID, 90)
+		totalSends, _, _, _, err := s.reader.GetConfigEngagement(cfg.ID, engagementDays)
 		if err != nil {
 			s.logger.Warn("get engagement", "config_id", cfg.ID, "err", err)
 			// Continue without engagement data
 		}
+		clicks, err := s.reader.CountItemClicks(ctx, cfg.ID, engagementDays)
+		if err != nil {
+			s.logger.Warn("count item clicks", "config_id", cfg.ID, "err", err)
+		}
 
 		// Calculate last active days and expiry
 		lastActiveDays := -1
@@ -163,16 +246,29 @@ func (s *Server) handleUser(w http.ResponseWriter, r *http.Request, fingerprint
 		expiryDate := expiryBase.AddDate(0, 0, 90)
 		daysUntilExpiry := int(time.Until(expiryDate).Hours() / 24)
 
+		var domains []domainGroup
+		if groupByDomain {
+			domains = groupFeedsByDomain(feeds)
+		}
+
+		archiveURL := ""
+		if cfg.PublicSlug.Valid {
+			archiveURL = s.basePath + "/archive/" + cfg.PublicSlug.String
+		}
+
 		configInfos = append(configInfos, configInfo{
 			Filename:        cfg.Filename,
 			FeedCount:       len(feeds),
-			URL:             "/" + fingerprint + "/" + cfg.Filename,
-			FeedXMLURL:      "/" + fingerprint + "/" + feedBaseName + ".xml",
-			FeedJSONURL:     "/" + fingerprint + "/" + feedBaseName + ".json",
+			URL:             s.basePath + "/" + fingerprint + "/" + cfg.Filename,
+			FeedXMLURL:      s.basePath + "/" + fingerprint + "/" + feedBaseName + ".xml",
+			FeedJSONURL:     s.basePath + "/" + fingerprint + "/" + feedBaseName + ".json",
+			ArchiveURL:      archiveURL,
 			IsActive:        isActive,
 			TotalSends:      totalSends,
+			Clicks:          clicks,
 			LastActiveDays:  lastActiveDays,
 			DaysUntilExpiry: daysUntilExpiry,
+			Domains:         domains,
 		})
 
 		if cfg.NextRun.Valid {
@@ -203,11 +299,12 @@ func (s *Server) handleUser(w http.ResponseWriter, r *http.Request, fingerprint
 		Status:           status,
 		NextRun:          nextRunStr,
 		Origin:           s.origin,
+		GroupByDomain:    groupByDomain,
 	}
 
 	if err := s.tmpl.ExecuteTemplate(w, "user.html", data); err != nil {
 		s.logger.Warn("render user", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
@@ -218,11 +315,6 @@ type rssItem struct {
 	PubDate string `xml:"pubDate"`
 }
 
-type rssItemWithTime struct {
-	rssItem
-	parsedTime time.Time
-}
-
 type rssChannel struct {
 	Title       string    `xml:"title"`
 	Link        string    `xml:"link"`
@@ -236,10 +328,42 @@ type rssFeed struct {
 	Channel rssChannel `xml:"channel"`
 }
 
+// feedItemLimit reads ?limit=N on the feed endpoints and returns the total
+// item cap and how many recent items to read from each feed. Without a limit
+// the defaults apply and over-cap values clamp to maxFeedItems; ok is false
+// when the limit isn't a positive number.
+func feedItemLimit(r *http.Request) (total, perFeed int, ok bool) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return maxFeedItems, recentItemsLimit, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return 0, 0, false
+	}
+	n = min(n, maxFeedItems)
+	return n, n, true
+}
+
+// feedItems reads a config's newest seen items for the feed endpoints in one
+// query; ?delivered=true skips items seen at upload or filtered out of digests
+func (s *Server) feedItems(r *http.Request, configID int64, limit, perFeed int) ([]*store.SeenItem, error) {
+	return s.reader.GetSeenItemsByConfig(r.Context(), configID, limit, store.SeenItemsFilter{
+		PerFeed:       perFeed,
+		DeliveredOnly: r.URL.Query().Get("delivered") == "true",
+	})
+}
+
 func (s *Server) handleFeedXML(w http.ResponseWriter, r *http.Request, fingerprint, configFilename string) {
 	ctx := r.Context()
 
-	user, err := s.store.GetUserByFingerprint(ctx, fingerprint)
+	limit, perFeed, ok := feedItemLimit(r)
+	if !ok {
+		httpError(w, r, "Invalid limit, use a positive number", http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.reader.GetUserByFingerprint(ctx, fingerprint)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			s.handle404(w, r)
@@ -249,66 +373,39 @@ func (s *Server) handleFeedXML(w http.ResponseWriter, r *http.Request, fingerpri
 			return // Client disconnected
 		}
 		s.logger.Warn("get user", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	cfg, err := s.store.GetConfig(ctx, user.ID, configFilename)
+	cfg, err := s.reader.GetConfig(ctx, user.ID, configFilename)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			s.handle404(w, r)
 			return
 		}
 		s.logger.Warn("get config", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	var items []rssItemWithTime
-	feeds, err := s.store.GetFeedsByConfig(ctx, cfg.ID)
+	s.touchConfig(ctx, cfg.ID)
+	seenItems, err := s.feedItems(r, cfg.ID, limit, perFeed)
 	if err != nil {
-		s.logger.Warn("get feeds", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		s.logger.Warn("get seen items", "err", err)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	for _, feed := range feeds {
-		seenItems, err := s.store.GetSeenItems(ctx, feed.ID, 50)
-		if err != nil {
-			continue
-		}
-		for _, item := range seenItems {
-			rItem := rssItemWithTime{
-				rssItem: rssItem{
-					GUID:    item.GUID,
-					PubDate: item.SeenAt.Format(time.RFC1123Z),
-				},
-				parsedTime: item.SeenAt,
-			}
-			if item.Title.Valid {
-				rItem.Title = item.Title.String
-			}
-			if item.Link.Valid {
-				rItem.Link = item.Link.String
-			}
-			items = append(items, rItem)
+	rssItems := make([]rssItem, len(seenItems))
+	for i, item := range seenItems {
+		rssItems[i] = rssItem{
+			Title:   item.Title.String,
+			Link:    item.Link.String,
+			GUID:    item.GUID,
+			PubDate: item.SeenAt.Format(time.RFC1123Z),
 		}
 	}
 
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].parsedTime.After(items[j].parsedTime)
-	})
-
-	if len(items) > maxFeedItems {
-		items = items[:maxFeedItems]
-	}
-
-	// Convert to rssItem for XML encoding
-	rssItems := make([]rssItem, len(items))
-	for i, item := range items {
-		rssItems[i] = item.rssItem
-	}
-
 	feed := rssFeed{
 		Version: "2.0",
 		Channel: rssChannel{
@@ -363,15 +460,16 @@ type jsonFeedItem struct {
 	DatePublished string `json:"date_published"`
 }
 
-type jsonFeedItemWithTime struct {
-	jsonFeedItem
-	parsedTime time.Time
-}
-
 func (s *Server) handleFeedJSON(w http.ResponseWriter, r *http.Request, fingerprint, configFilename string) {
 	ctx := r.Context()
 
-	user, err := s.store.GetUserByFingerprint(ctx, fingerprint)
+	limit, perFeed, ok := feedItemLimit(r)
+	if !ok {
+		httpError(w, r, "Invalid limit, use a positive number", http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.reader.GetUserByFingerprint(ctx, fingerprint)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			s.handle404(w, r)
@@ -381,66 +479,39 @@ func (s *Server) handleFeedJSON(w http.ResponseWriter, r *http.Request, fingerpr
 			return // Client disconnected
 		}
 		s.logger.Warn("get user", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	cfg, err := s.store.GetConfig(ctx, user.ID, configFilename)
+	cfg, err := s.reader.GetConfig(ctx, user.ID, configFilename)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			s.handle404(w, r)
 			return
 		}
 		s.logger.Warn("get config", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	var items []jsonFeedItemWithTime
-	feeds, err := s.store.GetFeedsByConfig(ctx, cfg.ID)
+	s.touchConfig(ctx, cfg.ID)
+	seenItems, err := s.feedItems(r, cfg.ID, limit, perFeed)
 	if err != nil {
-		s.logger.Warn("get feeds", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		s.logger.Warn("get seen items", "err", err)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	for _, feed := range feeds {
-		seenItems, err := s.store.GetSeenItems(ctx, feed.ID, recentItemsLimit)
-		if err != nil {
-			continue
-		}
-		for _, item := range seenItems {
-			jItem := jsonFeedItemWithTime{
-				jsonFeedItem: jsonFeedItem{
-					ID:            item.GUID,
-					DatePublished: item.SeenAt.Format(time.RFC3339),
-				},
-				parsedTime: item.SeenAt,
-			}
-			if item.Title.Valid {
-				jItem.Title = item.Title.String
-			}
-			if item.Link.Valid {
-				jItem.URL = item.Link.String
-			}
-			items = append(items, jItem)
+	jsonItems := make([]jsonFeedItem, len(seenItems))
+	for i, item := range seenItems {
+		jsonItems[i] = jsonFeedItem{
+			ID:            item.GUID,
+			URL:           item.Link.String,
+			Title:         item.Title.String,
+			DatePublished: item.SeenAt.Format(time.RFC3339),
 		}
 	}
 
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].parsedTime.After(items[j].parsedTime)
-	})
-
-	if len(items) > maxFeedItems {
-		items = items[:maxFeedItems]
-	}
-
-	// Convert to jsonFeedItem for JSON encoding
-	jsonItems := make([]jsonFeedItem, len(items))
-	for i, item := range items {
-		jsonItems[i] = item.jsonFeedItem
-	}
-
 	feed := jsonFeed{
 		Version:     "https://jsonfeed.org/version/1.1",
 		Title:       "Herald - " + configFilename,
@@ -480,7 +551,7 @@ func (s *Server) handleFeedJSON(w http.ResponseWriter, r *http.Request, fingerpr
 func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request, fingerprint, filename string) {
 	ctx := r.Context()
 
-	user, err := s.store.GetUserByFingerprint(ctx, fingerprint)
+	user, err := s.reader.GetUserByFingerprint(ctx, fingerprint)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			s.handle404(w, r)
@@ -490,18 +561,18 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request, fingerprin
 			return // Client disconnected
 		}
 		s.logger.Warn("get user", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	cfg, err := s.store.GetConfig(ctx, user.ID, filename)
+	cfg, err := s.reader.GetConfig(ctx, user.ID, filename)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			s.handle404(w, r)
 			return
 		}
 		s.logger.Warn("get config", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
@@ -509,10 +580,192 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request, fingerprin
 	_, _ = w.Write([]byte(cfg.RawText))
 }
 
+// configStats is the JSON body of /{fingerprint}/{filename}/stats.json
+type configStats struct {
+	Filename   string     `json:"filename"`
+	FeedCount  int        `json:"feed_count"`
+	Active     bool       `json:"active"`
+	NextRun    *time.Time `json:"next_run"`
+	LastRun    *time.Time `json:"last_run"`
+	WindowDays int        `json:"window_days"`
+	TotalSends int        `json:"total_sends"`
+	Opens      int        `json:"opens"`
+	Bounces    int        `json:"bounces"`
+	Clicks     int        `json:"clicks"`
+	LastOpen   *time.Time `json:"last_open"`
+}
+
+func (s *Server) handleConfigStats(w http.ResponseWriter, r *http.Request, fingerprint, filename string) {
+	ctx := r.Context()
+
+	user, err := s.reader.GetUserByFingerprint(ctx, fingerprint)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.handle404(w, r)
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			return // Client disconnected
+		}
+		s.logger.Warn("get user", "err", err)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	cfg, err := s.reader.GetConfig(ctx, user.ID, filename)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.handle404(w, r)
+			return
+		}
+		s.logger.Warn("get config", "err", err)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	feeds, err := s.reader.GetFeedsByConfig(ctx, cfg.ID)
+	if err != nil {
+		s.logger.Warn("get feeds", "err", err)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	totalSends, opens, bounces, lastOpen, err := s.reader.GetConfigEngagement(cfg.ID, engagementDays)
+	if err != nil {
+		s.logger.Warn("get engagement", "config_id", cfg.ID, "err", err)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	clicks, err := s.reader.CountItemClicks(ctx, cfg.ID, engagementDays)
+	if err != nil {
+		s.logger.Warn("count item clicks", "config_id", cfg.ID, "err", err)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	stats := configStats{
+		Filename:   cfg.Filename,
+		FeedCount:  len(feeds),
+		Active:     cfg.NextRun.Valid,
+		WindowDays: engagementDays,
+		TotalSends: totalSends,
+		Opens:      opens,
+		Bounces:    bounces,
+		Clicks:     clicks,
+		LastOpen:   lastOpen,
+	}
+	if cfg.NextRun.Valid {
+		next := cfg.NextRun.Time.UTC()
+		stats.NextRun = &next
+	}
+	if cfg.LastRun.Valid {
+		last := cfg.LastRun.Time.UTC()
+		stats.LastRun = &last
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", statsCacheMaxAge))
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(stats)
+}
+
+// logLevels are the levels ?level= accepts on logs.json
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+// configLogs is the JSON body of /{fingerprint}/{filename}/logs.json
+type configLogs struct {
+	Filename string           `json:"filename"`
+	Logs     []configLogEntry `json:"logs"`
+}
+
+type configLogEntry struct {
+	Level     string    `json:"level"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// logsLimit reads ?limit=N on logs.json, clamping over-cap values to
+// maxLogsLimit like the feed endpoints; ok is false when the limit isn't a
+// positive number
+func logsLimit(r *http.Request) (limit int, ok bool) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return defaultLogsLimit, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return min(n, maxLogsLimit), true
+}
+
+func (s *Server) handleConfigLogs(w http.ResponseWriter, r *http.Request, fingerprint, filename string) {
+	ctx := r.Context()
+
+	level := strings.ToLower(r.URL.Query().Get("level"))
+	if level != "" && !slices.Contains(logLevels, level) {
+		httpError(w, r, "Unknown log level, use one of: "+strings.Join(logLevels, ", "), http.StatusBadRequest)
+		return
+	}
+	limit, ok := logsLimit(r)
+	if !ok {
+		httpError(w, r, "Invalid limit, use a positive number", http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.reader.GetUserByFingerprint(ctx, fingerprint)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.handle404(w, r)
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			return // Client disconnected
+		}
+		s.logger.Warn("get user", "err", err)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	cfg, err := s.reader.GetConfig(ctx, user.ID, filename)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.handle404(w, r)
+			return
+		}
+		s.logger.Warn("get config", "err", err)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	logs, err := s.reader.GetLogsByLevel(ctx, cfg.ID, level, limit)
+	if err != nil {
+		s.logger.Warn("get logs", "config_id", cfg.ID, "err", err)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	body := configLogs{Filename: cfg.Filename, Logs: make([]configLogEntry, len(logs))}
+	for i, l := range logs {
+		body.Logs[i] = configLogEntry{Level: l.Level, Message: l.Message, CreatedAt: l.CreatedAt.UTC()}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", statsCacheMaxAge))
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(body)
+}
+
+// snoozeDays are the periods the unsubscribe page offers to pause a config
+var snoozeDays = []int{7, 30}
+
 type unsubscribePageData struct {
 	Token            string
 	ShortFingerprint string
 	Filename         string
+	SnoozeDays       []int
 	Success          bool
 	Message          string
 	Error            string
@@ -528,14 +781,14 @@ func (s *Server) handleUnsubscribeGET(w http.ResponseWriter, r *http.Request, to
 			return
 		}
 		s.logger.Error("get config by token", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
 	user, err := s.store.GetUserByID(ctx, cfg.UserID)
 	if err != nil {
 		s.logger.Warn("get user", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
@@ -548,40 +801,41 @@ func (s *Server) handleUnsubscribeGET(w http.ResponseWriter, r *http.Request, to
 		Token:            token,
 		ShortFingerprint: shortFP,
 		Filename:         cfg.Filename,
+		SnoozeDays:       snoozeDays,
 	}
 
 	if err := s.tmpl.ExecuteTemplate(w, "unsubscribe.html", data); err != nil {
 		s.logger.Warn("render unsubscribe", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
 func (s *Server) handleUnsubscribePOST(w http.ResponseWriter, r *http.Request, token string) {
 	ctx := r.Context()
 
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+	// RFC 8058 allows the one-click body as urlencoded or multipart form data
+	if err := r.ParseMultipartForm(maxUnsubscribeFormSize); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		httpError(w, r, "Bad Request", http.StatusBadRequest)
 		return
 	}
 
 	// RFC 8058: Check for one-click unsubscribe format
-	oneClick := r.FormValue("List-Unsubscribe")
-	if oneClick == "One-Click" {
+	if r.FormValue("List-Unsubscribe") == "One-Click" || isOneClickBody(r) {
 		// One-click unsubscribe: deactivate config without rendering HTML
 		cfg, err := s.store.GetConfigByToken(ctx, token)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
-				http.Error(w, "Invalid token", http.StatusNotFound)
+				httpError(w, r, "Invalid token", http.StatusNotFound)
 				return
 			}
 			s.logger.Error("get config by token", "err", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
 
 		if err := s.store.DeactivateConfig(ctx, cfg.ID); err != nil {
 			s.logger.Error("deactivate config", "err", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
 
@@ -599,8 +853,8 @@ func (s *Server) handleUnsubscribePOST(w http.ResponseWriter, r *http.Request, t
 
 	// Manual unsubscribe flow
 	action := r.FormValue("action")
-	if action != "deactivate" && action != "delete" {
-		http.Error(w, "Invalid action", http.StatusBadRequest)
+	if action != "deactivate" && action != "delete" && action != "snooze" {
+		httpError(w, r, "Invalid action", http.StatusBadRequest)
 		return
 	}
 
@@ -611,32 +865,48 @@ func (s *Server) handleUnsubscribePOST(w http.ResponseWriter, r *http.Request, t
 			return
 		}
 		s.logger.Error("get config by token", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
 	var message string
 
-	if action == "deactivate" {
+	switch action {
+	case "snooze":
+		days, err := strconv.Atoi(r.FormValue("days"))
+		if err != nil || !slices.Contains(snoozeDays, days) {
+			httpError(w, r, "Invalid snooze period", http.StatusBadRequest)
+			return
+		}
+		message, err = s.snoozeConfig(ctx, cfg, days)
+		if err != nil {
+			s.logger.Error("snooze config", "err", err)
+			httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	case "deactivate":
 		if err := s.store.DeactivateConfig(ctx, cfg.ID); err != nil {
 			s.logger.Error("deactivate config", "err", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
 		message = fmt.Sprintf("Config '%s' deactivated. You will no longer receive emails for this config. Other configs remain active. Files remain accessible via SSH/SCP.", cfg.Filename)
 		s.logger.Info("config deactivated", "config_id", cfg.ID, "filename", cfg.Filename)
-	} else {
+	default:
 		if err := s.store.DeleteUser(ctx, cfg.UserID); err != nil {
 			s.logger.Error("delete user", "err", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
 		message = "All data deleted. You have been completely removed from Herald."
 		s.logger.Info("user deleted", "user_id", cfg.UserID)
 	}
 
-	if err := s.store.DeleteToken(ctx, token); err != nil {
-		s.logger.Warn("delete token", "err", err)
+	// A snoozed config keeps its link so the next digest can still unsubscribe
+	if action != "snooze" {
+		if err := s.store.DeleteToken(ctx, token); err != nil {
+			s.logger.Warn("delete token", "err", err)
+		}
 	}
 
 	data := unsubscribePageData{
@@ -646,10 +916,38 @@ func (s *Server) handleUnsubscribePOST(w http.ResponseWriter, r *http.Request, t
 
 	if err := s.tmpl.ExecuteTemplate(w, "unsubscribe.html", data); err != nil {
 		s.logger.Warn("render unsubscribe", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
+// snoozeConfig moves an active config's next run to its first cron tick
+// after days from now and returns the message to show
+func (s *Server) snoozeConfig(ctx context.Context, cfg *store.Config, days int) (string, error) {
+	if !cfg.NextRun.Valid {
+		return fmt.Sprintf("Config '%s' is deactivated, so there is nothing to snooze.", cfg.Filename), nil
+	}
+
+	nextRun, err := cfg.NextRunAfter(time.Now().UTC().AddDate(0, 0, days))
+	if err != nil {
+		return "", fmt.Errorf("calculate next run: %w", err)
+	}
+	if err := s.store.UpdateNextRun(ctx, cfg.ID, &nextRun); err != nil {
+		return "", err
+	}
+	_ = s.store.AddLog(ctx, cfg.ID, "info", fmt.Sprintf("Snoozed for %d days from the unsubscribe page, next run %s", days, nextRun.Format(time.RFC3339)))
+	s.logger.Info("config snoozed", "config_id", cfg.ID, "filename", cfg.Filename, "days", days)
+
+	return fmt.Sprintf("Config '%s' snoozed for %d days. The next digest is due %s. Other configs are unaffected.", cfg.Filename, days, nextRun.Format("Mon, 02 Jan 2006 15:04 MST")), nil
+}
+
+// isOneClickBody reports whether a POST sent without a form content type
+// carries the RFC 8058 one-click payload. Form bodies were already consumed
+// while parsing the form, so this only sees bodies left unread.
+func isOneClickBody(r *http.Request) bool {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 256))
+	return err == nil && strings.TrimSpace(string(body)) == "List-Unsubscribe=One-Click"
+}
+
 func (s *Server) handleUnsubscribe(w http.ResponseWriter, r *http.Request, token string) {
 	switch r.Method {
 	case http.MethodGet:
@@ -657,41 +955,43 @@ func (s *Server) handleUnsubscribe(w http.ResponseWriter, r *http.Request, token
 	case http.MethodPost:
 		s.handleUnsubscribePOST(w, r, token)
 	default:
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		httpError(w, r, "Method Not Allowed", http.StatusMethodNotAllowed)
 	}
 }
 
-func stripProtocol(origin string) string {
-	if len(origin) == 0 {
-		return origin
+// parseOrigin parses the configured origin, tolerating a missing scheme
+func parseOrigin(origin string) (*url.URL, error) {
+	if !strings.Contains(origin, "://") {
+		origin = "//" + origin
 	}
+	return url.Parse(origin)
+}
 
-	// Remove http:// or https://
-	if len(origin) > 7 && origin[:7] == "http://" {
-		return origin[7:]
-	}
-	if len(origin) > 8 && origin[:8] == "https://" {
-		return origin[8:]
+// stripProtocol returns the origin without its scheme, e.g. example.com:8443/herald
+func stripProtocol(origin string) string {
+	u, err := parseOrigin(origin)
+	if err != nil || u.Host == "" {
+		return origin
 	}
-
-	return origin
+	return u.Host + strings.TrimSuffix(u.Path, "/")
 }
 
+// parseOriginHost returns the bare hostname of the origin for SSH commands,
+// falling back to localhost when the origin can't be parsed.
 func parseOriginHost(origin string) string {
-	// Strip protocol
-	hostPort := stripProtocol(origin)
-	if hostPort == "" {
+	u, err := parseOrigin(origin)
+	if err != nil || u.Hostname() == "" {
 		return "localhost"
 	}
+	return u.Hostname()
+}
 
-	// Strip port if present
-	for i := len(hostPort) - 1; i >= 0; i-- {
-		if hostPort[i] == ':' {
-			return hostPort[:i]
-		}
+// scpHost brackets IPv6 addresses so scp doesn't mistake them for a path
+func scpHost(host string) string {
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
 	}
-
-	return hostPort
+	return host
 }
 
 type keepAlivePageData struct {
@@ -701,14 +1001,14 @@ type keepAlivePageData struct {
 func (s *Server) handleKeepAlive(w http.ResponseWriter, r *http.Request, token string) {
 	// Only allow GET requests
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		httpError(w, r, "Method Not Allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	// Update last_active_at for this config
 	if err := s.store.UpdateLastActive(token); err != nil {
 		s.logger.Debug("keep-alive error", "token", token, "err", err)
-		http.Error(w, "Invalid or expired link", http.StatusNotFound)
+		httpError(w, r, "Invalid or expired link", http.StatusNotFound)
 		return
 	}
 
@@ -721,11 +1021,39 @@ func (s *Server) handleKeepAlive(w http.ResponseWriter, r *http.Request, token s
 
 	if err := s.tmpl.ExecuteTemplate(w, "keepalive.html", data); err != nil {
 		s.logger.Warn("render keepalive", "err", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpError(w, r, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleItemClick records a click on a digest item and redirects to it
+func (s *Server) handleItemClick(w http.ResponseWriter, r *http.Request, token, itemHash string) {
+	if r.Method != http.MethodGet {
+		httpError(w, r, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
 	}
+
+	link, err := s.store.RecordItemClick(r.Context(), token, itemHash)
+	if err != nil {
+		s.logger.Debug("item click error", "token", token, "item", itemHash, "err", err)
+		httpError(w, r, "Invalid or expired link", http.StatusNotFound)
+		return
+	}
+
+	// Only redirect to web links, never to whatever scheme a feed supplied
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		httpError(w, r, "Invalid or expired link", http.StatusNotFound)
+		return
+	}
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
 }
 
 func (s *Server) handle404(w http.ResponseWriter, r *http.Request) {
+	if wantsJSON(r) {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Not Found")
+		return
+	}
 	w.WriteHeader(http.StatusNotFound)
 	data := struct {
 		Title   string
@@ -738,6 +1066,10 @@ func (s *Server) handle404(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handle404WithMessage(w http.ResponseWriter, r *http.Request, title, message string) {
+	if wantsJSON(r) {
+		writeJSONError(w, http.StatusNotFound, "not_found", message)
+		return
+	}
 	w.WriteHeader(http.StatusNotFound)
 	data := struct {
 		Title   string
@@ -0,0 +1,372 @@
+package web
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/kierank/herald/store"
+)
+
+// newTestServer returns a server over an in-memory store holding one
+// active config, feeds.txt, owned by the user SHA256:abc
+func newTestServer(t *testing.T) (*Server, *store.DB, *store.Config) {
+	t.Helper()
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx := context.Background()
+	user, err := db.GetOrCreateUser(ctx, "SHA256:abc", "test-pubkey")
+	if err != nil {
+		t.Fatalf("GetOrCreateUser failed: %v", err)
+	}
+	cfg, err := db.CreateConfig(ctx, user.ID, "feeds.txt", "user@example.com", "0 8 * * *", true, false, "raw", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("CreateConfig failed: %v", err)
+	}
+	return NewServer(db, "", "http://localhost:8080", 22, log.New(io.Discard), "dev"), db, cfg
+}
+
+func TestParseOriginHost(t *testing.T) {
+	tests := []struct {
+		origin   string
+		expected string
+	}{
+		{"http://localhost:8080", "localhost"},
+		{"https://herald.example.com", "herald.example.com"},
+		{"https://herald.example.com/herald", "herald.example.com"},
+		{"https://[2001:db8::1]:8443/herald", "2001:db8::1"},
+		{"http://[::1]", "::1"},
+		{"herald.example.com:2222", "herald.example.com"},
+		{"", "localhost"},
+		{"http://%zz", "localhost"},
+	}
+
+	for _, tt := range tests {
+		if got := parseOriginHost(tt.origin); got != tt.expected {
+			t.Errorf("parseOriginHost(%q) = %q, expected %q", tt.origin, got, tt.expected)
+		}
+	}
+}
+
+func TestStripProtocol(t *testing.T) {
+	tests := []struct {
+		origin   string
+		expected string
+	}{
+		{"http://localhost:8080", "localhost:8080"},
+		{"https://herald.example.com/", "herald.example.com"},
+		{"https://herald.example.com/herald", "herald.example.com/herald"},
+		{"https://[2001:db8::1]:8443/herald", "[2001:db8::1]:8443/herald"},
+		{"herald.example.com", "herald.example.com"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := stripProtocol(tt.origin); got != tt.expected {
+			t.Errorf("stripProtocol(%q) = %q, expected %q", tt.origin, got, tt.expected)
+		}
+	}
+}
+
+func TestSCPHost(t *testing.T) {
+	if got := scpHost("2001:db8::1"); got != "[2001:db8::1]" {
+		t.Errorf("expected bracketed IPv6 host, got %q", got)
+	}
+	if got := scpHost("herald.example.com"); got != "herald.example.com" {
+		t.Errorf("expected hostname unchanged, got %q", got)
+	}
+}
+
+func TestGroupFeedsByDomain(t *testing.T) {
+	feeds := []*store.Feed{
+		{URL: "https://blog.example.com/feed.xml"},
+		{URL: "https://www.Example.org/a.xml", Name: sql.NullString{String: "A", Valid: true}},
+		{URL: "https://example.org/b.xml"},
+		{URL: "https://blog.example.com/other.xml"},
+		{URL: "https://aaa.dev/rss"},
+	}
+
+	groups := groupFeedsByDomain(feeds)
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got %d: %+v", len(groups), groups)
+	}
+	if groups[0].Domain != "blog.example.com" || groups[0].Count != 2 {
+		t.Errorf("unexpected first group: %+v", groups[0])
+	}
+	if groups[1].Domain != "example.org" || groups[1].Count != 2 {
+		t.Errorf("unexpected second group: %+v", groups[1])
+	}
+	if groups[1].Feeds[0] != "A" || groups[1].Feeds[1] != "https://example.org/b.xml" {
+		t.Errorf("expected feed names or URLs, got %v", groups[1].Feeds)
+	}
+	if groups[2].Domain != "aaa.dev" || groups[2].Count != 1 {
+		t.Errorf("unexpected third group: %+v", groups[2])
+	}
+}
+
+func TestHandleConfigStats(t *testing.T) {
+	s, db, cfg := newTestServer(t)
+
+	ctx := context.Background()
+	_, _ = db.CreateFeed(ctx, cfg.ID, "https://example.com/a.xml", "", store.FeedOptions{})
+	_, _ = db.CreateFeed(ctx, cfg.ID, "https://example.com/b.xml", "", store.FeedOptions{})
+	token, _ := db.RecordEmailSend(cfg.ID, "user@example.com", "feed digest", true)
+	_ = db.MarkEmailOpened(token)
+
+	rec := httptest.NewRecorder()
+	s.routeHandler(rec, httptest.NewRequest(http.MethodGet, "/SHA256:abc/feeds.txt/stats.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if cc := rec.Header().Get("Cache-Control"); !strings.Contains(cc, "max-age=") {
+		t.Errorf("expected a max-age cache header, got %q", cc)
+	}
+
+	var stats configStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("decode stats: %v", err)
+	}
+	if stats.FeedCount != 2 || stats.TotalSends != 1 || stats.Opens != 1 || !stats.Active || stats.NextRun == nil {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	rec = httptest.NewRecorder()
+	s.routeHandler(rec, httptest.NewRequest(http.MethodGet, "/SHA256:abc/missing.txt/stats.json", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown config, got %d", rec.Code)
+	}
+}
+
+func TestHandleConfigLogs(t *testing.T) {
+	s, db, cfg := newTestServer(t)
+
+	ctx := context.Background()
+	_ = db.AddLog(ctx, cfg.ID, "info", "Sent digest")
+	_ = db.AddLog(ctx, cfg.ID, "error", "Feed failed")
+	_ = db.AddLog(ctx, cfg.ID, "info", "Sent another digest")
+
+	get := func(path string) (*httptest.ResponseRecorder, configLogs) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.routeHandler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var body configLogs
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode logs: %v", err)
+			}
+		}
+		return rec, body
+	}
+
+	rec, body := get("/SHA256:abc/feeds.txt/logs.json")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if body.Filename != "feeds.txt" || len(body.Logs) != 3 {
+		t.Fatalf("expected 3 logs for feeds.txt, got %+v", body)
+	}
+	if body.Logs[0].Level == "" || body.Logs[0].Message == "" || body.Logs[0].CreatedAt.IsZero() {
+		t.Errorf("expected level, message, and created_at, got %+v", body.Logs[0])
+	}
+
+	if _, body = get("/SHA256:abc/feeds.txt/logs.json?level=ERROR"); len(body.Logs) != 1 || body.Logs[0].Message != "Feed failed" {
+		t.Errorf("expected only the error log, got %+v", body.Logs)
+	}
+	if _, body = get("/SHA256:abc/feeds.txt/logs.json?limit=2"); len(body.Logs) != 2 {
+		t.Errorf("expected 2 logs with limit=2, got %d", len(body.Logs))
+	}
+	if rec, _ = get("/SHA256:abc/feeds.txt/logs.json?level=loud"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown level, got %d", rec.Code)
+	}
+	if _, body = get("/SHA256:abc/feeds.txt/logs.json?limit=500"); len(body.Logs) != 3 {
+		t.Errorf("expected an over-cap limit to clamp, got %d logs", len(body.Logs))
+	}
+	for _, limit := range []string{"abc", "0", "-1"} {
+		if rec, _ = get("/SHA256:abc/feeds.txt/logs.json?limit=" + limit); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for limit=%s, got %d", limit, rec.Code)
+		}
+	}
+	if rec, _ = get("/SHA256:abc/missing.txt/logs.json"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown config, got %d", rec.Code)
+	}
+}
+
+func TestUnsubscribeOneClick(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"form encoded", "application/x-www-form-urlencoded", "List-Unsubscribe=One-Click"},
+		{"multipart", "multipart/form-data; boundary=b", "--b\r\nContent-Disposition: form-data; name=\"List-Unsubscribe\"\r\n\r\nOne-Click\r\n--b--\r\n"},
+		{"no content type", "", "List-Unsubscribe=One-Click"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, db, cfg := newTestServer(t)
+
+			ctx := context.Background()
+			token, err := db.GetOrCreateUnsubscribeToken(ctx, cfg.ID)
+			if err != nil {
+				t.Fatalf("create token: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/unsubscribe/"+token, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			s.routeHandler(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			updated, _ := db.GetConfigByID(ctx, cfg.ID)
+			if updated.NextRun.Valid {
+				t.Error("expected config to be deactivated")
+			}
+		})
+	}
+}
+
+func TestUnsubscribeSnooze(t *testing.T) {
+	s, db, cfg := newTestServer(t)
+
+	ctx := context.Background()
+	token, err := db.GetOrCreateUnsubscribeToken(ctx, cfg.ID)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	s.routeHandler(rec, httptest.NewRequest(http.MethodGet, "/unsubscribe/"+token, nil))
+	if !strings.Contains(rec.Body.String(), `name="days" value="30"`) {
+		t.Errorf("expected the page to offer a 30 day snooze, got %s", rec.Body.String())
+	}
+
+	post := func(form string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/unsubscribe/"+token, strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		s.routeHandler(rec, req)
+		return rec
+	}
+
+	if rec := post("action=snooze&days=3"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unlisted period, got %d", rec.Code)
+	}
+
+	if rec := post("action=snooze&days=7"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	updated, _ := db.GetConfigByID(ctx, cfg.ID)
+	if !updated.NextRun.Valid || updated.NextRun.Time.Before(time.Now().AddDate(0, 0, 7)) {
+		t.Errorf("expected next run at least 7 days out, got %+v", updated.NextRun)
+	}
+	if _, err := db.GetConfigByToken(ctx, token); err != nil {
+		t.Errorf("expected the unsubscribe link to survive a snooze, got %v", err)
+	}
+}
+
+func TestFeedReadsCountAsActivity(t *testing.T) {
+	s, db, cfg := newTestServer(t)
+	ctx := context.Background()
+
+	rec := httptest.NewRecorder()
+	s.routeHandler(rec, httptest.NewRequest(http.MethodGet, "/SHA256:abc/feeds.xml", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got, _ := db.GetConfigByID(ctx, cfg.ID); !got.LastActiveAt.Valid {
+		t.Error("expected reading the feed to mark the config active")
+	}
+}
+
+func TestFeedDeliveredOnly(t *testing.T) {
+	s, db, cfg := newTestServer(t)
+
+	ctx := context.Background()
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/a.xml", "", store.FeedOptions{})
+	_ = db.MarkItemSeen(ctx, feed.ID, "preseeded", "Preseeded", "https://example.com/preseeded")
+	tx, _ := db.BeginTx(ctx)
+	_ = db.MarkItemDeliveredTx(ctx, tx, feed.ID, 0, "sent", "Sent", "https://example.com/sent")
+	_ = tx.Commit()
+
+	for _, path := range []string{"/SHA256:abc/feeds.xml", "/SHA256:abc/feeds.json"} {
+		rec := httptest.NewRecorder()
+		s.routeHandler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if body := rec.Body.String(); !strings.Contains(body, "Preseeded") || !strings.Contains(body, "Sent") {
+			t.Errorf("%s: expected both items by default, got %s", path, body)
+		}
+
+		rec = httptest.NewRecorder()
+		s.routeHandler(rec, httptest.NewRequest(http.MethodGet, path+"?delivered=true", nil))
+		if body := rec.Body.String(); strings.Contains(body, "Preseeded") || !strings.Contains(body, "Sent") {
+			t.Errorf("%s?delivered=true: expected only the sent item, got %s", path, body)
+		}
+	}
+}
+
+func TestFeedItemLimit(t *testing.T) {
+	s, db, cfg := newTestServer(t)
+
+	ctx := context.Background()
+	feed, _ := db.CreateFeed(ctx, cfg.ID, "https://example.com/a.xml", "", store.FeedOptions{})
+	for i := 0; i < 60; i++ {
+		guid := fmt.Sprintf("item-%d", i)
+		_ = db.MarkItemSeen(ctx, feed.ID, guid, guid, "https://example.com/"+guid)
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", recentItemsLimit},
+		{"?limit=5", 5},
+		{"?limit=60", 60},
+		{"?limit=500", 60},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.routeHandler(rec, httptest.NewRequest(http.MethodGet, "/SHA256:abc/feeds.json"+tt.query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: expected 200, got %d", tt.query, rec.Code)
+		}
+		var got jsonFeed
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%q: decode feed: %v", tt.query, err)
+		}
+		if len(got.Items) != tt.want {
+			t.Errorf("%q: expected %d items, got %d", tt.query, tt.want, len(got.Items))
+		}
+
+		rec = httptest.NewRecorder()
+		s.routeHandler(rec, httptest.NewRequest(http.MethodGet, "/SHA256:abc/feeds.xml"+tt.query, nil))
+		if n := strings.Count(rec.Body.String(), "<item>"); n != tt.want {
+			t.Errorf("%q: expected %d RSS items, got %d", tt.query, tt.want, n)
+		}
+	}
+
+	for _, query := range []string{"?limit=abc", "?limit=0", "?limit=-5"} {
+		for _, path := range []string{"/SHA256:abc/feeds.json", "/SHA256:abc/feeds.xml"} {
+			rec := httptest.NewRecorder()
+			s.routeHandler(rec, httptest.NewRequest(http.MethodGet, path+query, nil))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s%s: expected 400, got %d", path, query, rec.Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// SetHostKeyPath points the server at the SSH host key so its fingerprint can be published
+func (s *Server) SetHostKeyPath(path string) {
+	s.hostKeyPath = path
+}
+
+// hostKeyFingerprint returns the SHA256 fingerprint and type of the SSH host public key.
+// The key is read on each call since the SSH server may generate it after startup.
+func (s *Server) hostKeyFingerprint() (fingerprint, keyType string, err error) {
+	if s.hostKeyPath == "" {
+		return "", "", fmt.Errorf("host key path not configured")
+	}
+	data, err := os.ReadFile(s.hostKeyPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read host key: %w", err)
+	}
+	signer, err := gossh.ParsePrivateKey(data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse host key: %w", err)
+	}
+	pub := signer.PublicKey()
+	return gossh.FingerprintSHA256(pub), pub.Type(), nil
+}
+
+// handleSSHFingerprint serves the /ssh-fingerprint endpoint
+func (s *Server) handleSSHFingerprint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httpError(w, r, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fingerprint, keyType, err := s.hostKeyFingerprint()
+	if err != nil {
+		s.logger.Warn("failed to fingerprint host key", "err", err)
+		httpError(w, r, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = fmt.Fprintf(w, "%s %s\n", keyType, fingerprint)
+}
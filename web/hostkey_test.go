@@ -0,0 +1,61 @@
+package web
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/log"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestHandleSSHFingerprint(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := gossh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "host_key")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := gossh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{logger: log.New(io.Discard)}
+	s.SetHostKeyPath(path)
+
+	rec := httptest.NewRecorder()
+	s.handleSSHFingerprint(rec, httptest.NewRequest(http.MethodGet, "/ssh-fingerprint", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	want := "ssh-ed25519 " + gossh.FingerprintSHA256(sshPub) + "\n"
+	if rec.Body.String() != want {
+		t.Errorf("expected %q, got %q", want, rec.Body.String())
+	}
+}
+
+func TestHandleSSHFingerprint_MissingKey(t *testing.T) {
+	s := &Server{logger: log.New(io.Discard)}
+	s.SetHostKeyPath(filepath.Join(t.TempDir(), "missing"))
+
+	rec := httptest.NewRecorder()
+	s.handleSSHFingerprint(rec, httptest.NewRequest(http.MethodGet, "/ssh-fingerprint", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", rec.Code)
+	}
+}
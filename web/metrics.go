@@ -19,6 +19,16 @@ type Metrics struct {
 	ConfigsActive  atomic.Uint64
 	ErrorsTotal    atomic.Uint64
 	RateLimitHits  atomic.Uint64
+
+	// Feed fetches answered 304 Not Modified, written by the scheduler
+	FeedsNotModified atomic.Uint64
+	// Digests held back by the per-user email rate limit
+	EmailsRateLimited atomic.Uint64
+
+	// Scheduler health, written by the scheduler after each tick
+	LastTickDuration atomic.Int64 // nanoseconds
+	LastTickConfigs  atomic.Int64
+	OverdueConfigs   atomic.Int64
 }
 
 // NewMetrics creates a new Metrics instance
@@ -28,6 +38,23 @@ func NewMetrics() *Metrics {
 	}
 }
 
+// RecordTick stores the outcome of a scheduler tick
+func (m *Metrics) RecordTick(duration time.Duration, processed, overdue int) {
+	m.LastTickDuration.Store(int64(duration))
+	m.LastTickConfigs.Store(int64(processed))
+	m.OverdueConfigs.Store(int64(overdue))
+}
+
+// RecordNotModified counts feed fetches answered 304 Not Modified
+func (m *Metrics) RecordNotModified(n int) {
+	m.FeedsNotModified.Add(uint64(n))
+}
+
+// RecordRateLimited counts a digest held back by the email rate limit
+func (m *Metrics) RecordRateLimited() {
+	m.EmailsRateLimited.Add(1)
+}
+
 // MetricsSnapshot represents a point-in-time view of metrics
 type MetricsSnapshot struct {
 	// System info
@@ -47,6 +74,14 @@ type MetricsSnapshot struct {
 	ConfigsActive  uint64 `json:"configs_active"`
 	ErrorsTotal    uint64 `json:"errors_total"`
 	RateLimitHits  uint64 `json:"rate_limit_hits"`
+
+	FeedsNotModified  uint64 `json:"feeds_not_modified"`
+	EmailsRateLimited uint64 `json:"emails_rate_limited"`
+
+	// Scheduler metrics
+	LastTickDurationMs int64 `json:"last_tick_duration_ms"`
+	LastTickConfigs    int64 `json:"last_tick_configs"`
+	OverdueConfigs     int64 `json:"overdue_configs"`
 }
 
 // Snapshot creates a snapshot of current metrics
@@ -69,6 +104,13 @@ func (m *Metrics) Snapshot() MetricsSnapshot {
 		ConfigsActive:   m.ConfigsActive.Load(),
 		ErrorsTotal:     m.ErrorsTotal.Load(),
 		RateLimitHits:   m.RateLimitHits.Load(),
+
+		FeedsNotModified:  m.FeedsNotModified.Load(),
+		EmailsRateLimited: m.EmailsRateLimited.Load(),
+
+		LastTickDurationMs: time.Duration(m.LastTickDuration.Load()).Milliseconds(),
+		LastTickConfigs:    m.LastTickConfigs.Load(),
+		OverdueConfigs:     m.OverdueConfigs.Load(),
 	}
 }
 
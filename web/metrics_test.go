@@ -0,0 +1,42 @@
+package web
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMetricsRecordTick(t *testing.T) {
+	m := NewMetrics()
+	m.RecordTick(1500*time.Millisecond, 4, 2)
+
+	snap := m.Snapshot()
+	if snap.LastTickDurationMs != 1500 {
+		t.Errorf("expected 1500ms tick, got %d", snap.LastTickDurationMs)
+	}
+	if snap.LastTickConfigs != 4 {
+		t.Errorf("expected 4 configs, got %d", snap.LastTickConfigs)
+	}
+	if snap.OverdueConfigs != 2 {
+		t.Errorf("expected 2 overdue, got %d", snap.OverdueConfigs)
+	}
+}
+
+func TestMetricsRecordNotModified(t *testing.T) {
+	m := NewMetrics()
+	m.RecordNotModified(2)
+	m.RecordNotModified(3)
+
+	if got := m.Snapshot().FeedsNotModified; got != 5 {
+		t.Errorf("expected 5 not-modified fetches, got %d", got)
+	}
+}
+
+func TestMetricsRecordRateLimited(t *testing.T) {
+	m := NewMetrics()
+	m.RecordRateLimited()
+	m.RecordRateLimited()
+
+	if got := m.Snapshot().EmailsRateLimited; got != 2 {
+		t.Errorf("expected 2 rate-limited digests, got %d", got)
+	}
+}
@@ -0,0 +1,167 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMetricsPushInterval = time.Minute
+	metricsPushTimeout         = 10 * time.Second
+
+	// statsdPrefix namespaces pushed StatsD gauges, e.g. herald.emails_sent
+	statsdPrefix = "herald."
+	// maxStatsDPacket keeps UDP packets under a typical 1500 byte MTU
+	maxStatsDPacket = 1400
+)
+
+// SetMetricsPush periodically sends a metrics snapshot to target: http(s)
+// URLs receive a JSON POST, udp://host:port receives StatsD gauges.
+// An interval <= 0 uses one minute.
+func (s *Server) SetMetricsPush(target string, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultMetricsPushInterval
+	}
+	s.pushURL = target
+	s.pushInterval = interval
+}
+
+// pushMetrics sends metrics on every interval until ctx is cancelled.
+// Failures are logged and retried on the next interval.
+func (s *Server) pushMetrics(ctx context.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("panic in metrics push", "panic", r)
+		}
+	}()
+
+	ticker := time.NewTicker(s.pushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.pushMetricsOnce(ctx); err != nil {
+				s.logger.Warn("failed to push metrics", "url", s.pushURL, "err", err)
+			}
+		}
+	}
+}
+
+func (s *Server) pushMetricsOnce(ctx context.Context) error {
+	u, err := url.Parse(s.pushURL)
+	if err != nil {
+		return fmt.Errorf("parse push url: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, metricsPushTimeout)
+	defer cancel()
+
+	snapshot := s.metrics.Snapshot()
+	switch u.Scheme {
+	case "http", "https":
+		return postMetrics(ctx, s.pushURL, snapshot)
+	case "udp", "statsd":
+		return sendStatsD(ctx, u.Host, snapshot)
+	default:
+		return fmt.Errorf("unsupported push scheme %q", u.Scheme)
+	}
+}
+
+// postMetrics POSTs the snapshot as JSON, the same body /metrics serves
+func postMetrics(ctx context.Context, target string, snapshot MetricsSnapshot) error {
+	body, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("encode metrics: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Herald/1.0")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sendStatsD writes each numeric metric as a StatsD gauge, batching lines
+// into packets that fit a single datagram
+func sendStatsD(ctx context.Context, addr string, snapshot MetricsSnapshot) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	lines, err := statsdLines(snapshot)
+	if err != nil {
+		return err
+	}
+
+	var packet strings.Builder
+	flush := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := conn.Write([]byte(packet.String()))
+		packet.Reset()
+		return err
+	}
+	for _, line := range lines {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxStatsDPacket {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	return flush()
+}
+
+// statsdLines renders the snapshot's numeric fields as sorted StatsD gauges
+// named after their JSON keys
+func statsdLines(snapshot MetricsSnapshot) ([]string, error) {
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("encode metrics: %w", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("decode metrics: %w", err)
+	}
+
+	lines := make([]string, 0, len(fields))
+	for name, value := range fields {
+		n, ok := value.(float64)
+		if !ok {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s%s:%g|g", statsdPrefix, name, n))
+	}
+	sort.Strings(lines)
+	return lines, nil
+}
@@ -0,0 +1,104 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+func TestStatsDLines(t *testing.T) {
+	m := NewMetrics()
+	m.EmailsSent.Add(3)
+	m.RecordTick(250*time.Millisecond, 2, 1)
+
+	lines, err := statsdLines(m.Snapshot())
+	if err != nil {
+		t.Fatalf("statsdLines failed: %v", err)
+	}
+	joined := strings.Join(lines, "\n")
+	for _, want := range []string{"herald.emails_sent:3|g", "herald.last_tick_duration_ms:250|g", "herald.overdue_configs:1|g"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected %q in %q", want, joined)
+		}
+	}
+	if strings.Contains(joined, "go_version") {
+		t.Error("non-numeric metrics should be skipped")
+	}
+}
+
+func TestPushMetricsHTTP(t *testing.T) {
+	got := make(chan MetricsSnapshot, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var snap MetricsSnapshot
+		if err := json.NewDecoder(r.Body).Decode(&snap); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		got <- snap
+	}))
+	defer srv.Close()
+
+	s := &Server{metrics: NewMetrics(), logger: log.New(io.Discard)}
+	s.metrics.EmailsSent.Add(5)
+	s.SetMetricsPush(srv.URL, time.Minute)
+
+	if err := s.pushMetricsOnce(context.Background()); err != nil {
+		t.Fatalf("pushMetricsOnce failed: %v", err)
+	}
+	if snap := <-got; snap.EmailsSent != 5 {
+		t.Errorf("expected 5 emails sent, got %d", snap.EmailsSent)
+	}
+}
+
+func TestPushMetricsStatsD(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer func() { _ = pc.Close() }()
+
+	s := &Server{metrics: NewMetrics(), logger: log.New(io.Discard)}
+	s.SetMetricsPush("udp://"+pc.LocalAddr().String(), time.Minute)
+
+	if err := s.pushMetricsOnce(context.Background()); err != nil {
+		t.Fatalf("pushMetricsOnce failed: %v", err)
+	}
+
+	_ = pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, maxStatsDPacket)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read packet: %v", err)
+	}
+	if !strings.Contains(string(buf[:n]), "herald.uptime_seconds:") {
+		t.Errorf("unexpected packet %q", buf[:n])
+	}
+}
+
+func TestPushMetricsStopsOnCancel(t *testing.T) {
+	s := &Server{metrics: NewMetrics(), logger: log.New(io.Discard)}
+	s.SetMetricsPush("bogus://nowhere", time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.pushMetrics(ctx)
+		close(done)
+	}()
+
+	// Failing pushes are logged, not fatal
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("pushMetrics did not stop after cancel")
+	}
+}
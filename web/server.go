@@ -29,6 +29,7 @@ const (
 
 type Server struct {
 	store       *store.DB
+	reader      *store.DB // read-only pool for pages that never write
 	addr        string
 	origin      string
 	sshPort     int
@@ -37,21 +38,52 @@ type Server struct {
 	commitHash  string
 	rateLimiter *ratelimit.Limiter
 	metrics     *Metrics
+	activity    *activityThrottle
+	tlsCertFile string
+	tlsKeyFile  string
+	hostKeyPath string
+	basePath    string
+	adminToken  string
+
+	// Optional metrics push, see SetMetricsPush
+	pushURL      string
+	pushInterval time.Duration
 }
 
 func NewServer(st *store.DB, addr string, origin string, sshPort int, logger *log.Logger, commitHash string) *Server {
-	tmpl := template.Must(template.ParseFS(templatesFS, "templates/*.html"))
-	return &Server{
+	s := &Server{
 		store:       st,
+		reader:      st.ReadOnly(),
 		addr:        addr,
 		origin:      origin,
 		sshPort:     sshPort,
 		logger:      logger,
-		tmpl:        tmpl,
 		commitHash:  commitHash,
 		rateLimiter: ratelimit.New(httpRequestsPerSecond, httpRateLimiterBurst),
 		metrics:     NewMetrics(),
+		activity:    newActivityThrottle(),
 	}
+	s.tmpl = template.Must(template.New("").Funcs(template.FuncMap{
+		"base": func() string { return s.basePath },
+	}).ParseFS(templatesFS, "templates/*.html"))
+	return s
+}
+
+// SetBasePath serves the web UI under a path prefix such as /herald, for
+// running behind a reverse proxy that doesn't strip it
+func (s *Server) SetBasePath(basePath string) {
+	s.basePath = strings.TrimSuffix(basePath, "/")
+}
+
+// Metrics returns the server's metrics so other components can record into them
+func (s *Server) Metrics() *Metrics {
+	return s.metrics
+}
+
+// SetTLS serves HTTPS with the given certificate and key instead of plain HTTP
+func (s *Server) SetTLS(certFile, keyFile string) {
+	s.tlsCertFile = certFile
+	s.tlsKeyFile = keyFile
 }
 
 func (s *Server) ListenAndServe(ctx context.Context) error {
@@ -60,12 +92,15 @@ func (s *Server) ListenAndServe(ctx context.Context) error {
 	mux.HandleFunc("/", s.routeHandler)
 	mux.HandleFunc("/style.css", s.handleStyleCSS)
 	mux.HandleFunc("/favicon.svg", s.handleFaviconSVG)
+	mux.HandleFunc("/robots.txt", s.handleRobotsTXT)
 	mux.HandleFunc("/health", s.handleHealth)
 	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/ssh-fingerprint", s.handleSSHFingerprint)
+	mux.HandleFunc("/admin/audit/", s.handleEmailAudit)
 
 	srv := &http.Server{
 		Addr:              s.addr,
-		Handler:           s.loggingMiddleware(s.rateLimitMiddleware(mux)),
+		Handler:           s.loggingMiddleware(s.rateLimitMiddleware(s.stripBasePath(mux))),
 		ReadHeaderTimeout: 10 * time.Second,
 	}
 
@@ -74,14 +109,49 @@ func (s *Server) ListenAndServe(ctx context.Context) error {
 		_ = srv.Shutdown(context.Background())
 	}()
 
-	s.logger.Info("web server listening", "addr", s.addr)
-	err := srv.ListenAndServe()
+	if s.pushURL != "" {
+		go s.pushMetrics(ctx)
+	}
+
+	var err error
+	if s.tlsCertFile != "" {
+		s.logger.Info("web server listening", "addr", s.addr, "tls", true)
+		err = srv.ListenAndServeTLS(s.tlsCertFile, s.tlsKeyFile)
+	} else {
+		s.logger.Info("web server listening", "addr", s.addr)
+		err = srv.ListenAndServe()
+	}
 	if err == http.ErrServerClosed {
 		return nil
 	}
 	return err
 }
 
+// stripBasePath removes the base path from request URLs. Requests without
+// it pass through unchanged, so proxies that already strip the prefix work too.
+func (s *Server) stripBasePath(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.basePath == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		path := r.URL.Path
+		if path != s.basePath && !strings.HasPrefix(path, s.basePath+"/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = strings.TrimPrefix(path, s.basePath)
+		if r2.URL.Path == "" {
+			r2.URL.Path = "/"
+		}
+		r2.URL.RawPath = ""
+		next.ServeHTTP(w, r2)
+	})
+}
+
 func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ip, _, err := net.SplitHostPort(r.RemoteAddr)
@@ -92,7 +162,7 @@ func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
 		if !s.rateLimiter.Allow(ip) {
 			s.metrics.RateLimitHits.Add(1)
 			s.logger.Warn("rate limit exceeded", "ip", ip, "path", r.URL.Path)
-			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			httpError(w, r, "Too Many Requests", http.StatusTooManyRequests)
 			return
 		}
 
@@ -149,6 +219,15 @@ func (s *Server) routeHandler(w http.ResponseWriter, r *http.Request) {
 
 	parts := strings.Split(path, "/")
 
+	// Public archives are meant to be shared, so they may be indexed
+	if len(parts) == 2 && parts[0] == "archive" {
+		s.handleArchive(w, r, parts[1])
+		return
+	}
+
+	// Everything else past the landing page is a user, config, feed, or token URL
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+
 	if len(parts) == 2 && parts[0] == "unsubscribe" {
 		s.handleUnsubscribe(w, r, parts[1])
 		return
@@ -159,6 +238,21 @@ func (s *Server) routeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(parts) == 3 && parts[0] == "r" {
+		s.handleItemClick(w, r, parts[1], parts[2])
+		return
+	}
+
+	if len(parts) == 3 && parts[2] == "stats.json" {
+		s.handleConfigStats(w, r, parts[0], parts[1])
+		return
+	}
+
+	if len(parts) == 3 && parts[2] == "logs.json" {
+		s.handleConfigLogs(w, r, parts[0], parts[1])
+		return
+	}
+
 	switch len(parts) {
 	case 1:
 		s.handleUser(w, r, parts[0])
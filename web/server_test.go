@@ -0,0 +1,69 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStripBasePath(t *testing.T) {
+	s := &Server{}
+	s.SetBasePath("/herald/")
+
+	var got string
+	h := s.stripBasePath(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Path
+	}))
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/herald", "/"},
+		{"/herald/", "/"},
+		{"/herald/style.css", "/style.css"},
+		{"/herald/SHA256:abc/feeds.xml", "/SHA256:abc/feeds.xml"},
+		// Already stripped by the proxy
+		{"/style.css", "/style.css"},
+		{"/heraldry", "/heraldry"},
+	}
+	for _, tt := range tests {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if got != tt.expected {
+			t.Errorf("stripBasePath(%q) = %q, expected %q", tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestTemplatesUseBasePath(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	s.SetBasePath("/herald")
+
+	rec := httptest.NewRecorder()
+	s.handleIndex(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	body := rec.Body.String()
+	for _, want := range []string{`href="/herald/style.css"`, `href="/herald/favicon.svg"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected index to contain %s", want)
+		}
+	}
+}
+
+func TestRobots(t *testing.T) {
+	s, _, _ := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	s.handleRobotsTXT(rec, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+	if !strings.Contains(rec.Body.String(), "Disallow: /\n") {
+		t.Errorf("expected robots.txt to disallow everything, got %q", rec.Body.String())
+	}
+
+	for _, path := range []string{"/SHA256:abc", "/SHA256:abc/feeds.xml", "/SHA256:abc/feeds.txt", "/unsubscribe/token"} {
+		rec := httptest.NewRecorder()
+		s.routeHandler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if got := rec.Header().Get("X-Robots-Tag"); !strings.Contains(got, "noindex") {
+			t.Errorf("%s: expected X-Robots-Tag noindex, got %q", path, got)
+		}
+	}
+}